@@ -0,0 +1,114 @@
+// Package avatar turns an uploaded picture into square thumbnails. The
+// pictures are decoded and encoded again, which drops their metadata.
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers the WebP decoder
+)
+
+// MaxPixels bounds the pictures which are decoded, a small file can describe a huge image
+const MaxPixels = 40_000_000
+
+const jpegQuality = 85
+
+var (
+	// ErrUnsupportedType is returned when the content isn't a JPEG, PNG, GIF or WebP picture
+	ErrUnsupportedType = errors.New("unsupported image type")
+	// ErrInvalidImage is returned when the content can't be decoded
+	ErrInvalidImage = errors.New("invalid image")
+	// ErrTooManyPixels is returned when the picture has more than MaxPixels
+	ErrTooManyPixels = errors.New("image has too many pixels")
+)
+
+var acceptedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Thumbnail is the encoded picture at Size by Size pixels
+type Thumbnail struct {
+	Size int
+	Data []byte
+}
+
+// Avatar holds the thumbnails, JPEG unless the picture has transparency
+type Avatar struct {
+	ContentType string
+	Ext         string
+	Thumbnails  []Thumbnail
+}
+
+// Process sniffs and decodes the picture, then crops its center into a square
+// for each of the ascending sizes, upright according to its EXIF orientation
+func Process(data []byte, sizes []int) (*Avatar, error) {
+	if !acceptedTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	orientation := exifOrientation(data)
+
+	// The largest thumbnail is scaled from the picture, the others from it
+	thumbnails := make([]*image.NRGBA, len(sizes))
+	source, sourceRect := src, centerSquare(src.Bounds())
+	for i := len(sizes) - 1; i >= 0; i-- {
+		thumbnail := image.NewNRGBA(image.Rect(0, 0, sizes[i], sizes[i]))
+		xdraw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), source, sourceRect, draw.Src, nil)
+		thumbnails[i] = thumbnail
+		source, sourceRect = thumbnail, thumbnail.Bounds()
+	}
+
+	avatar := &Avatar{ContentType: "image/jpeg", Ext: ".jpg"}
+	if len(thumbnails) > 0 && !thumbnails[len(thumbnails)-1].Opaque() {
+		avatar.ContentType, avatar.Ext = "image/png", ".png"
+	}
+
+	for i, thumbnail := range thumbnails {
+		var buf bytes.Buffer
+		upright := orient(thumbnail, orientation)
+		if avatar.ContentType == "image/png" {
+			err = png.Encode(&buf, upright)
+		} else {
+			err = jpeg.Encode(&buf, upright, &jpeg.Options{Quality: jpegQuality})
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		avatar.Thumbnails = append(avatar.Thumbnails, Thumbnail{Size: sizes[i], Data: buf.Bytes()})
+	}
+
+	return avatar, nil
+}
+
+func centerSquare(bounds image.Rectangle) image.Rectangle {
+	side := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+
+	return image.Rect(x, y, x+side, y+side)
+}
@@ -0,0 +1,109 @@
+package avatar
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+const exifOrientationTag = 0x0112
+
+// exifOrientation reads the orientation of a JPEG from its EXIF segment, 1
+// (upright) when there is none
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		// The metadata segments come before the start of scan
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			return 1
+		}
+
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+
+	return 1
+}
+
+// tiffOrientation finds the orientation tag in the first IFD of a TIFF header
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+			return 1
+		}
+	}
+
+	return 1
+}
+
+// orient flips and rotates the square thumbnail upright
+func orient(img *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	size := img.Bounds().Dx()
+	last := size - 1
+	upright := image.NewNRGBA(img.Bounds())
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = last-x, y
+			case 3:
+				sx, sy = last-x, last-y
+			case 4:
+				sx, sy = x, last-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, last-x
+			case 7:
+				sx, sy = last-y, last-x
+			case 8:
+				sx, sy = last-y, x
+			}
+			copy(upright.Pix[upright.PixOffset(x, y):upright.PixOffset(x, y)+4], img.Pix[img.PixOffset(sx, sy):])
+		}
+	}
+
+	return upright
+}
@@ -1,84 +1,665 @@
 package config
 
 import (
-	"app/src/utils"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
-var (
-	IsProd              bool
-	AppHost             string
-	AppPort             int
-	DBHost              string
-	DBUser              string
-	DBPassword          string
-	DBName              string
-	DBPort              int
-	JWTSecret           string
-	JWTAccessExp        int
-	JWTRefreshExp       int
-	JWTResetPasswordExp int
-	JWTVerifyEmailExp   int
-	SMTPHost            string
-	SMTPPort            int
-	SMTPUsername        string
-	SMTPPassword        string
-	EmailFrom           string
-	GoogleClientID      string
-	GoogleClientSecret  string
-	RedirectURL         string
+// Config is the whole configuration of the app, it is loaded once at startup
+// and handed to the parts that need it
+type Config struct {
+	App         App
+	DB          Database
+	JWT         JWT
+	SMTP        SMTP
+	Google      Google
+	RateLimit   RateLimit
+	Idempotency Idempotency
+	Storage     Storage
+	Avatar      Avatar
+	Privacy     Privacy
+	Import      Import
+	Jobs        Jobs
+	Log         Log
+	Metrics     Metrics
+	Tracing     Tracing
+	Health      Health
+	// File is the config file it was read from, if any
+	File string
+}
+
+type App struct {
+	Env  string
+	Host string
+	Port int
+	// ShutdownDrain is how long readiness fails before the server stops
+	ShutdownDrain time.Duration
+	// ErrorFormat is problem, or legacy for the clients of the former error responses
+	ErrorFormat string
+}
+
+func (a App) IsProd() bool {
+	return a.Env == "prod"
+}
+
+type Database struct {
+	Driver          string
+	Host            string
+	User            string
+	Password        string
+	Name            string
+	Port            int
+	TimeZone        string
+	AutoMigrate     bool
+	ReplicaHosts    []string
+	MaxIdleConns    int
+	MaxOpenConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+type JWT struct {
+	Secret           string
+	AccessExp        time.Duration
+	RefreshExp       time.Duration
+	ResetPasswordExp time.Duration
+	VerifyEmailExp   time.Duration
+}
+
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	// TemplatesDir holds templates overriding the built-in emails
+	TemplatesDir string
+}
+
+type Google struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// RateLimit holds the policies the routes enforce, by name, and where the requests are counted
+type RateLimit struct {
+	Store    string
+	RedisURL string
+	Policies map[string]RateLimitPolicy
+}
+
+// Storage is where the uploaded files are kept
+type Storage struct {
+	// Driver is local or s3
+	Driver string
+	// Dir holds the files of the local driver
+	Dir string
+	S3  S3
+}
+
+// S3 is a bucket of S3 or of a compatible server, PathStyle addresses it as
+// endpoint/bucket instead of bucket.endpoint
+type S3 struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool
+}
+
+// Avatar limits the uploaded avatars, which are resized to each of Sizes
+type Avatar struct {
+	MaxSize int64
+	Sizes   []int
+}
+
+// Privacy sets how long a data export can be downloaded, and how long a
+// confirmed erasure waits, during which the user can cancel it
+type Privacy struct {
+	ExportRetention time.Duration
+	ErasureGrace    time.Duration
+}
+
+// Import limits the files of users imported by the admins. Files with more
+// than SyncRows rows are imported by the import-users job, the request only
+// takes a few since each row hashes a password and may send an email.
+type Import struct {
+	MaxSize  int64
+	MaxRows  int
+	SyncRows int
+	// InvitationExp is how long the link of an invitation sets the password
+	InvitationExp time.Duration
+}
+
+// Idempotency keeps the responses replayed to the retries sent with the same Idempotency-Key for TTL
+type Idempotency struct {
+	TTL time.Duration
+}
+
+type Jobs struct {
+	UserRetention       time.Duration
+	UserPurgeSchedule   string
+	TokenPurgeSchedule  string
+	TokenPurgeBatchSize int
+	// RateLimitPurgeSchedule deletes the expired counters of the database rate limit store
+	RateLimitPurgeSchedule string
+	// IdempotencyPurgeSchedule deletes the expired idempotency keys
+	IdempotencyPurgeSchedule string
+	// DataRequestSchedule builds the requested exports and runs the due erasures
+	DataRequestSchedule string
+	// UserImportSchedule imports the files too large to be imported by the request
+	UserImportSchedule string
+}
+
+type Log struct {
+	Format string
+	Level  string
+}
+
+type Metrics struct {
+	Enabled bool
+	Token   string
+}
+
+type Tracing struct {
+	Enabled     bool
+	ServiceName string
+	Endpoint    string
+	SampleRatio float64
+}
+
+type Health struct {
+	CheckTimeout  time.Duration
+	CacheFor      time.Duration
+	HeapThreshold uint64
+	DiskPath      string
+	DiskMinFree   uint64
+	JobStuckAfter time.Duration
+}
+
+const megabyte = 1024 * 1024
+
+var drivers = []string{"postgres", "mysql", "sqlite"}
+
+const (
+	StorageDriverLocal = "local"
+	StorageDriverS3    = "s3"
 )
 
-func init() {
-	loadConfig()
-
-	// server configuration
-	IsProd = viper.GetString("APP_ENV") == "prod"
-	AppHost = viper.GetString("APP_HOST")
-	AppPort = viper.GetInt("APP_PORT")
-
-	// database configuration
-	DBHost = viper.GetString("DB_HOST")
-	DBUser = viper.GetString("DB_USER")
-	DBPassword = viper.GetString("DB_PASSWORD")
-	DBName = viper.GetString("DB_NAME")
-	DBPort = viper.GetInt("DB_PORT")
-
-	// jwt configuration
-	JWTSecret = viper.GetString("JWT_SECRET")
-	JWTAccessExp = viper.GetInt("JWT_ACCESS_EXP_MINUTES")
-	JWTRefreshExp = viper.GetInt("JWT_REFRESH_EXP_DAYS")
-	JWTResetPasswordExp = viper.GetInt("JWT_RESET_PASSWORD_EXP_MINUTES")
-	JWTVerifyEmailExp = viper.GetInt("JWT_VERIFY_EMAIL_EXP_MINUTES")
-
-	// SMTP configuration
-	SMTPHost = viper.GetString("SMTP_HOST")
-	SMTPPort = viper.GetInt("SMTP_PORT")
-	SMTPUsername = viper.GetString("SMTP_USERNAME")
-	SMTPPassword = viper.GetString("SMTP_PASSWORD")
-	EmailFrom = viper.GetString("EMAIL_FROM")
-
-	// oauth2 configuration
-	GoogleClientID = viper.GetString("GOOGLE_CLIENT_ID")
-	GoogleClientSecret = viper.GetString("GOOGLE_CLIENT_SECRET")
-	RedirectURL = viper.GetString("REDIRECT_URL")
-}
-
-func loadConfig() {
-	configPaths := []string{
-		"./",     // For app
-		"../../", // For test folder
-	}
-
-	for _, path := range configPaths {
-		viper.SetConfigFile(path + ".env")
-
-		if err := viper.ReadInConfig(); err == nil {
-			utils.Log.Infof("Config file loaded from %s", path)
-			return
+const (
+	ErrorFormatProblem = "problem"
+	ErrorFormatLegacy  = "legacy"
+)
+
+// secretKeys can be read from the file at <key>_FILE instead, e.g. a Docker or Kubernetes secret
+var secretKeys = []string{"JWT_SECRET", "DB_PASSWORD", "SMTP_PASSWORD", "GOOGLE_CLIENT_SECRET", "S3_SECRET_ACCESS_KEY"}
+
+// flagKeys maps the command line flags to the keys they override
+var flagKeys = map[string]string{
+	"env":        "APP_ENV",
+	"host":       "APP_HOST",
+	"port":       "APP_PORT",
+	"db-driver":  "DB_DRIVER",
+	"log-format": "LOG_FORMAT",
+	"log-level":  "LOG_LEVEL",
+}
+
+// NewFlagSet defines the command line flags Load reads
+func NewFlagSet(name string) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.String("config", "", "config file (.env, .yaml or .json), defaults to CONFIG_FILE or ./.env")
+	flags.String("env", "", "environment, prod enables prefork")
+	flags.String("host", "", "host to listen on")
+	flags.Int("port", 0, "port to listen on")
+	flags.String("db-driver", "", "database driver: postgres, mysql or sqlite")
+	flags.String("log-format", "", "log format: json or text")
+	flags.String("log-level", "", "log level: trace, debug, info, warn or error")
+
+	return flags
+}
+
+// Load reads the configuration and validates it
+func Load(flags *pflag.FlagSet, files ...string) (*Config, error) {
+	cfg, err := Read(flags, files...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Read reads the configuration from, by precedence, the flags, the environment
+// variables and a config file. The file is the --config flag or CONFIG_FILE,
+// which must exist, else the first of files that exists.
+func Read(flags *pflag.FlagSet, files ...string) (*Config, error) {
+	v := viper.New()
+	v.AutomaticEnv()
+	setDefaults(v)
+
+	if flags != nil {
+		for name, key := range flagKeys {
+			if flag := flags.Lookup(name); flag != nil {
+				if err := v.BindPFlag(key, flag); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	path, err := readConfigFile(v, flags, files)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := readSecretFiles(v); err != nil {
+		return nil, err
+	}
+
+	cfg := build(v)
+	cfg.File = path
+
+	return cfg, nil
+}
+
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("APP_ENV", "dev")
+	v.SetDefault("APP_HOST", "0.0.0.0")
+	v.SetDefault("APP_PORT", 3000)
+	v.SetDefault("ERROR_FORMAT", ErrorFormatProblem)
+	v.SetDefault("DB_DRIVER", "postgres")
+	v.SetDefault("DB_TIMEZONE", "UTC")
+	v.SetDefault("DB_MAX_IDLE_CONNS", 10)
+	v.SetDefault("DB_MAX_OPEN_CONNS", 100)
+	v.SetDefault("DB_CONN_MAX_LIFETIME_MINUTES", 60)
+	v.SetDefault("JWT_ACCESS_EXP_MINUTES", 30)
+	v.SetDefault("JWT_REFRESH_EXP_DAYS", 30)
+	v.SetDefault("JWT_RESET_PASSWORD_EXP_MINUTES", 10)
+	v.SetDefault("JWT_VERIFY_EMAIL_EXP_MINUTES", 10)
+	v.SetDefault("USER_RETENTION_DAYS", 30)
+	v.SetDefault("USER_PURGE_SCHEDULE", "@hourly")
+	v.SetDefault("TOKEN_PURGE_SCHEDULE", "@hourly")
+	v.SetDefault("TOKEN_PURGE_BATCH_SIZE", 1000)
+	v.SetDefault("RATE_LIMIT_STORE", RateLimitStoreMemory)
+	v.SetDefault("RATE_LIMIT_PURGE_SCHEDULE", "@hourly")
+	v.SetDefault("IDEMPOTENCY_TTL_HOURS", 24)
+	v.SetDefault("IDEMPOTENCY_PURGE_SCHEDULE", "@hourly")
+	v.SetDefault("STORAGE_DRIVER", StorageDriverLocal)
+	v.SetDefault("STORAGE_DIR", "uploads")
+	v.SetDefault("S3_REGION", "us-east-1")
+	v.SetDefault("AVATAR_MAX_SIZE_MB", 2)
+	v.SetDefault("AVATAR_SIZES", "64,128,256")
+	v.SetDefault("EXPORT_RETENTION_DAYS", 7)
+	v.SetDefault("ERASURE_GRACE_DAYS", 14)
+	v.SetDefault("DATA_REQUEST_SCHEDULE", "@every 1m")
+	v.SetDefault("USER_IMPORT_MAX_SIZE_MB", 10)
+	v.SetDefault("USER_IMPORT_MAX_ROWS", 10000)
+	v.SetDefault("USER_IMPORT_SYNC_ROWS", 10)
+	v.SetDefault("USER_INVITATION_EXP_DAYS", 7)
+	v.SetDefault("USER_IMPORT_SCHEDULE", "@every 1m")
+	for name, policy := range rateLimitPolicies {
+		v.SetDefault(rateLimitKey(name), formatRateLimit(policy))
+	}
+	v.SetDefault("LOG_FORMAT", "json")
+	v.SetDefault("LOG_LEVEL", "info")
+	v.SetDefault("TRACING_SERVICE_NAME", "go-fiber-boilerplate")
+	v.SetDefault("TRACING_SAMPLE_RATIO", 1)
+	v.SetDefault("HEALTH_CHECK_TIMEOUT_SECONDS", 2)
+	v.SetDefault("HEALTH_CHECK_CACHE_SECONDS", 5)
+	v.SetDefault("HEALTH_HEAP_THRESHOLD_MB", 300)
+	v.SetDefault("HEALTH_DISK_PATH", ".")
+	v.SetDefault("HEALTH_DISK_MIN_FREE_MB", 100)
+	v.SetDefault("HEALTH_JOB_STUCK_MINUTES", 60)
+
+	// The keys without a default are set empty, so Keys lists them too
+	for _, key := range []string{
+		"DB_HOST", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_REPLICA_HOSTS", "JWT_SECRET",
+		"SMTP_HOST", "SMTP_USERNAME", "SMTP_PASSWORD", "EMAIL_FROM", "EMAIL_TEMPLATES_DIR",
+		"GOOGLE_CLIENT_ID", "GOOGLE_CLIENT_SECRET", "REDIRECT_URL", "METRICS_TOKEN", "TRACING_OTLP_ENDPOINT",
+		"RATE_LIMIT_REDIS_URL", "S3_BUCKET", "S3_ENDPOINT", "S3_ACCESS_KEY_ID", "S3_SECRET_ACCESS_KEY",
+	} {
+		v.SetDefault(key, "")
+	}
+	for _, key := range []string{"DB_PORT", "DB_CONN_MAX_IDLE_TIME_MINUTES", "SMTP_PORT", "SHUTDOWN_DRAIN_SECONDS"} {
+		v.SetDefault(key, 0)
+	}
+	for _, key := range []string{"DB_AUTO_MIGRATE", "METRICS_ENABLED", "TRACING_ENABLED", "S3_PATH_STYLE"} {
+		v.SetDefault(key, false)
+	}
+}
+
+// Keys lists every key the config reads, with the CONFIG_FILE and the
+// <key>_FILE of the secrets
+func Keys() []string {
+	v := viper.New()
+	setDefaults(v)
+
+	keys := []string{"CONFIG_FILE"}
+	for _, key := range v.AllKeys() {
+		keys = append(keys, strings.ToUpper(key))
+	}
+	for _, key := range secretKeys {
+		keys = append(keys, key+"_FILE")
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+func readConfigFile(v *viper.Viper, flags *pflag.FlagSet, files []string) (string, error) {
+	path := os.Getenv("CONFIG_FILE")
+	if flags != nil {
+		if flag := flags.Lookup("config"); flag != nil && flag.Changed {
+			path = flag.Value.String()
+		}
+	}
+
+	if path == "" {
+		for _, file := range files {
+			if _, err := os.Stat(file); err == nil {
+				path = file
+				break
+			}
+		}
+	}
+
+	// Environment variables alone are enough, e.g. in containers
+	if path == "" {
+		return "", nil
+	}
+
+	v.SetConfigFile(path)
+	if strings.HasSuffix(path, ".env") {
+		v.SetConfigType("env")
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		return "", fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	return path, nil
+}
+
+func readSecretFiles(v *viper.Viper) error {
+	for _, key := range secretKeys {
+		path := v.GetString(key + "_FILE")
+		if path == "" {
+			continue
+		}
+
+		if v.GetString(key) != "" {
+			return fmt.Errorf("%s and %s_FILE are both set, set only one", key, key)
+		}
+
+		secret, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s_FILE: %w", key, err)
+		}
+
+		// Secret files usually end with a newline
+		v.Set(key, strings.TrimRight(string(secret), "\r\n"))
+	}
+
+	return nil
+}
+
+func build(v *viper.Viper) *Config {
+	return &Config{
+		App: App{
+			Env:           v.GetString("APP_ENV"),
+			Host:          v.GetString("APP_HOST"),
+			Port:          v.GetInt("APP_PORT"),
+			ShutdownDrain: time.Duration(v.GetInt("SHUTDOWN_DRAIN_SECONDS")) * time.Second,
+			ErrorFormat:   v.GetString("ERROR_FORMAT"),
+		},
+		DB: Database{
+			Driver:          v.GetString("DB_DRIVER"),
+			Host:            v.GetString("DB_HOST"),
+			User:            v.GetString("DB_USER"),
+			Password:        v.GetString("DB_PASSWORD"),
+			Name:            v.GetString("DB_NAME"),
+			Port:            v.GetInt("DB_PORT"),
+			TimeZone:        v.GetString("DB_TIMEZONE"),
+			AutoMigrate:     v.GetBool("DB_AUTO_MIGRATE"),
+			ReplicaHosts:    splitList(v.GetString("DB_REPLICA_HOSTS")),
+			MaxIdleConns:    v.GetInt("DB_MAX_IDLE_CONNS"),
+			MaxOpenConns:    v.GetInt("DB_MAX_OPEN_CONNS"),
+			ConnMaxLifetime: time.Duration(v.GetInt("DB_CONN_MAX_LIFETIME_MINUTES")) * time.Minute,
+			ConnMaxIdleTime: time.Duration(v.GetInt("DB_CONN_MAX_IDLE_TIME_MINUTES")) * time.Minute,
+		},
+		JWT: JWT{
+			Secret:           v.GetString("JWT_SECRET"),
+			AccessExp:        time.Duration(v.GetInt("JWT_ACCESS_EXP_MINUTES")) * time.Minute,
+			RefreshExp:       time.Duration(v.GetInt("JWT_REFRESH_EXP_DAYS")) * 24 * time.Hour,
+			ResetPasswordExp: time.Duration(v.GetInt("JWT_RESET_PASSWORD_EXP_MINUTES")) * time.Minute,
+			VerifyEmailExp:   time.Duration(v.GetInt("JWT_VERIFY_EMAIL_EXP_MINUTES")) * time.Minute,
+		},
+		SMTP: SMTP{
+			Host:         v.GetString("SMTP_HOST"),
+			Port:         v.GetInt("SMTP_PORT"),
+			Username:     v.GetString("SMTP_USERNAME"),
+			Password:     v.GetString("SMTP_PASSWORD"),
+			From:         v.GetString("EMAIL_FROM"),
+			TemplatesDir: v.GetString("EMAIL_TEMPLATES_DIR"),
+		},
+		Google: Google{
+			ClientID:     v.GetString("GOOGLE_CLIENT_ID"),
+			ClientSecret: v.GetString("GOOGLE_CLIENT_SECRET"),
+			RedirectURL:  v.GetString("REDIRECT_URL"),
+		},
+		RateLimit: RateLimit{
+			Store:    v.GetString("RATE_LIMIT_STORE"),
+			RedisURL: v.GetString("RATE_LIMIT_REDIS_URL"),
+			Policies: buildRateLimitPolicies(v),
+		},
+		Idempotency: Idempotency{
+			TTL: time.Duration(v.GetInt("IDEMPOTENCY_TTL_HOURS")) * time.Hour,
+		},
+		Storage: Storage{
+			Driver: v.GetString("STORAGE_DRIVER"),
+			Dir:    v.GetString("STORAGE_DIR"),
+			S3: S3{
+				Endpoint:        v.GetString("S3_ENDPOINT"),
+				Region:          v.GetString("S3_REGION"),
+				Bucket:          v.GetString("S3_BUCKET"),
+				AccessKeyID:     v.GetString("S3_ACCESS_KEY_ID"),
+				SecretAccessKey: v.GetString("S3_SECRET_ACCESS_KEY"),
+				PathStyle:       v.GetBool("S3_PATH_STYLE"),
+			},
+		},
+		Avatar: Avatar{
+			MaxSize: v.GetInt64("AVATAR_MAX_SIZE_MB") * megabyte,
+			Sizes:   splitSizes(v.GetString("AVATAR_SIZES")),
+		},
+		Privacy: Privacy{
+			ExportRetention: time.Duration(v.GetInt("EXPORT_RETENTION_DAYS")) * 24 * time.Hour,
+			ErasureGrace:    time.Duration(v.GetInt("ERASURE_GRACE_DAYS")) * 24 * time.Hour,
+		},
+		Import: Import{
+			MaxSize:       v.GetInt64("USER_IMPORT_MAX_SIZE_MB") * megabyte,
+			MaxRows:       v.GetInt("USER_IMPORT_MAX_ROWS"),
+			SyncRows:      v.GetInt("USER_IMPORT_SYNC_ROWS"),
+			InvitationExp: time.Duration(v.GetInt("USER_INVITATION_EXP_DAYS")) * 24 * time.Hour,
+		},
+		Jobs: Jobs{
+			UserRetention:            time.Duration(v.GetInt("USER_RETENTION_DAYS")) * 24 * time.Hour,
+			UserPurgeSchedule:        v.GetString("USER_PURGE_SCHEDULE"),
+			TokenPurgeSchedule:       v.GetString("TOKEN_PURGE_SCHEDULE"),
+			TokenPurgeBatchSize:      v.GetInt("TOKEN_PURGE_BATCH_SIZE"),
+			RateLimitPurgeSchedule:   v.GetString("RATE_LIMIT_PURGE_SCHEDULE"),
+			IdempotencyPurgeSchedule: v.GetString("IDEMPOTENCY_PURGE_SCHEDULE"),
+			DataRequestSchedule:      v.GetString("DATA_REQUEST_SCHEDULE"),
+			UserImportSchedule:       v.GetString("USER_IMPORT_SCHEDULE"),
+		},
+		Log: Log{
+			Format: v.GetString("LOG_FORMAT"),
+			Level:  v.GetString("LOG_LEVEL"),
+		},
+		Metrics: Metrics{
+			Enabled: v.GetBool("METRICS_ENABLED"),
+			Token:   v.GetString("METRICS_TOKEN"),
+		},
+		Tracing: Tracing{
+			Enabled:     v.GetBool("TRACING_ENABLED"),
+			ServiceName: v.GetString("TRACING_SERVICE_NAME"),
+			Endpoint:    v.GetString("TRACING_OTLP_ENDPOINT"),
+			SampleRatio: v.GetFloat64("TRACING_SAMPLE_RATIO"),
+		},
+		Health: Health{
+			CheckTimeout:  time.Duration(v.GetInt("HEALTH_CHECK_TIMEOUT_SECONDS")) * time.Second,
+			CacheFor:      time.Duration(v.GetInt("HEALTH_CHECK_CACHE_SECONDS")) * time.Second,
+			HeapThreshold: v.GetUint64("HEALTH_HEAP_THRESHOLD_MB") * megabyte,
+			DiskPath:      v.GetString("HEALTH_DISK_PATH"),
+			DiskMinFree:   v.GetUint64("HEALTH_DISK_MIN_FREE_MB") * megabyte,
+			JobStuckAfter: time.Duration(v.GetInt("HEALTH_JOB_STUCK_MINUTES")) * time.Minute,
+		},
+	}
+}
+
+// Validate reports every invalid setting at once, by the name of its variable
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
 		}
 	}
 
-	utils.Log.Error("Failed to load any config file")
+	check(c.App.Port > 0 && c.App.Port <= 65535, "APP_PORT must be between 1 and 65535, got %d", c.App.Port)
+	check(c.App.ShutdownDrain >= 0, "SHUTDOWN_DRAIN_SECONDS must not be negative")
+	check(c.App.ErrorFormat == ErrorFormatProblem || c.App.ErrorFormat == ErrorFormatLegacy,
+		"ERROR_FORMAT must be problem or legacy, got %q", c.App.ErrorFormat)
+
+	check(slices.Contains(drivers, c.DB.Driver),
+		"DB_DRIVER must be one of %s, got %q", strings.Join(drivers, ", "), c.DB.Driver)
+	check(c.DB.Name != "", "DB_NAME is required")
+	if c.DB.Driver != "sqlite" {
+		check(c.DB.Host != "", "DB_HOST is required")
+		check(c.DB.Port > 0 && c.DB.Port <= 65535, "DB_PORT must be between 1 and 65535, got %d", c.DB.Port)
+	}
+
+	check(c.JWT.Secret != "", "JWT_SECRET is required")
+	check(c.JWT.AccessExp > 0, "JWT_ACCESS_EXP_MINUTES must be positive")
+	check(c.JWT.RefreshExp > 0, "JWT_REFRESH_EXP_DAYS must be positive")
+	check(c.JWT.ResetPasswordExp > 0, "JWT_RESET_PASSWORD_EXP_MINUTES must be positive")
+	check(c.JWT.VerifyEmailExp > 0, "JWT_VERIFY_EMAIL_EXP_MINUTES must be positive")
+
+	if c.SMTP.Host != "" {
+		check(c.SMTP.Port > 0 && c.SMTP.Port <= 65535, "SMTP_PORT must be between 1 and 65535, got %d", c.SMTP.Port)
+		check(c.SMTP.From != "", "EMAIL_FROM is required when SMTP_HOST is set")
+	}
+
+	if c.Google.ClientID != "" {
+		check(c.Google.ClientSecret != "", "GOOGLE_CLIENT_SECRET is required when GOOGLE_CLIENT_ID is set")
+		check(c.Google.RedirectURL != "", "REDIRECT_URL is required when GOOGLE_CLIENT_ID is set")
+	}
+
+	check(slices.Contains(rateLimitStores, c.RateLimit.Store), "RATE_LIMIT_STORE must be one of %s, got %q",
+		strings.Join(rateLimitStores, ", "), c.RateLimit.Store)
+	if c.RateLimit.Store == RateLimitStoreRedis {
+		check(c.RateLimit.RedisURL != "", "RATE_LIMIT_REDIS_URL is required when RATE_LIMIT_STORE is redis")
+	}
+	names := make([]string, 0, len(c.RateLimit.Policies))
+	for name := range c.RateLimit.Policies {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		policy := c.RateLimit.Policies[name]
+		check(policy.Max > 0 && policy.Window >= time.Second,
+			"%s must be a positive number of requests per window of at least 1s, e.g. 5/15m", rateLimitKey(name))
+	}
+
+	check(c.Idempotency.TTL > 0, "IDEMPOTENCY_TTL_HOURS must be positive")
+
+	check(c.Storage.Driver == StorageDriverLocal || c.Storage.Driver == StorageDriverS3,
+		"STORAGE_DRIVER must be local or s3, got %q", c.Storage.Driver)
+	if c.Storage.Driver == StorageDriverLocal {
+		check(c.Storage.Dir != "", "STORAGE_DIR is required when STORAGE_DRIVER is local")
+	}
+	if c.Storage.Driver == StorageDriverS3 {
+		check(c.Storage.S3.Endpoint != "", "S3_ENDPOINT is required when STORAGE_DRIVER is s3")
+		check(c.Storage.S3.Bucket != "", "S3_BUCKET is required when STORAGE_DRIVER is s3")
+		check(c.Storage.S3.AccessKeyID != "" && c.Storage.S3.SecretAccessKey != "",
+			"S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required when STORAGE_DRIVER is s3")
+	}
+
+	check(c.Avatar.MaxSize > 0, "AVATAR_MAX_SIZE_MB must be positive")
+	check(len(c.Avatar.Sizes) > 0, "AVATAR_SIZES must list the sizes in pixels, e.g. 64,128,256")
+	for _, size := range c.Avatar.Sizes {
+		check(size >= 16 && size <= 1024, "AVATAR_SIZES must be between 16 and 1024 pixels, got %d", size)
+	}
+
+	check(c.Privacy.ExportRetention > 0, "EXPORT_RETENTION_DAYS must be positive")
+	check(c.Privacy.ErasureGrace >= 0, "ERASURE_GRACE_DAYS must not be negative")
+
+	check(c.Import.MaxSize > 0, "USER_IMPORT_MAX_SIZE_MB must be positive")
+	check(c.Import.MaxRows > 0, "USER_IMPORT_MAX_ROWS must be positive")
+	check(c.Import.SyncRows >= 0 && c.Import.SyncRows <= c.Import.MaxRows,
+		"USER_IMPORT_SYNC_ROWS must be between 0 and USER_IMPORT_MAX_ROWS")
+	check(c.Import.InvitationExp > 0, "USER_INVITATION_EXP_DAYS must be positive")
+
+	check(c.Jobs.UserRetention >= 0, "USER_RETENTION_DAYS must not be negative")
+	check(c.Jobs.TokenPurgeBatchSize > 0, "TOKEN_PURGE_BATCH_SIZE must be positive")
+
+	check(c.Log.Format == "json" || c.Log.Format == "text", "LOG_FORMAT must be json or text, got %q", c.Log.Format)
+	_, err := logrus.ParseLevel(c.Log.Level)
+	check(err == nil, "LOG_LEVEL %q is not a log level", c.Log.Level)
+
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+
+	check(c.Health.CheckTimeout > 0, "HEALTH_CHECK_TIMEOUT_SECONDS must be positive")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func buildRateLimitPolicies(v *viper.Viper) map[string]RateLimitPolicy {
+	policies := make(map[string]RateLimitPolicy, len(rateLimitPolicies))
+	for name, policy := range rateLimitPolicies {
+		policy.Max, policy.Window = parseRateLimit(v.GetString(rateLimitKey(name)))
+		policies[name] = policy
+	}
+	return policies
+}
+
+// splitList parses a comma separated value, ignoring empty entries
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// splitSizes parses a list like 64,128,256 in ascending order, the sizes which
+// aren't numbers are 0 so Validate reports them
+func splitSizes(value string) []int {
+	var sizes []int
+	for _, item := range splitList(value) {
+		size, _ := strconv.Atoi(item)
+		sizes = append(sizes, size)
+	}
+	slices.Sort(sizes)
+
+	return slices.Compact(sizes)
 }
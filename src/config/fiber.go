@@ -7,14 +7,24 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func FiberConfig() fiber.Config {
+// uploadFormOverhead is the room left for the multipart encoding around an uploaded avatar or import
+const uploadFormOverhead = megabyte
+
+func FiberConfig(cfg *Config) fiber.Config {
+	errorHandler := utils.ErrorHandler
+	if cfg.App.ErrorFormat == ErrorFormatLegacy {
+		errorHandler = utils.LegacyErrorHandler
+	}
+
 	return fiber.Config{
-		Prefork:       IsProd,
+		Prefork:       cfg.App.IsProd(),
 		CaseSensitive: true,
 		ServerHeader:  "Fiber",
 		AppName:       "Fiber API",
-		ErrorHandler:  utils.ErrorHandler,
+		ErrorHandler:  errorHandler,
 		JSONEncoder:   sonic.Marshal,
 		JSONDecoder:   sonic.Unmarshal,
+		BodyLimit: max(fiber.DefaultBodyLimit, int(cfg.Avatar.MaxSize+uploadFormOverhead),
+			int(cfg.Import.MaxSize+uploadFormOverhead)),
 	}
 }
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"app/src/utils"
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce groups the events of a single save, editors and Kubernetes
+// write a file in several steps
+const reloadDebounce = 200 * time.Millisecond
+
+// Live holds the config the app runs with. Reload swaps in the settings that
+// are safe to change at runtime: rate limits, log level, token lifetimes and
+// email templates. The others need a restart.
+type Live struct {
+	current   atomic.Pointer[Config]
+	load      func() (*Config, error)
+	mu        sync.Mutex
+	listeners []func(*Config)
+}
+
+// NewLive holds cfg, load reads the config again on Reload
+func NewLive(cfg *Config, load func() (*Config, error)) *Live {
+	live := &Live{load: load}
+	live.current.Store(cfg)
+
+	return live
+}
+
+// Get returns the current config, which must not be modified
+func (l *Live) Get() *Config {
+	return l.current.Load()
+}
+
+// OnReload calls fn with the new config after every reload
+func (l *Live) OnReload(fn func(*Config)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.listeners = append(l.listeners, fn)
+}
+
+// Reload reads the config again and swaps in its reloadable settings.
+// An invalid config is rejected as a whole and the current one is kept.
+func (l *Live) Reload() error {
+	if l.load == nil {
+		return errors.New("config can't be reloaded")
+	}
+
+	loaded, err := l.load()
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	next := *l.Get()
+	next.RateLimit.Policies = loaded.RateLimit.Policies
+	next.Log.Level = loaded.Log.Level
+	next.JWT.AccessExp = loaded.JWT.AccessExp
+	next.JWT.RefreshExp = loaded.JWT.RefreshExp
+	next.JWT.ResetPasswordExp = loaded.JWT.ResetPasswordExp
+	next.JWT.VerifyEmailExp = loaded.JWT.VerifyEmailExp
+	next.SMTP.TemplatesDir = loaded.SMTP.TemplatesDir
+
+	if !reflect.DeepEqual(next, *loaded) {
+		utils.Log.Warn("Config reloaded, the changes to settings other than rate limits, log level, " +
+			"token lifetimes and email templates apply after a restart")
+	}
+
+	l.current.Store(&next)
+	for _, listener := range l.listeners {
+		listener(&next)
+	}
+
+	return nil
+}
+
+// Watch reloads the config on SIGHUP and when one of paths changes, until ctx is done.
+// Paths are files or directories, the parent directory of a file is watched so the
+// file can be replaced, as Kubernetes does for mounted config maps.
+func (l *Live) Watch(ctx context.Context, paths ...string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	watched := make(map[string]bool)
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return errors.Join(err, watcher.Close())
+		}
+		watched[path] = true
+
+		dir := path
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			dir = filepath.Dir(path)
+		}
+		if err := watcher.Add(dir); err != nil {
+			return errors.Join(err, watcher.Close())
+		}
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hangup)
+		defer watcher.Close()
+
+		reload := func(reason string) {
+			if err := l.Reload(); err != nil {
+				utils.Log.Errorf("Failed to reload config after %s, keeping the current one: %v", reason, err)
+				return
+			}
+			utils.Log.Infof("Config reloaded after %s", reason)
+		}
+
+		// A stopped timer, reset by the file events
+		debounce := time.NewTimer(time.Hour)
+		debounce.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangup:
+				reload("SIGHUP")
+			case event := <-watcher.Events:
+				if watched[event.Name] || watched[filepath.Dir(event.Name)] || isSymlinkSwap(event.Name) {
+					debounce.Reset(reloadDebounce)
+				}
+			case <-debounce.C:
+				reload("a file change")
+			case err := <-watcher.Errors:
+				utils.Log.Errorf("Failed to watch config files: %v", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// isSymlinkSwap reports the ..data symlink Kubernetes swaps to update a mounted volume
+func isSymlinkSwap(name string) bool {
+	return filepath.Base(name) == "..data"
+}
@@ -5,23 +5,16 @@ import (
 	"golang.org/x/oauth2/google"
 )
 
-type Config struct {
-	GoogleLoginConfig oauth2.Config
-}
-
-var AppConfig Config
-
-func GoogleConfig() oauth2.Config {
-	AppConfig.GoogleLoginConfig = oauth2.Config{
-		RedirectURL:  RedirectURL,
-		ClientID:     GoogleClientID,
-		ClientSecret: GoogleClientSecret,
+// OAuth2 returns the config of the Google login flow
+func (g Google) OAuth2() *oauth2.Config {
+	return &oauth2.Config{
+		RedirectURL:  g.RedirectURL,
+		ClientID:     g.ClientID,
+		ClientSecret: g.ClientSecret,
 		Scopes: []string{
 			"https://www.googleapis.com/auth/userinfo.email",
 			"https://www.googleapis.com/auth/userinfo.profile",
 		},
 		Endpoint: google.Endpoint,
 	}
-
-	return AppConfig.GoogleLoginConfig
 }
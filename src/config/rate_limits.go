@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate limit policies, the routes refer to them by name
+const (
+	RateLimitAuth              = "auth"
+	RateLimitLogin             = "login"
+	RateLimitForgotPassword    = "forgot-password"
+	RateLimitVerificationEmail = "verification-email"
+)
+
+// The requests of a policy are counted per client IP, per user or per email of the request body
+const (
+	RateLimitByIP    = "ip"
+	RateLimitByUser  = "user"
+	RateLimitByEmail = "email"
+)
+
+const (
+	RateLimitStoreMemory   = "memory"
+	RateLimitStoreDatabase = "database"
+	RateLimitStoreRedis    = "redis"
+)
+
+var rateLimitStores = []string{RateLimitStoreMemory, RateLimitStoreDatabase, RateLimitStoreRedis}
+
+// RateLimitPolicy allows Max requests sharing a key per Window
+type RateLimitPolicy struct {
+	Key    string
+	Max    int
+	Window time.Duration
+	// FailedOnly counts the requests answered with an error status only
+	FailedOnly bool
+}
+
+// rateLimitPolicies are the defaults, RATE_LIMIT_<NAME> overrides the limit, e.g. RATE_LIMIT_LOGIN=5/15m
+var rateLimitPolicies = map[string]RateLimitPolicy{
+	RateLimitAuth:              {Key: RateLimitByIP, Max: 20, Window: 15 * time.Minute, FailedOnly: true},
+	RateLimitLogin:             {Key: RateLimitByEmail, Max: 5, Window: 15 * time.Minute, FailedOnly: true},
+	RateLimitForgotPassword:    {Key: RateLimitByEmail, Max: 3, Window: time.Hour},
+	RateLimitVerificationEmail: {Key: RateLimitByUser, Max: 3, Window: time.Hour},
+}
+
+// rateLimitKey is the variable overriding the limit of a policy
+func rateLimitKey(name string) string {
+	return "RATE_LIMIT_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// parseRateLimit reads a limit like 5/15m, a zero limit is returned for an invalid one
+func parseRateLimit(value string) (int, time.Duration) {
+	count, period, found := strings.Cut(strings.TrimSpace(value), "/")
+	if !found {
+		return 0, 0
+	}
+
+	limit, err := strconv.Atoi(count)
+	if err != nil {
+		return 0, 0
+	}
+
+	window, err := time.ParseDuration(period)
+	if err != nil {
+		return 0, 0
+	}
+
+	return limit, window
+}
+
+func formatRateLimit(policy RateLimitPolicy) string {
+	return fmt.Sprintf("%d/%s", policy.Max, policy.Window)
+}
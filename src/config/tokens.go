@@ -5,4 +5,9 @@ const (
 	TokenTypeRefresh       = "refresh"
 	TokenTypeResetPassword = "resetPassword"
 	TokenTypeVerifyEmail   = "verifyEmail"
+	// TokenTypeConfirmErasure confirms the erasure of an account, it expires like TokenTypeVerifyEmail
+	TokenTypeConfirmErasure = "confirmErasure"
+	// TokenTypeReauth stands in for the password of an account created with Google, it
+	// expires like TokenTypeVerifyEmail
+	TokenTypeReauth = "reauth"
 )
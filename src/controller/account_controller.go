@@ -0,0 +1,294 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/problem"
+	"app/src/response"
+	"app/src/service"
+	"app/src/utils"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccountController struct {
+	AccountService service.AccountService
+	TokenService   service.TokenService
+	EmailService   service.EmailService
+}
+
+func NewAccountController(
+	accountService service.AccountService, tokenService service.TokenService, emailService service.EmailService,
+) *AccountController {
+	return &AccountController{
+		AccountService: accountService,
+		TokenService:   tokenService,
+		EmailService:   emailService,
+	}
+}
+
+// @Tags         Account
+// @Summary      Get my profile
+// @Description  Returns the signed in user.
+// @Security BearerAuth
+// @Produce      json
+// @Param        If-None-Match  header  string  false  "ETag of a cached copy, answered with 304 while it is current"
+// @Router       /me [get]
+// @Success      200  {object}  example.GetUserResponse
+// @Header       200  {string}  ETag  "Version of the user, sent back in If-Match"
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+func (a *AccountController) GetMe(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	c.Set(fiber.HeaderETag, etag(user.Version))
+	if c.Fresh() {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithUser{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Get user successfully",
+			User:    *user,
+		})
+}
+
+// @Tags         Account
+// @Summary      Update my profile
+// @Description  Updates the name and the language, the email and the password have their own routes.
+// @Security BearerAuth
+// @Produce      json
+// @Param        request  body  validation.UpdateProfile  true  "Request body"
+// @Param        If-Match  header  string  false  "ETag the user must still have, else 412"
+// @Router       /me [patch]
+// @Success      200  {object}  example.UpdateUserResponse
+// @Header       200  {string}  ETag  "Version of the updated user"
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      412  {object}  example.PreconditionFailed  "User was modified"
+func (a *AccountController) UpdateMe(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+	req := new(validation.UpdateProfile)
+
+	if err := c.BodyParser(req); err != nil {
+		return problem.ErrInvalidRequestBody
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		return err
+	}
+
+	updated, err := a.AccountService.UpdateProfile(c.UserContext(), user, req, version)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderETag, etag(updated.Version))
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithUser{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Update user successfully",
+			User:    *updated,
+		})
+}
+
+// @Tags         Account
+// @Summary      Change my password
+// @Description  Requires the current password. Every session is signed out, new tokens are returned for this one.
+// @Description  An account created with Google has no password, it sets one with forgot-password.
+// @Security BearerAuth
+// @Produce      json
+// @Param        request  body  validation.ChangePassword  true  "Request body"
+// @Router       /me/password [post]
+// @Success      200  {object}  example.ChangePasswordResponse
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      403  {object}  example.CurrentPasswordInvalid  "Current password is incorrect"
+func (a *AccountController) ChangePassword(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+	req := new(validation.ChangePassword)
+
+	if err := c.BodyParser(req); err != nil {
+		return problem.ErrInvalidRequestBody
+	}
+
+	if err := a.AccountService.ChangePassword(c.UserContext(), user, req); err != nil {
+		return err
+	}
+
+	tokens, err := a.TokenService.GenerateAuthTokens(c.UserContext(), user)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithTokens{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Change password successfully",
+			User:    *user,
+			Tokens:  *tokens,
+		})
+}
+
+// @Tags         Account
+// @Summary      Change my email
+// @Description  Requires the current password, or the reauth_token of an account created with Google. The new
+// @Description  email must be verified, a link is sent to it.
+// @Security BearerAuth
+// @Produce      json
+// @Param        request  body  validation.ChangeEmail  true  "Request body"
+// @Param        If-Match  header  string  false  "ETag the user must still have, else 412"
+// @Router       /me/email [post]
+// @Success      200  {object}  example.ChangeEmailResponse
+// @Header       200  {string}  ETag  "Version of the updated user"
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      403  {object}  example.CurrentPasswordInvalid  "Current password is incorrect or reauth required"
+// @Failure      409  {object}  example.DuplicateEmail  "Email already taken"
+// @Failure      412  {object}  example.PreconditionFailed  "User was modified"
+func (a *AccountController) ChangeEmail(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+	req := new(validation.ChangeEmail)
+
+	if err := c.BodyParser(req); err != nil {
+		return problem.ErrInvalidRequestBody
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		return err
+	}
+
+	updated, err := a.AccountService.ChangeEmail(c.UserContext(), user, req, version)
+	if err != nil {
+		return err
+	}
+
+	// The email is changed either way, the link can be sent again with send-verification-email
+	if err := a.sendVerificationEmail(c, updated); err != nil {
+		utils.Logger(c.UserContext()).Errorf("Failed to send the verification of the new email: %+v", err)
+	}
+
+	c.Set(fiber.HeaderETag, etag(updated.Version))
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithUser{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Change email successfully, please check your email for a link to verify it",
+			User:    *updated,
+		})
+}
+
+// @Tags         Account
+// @Summary      Delete my account
+// @Description  Requires the email of the account in confirm, and the current password or the reauth_token of an
+// @Description  account created with Google. Every session is signed out.
+// @Security BearerAuth
+// @Produce      json
+// @Param        request  body  validation.DeleteAccount  true  "Request body"
+// @Param        If-Match  header  string  false  "ETag the user must still have, else 412"
+// @Router       /me [delete]
+// @Success      200  {object}  example.DeleteUserResponse
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      403  {object}  example.CurrentPasswordInvalid  "Current password is incorrect or reauth required"
+// @Failure      412  {object}  example.PreconditionFailed  "User was modified"
+// @Failure      422  {object}  example.ConfirmationMismatch  "Confirm doesn't match"
+func (a *AccountController) DeleteMe(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+	req := new(validation.DeleteAccount)
+
+	if err := c.BodyParser(req); err != nil {
+		return problem.ErrInvalidRequestBody
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		return err
+	}
+
+	if err := a.AccountService.DeleteAccount(c.UserContext(), user, req, version); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Delete user successfully",
+		})
+}
+
+// @Tags         Account
+// @Summary      Confirm it is me
+// @Description  Emails a link with a reauth_token. An account created with Google has no password, it sends the
+// @Description  token in its place to change its email, delete itself or request its erasure. It is used once.
+// @Security BearerAuth
+// @Produce      json
+// @Router       /me/reauth [post]
+// @Success      200  {object}  example.ReauthResponse
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+func (a *AccountController) Reauth(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	reauthToken, err := a.TokenService.GenerateReauthToken(c.UserContext(), user)
+	if err != nil {
+		return err
+	}
+
+	if err := a.EmailService.SendReauthEmail(c.UserContext(), user.Email, reauthToken); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Please check your email for a link confirming it is you",
+		})
+}
+
+// @Tags         Account
+// @Summary      Get my sessions
+// @Description  Lists the signed in clients, the ones which can refresh their tokens.
+// @Security BearerAuth
+// @Produce      json
+// @Router       /me/sessions [get]
+// @Success      200  {object}  example.GetSessionsResponse
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+func (a *AccountController) GetSessions(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	tokens, err := a.AccountService.GetSessions(c.UserContext(), user)
+	if err != nil {
+		return err
+	}
+
+	sessions := make([]response.Session, 0, len(tokens))
+	for _, token := range tokens {
+		sessions = append(sessions, response.Session{
+			ID:        token.ID,
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.Expires,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithSessions{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Get sessions successfully",
+			Results: sessions,
+		})
+}
+
+func (a *AccountController) sendVerificationEmail(c *fiber.Ctx, user *model.User) error {
+	verifyEmailToken, err := a.TokenService.GenerateVerifyEmailToken(c.UserContext(), user)
+	if err != nil {
+		return err
+	}
+
+	return a.EmailService.SendVerificationEmail(c.UserContext(), user.Email, *verifyEmailToken)
+}
@@ -2,9 +2,12 @@ package controller
 
 import (
 	"app/src/config"
+	"app/src/metrics"
 	"app/src/model"
+	"app/src/problem"
 	"app/src/response"
 	"app/src/service"
+	"app/src/tracing"
 	"app/src/validation"
 	"context"
 	"encoding/json"
@@ -13,24 +16,32 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"golang.org/x/oauth2"
 )
 
+// googleUserInfoURL returns the profile of the user of a Google access token
+const googleUserInfoURL = "https://www.googleapis.com/oauth2/v2/userinfo"
+
 type AuthController struct {
-	AuthService  service.AuthService
-	UserService  service.UserService
-	TokenService service.TokenService
-	EmailService service.EmailService
+	AuthService       service.AuthService
+	UserService       service.UserService
+	TokenService      service.TokenService
+	EmailService      service.EmailService
+	GoogleConfig      *oauth2.Config
+	GoogleUserInfoURL string
 }
 
 func NewAuthController(
 	authService service.AuthService, userService service.UserService,
-	tokenService service.TokenService, emailService service.EmailService,
+	tokenService service.TokenService, emailService service.EmailService, google config.Google,
 ) *AuthController {
 	return &AuthController{
-		AuthService:  authService,
-		UserService:  userService,
-		TokenService: tokenService,
-		EmailService: emailService,
+		AuthService:       authService,
+		UserService:       userService,
+		TokenService:      tokenService,
+		EmailService:      emailService,
+		GoogleConfig:      google.OAuth2(),
+		GoogleUserInfoURL: googleUserInfoURL,
 	}
 }
 
@@ -39,22 +50,24 @@ func NewAuthController(
 // @Accept       json
 // @Produce      json
 // @Param        request  body  validation.Register  true  "Request body"
+// @Param        Idempotency-Key  header  string  false  "Replays the response of the first request sent with the key"
 // @Router       /auth/register [post]
 // @Success      201  {object}  example.RegisterResponse
 // @Failure      409  {object}  example.DuplicateEmail  "Email already taken"
+// @Failure      422  {object}  example.IdempotencyKeyReused  "Idempotency-Key reused"
 func (a *AuthController) Register(c *fiber.Ctx) error {
 	req := new(validation.Register)
 
 	if err := c.BodyParser(req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		return problem.ErrInvalidRequestBody
 	}
 
-	user, err := a.AuthService.Register(c, req)
+	user, err := a.AuthService.Register(c.UserContext(), req)
 	if err != nil {
 		return err
 	}
 
-	tokens, err := a.TokenService.GenerateAuthTokens(c, user)
+	tokens, err := a.TokenService.GenerateAuthTokens(c.UserContext(), user)
 	if err != nil {
 		return err
 	}
@@ -81,15 +94,15 @@ func (a *AuthController) Login(c *fiber.Ctx) error {
 	req := new(validation.Login)
 
 	if err := c.BodyParser(req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		return problem.ErrInvalidRequestBody
 	}
 
-	user, err := a.AuthService.Login(c, req)
+	user, err := a.AuthService.Login(c.UserContext(), req)
 	if err != nil {
 		return err
 	}
 
-	tokens, err := a.TokenService.GenerateAuthTokens(c, user)
+	tokens, err := a.TokenService.GenerateAuthTokens(c.UserContext(), user)
 	if err != nil {
 		return err
 	}
@@ -116,10 +129,10 @@ func (a *AuthController) Logout(c *fiber.Ctx) error {
 	req := new(validation.Logout)
 
 	if err := c.BodyParser(req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		return problem.ErrInvalidRequestBody
 	}
 
-	if err := a.AuthService.Logout(c, req); err != nil {
+	if err := a.AuthService.Logout(c.UserContext(), req); err != nil {
 		return err
 	}
 
@@ -143,10 +156,10 @@ func (a *AuthController) RefreshTokens(c *fiber.Ctx) error {
 	req := new(validation.RefreshToken)
 
 	if err := c.BodyParser(req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		return problem.ErrInvalidRequestBody
 	}
 
-	tokens, err := a.AuthService.RefreshAuth(c, req)
+	tokens, err := a.AuthService.RefreshAuth(c.UserContext(), req)
 	if err != nil {
 		return err
 	}
@@ -172,15 +185,15 @@ func (a *AuthController) ForgotPassword(c *fiber.Ctx) error {
 	req := new(validation.ForgotPassword)
 
 	if err := c.BodyParser(req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		return problem.ErrInvalidRequestBody
 	}
 
-	resetPasswordToken, err := a.TokenService.GenerateResetPasswordToken(c, req)
+	resetPasswordToken, err := a.TokenService.GenerateResetPasswordToken(c.UserContext(), req)
 	if err != nil {
 		return err
 	}
 
-	if errEmail := a.EmailService.SendResetPasswordEmail(req.Email, resetPasswordToken); errEmail != nil {
+	if errEmail := a.EmailService.SendResetPasswordEmail(c.UserContext(), req.Email, resetPasswordToken); errEmail != nil {
 		return errEmail
 	}
 
@@ -208,10 +221,10 @@ func (a *AuthController) ResetPassword(c *fiber.Ctx) error {
 	}
 
 	if err := c.BodyParser(req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		return problem.ErrInvalidRequestBody
 	}
 
-	if err := a.AuthService.ResetPassword(c, query, req); err != nil {
+	if err := a.AuthService.ResetPassword(c.UserContext(), query, req); err != nil {
 		return err
 	}
 
@@ -234,12 +247,12 @@ func (a *AuthController) ResetPassword(c *fiber.Ctx) error {
 func (a *AuthController) SendVerificationEmail(c *fiber.Ctx) error {
 	user, _ := c.Locals("user").(*model.User)
 
-	verifyEmailToken, err := a.TokenService.GenerateVerifyEmailToken(c, user)
+	verifyEmailToken, err := a.TokenService.GenerateVerifyEmailToken(c.UserContext(), user)
 	if err != nil {
 		return err
 	}
 
-	if errEmail := a.EmailService.SendVerificationEmail(user.Email, *verifyEmailToken); errEmail != nil {
+	if errEmail := a.EmailService.SendVerificationEmail(c.UserContext(), user.Email, *verifyEmailToken); errEmail != nil {
 		return errEmail
 	}
 
@@ -263,7 +276,7 @@ func (a *AuthController) VerifyEmail(c *fiber.Ctx) error {
 		Token: c.Query("token"),
 	}
 
-	if err := a.AuthService.VerifyEmail(c, query); err != nil {
+	if err := a.AuthService.VerifyEmail(c.UserContext(), query); err != nil {
 		return err
 	}
 
@@ -290,37 +303,38 @@ func (a *AuthController) GoogleLogin(c *fiber.Ctx) error {
 		MaxAge: 30,
 	})
 
-	url := config.AppConfig.GoogleLoginConfig.AuthCodeURL(state)
+	url := a.GoogleConfig.AuthCodeURL(state)
 
 	return c.Status(fiber.StatusSeeOther).Redirect(url)
 }
 
-func (a *AuthController) GoogleCallback(c *fiber.Ctx) error {
+func (a *AuthController) GoogleCallback(c *fiber.Ctx) (err error) {
+	defer func() { metrics.ObserveLogin(metrics.LoginGoogle, err) }()
+
 	state := c.Query("state")
 	storedState := c.Cookies("oauth_state")
 
 	if state != storedState {
-		return fiber.NewError(fiber.StatusUnauthorized, "States don't Match!")
+		return problem.ErrOAuthStateMismatch
 	}
 
 	code := c.Query("code")
-	googlecon := config.GoogleConfig()
 
-	token, err := googlecon.Exchange(context.Background(), code)
+	// The token exchange and the userinfo call go through the traced client
+	ctx := context.WithValue(c.UserContext(), oauth2.HTTPClient, tracing.HTTPClient)
+
+	token, err := a.GoogleConfig.Exchange(ctx, code)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(
-		c.Context(), http.MethodGet,
-		"https://www.googleapis.com/oauth2/v2/userinfo?access_token="+token.AccessToken,
-		nil,
-	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.GoogleUserInfoURL, nil)
 	if err != nil {
 		return err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	// The token goes in the Authorization header, the spans record the URL
+	resp, err := a.GoogleConfig.Client(ctx, token).Do(req)
 	if err != nil {
 		return err
 	}
@@ -336,12 +350,12 @@ func (a *AuthController) GoogleCallback(c *fiber.Ctx) error {
 		return errJSON
 	}
 
-	user, err := a.UserService.CreateGoogleUser(c, googleUser)
+	user, err := a.UserService.CreateGoogleUser(c.UserContext(), googleUser)
 	if err != nil {
 		return err
 	}
 
-	tokens, err := a.TokenService.GenerateAuthTokens(c, user)
+	tokens, err := a.TokenService.GenerateAuthTokens(c.UserContext(), user)
 	if err != nil {
 		return err
 	}
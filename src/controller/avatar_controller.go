@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"app/src/problem"
+	"app/src/response"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AvatarController struct {
+	AvatarService service.AvatarService
+}
+
+func NewAvatarController(avatarService service.AvatarService) *AvatarController {
+	return &AvatarController{
+		AvatarService: avatarService,
+	}
+}
+
+// @Tags         Users
+// @Summary      Upload the avatar of a user
+// @Description  Logged in users can upload only their own avatar. Only admins can upload the avatar of other users.
+// @Description  The picture is cropped to a square and resized, its metadata is dropped.
+// @Security BearerAuth
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id  path  string  true  "User id"
+// @Param        avatar  formData  file  true  "JPEG, PNG, GIF or WebP picture"
+// @Param        If-Match  header  string  false  "ETag the user must still have, else 412"
+// @Router       /users/{id}/avatar [put]
+// @Success      200  {object}  example.UpdateAvatarResponse
+// @Failure      400  {object}  example.AvatarMissing  "Avatar file missing"
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      403  {object}  example.Forbidden  "Forbidden"
+// @Failure      404  {object}  example.NotFound  "Not found"
+// @Failure      412  {object}  example.PreconditionFailed  "User was modified"
+// @Failure      413  {object}  example.AvatarTooLarge  "Avatar too large"
+// @Failure      415  {object}  example.AvatarUnsupportedType  "Unsupported picture type"
+// @Failure      422  {object}  example.AvatarInvalid  "Invalid picture"
+func (a *AvatarController) UpdateAvatar(c *fiber.Ctx) error {
+	userID := c.Params("userId")
+
+	if _, err := uuid.Parse(userID); err != nil {
+		return problem.ErrInvalidUserID
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		return err
+	}
+
+	header, err := c.FormFile("avatar")
+	if err != nil {
+		return problem.ErrAvatarMissing
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	user, err := a.AvatarService.SetAvatar(c.UserContext(), userID, file, version)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderETag, etag(user.Version))
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithUser{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Update avatar successfully",
+			User:    *user,
+		})
+}
+
+// @Tags         Users
+// @Summary      Delete the avatar of a user
+// @Description  Logged in users can delete only their own avatar. Only admins can delete the avatar of other users.
+// @Security BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "User id"
+// @Param        If-Match  header  string  false  "ETag the user must still have, else 412"
+// @Router       /users/{id}/avatar [delete]
+// @Success      200  {object}  example.DeleteAvatarResponse
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      403  {object}  example.Forbidden  "Forbidden"
+// @Failure      404  {object}  example.AvatarNotFound  "Not found"
+// @Failure      412  {object}  example.PreconditionFailed  "User was modified"
+func (a *AvatarController) DeleteAvatar(c *fiber.Ctx) error {
+	userID := c.Params("userId")
+
+	if _, err := uuid.Parse(userID); err != nil {
+		return problem.ErrInvalidUserID
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.AvatarService.DeleteAvatar(c.UserContext(), userID, version)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderETag, etag(user.Version))
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithUser{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Delete avatar successfully",
+			User:    *user,
+		})
+}
+
+// @Tags         Avatars
+// @Summary      Get an avatar
+// @Description  Serves the avatar_url of the users. A new upload gets a new URL, so the avatars are cached for good.
+// @Produce      image/jpeg,image/png
+// @Param        name  path  string  true  "File name of the avatar"
+// @Param        size  query  int  false  "Size of the thumbnail in pixels, one of AVATAR_SIZES, the largest by default"
+// @Router       /avatars/{name} [get]
+// @Success      200  {file}  binary
+// @Failure      404  {object}  example.AvatarNotFound  "Not found"
+func (a *AvatarController) GetAvatar(c *fiber.Ctx) error {
+	body, contentType, err := a.AvatarService.GetAvatar(c.UserContext(), c.Params("name"), c.QueryInt("size"))
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderCacheControl, "public, max-age=31536000, immutable")
+	// Unlike the API, the avatars are embedded by other origins
+	c.Set("Cross-Origin-Resource-Policy", "cross-origin")
+
+	return c.SendStream(body)
+}
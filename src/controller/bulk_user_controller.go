@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/problem"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type BulkUserController struct {
+	BulkUserService service.BulkUserService
+}
+
+func NewBulkUserController(bulkUserService service.BulkUserService) *BulkUserController {
+	return &BulkUserController{
+		BulkUserService: bulkUserService,
+	}
+}
+
+// @Tags         Users
+// @Summary      Import users from a CSV or NDJSON file
+// @Description  Only admins can import users. Each row is validated like a created user and reported in results.
+// @Description  A CSV file has a header naming its columns among name, email, password, role and language.
+// @Description  With invite, the users get an email to set their password, which the rows may then leave out.
+// @Description  Files with more than USER_IMPORT_SYNC_ROWS rows are imported in the background, get the import
+// @Description  to follow it.
+// @Security BearerAuth
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file  formData  file  true  "CSV or NDJSON file"
+// @Param        format  formData  string  false  "Format of the file, guessed from its extension by default"  Enums(csv, ndjson)
+// @Param        invite  formData  bool  false  "Email the created users an invitation"
+// @Param        Idempotency-Key  header  string  false  "Replays the response of the first request sent with the key"
+// @Router       /users/import [post]
+// @Success      200  {object}  example.ImportUsersResponse
+// @Success      202  {object}  example.ImportUsersAcceptedResponse
+// @Failure      400  {object}  example.ImportMissing  "Import file missing"
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      403  {object}  example.Forbidden  "Forbidden"
+// @Failure      413  {object}  example.ImportTooLarge  "Import file too large"
+// @Failure      415  {object}  example.ImportUnsupportedType  "Unsupported file type"
+// @Failure      422  {object}  example.ImportInvalid  "No users in the file"
+func (b *BulkUserController) ImportUsers(c *fiber.Ctx) error {
+	admin, _ := c.Locals("user").(*model.User)
+	req := new(validation.ImportUsers)
+
+	if err := c.BodyParser(req); err != nil {
+		return problem.ErrInvalidRequestBody
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return problem.ErrImportMissing
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	userImport, err := b.BulkUserService.ImportUsers(c.UserContext(), admin, req, header.Filename, file)
+	if err != nil {
+		return err
+	}
+
+	if userImport.Status == model.UserImportPending {
+		c.Location("/v1/users/imports/" + userImport.ID.String())
+
+		return c.Status(fiber.StatusAccepted).
+			JSON(response.SuccessWithUserImport{
+				Code:       fiber.StatusAccepted,
+				Status:     "success",
+				Message:    "Import accepted, get it to follow its progress",
+				UserImport: *userImport,
+			})
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithUserImport{
+			Code:       fiber.StatusOK,
+			Status:     "success",
+			Message:    "Import users successfully",
+			UserImport: *userImport,
+		})
+}
+
+// @Tags         Users
+// @Summary      Get an import of users
+// @Description  Only admins can get the imports. The results are set once the import is completed.
+// @Security BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "Import id"
+// @Router       /users/imports/{id} [get]
+// @Success      200  {object}  example.GetImportResponse
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      403  {object}  example.Forbidden  "Forbidden"
+// @Failure      404  {object}  example.ImportNotFound  "Not found"
+func (b *BulkUserController) GetImport(c *fiber.Ctx) error {
+	userImport, err := b.BulkUserService.GetImport(c.UserContext(), c.Params("importId"))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithUserImport{
+			Code:       fiber.StatusOK,
+			Status:     "success",
+			Message:    "Get import successfully",
+			UserImport: *userImport,
+		})
+}
+
+// @Tags         Users
+// @Summary      Export users as CSV or NDJSON
+// @Description  Only admins can export users. Takes the search, sort and filters of the list of users and
+// @Description  streams every matching user, the CSV columns are the fields of the users.
+// @Security BearerAuth
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Param        format   query     string  false  "Format of the file"  Enums(csv, ndjson)  default(csv)
+// @Param        search   query     string  false  "Search by name or email or role, ranked by relevance unless sortBy is set"
+// @Param        sortBy   query     string  false  "Sort column"  Enums(relevance, created_at, name, email, role)  default(created_at)
+// @Param        order    query     string  false  "Sort order"  Enums(asc, desc)  default(asc)
+// @Param        role     query     string  false  "Filter by role"  Enums(user, admin)
+// @Param        verified_email  query  bool  false  "Filter by verified email"
+// @Router       /users/export [get]
+// @Success      200  {file}  binary
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      403  {object}  example.Forbidden  "Forbidden"
+func (b *BulkUserController) ExportUsers(c *fiber.Ctx) error {
+	query := &validation.ExportUsers{
+		Format:        c.Query("format", ""),
+		Search:        c.Query("search", ""),
+		SortBy:        c.Query("sortBy", ""),
+		Order:         c.Query("order", ""),
+		Role:          c.Query("role", ""),
+		VerifiedEmail: c.Query("verified_email", ""),
+	}
+
+	body, contentType, err := b.BulkUserService.ExportUsers(c.UserContext(), query)
+	if err != nil {
+		return err
+	}
+
+	extension := "csv"
+	if query.Format != "" {
+		extension = query.Format
+	}
+
+	c.Attachment("users." + extension)
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	return c.SendStream(body)
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"app/src/problem"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// etag is the strong entity tag of a row at version
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatch returns the version of the first strong tag of the If-Match header,
+// 0 when any version is accepted. Weak or unknown tags never match, so a
+// header without a valid tag fails with 412.
+func ifMatch(c *fiber.Ctx) (int64, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+
+		if version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64); err == nil && version > 0 {
+			return version, nil
+		}
+	}
+
+	return 0, problem.ErrIfMatchMismatch
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"app/src/health"
 	"app/src/response"
 	"app/src/service"
 
@@ -17,23 +18,6 @@ func NewHealthCheckController(healthCheckService service.HealthCheckService) *He
 	}
 }
 
-func (h *HealthCheckController) addServiceStatus(
-	serviceList *[]response.HealthCheck, name string, isUp bool, message *string,
-) {
-	status := "Up"
-
-	if !isUp {
-		status = "Down"
-	}
-
-	*serviceList = append(*serviceList, response.HealthCheck{
-		Name:    name,
-		Status:  status,
-		IsUp:    isUp,
-		Message: message,
-	})
-}
-
 // @Tags Health
 // @Summary Health Check
 // @Description Check the status of services and database connections
@@ -43,31 +27,32 @@ func (h *HealthCheckController) addServiceStatus(
 // @Failure 500 {object} example.HealthCheckResponseError
 // @Router /health-check [get]
 func (h *HealthCheckController) Check(c *fiber.Ctx) error {
-	isHealthy := true
-	var serviceList []response.HealthCheck
-
-	// Check the database connection
-	if err := h.HealthCheckService.GormCheck(); err != nil {
-		isHealthy = false
-		errMsg := err.Error()
-		h.addServiceStatus(&serviceList, "Postgre", false, &errMsg)
-	} else {
-		h.addServiceStatus(&serviceList, "Postgre", true, nil)
-	}
+	report := h.HealthCheckService.Readiness(c.UserContext())
+
+	serviceList := make([]response.HealthCheck, 0, len(report.Results))
+	for _, result := range report.Results {
+		status := "Up"
+		var message *string
+
+		if !result.Up {
+			status = "Down"
+			errMsg := result.Error
+			message = &errMsg
+		}
 
-	if err := h.HealthCheckService.MemoryHeapCheck(); err != nil {
-		isHealthy = false
-		errMsg := err.Error()
-		h.addServiceStatus(&serviceList, "Memory", false, &errMsg)
-	} else {
-		h.addServiceStatus(&serviceList, "Memory", true, nil)
+		serviceList = append(serviceList, response.HealthCheck{
+			Name:    result.Name,
+			Status:  status,
+			IsUp:    result.Up,
+			Message: message,
+		})
 	}
 
 	// Return the response based on health check result
 	statusCode := fiber.StatusOK
 	status := "success"
 
-	if !isHealthy {
+	if !report.Healthy {
 		statusCode = fiber.StatusInternalServerError
 		status = "error"
 	}
@@ -76,7 +61,34 @@ func (h *HealthCheckController) Check(c *fiber.Ctx) error {
 		Status:    status,
 		Message:   "Health check completed",
 		Code:      statusCode,
-		IsHealthy: isHealthy,
+		IsHealthy: report.Healthy,
 		Result:    serviceList,
 	})
 }
+
+// Livez tells the orchestrator whether the process must be restarted
+func (h *HealthCheckController) Livez(c *fiber.Ctx) error {
+	return probe(c, h.HealthCheckService.Liveness(c.UserContext()))
+}
+
+// Readyz tells the load balancer whether the process can take traffic
+func (h *HealthCheckController) Readyz(c *fiber.Ctx) error {
+	return probe(c, h.HealthCheckService.Readiness(c.UserContext()))
+}
+
+func probe(c *fiber.Ctx, report health.Report) error {
+	statusCode := fiber.StatusOK
+	status := "ok"
+
+	if !report.Healthy {
+		statusCode = fiber.StatusServiceUnavailable
+		status = "fail"
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	return c.Status(statusCode).JSON(response.ProbeResponse{
+		Status: status,
+		Checks: report.Results,
+	})
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type JobController struct {
+	JobService service.JobService
+}
+
+func NewJobController(jobService service.JobService) *JobController {
+	return &JobController{
+		JobService: jobService,
+	}
+}
+
+// @Tags         Jobs
+// @Summary      Get job runs
+// @Description  Only admins can retrieve the run history of background jobs, latest first.
+// @Security BearerAuth
+// @Produce      json
+// @Param        page     query     int     false   "Page number"  default(1)
+// @Param        limit    query     int     false   "Maximum number of runs"    default(20)
+// @Param        cursor   query     string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        name     query     string  false  "Filter by job name"
+// @Param        status   query     string  false  "Filter by status"  Enums(running, succeeded, failed)
+// @Router       /jobs/runs [get]
+// @Success      200  {object}  example.GetJobRunsResponse
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      403  {object}  example.Forbidden  "Forbidden"
+func (j *JobController) GetJobRuns(c *fiber.Ctx) error {
+	cursor := c.Query("cursor", "")
+	query := &validation.QueryJobRun{
+		Page:   c.QueryInt("page", 1),
+		Limit:  c.QueryInt("limit", 20),
+		Cursor: cursor,
+		Name:   c.Query("name", ""),
+		Status: c.Query("status", ""),
+	}
+
+	result, err := j.JobService.GetJobRuns(c.UserContext(), query)
+	if err != nil {
+		return err
+	}
+
+	res := response.SuccessWithPaginate[model.JobRun]{
+		Code:       fiber.StatusOK,
+		Status:     "success",
+		Message:    "Get job runs successfully",
+		Results:    result.Items,
+		Limit:      query.Limit,
+		NextCursor: result.NextCursor,
+	}
+
+	if cursor == "" {
+		res.Page = query.Page
+	}
+
+	return c.Status(fiber.StatusOK).JSON(res)
+}
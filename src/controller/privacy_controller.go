@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/problem"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PrivacyController struct {
+	PrivacyService service.PrivacyService
+}
+
+func NewPrivacyController(privacyService service.PrivacyService) *PrivacyController {
+	return &PrivacyController{
+		PrivacyService: privacyService,
+	}
+}
+
+// @Tags         Privacy
+// @Summary      Request an export of my data
+// @Description  The export is built in the background, a link to download it is emailed once it is ready.
+// @Description  An export not built yet is returned instead of a new one.
+// @Security BearerAuth
+// @Produce      json
+// @Router       /me/exports [post]
+// @Success      202  {object}  example.RequestExportResponse
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+func (p *PrivacyController) RequestExport(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	request, err := p.PrivacyService.RequestExport(c.UserContext(), user)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).
+		JSON(response.SuccessWithDataRequest{
+			Code:        fiber.StatusAccepted,
+			Status:      "success",
+			Message:     "Export requested, a link to download it will be emailed once it is ready",
+			DataRequest: *request,
+		})
+}
+
+// @Tags         Privacy
+// @Summary      Download an export of my data
+// @Description  A ZIP archive with export.json and the avatar, until expires_at.
+// @Security BearerAuth
+// @Produce      application/zip
+// @Param        id  path  string  true  "Export id"
+// @Router       /me/exports/{id} [get]
+// @Success      200  {file}  binary
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      404  {object}  example.ExportNotFound  "Not found"
+// @Failure      409  {object}  example.ExportNotReady  "Not ready yet"
+func (p *PrivacyController) GetExport(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	body, err := p.PrivacyService.GetExport(c.UserContext(), user, c.Params("exportId"))
+	if err != nil {
+		return err
+	}
+
+	c.Attachment("export.zip")
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	return c.SendStream(body)
+}
+
+// @Tags         Privacy
+// @Summary      Get my data requests
+// @Description  Lists the exports and the erasures requested by the signed in user, the newest first.
+// @Security BearerAuth
+// @Produce      json
+// @Router       /me/data-requests [get]
+// @Success      200  {object}  example.GetDataRequestsResponse
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+func (p *PrivacyController) GetDataRequests(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	requests, err := p.PrivacyService.GetDataRequests(c.UserContext(), user)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithDataRequests{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Get data requests successfully",
+			Results: requests,
+		})
+}
+
+// @Tags         Privacy
+// @Summary      Request the erasure of my account
+// @Description  Requires the email of the account in confirm, and the current password or the reauth_token of an
+// @Description  account created with Google. A link confirming the erasure is emailed, the account is erased
+// @Description  ERASURE_GRACE_DAYS after it is used.
+// @Security BearerAuth
+// @Produce      json
+// @Param        request  body  validation.RequestErasure  true  "Request body"
+// @Router       /me/erasure [post]
+// @Success      202  {object}  example.RequestErasureResponse
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      403  {object}  example.CurrentPasswordInvalid  "Current password is incorrect or reauth required"
+// @Failure      409  {object}  example.ErasureScheduled  "Erasure already scheduled"
+// @Failure      422  {object}  example.ConfirmationMismatch  "Confirm doesn't match"
+func (p *PrivacyController) RequestErasure(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+	req := new(validation.RequestErasure)
+
+	if err := c.BodyParser(req); err != nil {
+		return problem.ErrInvalidRequestBody
+	}
+
+	request, err := p.PrivacyService.RequestErasure(c.UserContext(), user, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).
+		JSON(response.SuccessWithDataRequest{
+			Code:        fiber.StatusAccepted,
+			Status:      "success",
+			Message:     "Erasure requested, please check your email for a link to confirm it",
+			DataRequest: *request,
+		})
+}
+
+// @Tags         Privacy
+// @Summary      Confirm the erasure of my account
+// @Description  Schedules the erasure at scheduled_at, until then it can be cancelled.
+// @Security BearerAuth
+// @Produce      json
+// @Param        token  query  string  true  "The confirm erasure token"
+// @Router       /me/erasure/confirm [post]
+// @Success      200  {object}  example.ConfirmErasureResponse
+// @Failure      401  {object}  example.InvalidToken  "Invalid token"
+// @Failure      404  {object}  example.ErasureNotFound  "No erasure requested"
+// @Failure      409  {object}  example.ErasureScheduled  "Erasure already scheduled"
+func (p *PrivacyController) ConfirmErasure(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+	query := &validation.Token{
+		Token: c.Query("token"),
+	}
+
+	request, err := p.PrivacyService.ConfirmErasure(c.UserContext(), user, query)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithDataRequest{
+			Code:        fiber.StatusOK,
+			Status:      "success",
+			Message:     "Erasure confirmed successfully",
+			DataRequest: *request,
+		})
+}
+
+// @Tags         Privacy
+// @Summary      Cancel the erasure of my account
+// @Description  Cancels the erasure, confirmed or not, until it runs.
+// @Security BearerAuth
+// @Produce      json
+// @Router       /me/erasure [delete]
+// @Success      200  {object}  example.CancelErasureResponse
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      404  {object}  example.ErasureNotFound  "No erasure requested"
+// @Failure      409  {object}  example.ErasureRunning  "Erasure running"
+func (p *PrivacyController) CancelErasure(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	request, err := p.PrivacyService.CancelErasure(c.UserContext(), user)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithDataRequest{
+			Code:        fiber.StatusOK,
+			Status:      "success",
+			Message:     "Erasure cancelled successfully",
+			DataRequest: *request,
+		})
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"app/src/problem"
+	"app/src/response"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ProblemController struct{}
+
+func NewProblemController() *ProblemController {
+	return &ProblemController{}
+}
+
+// @Tags         Problems
+// @Summary      Get the problem types
+// @Description  Lists the codes of the errors returned by the API, by status.
+// @Produce      json
+// @Router       /problems [get]
+// @Success      200  {object}  example.GetProblemTypesResponse
+func (p *ProblemController) GetProblemTypes(c *fiber.Ctx) error {
+	all := problem.All()
+
+	types := make([]response.ProblemType, 0, len(all))
+	for _, e := range all {
+		types = append(types, problemType(e))
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithProblemTypes{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Get problem types successfully",
+			Results: types,
+		})
+}
+
+// @Tags         Problems
+// @Summary      Get a problem type
+// @Description  Describes the code of an error, the type of a problem resolves here.
+// @Produce      json
+// @Param        code  path  string  true  "Code of the error"
+// @Router       /problems/{code} [get]
+// @Success      200  {object}  example.GetProblemTypeResponse
+// @Failure      404  {object}  example.ProblemTypeNotFound  "Not found"
+func (p *ProblemController) GetProblemType(c *fiber.Ctx) error {
+	e, ok := problem.Lookup(c.Params("code"))
+	if !ok {
+		return problem.ErrProblemNotFound
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithProblemType{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Get problem type successfully",
+			Problem: problemType(e),
+		})
+}
+
+func problemType(e *problem.Error) response.ProblemType {
+	return response.ProblemType{
+		Type:   e.Type(),
+		Code:   e.Code,
+		Title:  e.Title,
+		Status: e.Status,
+	}
+}
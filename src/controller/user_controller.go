@@ -2,6 +2,7 @@ package controller
 
 import (
 	"app/src/model"
+	"app/src/problem"
 	"app/src/response"
 	"app/src/service"
 	"app/src/validation"
@@ -25,39 +26,61 @@ func NewUserController(userService service.UserService, tokenService service.Tok
 
 // @Tags         Users
 // @Summary      Get all users
-// @Description  Only admins can retrieve all users.
+// @Description  Only admins can retrieve all users. Follow next_cursor to get the next page,
+// @Description  the total is counted on the first page unless include_total is set.
 // @Security BearerAuth
 // @Produce      json
-// @Param        page     query     int     false   "Page number"  default(1)
+// @Param        page     query     int     false   "Page number, prefer cursor for deep pages"  default(1)
 // @Param        limit    query     int     false   "Maximum number of users"    default(10)
-// @Param        search   query     string  false  "Search by name or email or role"
+// @Param        search   query     string  false  "Search by name or email or role, ranked by relevance unless sortBy is set"
+// @Param        cursor   query     string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        sortBy   query     string  false  "Sort column"  Enums(relevance, created_at, name, email, role)  default(created_at)
+// @Param        order    query     string  false  "Sort order"  Enums(asc, desc)  default(asc)
+// @Param        role     query     string  false  "Filter by role"  Enums(user, admin)
+// @Param        verified_email  query  bool  false  "Filter by verified email"
+// @Param        include_total   query  bool  false  "Count the total results"
 // @Router       /users [get]
 // @Success      200  {object}  example.GetAllUserResponse
 // @Failure      401  {object}  example.Unauthorized  "Unauthorized"
 // @Failure      403  {object}  example.Forbidden  "Forbidden"
 func (u *UserController) GetUsers(c *fiber.Ctx) error {
+	cursor := c.Query("cursor", "")
 	query := &validation.QueryUser{
-		Page:   c.QueryInt("page", 1),
-		Limit:  c.QueryInt("limit", 10),
-		Search: c.Query("search", ""),
+		Page:          c.QueryInt("page", 1),
+		Limit:         c.QueryInt("limit", 10),
+		Search:        c.Query("search", ""),
+		Cursor:        cursor,
+		SortBy:        c.Query("sortBy", ""),
+		Order:         c.Query("order", ""),
+		Role:          c.Query("role", ""),
+		VerifiedEmail: c.Query("verified_email", ""),
+		IncludeTotal:  c.QueryBool("include_total", cursor == ""),
 	}
 
-	users, totalResults, err := u.UserService.GetUsers(c, query)
+	result, err := u.UserService.GetUsers(c.UserContext(), query)
 	if err != nil {
 		return err
 	}
 
-	return c.Status(fiber.StatusOK).
-		JSON(response.SuccessWithPaginate[model.User]{
-			Code:         fiber.StatusOK,
-			Status:       "success",
-			Message:      "Get all users successfully",
-			Results:      users,
-			Page:         query.Page,
-			Limit:        query.Limit,
-			TotalPages:   int64(math.Ceil(float64(totalResults) / float64(query.Limit))),
-			TotalResults: totalResults,
-		})
+	res := response.SuccessWithPaginate[model.User]{
+		Code:       fiber.StatusOK,
+		Status:     "success",
+		Message:    "Get all users successfully",
+		Results:    result.Items,
+		Limit:      query.Limit,
+		NextCursor: result.NextCursor,
+	}
+
+	if cursor == "" {
+		res.Page = query.Page
+	}
+
+	if result.Total != nil {
+		res.TotalResults = *result.Total
+		res.TotalPages = int64(math.Ceil(float64(*result.Total) / float64(query.Limit)))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(res)
 }
 
 // @Tags         Users
@@ -66,8 +89,11 @@ func (u *UserController) GetUsers(c *fiber.Ctx) error {
 // @Security BearerAuth
 // @Produce      json
 // @Param        id  path  string  true  "User id"
+// @Param        If-None-Match  header  string  false  "ETag of a cached copy, answered with 304 while it is current"
 // @Router       /users/{id} [get]
 // @Success      200  {object}  example.GetUserResponse
+// @Header       200  {string}  ETag  "Version of the user, sent back in If-Match"
+// @Success      304  "Not Modified"
 // @Failure      401  {object}  example.Unauthorized  "Unauthorized"
 // @Failure      403  {object}  example.Forbidden  "Forbidden"
 // @Failure      404  {object}  example.NotFound  "Not found"
@@ -75,14 +101,19 @@ func (u *UserController) GetUserByID(c *fiber.Ctx) error {
 	userID := c.Params("userId")
 
 	if _, err := uuid.Parse(userID); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+		return problem.ErrInvalidUserID
 	}
 
-	user, err := u.UserService.GetUserByID(c, userID)
+	user, err := u.UserService.GetUserByID(c.UserContext(), userID)
 	if err != nil {
 		return err
 	}
 
+	c.Set(fiber.HeaderETag, etag(user.Version))
+	if c.Fresh() {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
 	return c.Status(fiber.StatusOK).
 		JSON(response.SuccessWithUser{
 			Code:    fiber.StatusOK,
@@ -98,19 +129,21 @@ func (u *UserController) GetUserByID(c *fiber.Ctx) error {
 // @Security BearerAuth
 // @Produce      json
 // @Param        request  body  validation.CreateUser  true  "Request body"
+// @Param        Idempotency-Key  header  string  false  "Replays the response of the first request sent with the key"
 // @Router       /users [post]
 // @Success      201  {object}  example.CreateUserResponse
 // @Failure      401  {object}  example.Unauthorized  "Unauthorized"
 // @Failure      403  {object}  example.Forbidden  "Forbidden"
 // @Failure      409  {object}  example.DuplicateEmail  "Email already taken"
+// @Failure      422  {object}  example.IdempotencyKeyReused  "Idempotency-Key reused"
 func (u *UserController) CreateUser(c *fiber.Ctx) error {
 	req := new(validation.CreateUser)
 
 	if err := c.BodyParser(req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		return problem.ErrInvalidRequestBody
 	}
 
-	user, err := u.UserService.CreateUser(c, req)
+	user, err := u.UserService.CreateUser(c.UserContext(), req)
 	if err != nil {
 		return err
 	}
@@ -131,29 +164,39 @@ func (u *UserController) CreateUser(c *fiber.Ctx) error {
 // @Produce      json
 // @Param        id  path  string  true  "User id"
 // @Param        request  body  validation.UpdateUser  true  "Request body"
+// @Param        If-Match  header  string  false  "ETag the user must still have, else 412"
 // @Router       /users/{id} [patch]
 // @Success      200  {object}  example.UpdateUserResponse
+// @Header       200  {string}  ETag  "Version of the updated user"
 // @Failure      401  {object}  example.Unauthorized  "Unauthorized"
 // @Failure      403  {object}  example.Forbidden  "Forbidden"
 // @Failure      404  {object}  example.NotFound  "Not found"
 // @Failure      409  {object}  example.DuplicateEmail  "Email already taken"
+// @Failure      412  {object}  example.PreconditionFailed  "User was modified"
 func (u *UserController) UpdateUser(c *fiber.Ctx) error {
 	req := new(validation.UpdateUser)
 	userID := c.Params("userId")
 
 	if _, err := uuid.Parse(userID); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+		return problem.ErrInvalidUserID
 	}
 
 	if err := c.BodyParser(req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		return problem.ErrInvalidRequestBody
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		return err
 	}
 
-	user, err := u.UserService.UpdateUser(c, req, userID)
+	user, err := u.UserService.UpdateUser(c.UserContext(), req, userID, version)
 	if err != nil {
 		return err
 	}
 
+	c.Set(fiber.HeaderETag, etag(user.Version))
+
 	return c.Status(fiber.StatusOK).
 		JSON(response.SuccessWithUser{
 			Code:    fiber.StatusOK,
@@ -169,23 +212,31 @@ func (u *UserController) UpdateUser(c *fiber.Ctx) error {
 // @Security BearerAuth
 // @Produce      json
 // @Param        id  path  string  true  "User id"
+// @Param        If-Match  header  string  false  "ETag the user must still have, else 412"
 // @Router       /users/{id} [delete]
 // @Success      200  {object}  example.DeleteUserResponse
 // @Failure      401  {object}  example.Unauthorized  "Unauthorized"
 // @Failure      403  {object}  example.Forbidden  "Forbidden"
 // @Failure      404  {object}  example.NotFound  "Not found"
+// @Failure      412  {object}  example.PreconditionFailed  "User was modified"
 func (u *UserController) DeleteUser(c *fiber.Ctx) error {
 	userID := c.Params("userId")
 
 	if _, err := uuid.Parse(userID); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+		return problem.ErrInvalidUserID
 	}
 
-	if err := u.TokenService.DeleteAllToken(c, userID); err != nil {
+	version, err := ifMatch(c)
+	if err != nil {
 		return err
 	}
 
-	if err := u.UserService.DeleteUser(c, userID); err != nil {
+	if err := u.UserService.DeleteUser(c.UserContext(), userID, version); err != nil {
+		return err
+	}
+
+	// A deleted user can't authenticate, the tokens left by a failure here are unusable
+	if err := u.TokenService.DeleteAllToken(c.UserContext(), userID); err != nil {
 		return err
 	}
 
@@ -196,3 +247,38 @@ func (u *UserController) DeleteUser(c *fiber.Ctx) error {
 			Message: "Delete user successfully",
 		})
 }
+
+// @Tags         Users
+// @Summary      Restore a deleted user
+// @Description  Only admins can restore deleted users.
+// @Security BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "User id"
+// @Router       /users/{id}/restore [post]
+// @Success      200  {object}  example.RestoreUserResponse
+// @Header       200  {string}  ETag  "Version of the restored user"
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      403  {object}  example.Forbidden  "Forbidden"
+// @Failure      404  {object}  example.NotFound  "Not found"
+func (u *UserController) RestoreUser(c *fiber.Ctx) error {
+	userID := c.Params("userId")
+
+	if _, err := uuid.Parse(userID); err != nil {
+		return problem.ErrInvalidUserID
+	}
+
+	user, err := u.UserService.RestoreUser(c.UserContext(), userID)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderETag, etag(user.Version))
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithUser{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Restore user successfully",
+			User:    *user,
+		})
+}
@@ -3,26 +3,36 @@ package database
 import (
 	"app/src/config"
 	"app/src/utils"
+	"database/sql"
 	"fmt"
-	"time"
+	"net/url"
 
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-func Connect(dbHost, dbName string) *gorm.DB {
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=Asia/Shanghai",
-		dbHost, config.DBUser, config.DBPassword, dbName, config.DBPort,
-	)
+const (
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite"
+)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger:                 logger.Default.LogMode(logger.Info),
-		SkipDefaultTransaction: true,
-		PrepareStmt:            true,
-		TranslateError:         true,
-	})
+// dbSystems maps drivers to their OpenTelemetry db.system
+var dbSystems = map[string]string{
+	DriverPostgres: "postgresql",
+	DriverMySQL:    "mysql",
+	DriverSQLite:   "sqlite",
+}
+
+func Connect(cfg config.Database) *gorm.DB {
+	dialector, err := Dialector(cfg)
+	if err != nil {
+		utils.Log.Fatalf("Failed to connect to database: %+v", err)
+	}
+
+	db, err := gorm.Open(dialector, gormConfig())
 	if err != nil {
 		utils.Log.Errorf("Failed to connect to database: %+v", err)
 	}
@@ -32,10 +42,57 @@ func Connect(dbHost, dbName string) *gorm.DB {
 		utils.Log.Errorf("Failed to connect to database: %+v", errDB)
 	}
 
-	// Config connection pooling
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(60 * time.Minute)
+	configurePool(sqlDB, cfg)
+
+	if err := db.Use(tracingPlugin{system: dbSystems[cfg.Driver]}); err != nil {
+		utils.Log.Errorf("Failed to set up database tracing: %+v", err)
+	}
+
+	if len(cfg.ReplicaHosts) > 0 {
+		if err := useReplicas(db, cfg); err != nil {
+			utils.Log.Fatalf("Failed to connect to database replicas: %+v", err)
+		}
+	}
 
 	return db
 }
+
+func gormConfig() *gorm.Config {
+	return &gorm.Config{
+		Logger:                 newGormLogger(),
+		SkipDefaultTransaction: true,
+		PrepareStmt:            true,
+		TranslateError:         true,
+	}
+}
+
+// Config connection pooling
+func configurePool(sqlDB *sql.DB, cfg config.Database) {
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// Dialector builds the GORM dialector for the configured driver.
+// For SQLite the database name is used as the file path (or an in-memory DSN).
+func Dialector(cfg config.Database) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case DriverPostgres:
+		dsn := fmt.Sprintf(
+			"host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=%s",
+			cfg.Host, cfg.User, cfg.Password, cfg.Name, cfg.Port, cfg.TimeZone,
+		)
+		return postgres.Open(dsn), nil
+	case DriverMySQL:
+		dsn := fmt.Sprintf(
+			"%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=%s",
+			cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Name, url.QueryEscape(cfg.TimeZone),
+		)
+		return mysql.Open(dsn), nil
+	case DriverSQLite:
+		return sqlite.Open(sqliteDSN(cfg.Name)), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+}
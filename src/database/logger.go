@@ -0,0 +1,77 @@
+package database
+
+import (
+	"app/src/utils"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold is the duration above which a query is logged as a warning
+const slowQueryThreshold = 200 * time.Millisecond
+
+// gormLogger writes GORM logs through the logger of the query context,
+// so statements carry the request id of the request that ran them.
+// Statements are logged at debug level, slow ones at warn level.
+type gormLogger struct {
+	level logger.LogLevel
+}
+
+func newGormLogger() logger.Interface {
+	return &gormLogger{level: logger.Info}
+}
+
+func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return &gormLogger{level: level}
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		utils.Logger(ctx).Infof(msg, args...)
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		utils.Logger(ctx).Warnf(msg, args...)
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		utils.Logger(ctx).Errorf(msg, args...)
+	}
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	log := utils.Logger(ctx)
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		sql, rows := fc()
+		log.WithFields(queryFields(sql, rows, elapsed)).WithError(err).Error("Query failed")
+	case elapsed > slowQueryThreshold && l.level >= logger.Warn:
+		sql, rows := fc()
+		log.WithFields(queryFields(sql, rows, elapsed)).Warn("Slow query")
+	case l.level >= logger.Info && log.Logger.IsLevelEnabled(logrus.DebugLevel):
+		sql, rows := fc()
+		log.WithFields(queryFields(sql, rows, elapsed)).Debug("Query")
+	}
+}
+
+func queryFields(sql string, rows int64, elapsed time.Duration) logrus.Fields {
+	return logrus.Fields{
+		"sql":         sql,
+		"rows":        rows,
+		"duration_ms": float64(elapsed.Microseconds()) / 1000,
+	}
+}
@@ -0,0 +1,307 @@
+package database
+
+import (
+	"app/src/utils"
+	"embed"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// schemaMigration mirrors the table used by golang-migrate, so migrations
+// applied here and through `make migrate-up` share the same version.
+type schemaMigration struct {
+	Version int64 `gorm:"primaryKey;autoIncrement:false"`
+	Dirty   bool  `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type migration struct {
+	Version int64
+	File    string
+}
+
+// Migrate applies the pending up migrations of the given driver dialect.
+func Migrate(db *gorm.DB, driver string) error {
+	return MigrateTo(db, driver, math.MaxInt64)
+}
+
+// MigrateTo applies the pending up migrations up to the given version, e.g.
+// to seed the rows a later migration converts.
+func MigrateTo(db *gorm.DB, driver string, version int64) error {
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return err
+	}
+
+	current := new(schemaMigration)
+	if err := db.Limit(1).Find(current).Error; err != nil {
+		return err
+	}
+
+	if current.Dirty {
+		return fmt.Errorf("database is dirty at version %d, fix it and force the version", current.Version)
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current.Version {
+			continue
+		}
+		if m.Version > version {
+			break
+		}
+
+		if err := applyMigration(db, driver, m); err != nil {
+			return err
+		}
+
+		utils.Log.Infof("Applied migration %s", m.File)
+	}
+
+	return nil
+}
+
+// applyMigration runs the statements of a migration. Postgres and SQLite
+// run DDL in transactions, so a failed migration is rolled back there. MySQL
+// commits each DDL statement, a failed migration leaves the version dirty.
+func applyMigration(db *gorm.DB, driver string, m migration) error {
+	content, err := migrationFiles.ReadFile(path.Join("migrations", driver, m.File))
+	if err != nil {
+		return err
+	}
+
+	statements := SplitStatements(string(content))
+
+	if driver != DriverMySQL {
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := execStatements(tx, m, statements); err != nil {
+				return err
+			}
+
+			return setVersion(tx, m.Version, false)
+		})
+	}
+
+	if err := setVersion(db, m.Version, true); err != nil {
+		return err
+	}
+
+	if err := execStatements(db, m, statements); err != nil {
+		return err
+	}
+
+	return setVersion(db, m.Version, false)
+}
+
+func execStatements(db *gorm.DB, m migration, statements []string) error {
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("migration %s failed: %w", m.File, err)
+		}
+	}
+
+	return nil
+}
+
+func setVersion(db *gorm.DB, version int64, dirty bool) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&schemaMigration{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&schemaMigration{Version: version, Dirty: dirty}).Error
+	})
+}
+
+func loadMigrations(driver string) ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, path.Join("migrations", driver))
+	if err != nil {
+		return nil, fmt.Errorf("no migrations found for driver %q: %w", driver, err)
+	}
+
+	var migrations []migration
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".up.sql") {
+			continue
+		}
+
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file name %s: %w", name, err)
+		}
+
+		migrations = append(migrations, migration{Version: version, File: name})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// SplitStatements splits a migration file on the semicolons ending its
+// statements, since not every driver accepts multiple statements in one
+// Exec. Semicolons in quotes, comments, dollar-quoted bodies and the
+// BEGIN ... END blocks of triggers don't end a statement.
+func SplitStatements(content string) []string {
+	var statements []string
+	start, depth := 0, 0
+
+	add := func(statement string) {
+		if statement = strings.TrimSpace(statement); statement != "" && !isComment(statement) {
+			statements = append(statements, statement)
+		}
+	}
+
+	for i := 0; i < len(content); {
+		switch c := content[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(content, i, c)
+		case strings.HasPrefix(content[i:], "--"):
+			i = skipUntil(content, i, "\n")
+		case strings.HasPrefix(content[i:], "/*"):
+			i = skipUntil(content, i+2, "*/")
+		case c == '$':
+			i = skipDollarQuoted(content, i)
+		case isWordStart(content, i):
+			word := readWord(content, i)
+			depth = blockDepth(content, start, i, word, depth)
+			i += len(word)
+		case c == ';' && depth == 0:
+			add(content[start:i])
+			start = i + 1
+			i++
+		default:
+			i++
+		}
+	}
+	add(content[start:])
+
+	return statements
+}
+
+// skipQuoted returns the index after the quoted string at i, a doubled quote is an escaped one
+func skipQuoted(content string, i int, quote byte) int {
+	for i++; i < len(content); i++ {
+		if content[i] == quote {
+			if i+1 < len(content) && content[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return i
+}
+
+// skipUntil returns the index after the end marker searched from i, or the end of the content
+func skipUntil(content string, i int, end string) int {
+	if j := strings.Index(content[i:], end); j >= 0 {
+		return i + j + len(end)
+	}
+	return len(content)
+}
+
+// skipDollarQuoted skips the Postgres $tag$ ... $tag$ bodies, a lone $ such as $1 is left as is
+func skipDollarQuoted(content string, i int) int {
+	j := i + 1
+	for j < len(content) && (isWordChar(content[j]) && !(content[j] >= '0' && content[j] <= '9' && j == i+1)) {
+		j++
+	}
+	if j >= len(content) || content[j] != '$' {
+		return i + 1
+	}
+
+	return skipUntil(content, j+1, content[i:j+1])
+}
+
+// blockDepth tracks the BEGIN ... END blocks of the trigger, function and
+// procedure bodies, which hold semicolons. CASE ... END nests in them, while
+// END IF, END LOOP, END WHILE and END REPEAT close no block.
+func blockDepth(content string, start, i int, word string, depth int) int {
+	switch strings.ToUpper(word) {
+	case "BEGIN":
+		if depth > 0 || hasRoutine(content[start:i]) {
+			return depth + 1
+		}
+	case "CASE":
+		if depth > 0 {
+			return depth + 1
+		}
+	case "END":
+		next := strings.ToUpper(readWord(content, skipSpaces(content, i+len(word))))
+		if depth > 0 && next != "IF" && next != "LOOP" && next != "WHILE" && next != "REPEAT" {
+			return depth - 1
+		}
+	}
+	return depth
+}
+
+// hasRoutine reports whether the statement so far creates a trigger, function or procedure
+func hasRoutine(statement string) bool {
+	for _, word := range strings.Fields(strings.ToUpper(statement)) {
+		if word == "TRIGGER" || word == "FUNCTION" || word == "PROCEDURE" {
+			return true
+		}
+	}
+	return false
+}
+
+// isComment reports whether the statement is only made of comments
+func isComment(statement string) bool {
+	for i := 0; i < len(statement); {
+		switch {
+		case strings.HasPrefix(statement[i:], "--"):
+			i = skipUntil(statement, i, "\n")
+		case strings.HasPrefix(statement[i:], "/*"):
+			i = skipUntil(statement, i+2, "*/")
+		case strings.ContainsRune(" \t\r\n", rune(statement[i])):
+			i++
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isWordStart(content string, i int) bool {
+	return isWordChar(content[i]) && (i == 0 || !isWordChar(content[i-1]))
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func readWord(content string, i int) string {
+	j := i
+	for j < len(content) && isWordChar(content[j]) {
+		j++
+	}
+	return content[i:j]
+}
+
+func skipSpaces(content string, i int) int {
+	for i < len(content) && strings.ContainsRune(" \t\r\n", rune(content[i])) {
+		i++
+	}
+	return i
+}
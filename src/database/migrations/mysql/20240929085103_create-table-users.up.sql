@@ -0,0 +1,10 @@
+CREATE TABLE users(
+    id              CHAR(36)        PRIMARY KEY,
+    name            VARCHAR(255)    NOT NULL,
+    email           VARCHAR(255)    NOT NULL UNIQUE,
+    password        VARCHAR(255)    NOT NULL,
+    role            VARCHAR(255)    NOT NULL,
+    verified_email  BOOLEAN         DEFAULT FALSE  NOT NULL,
+    created_at      DATETIME(3)     DEFAULT CURRENT_TIMESTAMP(3)  NOT NULL,
+    updated_at      DATETIME(3)     DEFAULT CURRENT_TIMESTAMP(3)  NOT NULL
+);
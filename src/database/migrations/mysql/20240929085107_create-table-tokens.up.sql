@@ -0,0 +1,11 @@
+CREATE TABLE tokens(
+    id              CHAR(36)        PRIMARY KEY,
+    token           VARCHAR(255)    NOT NULL,
+    user_id         CHAR(36)        NOT NULL,
+    type            VARCHAR(255)    NOT NULL,
+    expires         DATETIME(3)     DEFAULT CURRENT_TIMESTAMP(3)  NOT NULL,
+    created_at      DATETIME(3)     DEFAULT CURRENT_TIMESTAMP(3)  NOT NULL,
+    updated_at      DATETIME(3)     DEFAULT CURRENT_TIMESTAMP(3)  NOT NULL,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,20 @@
+CREATE TABLE job_leases(
+    name            VARCHAR(100)    PRIMARY KEY,
+    owner           VARCHAR(255)    NOT NULL,
+    locked_until    DATETIME(3)     NOT NULL,
+    updated_at      DATETIME(3)     DEFAULT CURRENT_TIMESTAMP(3)  NOT NULL
+);
+
+CREATE TABLE job_runs(
+    id              CHAR(36)        PRIMARY KEY,
+    name            VARCHAR(100)    NOT NULL,
+    owner           VARCHAR(255)    NOT NULL,
+    status          VARCHAR(20)     NOT NULL,
+    error           TEXT,
+    started_at      DATETIME(3)     NOT NULL,
+    finished_at     DATETIME(3),
+    created_at      DATETIME(3)     DEFAULT CURRENT_TIMESTAMP(3)  NOT NULL
+);
+
+CREATE INDEX idx_job_runs_name_started_at ON job_runs(name, started_at);
+CREATE INDEX idx_job_runs_started_at ON job_runs(started_at);
@@ -0,0 +1 @@
+UPDATE tokens SET token = SHA2(token, 256);
@@ -0,0 +1,10 @@
+CREATE TABLE idempotency_keys(
+    id              CHAR(64)        PRIMARY KEY,
+    fingerprint     CHAR(64)        NOT NULL,
+    status          INT             NOT NULL,
+    response        MEDIUMBLOB,
+    expires_at      DATETIME(3)     NOT NULL,
+    created_at      DATETIME(3)     DEFAULT CURRENT_TIMESTAMP(3)  NOT NULL
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,17 @@
+CREATE TABLE data_requests(
+    id              CHAR(36)        PRIMARY KEY,
+    user_id         CHAR(36)        NOT NULL,
+    type            VARCHAR(20)     NOT NULL,
+    status          VARCHAR(20)     NOT NULL,
+    archive         VARCHAR(255),
+    error           TEXT,
+    scheduled_at    DATETIME(3),
+    expires_at      DATETIME(3),
+    completed_at    DATETIME(3),
+    created_at      DATETIME(3)     DEFAULT CURRENT_TIMESTAMP(3)  NOT NULL,
+    updated_at      DATETIME(3)     DEFAULT CURRENT_TIMESTAMP(3)  NOT NULL
+);
+
+-- No foreign key, the requests outlive the erased users as the record of the erasure
+CREATE INDEX idx_data_requests_user_id ON data_requests(user_id);
+CREATE INDEX idx_data_requests_status_scheduled_at ON data_requests(status, scheduled_at);
@@ -0,0 +1,20 @@
+CREATE TABLE job_leases(
+    name            VARCHAR(100)    PRIMARY KEY,
+    owner           VARCHAR(255)    NOT NULL,
+    locked_until    TIMESTAMP       NOT NULL,
+    updated_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL
+);
+
+CREATE TABLE job_runs(
+    id              UUID            PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name            VARCHAR(100)    NOT NULL,
+    owner           VARCHAR(255)    NOT NULL,
+    status          VARCHAR(20)     NOT NULL,
+    error           TEXT,
+    started_at      TIMESTAMP       NOT NULL,
+    finished_at     TIMESTAMP,
+    created_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL
+);
+
+CREATE INDEX idx_job_runs_name_started_at ON job_runs(name, started_at);
+CREATE INDEX idx_job_runs_started_at ON job_runs(started_at);
@@ -0,0 +1,10 @@
+CREATE TABLE idempotency_keys(
+    id              CHAR(64)        PRIMARY KEY,
+    fingerprint     CHAR(64)        NOT NULL,
+    status          INTEGER         NOT NULL,
+    response        BYTEA,
+    expires_at      TIMESTAMP       NOT NULL,
+    created_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
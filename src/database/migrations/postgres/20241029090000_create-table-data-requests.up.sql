@@ -0,0 +1,17 @@
+CREATE TABLE data_requests(
+    id              UUID            PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id         UUID            NOT NULL,
+    type            VARCHAR(20)     NOT NULL,
+    status          VARCHAR(20)     NOT NULL,
+    archive         VARCHAR(255),
+    error           TEXT,
+    scheduled_at    TIMESTAMP,
+    expires_at      TIMESTAMP,
+    completed_at    TIMESTAMP,
+    created_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    updated_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL
+);
+
+-- No foreign key, the requests outlive the erased users as the record of the erasure
+CREATE INDEX idx_data_requests_user_id ON data_requests(user_id);
+CREATE INDEX idx_data_requests_status_scheduled_at ON data_requests(status, scheduled_at);
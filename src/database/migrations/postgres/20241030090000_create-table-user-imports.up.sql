@@ -0,0 +1,20 @@
+CREATE TABLE user_imports(
+    id              UUID            PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_by      UUID            NOT NULL,
+    format          VARCHAR(10)     NOT NULL,
+    invite          BOOLEAN         DEFAULT FALSE  NOT NULL,
+    language        VARCHAR(10),
+    status          VARCHAR(20)     NOT NULL,
+    file            VARCHAR(255),
+    error           TEXT,
+    total           INTEGER         DEFAULT 0  NOT NULL,
+    created         INTEGER         DEFAULT 0  NOT NULL,
+    failed          INTEGER         DEFAULT 0  NOT NULL,
+    results         TEXT,
+    completed_at    TIMESTAMP,
+    created_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    updated_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL
+);
+
+-- No foreign key, the imports are kept as the record of the users created by an admin
+CREATE INDEX idx_user_imports_status_created_at ON user_imports(status, created_at);
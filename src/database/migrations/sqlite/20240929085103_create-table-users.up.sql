@@ -0,0 +1,10 @@
+CREATE TABLE users(
+    id              TEXT            PRIMARY KEY,
+    name            VARCHAR(255)    NOT NULL,
+    email           VARCHAR(255)    NOT NULL UNIQUE,
+    password        VARCHAR(255)    NOT NULL,
+    role            VARCHAR(255)    NOT NULL,
+    verified_email  BOOLEAN         DEFAULT FALSE  NOT NULL,
+    created_at      DATETIME        DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    updated_at      DATETIME        DEFAULT CURRENT_TIMESTAMP  NOT NULL
+);
@@ -0,0 +1,11 @@
+CREATE TABLE tokens(
+    id              TEXT            PRIMARY KEY,
+    token           VARCHAR(255)    NOT NULL,
+    user_id         TEXT            NOT NULL,
+    type            VARCHAR(255)    NOT NULL,
+    expires         DATETIME        DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    created_at      DATETIME        DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    updated_at      DATETIME        DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+-- Hashes can't be reversed, stored tokens are removed and users sign in again
+DELETE FROM tokens;
@@ -0,0 +1,2 @@
+-- SQLite has no SHA-256 function, the stored tokens are removed and users sign in again
+DELETE FROM tokens;
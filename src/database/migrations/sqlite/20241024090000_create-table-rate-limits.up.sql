@@ -0,0 +1,7 @@
+CREATE TABLE rate_limits(
+    rate_key        VARCHAR(128)    PRIMARY KEY,
+    hits            INTEGER         NOT NULL,
+    reset_at        DATETIME        NOT NULL
+);
+
+CREATE INDEX idx_rate_limits_reset_at ON rate_limits(reset_at);
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"app/src/config"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const replicaPluginName = "app:replicas"
+
+// Replica is a read-only connection that dbresolver routes queries to.
+type Replica struct {
+	Host string
+	DB   *sql.DB
+}
+
+// replicaSet keeps the replica pools reachable from the *gorm.DB
+// so they can be health checked and closed.
+type replicaSet struct {
+	replicas []Replica
+}
+
+func (r *replicaSet) Name() string {
+	return replicaPluginName
+}
+
+func (r *replicaSet) Initialize(_ *gorm.DB) error {
+	return nil
+}
+
+// Replicas returns the read replicas registered on db, if any.
+func Replicas(db *gorm.DB) []Replica {
+	if set, ok := db.Config.Plugins[replicaPluginName].(*replicaSet); ok {
+		return set.replicas
+	}
+	return nil
+}
+
+// useReplicas opens a pool per replica host and registers them with dbresolver,
+// which sends queries to the replicas and writes to the primary. With SQLite
+// the hosts are the files of the replicas. On failure the pools opened so far
+// are closed.
+func useReplicas(db *gorm.DB, cfg config.Database) (err error) {
+	set := new(replicaSet)
+	dialectors := make([]gorm.Dialector, 0, len(cfg.ReplicaHosts))
+
+	defer func() {
+		if err != nil {
+			for _, replica := range set.replicas {
+				err = errors.Join(err, replica.DB.Close())
+			}
+		}
+	}()
+
+	for _, host := range cfg.ReplicaHosts {
+		replicaCfg := cfg
+		replicaCfg.Host = host
+		if cfg.Driver == DriverSQLite {
+			replicaCfg.Name = host
+		}
+
+		dialector, err := Dialector(replicaCfg)
+		if err != nil {
+			return err
+		}
+
+		replicaDB, err := gorm.Open(dialector, gormConfig())
+		if err != nil {
+			return fmt.Errorf("replica %s: %w", host, err)
+		}
+
+		sqlDB, err := replicaDB.DB()
+		if err != nil {
+			return fmt.Errorf("replica %s: %w", host, err)
+		}
+
+		configurePool(sqlDB, cfg)
+		set.replicas = append(set.replicas, Replica{Host: host, DB: sqlDB})
+		dialectors = append(dialectors, connDialector(cfg.Driver, sqlDB))
+	}
+
+	if err := db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: dialectors,
+		Policy:   dbresolver.RandomPolicy{},
+	})); err != nil {
+		return err
+	}
+
+	return db.Use(set)
+}
+
+// connDialector wraps an already opened pool so dbresolver reuses it.
+func connDialector(driver string, sqlDB *sql.DB) gorm.Dialector {
+	switch driver {
+	case DriverMySQL:
+		return mysql.New(mysql.Config{Conn: sqlDB})
+	case DriverSQLite:
+		return &sqlite.Dialector{Conn: sqlDB}
+	default:
+		return postgres.New(postgres.Config{Conn: sqlDB})
+	}
+}
@@ -0,0 +1,18 @@
+package database
+
+import "strings"
+
+func sqliteDSN(dbName string) string {
+	dsn := dbName
+	if !strings.HasPrefix(dsn, "file:") && !strings.Contains(dsn, ".") && dsn != ":memory:" {
+		dsn += ".db"
+	}
+
+	// Foreign keys are disabled by default in SQLite
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+
+	return dsn + separator + "_pragma=foreign_keys(1)"
+}
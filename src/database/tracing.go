@@ -0,0 +1,87 @@
+package database
+
+import (
+	"app/src/tracing"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "app:span"
+
+// tracingPlugin adds a client span around every statement, as a child of the
+// span in the statement context. Only the SQL with placeholders is recorded.
+type tracingPlugin struct {
+	system string
+}
+
+func (p tracingPlugin) Name() string {
+	return "app:tracing"
+}
+
+func (p tracingPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("app:trace_before_create", p.before("create")),
+		callbacks.Create().After("gorm:create").Register("app:trace_after_create", p.after),
+		callbacks.Query().Before("gorm:query").Register("app:trace_before_query", p.before("select")),
+		callbacks.Query().After("gorm:query").Register("app:trace_after_query", p.after),
+		callbacks.Update().Before("gorm:update").Register("app:trace_before_update", p.before("update")),
+		callbacks.Update().After("gorm:update").Register("app:trace_after_update", p.after),
+		callbacks.Delete().Before("gorm:delete").Register("app:trace_before_delete", p.before("delete")),
+		callbacks.Delete().After("gorm:delete").Register("app:trace_after_delete", p.after),
+		callbacks.Row().Before("gorm:row").Register("app:trace_before_row", p.before("row")),
+		callbacks.Row().After("gorm:row").Register("app:trace_after_row", p.after),
+		callbacks.Raw().Before("gorm:raw").Register("app:trace_before_raw", p.before("raw")),
+		callbacks.Raw().After("gorm:raw").Register("app:trace_after_raw", p.after),
+	)
+}
+
+func (p tracingPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			// Statements outside of a request or job would each start their own trace
+			return
+		}
+
+		name := "gorm." + operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+
+		_, span := tracing.Tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemKey.String(p.system),
+				semconv.DBOperationName(operation),
+				semconv.DBCollectionName(db.Statement.Table),
+			),
+		)
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func (p tracingPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
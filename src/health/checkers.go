@@ -0,0 +1,110 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/textproto"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+type checkerFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c checkerFunc) Name() string {
+	return c.name
+}
+
+func (c checkerFunc) Check(ctx context.Context) error {
+	return c.fn(ctx)
+}
+
+// NewChecker turns a function into a Checker
+func NewChecker(name string, fn func(ctx context.Context) error) Checker {
+	return checkerFunc{name: name, fn: fn}
+}
+
+// Database pings a connection pool
+func Database(name string, db *sql.DB) Checker {
+	return NewChecker(name, db.PingContext)
+}
+
+// Memory fails when the heap grows above threshold bytes
+func Memory(threshold uint64) Checker {
+	return NewChecker("Memory", func(context.Context) error {
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+
+		if memStats.HeapAlloc > threshold {
+			return fmt.Errorf("heap memory usage too high: %d bytes", memStats.HeapAlloc)
+		}
+
+		return nil
+	})
+}
+
+// Disk fails when the file system of path has less than minFree bytes available
+func Disk(path string, minFree uint64) Checker {
+	return NewChecker("Disk", func(context.Context) error {
+		free, err := freeSpace(path)
+		if err != nil {
+			return err
+		}
+
+		if free < minFree {
+			return fmt.Errorf("low disk space: %d bytes free", free)
+		}
+
+		return nil
+	})
+}
+
+// SMTP connects to the mail server and waits for its greeting
+func SMTP(host string, port int) Checker {
+	return NewChecker("SMTP", func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+
+		text := textproto.NewConn(conn)
+		if _, _, err := text.ReadResponse(220); err != nil {
+			return err
+		}
+
+		// Best effort, the connection is closed anyway
+		_ = text.PrintfLine("QUIT")
+
+		return nil
+	})
+}
+
+// StuckCounter counts the job runs still running that started before a given time
+type StuckCounter func(ctx context.Context, startedBefore time.Time) (int64, error)
+
+// Jobs fails when runs have been running for longer than maxDuration
+func Jobs(countStuck StuckCounter, maxDuration time.Duration) Checker {
+	return NewChecker("Jobs", func(ctx context.Context) error {
+		stuck, err := countStuck(ctx, time.Now().Add(-maxDuration))
+		if err != nil {
+			return err
+		}
+
+		if stuck > 0 {
+			return fmt.Errorf("%d job runs running for more than %s", stuck, maxDuration)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,220 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrDraining = errors.New("shutting down")
+
+// Checker reports whether a dependency of the app works. Check must return
+// once ctx is done.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+// Check is a checker with how it is run
+type Check struct {
+	Checker Checker
+	// Live checks failing means the process must be restarted, the other
+	// checks only take it out of the load balancer
+	Live bool
+	// Optional checks are reported without failing the probe
+	Optional bool
+	// Timeout bounds a single run, defaults to the registry timeout
+	Timeout time.Duration
+	// CacheFor reuses the last result, so frequent probes don't hit the dependency
+	CacheFor time.Duration
+}
+
+type Result struct {
+	Name       string    `json:"name"`
+	Up         bool      `json:"up"`
+	Optional   bool      `json:"optional,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs float64   `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+type Report struct {
+	Healthy bool
+	Results []Result
+}
+
+type entry struct {
+	Check
+
+	mu   sync.Mutex
+	last *Result
+}
+
+// Registry runs the registered checks, all at once
+type Registry struct {
+	timeout time.Duration
+	entries []*entry
+}
+
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+// Register adds a check, the checks are reported in the order they were registered
+func (r *Registry) Register(check Check) {
+	if check.Timeout <= 0 {
+		check.Timeout = r.timeout
+	}
+
+	r.entries = append(r.entries, &entry{Check: check})
+}
+
+// Liveness runs the live checks
+func (r *Registry) Liveness(ctx context.Context) Report {
+	return r.run(ctx, func(e *entry) bool { return e.Live })
+}
+
+// Readiness runs every check, and fails once the process is draining
+func (r *Registry) Readiness(ctx context.Context) Report {
+	report := r.run(ctx, func(*entry) bool { return true })
+
+	if Draining() {
+		report.Healthy = false
+		report.Results = append(report.Results, Result{
+			Name:      "Shutdown",
+			Error:     ErrDraining.Error(),
+			CheckedAt: time.Now().UTC(),
+		})
+	}
+
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, include func(*entry) bool) Report {
+	var selected []*entry
+	for _, e := range r.entries {
+		if include(e) {
+			selected = append(selected, e)
+		}
+	}
+
+	results := make([]Result, len(selected))
+	var wg sync.WaitGroup
+
+	for i, e := range selected {
+		wg.Add(1)
+
+		go func(i int, e *entry) {
+			defer wg.Done()
+			results[i] = e.result(ctx)
+		}(i, e)
+	}
+	wg.Wait()
+
+	report := Report{Healthy: true, Results: results}
+	for _, result := range results {
+		if !result.Up && !result.Optional {
+			report.Healthy = false
+		}
+	}
+
+	return report
+}
+
+// result returns the cached result while it is fresh, concurrent probes wait
+// for a single run of the check
+func (e *entry) result(ctx context.Context) Result {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.last != nil && time.Since(e.last.CheckedAt) < e.CacheFor {
+		return *e.last
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := runCheck(ctx, e.Checker)
+
+	result := Result{
+		Name:       e.Checker.Name(),
+		Up:         err == nil,
+		Optional:   e.Optional,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+		CheckedAt:  start.UTC(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	e.last = &result
+
+	return result
+}
+
+// runCheck gives up on a checker ignoring ctx once the timeout is reached
+func runCheck(ctx context.Context, checker Checker) error {
+	done := make(chan error, 1)
+	go func() { done <- checker.Check(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var (
+	draining  atomic.Bool
+	drainFile atomic.Value
+)
+
+// DrainFile is the drain file shared by the prefork parent and its children
+func DrainFile(isChild bool) string {
+	parent := os.Getpid()
+	if isChild {
+		parent = os.Getppid()
+	}
+
+	return filepath.Join(os.TempDir(), fmt.Sprintf("app-%d.draining", parent))
+}
+
+// ShareDrain makes Drain visible to the other processes using the same path,
+// such as prefork children, which don't receive the signal of the parent
+func ShareDrain(path string) {
+	drainFile.Store(path)
+}
+
+// Drain makes readiness fail from now on, so load balancers stop sending
+// requests before the server shuts down
+func Drain() error {
+	draining.Store(true)
+
+	if path, ok := drainFile.Load().(string); ok {
+		return os.WriteFile(path, nil, 0o600)
+	}
+
+	return nil
+}
+
+// Draining reports whether this process, or the process sharing its drain file, is shutting down
+func Draining() bool {
+	if draining.Load() {
+		return true
+	}
+
+	if path, ok := drainFile.Load().(string); ok {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,136 @@
+// Package i18n holds the message catalogs of the languages the API speaks,
+// and picks the language of a request.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default is the language of the requests which ask for none we have
+const Default = "en"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+var catalogs = mustLoadCatalogs()
+
+func mustLoadCatalogs() map[string]map[string]string {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	loaded := make(map[string]map[string]string, len(files))
+	for _, file := range files {
+		data, err := locales.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		messages := make(map[string]string)
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("invalid catalog %s: %v", file.Name(), err))
+		}
+		loaded[strings.TrimSuffix(file.Name(), ".json")] = messages
+	}
+
+	return loaded
+}
+
+// Languages returns the languages with a catalog, sorted
+func Languages() []string {
+	languages := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+
+	return languages
+}
+
+// Supported reports whether lang has a catalog
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Lookup returns the message of key in lang, or in the default language
+func Lookup(lang, key string) (string, bool) {
+	if message, ok := catalogs[lang][key]; ok {
+		return message, true
+	}
+
+	message, ok := catalogs[Default][key]
+	return message, ok
+}
+
+// T formats the message of key in lang with args, the key itself is returned
+// when no catalog has it
+func T(lang, key string, args ...any) string {
+	message, ok := Lookup(lang, key)
+	if !ok {
+		return key
+	}
+
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Match picks the supported language the Accept-Language header prefers, by
+// quality then order. Regional variants match their language, e.g. id-ID is id.
+func Match(acceptLanguage string) string {
+	type candidate struct {
+		lang    string
+		quality float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if quality > 0 && Supported(lang) {
+			candidates = append(candidates, candidate{lang: lang, quality: quality})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return Default
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+	return candidates[0].lang
+}
+
+type languageKey struct{}
+
+// WithLanguage returns a copy of ctx speaking lang
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageKey{}, lang)
+}
+
+// Language returns the language of ctx, Default if it has none
+func Language(ctx context.Context) string {
+	if lang, ok := ctx.Value(languageKey{}).(string); ok {
+		return lang
+	}
+	return Default
+}
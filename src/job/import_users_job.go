@@ -0,0 +1,21 @@
+package job
+
+import (
+	"app/src/service"
+	"app/src/utils"
+	"context"
+)
+
+// ImportUsers imports the files of users too large to be imported by the
+// request, at most batchSize per run. What is left is picked up by the next run.
+func ImportUsers(bulkUserService service.BulkUserService, batchSize int) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		imported, err := bulkUserService.ProcessImports(ctx, batchSize)
+
+		if imported > 0 {
+			utils.Logger(ctx).Infof("Imported %d files of users", imported)
+		}
+
+		return err
+	}
+}
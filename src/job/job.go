@@ -0,0 +1,240 @@
+package job
+
+import (
+	"app/src/model"
+	"app/src/repository"
+	"app/src/tracing"
+	"app/src/utils"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultLeaseTTL is how long a lease outlives the last heartbeat of its run
+const defaultLeaseTTL = time.Minute
+
+type Func func(ctx context.Context) error
+
+type entry struct {
+	name     string
+	spec     string
+	schedule cron.Schedule
+	fn       Func
+	running  atomic.Bool
+}
+
+// Scheduler runs registered jobs on their cron schedule. Each scheduled run
+// is leased in the database first, so when several instances (or prefork
+// processes) run the scheduler only one of them runs it. The lease is renewed
+// while the run lasts, a run longer than the interval of its job holds off
+// the next occurrences.
+type Scheduler struct {
+	Jobs     repository.JobRepository
+	Owner    string
+	LeaseTTL time.Duration
+
+	entries map[string]*entry
+	wg      sync.WaitGroup
+}
+
+func NewScheduler(jobs repository.JobRepository) *Scheduler {
+	hostname, _ := os.Hostname()
+
+	return &Scheduler{
+		Jobs:     jobs,
+		Owner:    fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), uuid.NewString()[:8]),
+		LeaseTTL: defaultLeaseTTL,
+		entries:  make(map[string]*entry),
+	}
+}
+
+// Register adds a job with a standard cron spec, e.g. "0 3 * * *",
+// or a descriptor such as "@hourly" and "@every 30m".
+func (s *Scheduler) Register(name, spec string, fn Func) error {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q for job %s: %w", spec, name, err)
+	}
+
+	if _, exists := s.entries[name]; exists {
+		return fmt.Errorf("job %s is already registered", name)
+	}
+
+	s.entries[name] = &entry{name: name, spec: spec, schedule: schedule, fn: fn}
+
+	return nil
+}
+
+// Start runs every registered job in the background until ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, e := range s.entries {
+		s.wg.Add(1)
+
+		go func(e *entry) {
+			defer s.wg.Done()
+			s.loop(ctx, e)
+		}(e)
+	}
+}
+
+// Wait blocks until every job has stopped, cancel their context first.
+// A run in progress is finished so the database can be closed afterwards.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	log := utils.Logger(ctx).WithField("job", e.name)
+	log.Infof("Job %s scheduled with %q", e.name, e.spec)
+
+	for {
+		next := e.schedule.Next(time.Now())
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Infof("Job %s stopped", e.name)
+			return
+		case <-timer.C:
+			if _, err := s.Run(ctx, e.name, next); err != nil {
+				log.Errorf("Job %s failed: %v", e.name, err)
+			}
+		}
+	}
+}
+
+// Run runs the occurrence of a job scheduled at the given time, unless another
+// instance already leased it. It reports whether the job ran here.
+func (s *Scheduler) Run(ctx context.Context, name string, scheduledAt time.Time) (bool, error) {
+	e, ok := s.entries[name]
+	if !ok {
+		return false, fmt.Errorf("job %s is not registered", name)
+	}
+
+	// The previous run of this instance is still going
+	if !e.running.CompareAndSwap(false, true) {
+		return false, nil
+	}
+	defer e.running.Store(false)
+
+	// The lease lasts at least until the next occurrence, so this one runs only once
+	nextAt := e.schedule.Next(scheduledAt).UTC()
+	acquired, err := s.Jobs.AcquireLease(ctx, name, s.Owner, s.leaseUntil(nextAt))
+	if err != nil || !acquired {
+		return false, err
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "job "+name, trace.WithAttributes(attribute.String("job.name", name)))
+	defer span.End()
+
+	run := &model.JobRun{
+		Name:      name,
+		Owner:     s.Owner,
+		Status:    model.JobStatusRunning,
+		StartedAt: time.Now().UTC(),
+	}
+
+	if err := s.Jobs.CreateRun(ctx, run); err != nil {
+		return false, err
+	}
+
+	// Everything the job logs carries the run it belongs to
+	ctx = utils.WithLogger(ctx, utils.Logger(ctx).WithFields(logrus.Fields{"job": name, "run_id": run.ID.String()}))
+	runErr := s.callLeased(ctx, e, nextAt)
+
+	finishedAt := time.Now().UTC()
+	run.FinishedAt = &finishedAt
+	run.Status = model.JobStatusSucceeded
+	if runErr != nil {
+		run.Status = model.JobStatusFailed
+		run.Error = runErr.Error()
+		span.RecordError(runErr)
+		span.SetStatus(codes.Error, runErr.Error())
+	}
+
+	// Record the result even when the run was cut short by shutdown
+	if err := s.Jobs.FinishRun(context.WithoutCancel(ctx), run); err != nil {
+		utils.Logger(ctx).Errorf("Failed to record run of job %s: %v", name, err)
+	}
+
+	return true, runErr
+}
+
+// leaseUntil is the end of a lease renewed now, which covers the occurrence until nextAt
+func (s *Scheduler) leaseUntil(nextAt time.Time) time.Time {
+	if until := time.Now().UTC().Add(s.LeaseTTL); until.After(nextAt) {
+		return until
+	}
+	return nextAt
+}
+
+// callLeased calls the job while renewing its lease. A run whose lease was
+// taken over, e.g. after the instance stalled, is cancelled. Once the run is
+// done the lease is brought back to the next occurrence.
+func (s *Scheduler) callLeased(ctx context.Context, e *entry, nextAt time.Time) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	renewed := make(chan struct{})
+
+	go func() {
+		defer close(renewed)
+		s.renewLease(runCtx, cancel, e.name, nextAt, done)
+	}()
+
+	err := s.call(runCtx, e)
+	close(done)
+	<-renewed
+	cancel()
+
+	if _, renewErr := s.Jobs.RenewLease(context.WithoutCancel(ctx), e.name, s.Owner, nextAt); renewErr != nil {
+		utils.Logger(ctx).Errorf("Failed to release lease of job %s: %v", e.name, renewErr)
+	}
+
+	return err
+}
+
+func (s *Scheduler) renewLease(
+	ctx context.Context, cancel context.CancelFunc, name string, nextAt time.Time, done <-chan struct{},
+) {
+	ticker := time.NewTicker(s.LeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			renewed, err := s.Jobs.RenewLease(ctx, name, s.Owner, s.leaseUntil(nextAt))
+			if err != nil {
+				utils.Logger(ctx).Warnf("Failed to renew lease of job %s: %v", name, err)
+				continue
+			}
+			if !renewed {
+				utils.Logger(ctx).Errorf("Lease of job %s was taken over, cancelling the run", name)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+func (s *Scheduler) call(ctx context.Context, e *entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return e.fn(ctx)
+}
@@ -0,0 +1,32 @@
+package job
+
+import (
+	"app/src/service"
+	"app/src/utils"
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ProcessDataRequests builds the requested exports, runs the erasures whose
+// grace period is over and deletes the expired exports, at most batchSize of
+// each per run. What is left is picked up by the next run.
+func ProcessDataRequests(privacyService service.PrivacyService, batchSize int) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		exported, errExports := privacyService.ProcessExports(ctx, batchSize)
+		erased, errErasures := privacyService.ProcessErasures(ctx, batchSize)
+		expired, errExpired := privacyService.PurgeExpiredExports(ctx, batchSize)
+
+		if exported+erased+expired > 0 {
+			utils.Logger(ctx).WithFields(logrus.Fields{
+				"job":      "process-data-requests",
+				"exported": exported,
+				"erased":   erased,
+				"expired":  expired,
+			}).Info("Processed data requests")
+		}
+
+		return errors.Join(errExports, errErasures, errExpired)
+	}
+}
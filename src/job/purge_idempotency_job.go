@@ -0,0 +1,25 @@
+package job
+
+import (
+	"app/src/service"
+	"app/src/utils"
+	"context"
+	"time"
+)
+
+// PurgeIdempotencyKeys deletes the idempotency keys past their TTL, with the
+// responses they kept.
+func PurgeIdempotencyKeys(idempotencyService service.IdempotencyService) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		deleted, err := idempotencyService.DeleteExpiredKeys(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if deleted > 0 {
+			utils.Logger(ctx).Infof("Purged %d expired idempotency keys", deleted)
+		}
+
+		return nil
+	}
+}
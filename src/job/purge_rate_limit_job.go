@@ -0,0 +1,25 @@
+package job
+
+import (
+	"app/src/repository"
+	"app/src/utils"
+	"context"
+	"time"
+)
+
+// PurgeRateLimits deletes the counters of the database rate limit store whose
+// window is over, they would be reset by the next request anyway.
+func PurgeRateLimits(rateLimitRepository repository.RateLimitRepository) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		deleted, err := rateLimitRepository.DeleteExpired(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if deleted > 0 {
+			utils.Logger(ctx).Infof("Purged %d expired rate limit counters", deleted)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,45 @@
+package job
+
+import (
+	"app/src/service"
+	"app/src/utils"
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PurgeExpiredTokens removes expired refresh, reset password and verify email
+// tokens in batches, so a large backlog doesn't hold long locks on the table.
+func PurgeExpiredTokens(tokenService service.TokenService, batchSize int) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		start := time.Now()
+		now := start.UTC()
+
+		var deleted int64
+		batches := 0
+
+		for ctx.Err() == nil {
+			count, err := tokenService.DeleteExpiredTokens(ctx, now, batchSize)
+			if err != nil {
+				return err
+			}
+
+			deleted += count
+			batches++
+
+			if count < int64(batchSize) {
+				break
+			}
+		}
+
+		utils.Logger(ctx).WithFields(logrus.Fields{
+			"job":         "purge-expired-tokens",
+			"deleted":     deleted,
+			"batches":     batches,
+			"duration_ms": time.Since(start).Milliseconds(),
+		}).Info("Purged expired tokens")
+
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,41 @@
+package job
+
+import (
+	"app/src/service"
+	"app/src/utils"
+	"context"
+	"time"
+)
+
+// PurgeDeletedUsers permanently removes users that were soft deleted
+// longer than the retention period ago, with the thumbnails of their avatar.
+// The users are kept until every thumbnail is gone, the next run tries again.
+func PurgeDeletedUsers(
+	userService service.UserService, avatarService service.AvatarService, retention time.Duration,
+) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		deletedBefore := time.Now().Add(-retention)
+
+		avatars, err := userService.ListDeletedAvatars(ctx, deletedBefore)
+		if err != nil {
+			return err
+		}
+
+		for _, avatar := range avatars {
+			if err := avatarService.PurgeAvatar(ctx, avatar); err != nil {
+				return err
+			}
+		}
+
+		purged, err := userService.PurgeDeletedUsers(ctx, deletedBefore)
+		if err != nil {
+			return err
+		}
+
+		if purged > 0 {
+			utils.Logger(ctx).Infof("Purged %d deleted users", purged)
+		}
+
+		return nil
+	}
+}
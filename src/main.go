@@ -3,22 +3,42 @@ package main
 import (
 	"app/src/config"
 	"app/src/database"
+	"app/src/health"
+	"app/src/job"
+	"app/src/metrics"
 	"app/src/middleware"
+	"app/src/ratelimit"
+	"app/src/repository"
 	"app/src/router"
+	"app/src/service"
+	"app/src/storage"
+	"app/src/tracing"
 	"app/src/utils"
+	"app/src/validation"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/helmet"
+	"github.com/spf13/pflag"
 	"gorm.io/gorm"
 )
 
+const (
+	metricsSnapshotInterval = 5 * time.Second
+	// dataRequestBatchSize is the number of exports and of erasures run by each run of the job
+	dataRequestBatchSize = 20
+	// userImportBatchSize is the number of files of users imported by each run of the job
+	userImportBatchSize = 5
+)
+
 // @title go-fiber-boilerplate API documentation
 // @version 1.0.0
 // @license.name MIT
@@ -33,25 +53,85 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	app := setupFiberApp()
-	db := setupDatabase()
+	flags := config.NewFlagSet(os.Args[0])
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return
+		}
+		os.Exit(2)
+	}
+
+	live := loadConfig(flags)
+	cfg := live.Get()
+
+	// One-shot commands, e.g. `go run src/main.go purge-expired-tokens`
+	if flags.NArg() > 0 {
+		os.Exit(runCommand(ctx, live, flags.Arg(0)))
+	}
+
+	shutdownTracing := setupTracing(ctx, cfg)
+	defer shutdownTracing()
+
+	app := setupFiberApp(live)
+	db := setupDatabase(cfg)
 	defer closeDatabase(db)
-	setupRoutes(app, db)
+	rateLimits, closeRateLimits := setupRateLimitStore(cfg, db)
+	defer closeRateLimits()
+	blobs := setupStorage(cfg)
+	setupRoutes(app, db, live, rateLimits, blobs)
+	jobs := startJobs(ctx, db, live, blobs)
+	watchConfig(ctx, live)
+	stopMetrics := startMetricSnapshots(ctx, app, cfg)
+	defer stopMetrics()
+	removeDrainFile := shareDrain(app)
+	defer removeDrainFile()
 
-	address := fmt.Sprintf("%s:%d", config.AppHost, config.AppPort)
+	address := fmt.Sprintf("%s:%d", cfg.App.Host, cfg.App.Port)
 
 	// Start server and handle graceful shutdown
 	serverErrors := make(chan error, 1)
 	go startServer(app, address, serverErrors)
-	handleGracefulShutdown(ctx, app, serverErrors)
+	handleGracefulShutdown(ctx, app, cfg, serverErrors)
+
+	// Let a job run in progress finish before the database is closed
+	cancel()
+	jobs.Wait()
+}
+
+// loadConfig stops the app when the configuration is invalid, listing every problem
+func loadConfig(flags *pflag.FlagSet) *config.Live {
+	load := func() (*config.Config, error) { return config.Load(flags, ".env") }
+
+	cfg, err := load()
+	if err != nil {
+		utils.Log.Fatal(err)
+	}
+
+	utils.ConfigureLog(cfg.Log.Format, cfg.Log.Level)
+
+	live := config.NewLive(cfg, load)
+	live.OnReload(func(cfg *config.Config) { utils.ConfigureLog(cfg.Log.Format, cfg.Log.Level) })
+
+	return live
+}
+
+// watchConfig reloads the config on SIGHUP and when the config file or the email templates change
+func watchConfig(ctx context.Context, live *config.Live) {
+	cfg := live.Get()
+	if err := live.Watch(ctx, cfg.File, cfg.SMTP.TemplatesDir); err != nil {
+		utils.Log.Errorf("Failed to watch the config, it is reloaded on SIGHUP only: %v", err)
+	}
 }
 
-func setupFiberApp() *fiber.App {
-	app := fiber.New(config.FiberConfig())
+func setupFiberApp(live *config.Live) *fiber.App {
+	app := fiber.New(config.FiberConfig(live.Get()))
 
 	// Middleware setup
-	app.Use("/v1/auth", middleware.LimiterConfig())
+	app.Use(middleware.RequestID())
+	app.Use(middleware.Tracing())
+	app.Use(middleware.Metrics())
 	app.Use(middleware.LoggerConfig())
+	app.Use(middleware.Language())
 	app.Use(helmet.New())
 	app.Use(compress.New())
 	app.Use(cors.New())
@@ -60,17 +140,207 @@ func setupFiberApp() *fiber.App {
 	return app
 }
 
-func setupDatabase() *gorm.DB {
-	db := database.Connect(config.DBHost, config.DBName)
+// setupTracing returns a func flushing the spans not exported yet
+func setupTracing(ctx context.Context, cfg *config.Config) func() {
+	shutdown, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		utils.Log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := shutdown(ctx); err != nil {
+			utils.Log.Errorf("Failed to flush traces: %v", err)
+		}
+	}
+}
+
+func setupDatabase(cfg *config.Config) *gorm.DB {
+	db := database.Connect(cfg.DB)
+
+	if cfg.DB.AutoMigrate {
+		if err := database.Migrate(db, cfg.DB.Driver); err != nil {
+			utils.Log.Fatalf("Failed to run migrations: %v", err)
+		}
+	}
+
 	// Add any additional database setup if needed
 	return db
 }
 
-func setupRoutes(app *fiber.App, db *gorm.DB) {
-	router.Routes(app, db)
+// setupRateLimitStore picks where the rate limits are counted, the returned func closes it
+func setupRateLimitStore(cfg *config.Config, db *gorm.DB) (ratelimit.Store, func()) {
+	switch cfg.RateLimit.Store {
+	case config.RateLimitStoreDatabase:
+		return repository.NewRateLimitRepository(db), func() {}
+	case config.RateLimitStoreRedis:
+		store, err := ratelimit.NewRedisStore(cfg.RateLimit.RedisURL)
+		if err != nil {
+			utils.Log.Fatalf("Failed to set up the rate limit store: %v", err)
+		}
+		return store, func() { _ = store.Close() }
+	default:
+		return ratelimit.NewMemoryStore(), func() {}
+	}
+}
+
+// setupStorage picks where the uploaded files are kept
+func setupStorage(cfg *config.Config) storage.Store {
+	var (
+		blobs storage.Store
+		err   error
+	)
+	switch cfg.Storage.Driver {
+	case config.StorageDriverS3:
+		blobs, err = storage.NewS3Store(cfg.Storage.S3)
+	default:
+		blobs, err = storage.NewFileStore(cfg.Storage.Dir)
+	}
+	if err != nil {
+		utils.Log.Fatalf("Failed to set up the storage: %v", err)
+	}
+
+	return blobs
+}
+
+func setupRoutes(app *fiber.App, db *gorm.DB, live *config.Live, rateLimits ratelimit.Store, blobs storage.Store) {
+	router.Routes(app, db, live, rateLimits, blobs)
 	app.Use(utils.NotFoundHandler)
 }
 
+func startJobs(ctx context.Context, db *gorm.DB, live *config.Live, blobs storage.Store) *job.Scheduler {
+	scheduler := job.NewScheduler(repository.NewJobRepository(db))
+
+	// With prefork every child runs main, only the parent process runs the jobs.
+	// The lease still keeps several instances from running the same job.
+	if fiber.IsChild() {
+		return scheduler
+	}
+
+	users := repository.NewUserRepository(db)
+	userService := service.NewUserService(users, validation.Validator())
+	cfg := live.Get()
+	avatarService := service.NewAvatarService(users, blobs, cfg.Avatar)
+	purgeUsers := job.PurgeDeletedUsers(userService, avatarService, cfg.Jobs.UserRetention)
+	purgeTokens := job.PurgeExpiredTokens(newTokenService(db, live), cfg.Jobs.TokenPurgeBatchSize)
+	idempotencyService := service.NewIdempotencyService(repository.NewIdempotencyRepository(db), cfg.Idempotency)
+	purgeIdempotencyKeys := job.PurgeIdempotencyKeys(idempotencyService)
+	processDataRequests := job.ProcessDataRequests(newPrivacyService(db, live, blobs), dataRequestBatchSize)
+	importUsers := job.ImportUsers(newBulkUserService(db, live, blobs), userImportBatchSize)
+
+	errs := []error{
+		scheduler.Register("purge-deleted-users", cfg.Jobs.UserPurgeSchedule, purgeUsers),
+		scheduler.Register("purge-expired-tokens", cfg.Jobs.TokenPurgeSchedule, purgeTokens),
+		scheduler.Register("purge-idempotency-keys", cfg.Jobs.IdempotencyPurgeSchedule, purgeIdempotencyKeys),
+		scheduler.Register("process-data-requests", cfg.Jobs.DataRequestSchedule, processDataRequests),
+		scheduler.Register("import-users", cfg.Jobs.UserImportSchedule, importUsers),
+	}
+	if cfg.RateLimit.Store == config.RateLimitStoreDatabase {
+		purgeRateLimits := job.PurgeRateLimits(repository.NewRateLimitRepository(db))
+		errs = append(errs, scheduler.Register("purge-rate-limits", cfg.Jobs.RateLimitPurgeSchedule, purgeRateLimits))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		utils.Log.Fatalf("Failed to register jobs: %v", err)
+	}
+
+	scheduler.Start(ctx)
+
+	return scheduler
+}
+
+// startMetricSnapshots shares the metrics of prefork children, so any of them can answer a scrape.
+// The returned func removes the snapshots once the parent process stops.
+func startMetricSnapshots(ctx context.Context, app *fiber.App, cfg *config.Config) func() {
+	if !cfg.Metrics.Enabled || !app.Config().Prefork {
+		return func() {}
+	}
+
+	if !fiber.IsChild() {
+		dir := metrics.Dir(false)
+		return func() { _ = os.RemoveAll(dir) }
+	}
+
+	go func() {
+		if err := metrics.WriteSnapshots(ctx, metrics.Dir(true), metricsSnapshotInterval); err != nil {
+			utils.Log.Errorf("Failed to write metrics snapshot: %v", err)
+		}
+	}()
+
+	return func() {}
+}
+
+// shareDrain lets the prefork children fail readiness once the parent, which gets the signal, drains.
+// The returned func removes the drain file once the parent process stops.
+func shareDrain(app *fiber.App) func() {
+	if !app.Config().Prefork {
+		return func() {}
+	}
+
+	path := health.DrainFile(fiber.IsChild())
+	health.ShareDrain(path)
+
+	if fiber.IsChild() {
+		return func() {}
+	}
+
+	return func() { _ = os.Remove(path) }
+}
+
+func newTokenService(db *gorm.DB, live *config.Live) service.TokenService {
+	validate := validation.Validator()
+	userService := service.NewUserService(repository.NewUserRepository(db), validate)
+
+	return service.NewTokenService(
+		repository.NewTokenRepository(db), repository.NewUnitOfWork(db), validate, userService, live,
+	)
+}
+
+func newPrivacyService(db *gorm.DB, live *config.Live, blobs storage.Store) service.PrivacyService {
+	cfg := live.Get()
+	users := repository.NewUserRepository(db)
+
+	return service.NewPrivacyService(
+		users, repository.NewTokenRepository(db), repository.NewDataRequestRepository(db),
+		repository.NewUnitOfWork(db), validation.Validator(), newTokenService(db, live),
+		service.NewEmailService(live), service.NewAvatarService(users, blobs, cfg.Avatar), blobs, cfg.Privacy,
+	)
+}
+
+func newBulkUserService(db *gorm.DB, live *config.Live, blobs storage.Store) service.BulkUserService {
+	validate := validation.Validator()
+	users := repository.NewUserRepository(db)
+
+	return service.NewBulkUserService(
+		users, repository.NewUserImportRepository(db), validate, service.NewUserService(users, validate),
+		newTokenService(db, live), service.NewEmailService(live), blobs, live.Get().Import,
+	)
+}
+
+// runCommand runs a job once and returns the exit code
+func runCommand(ctx context.Context, live *config.Live, name string) int {
+	if name != "purge-expired-tokens" {
+		utils.Log.Errorf("Unknown command %q, available commands: purge-expired-tokens", name)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db := setupDatabase(live.Get())
+	defer closeDatabase(db)
+
+	purgeTokens := job.PurgeExpiredTokens(newTokenService(db, live), live.Get().Jobs.TokenPurgeBatchSize)
+	if err := purgeTokens(ctx); err != nil {
+		utils.Log.Errorf("Command %s failed: %v", name, err)
+		return 1
+	}
+
+	return 0
+}
+
 func startServer(app *fiber.App, address string, errs chan<- error) {
 	if err := app.Listen(address); err != nil {
 		errs <- fmt.Errorf("error starting server: %w", err)
@@ -89,9 +359,15 @@ func closeDatabase(db *gorm.DB) {
 	} else {
 		utils.Log.Info("Database connection closed successfully")
 	}
+
+	for _, replica := range database.Replicas(db) {
+		if err := replica.DB.Close(); err != nil {
+			utils.Log.Errorf("Error closing database replica %s: %v", replica.Host, err)
+		}
+	}
 }
 
-func handleGracefulShutdown(ctx context.Context, app *fiber.App, serverErrors <-chan error) {
+func handleGracefulShutdown(ctx context.Context, app *fiber.App, cfg *config.Config, serverErrors <-chan error) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
@@ -99,6 +375,7 @@ func handleGracefulShutdown(ctx context.Context, app *fiber.App, serverErrors <-
 	case err := <-serverErrors:
 		utils.Log.Fatalf("Server error: %v", err)
 	case <-quit:
+		drain(cfg.App.ShutdownDrain)
 		utils.Log.Info("Shutting down server...")
 		if err := app.Shutdown(); err != nil {
 			utils.Log.Fatalf("Error during server shutdown: %v", err)
@@ -109,3 +386,17 @@ func handleGracefulShutdown(ctx context.Context, app *fiber.App, serverErrors <-
 
 	utils.Log.Info("Server exited")
 }
+
+// drain fails readiness and waits for load balancers to notice before the server stops accepting requests
+func drain(duration time.Duration) {
+	if err := health.Drain(); err != nil {
+		utils.Log.Errorf("Failed to share drain state: %v", err)
+	}
+
+	if duration <= 0 {
+		return
+	}
+
+	utils.Log.Infof("Draining for %s...", duration)
+	time.Sleep(duration)
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const Path = "/metrics"
+
+const (
+	LoginPassword = "password"
+	LoginGoogle   = "google"
+
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+)
+
+// Registry holds the application metrics, which are merged across prefork processes.
+// Go runtime and process metrics are kept apart in runtimeRegistry since they only
+// make sense per process.
+var (
+	Registry        = prometheus.NewRegistry()
+	runtimeRegistry = prometheus.NewRegistry()
+)
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests by method, route template and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_logins_total",
+		Help: "Number of login attempts by method and result.",
+	}, []string{"method", "result"})
+
+	tokensIssued = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_tokens_issued_total",
+		Help: "Number of tokens issued by type.",
+	}, []string{"type"})
+
+	emails = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "emails_total",
+		Help: "Number of emails by result.",
+	}, []string{"result"})
+
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rate_limited_requests_total",
+		Help: "Number of requests rejected by rate limit policy.",
+	}, []string{"policy"})
+)
+
+func init() {
+	Registry.MustRegister(httpRequests, httpDuration, logins, tokensIssued, emails, rateLimited)
+	runtimeRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegisterDB exposes the connection pool stats of a database, name tells the pools apart
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+func ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(elapsed.Seconds())
+}
+
+func ObserveLogin(method string, err error) {
+	logins.WithLabelValues(method, result(err)).Inc()
+}
+
+func ObserveTokenIssued(tokenType string) {
+	tokensIssued.WithLabelValues(tokenType).Inc()
+}
+
+func ObserveEmail(err error) {
+	status := "sent"
+	if err != nil {
+		status = ResultFailed
+	}
+	emails.WithLabelValues(status).Inc()
+}
+
+func ObserveRateLimited(policy string) {
+	rateLimited.WithLabelValues(policy).Inc()
+}
+
+func result(err error) string {
+	if err != nil {
+		return ResultFailed
+	}
+	return ResultSucceeded
+}
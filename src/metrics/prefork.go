@@ -0,0 +1,240 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+)
+
+// With prefork every request, scrapes included, lands on a random child process,
+// each with its own counters. Children therefore write a snapshot of Registry to
+// a directory shared by the processes of one server, and the child answering the
+// scrape sums the snapshots of the others with its own live values.
+
+const snapshotExt = ".pb"
+
+var snapshotFormat = expfmt.NewFormat(expfmt.TypeProtoDelim)
+
+// Dir is the snapshot directory of the server the current process belongs to
+func Dir(isChild bool) string {
+	parent := os.Getpid()
+	if isChild {
+		parent = os.Getppid()
+	}
+
+	return filepath.Join(os.TempDir(), fmt.Sprintf("app-metrics-%d", parent))
+}
+
+// Gatherer returns what /metrics serves. dir is empty when the server doesn't prefork.
+func Gatherer(dir string) prometheus.Gatherer {
+	if dir == "" {
+		return prometheus.Gatherers{Registry, runtimeRegistry}
+	}
+
+	return prometheus.Gatherers{&snapshotGatherer{dir: dir}, runtimeRegistry}
+}
+
+// WriteSnapshots writes the metrics of this process to dir every interval until ctx is done
+func WriteSnapshots(ctx context.Context, dir string, interval time.Duration) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, strconv.Itoa(os.Getpid())+snapshotExt)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := writeSnapshot(path); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return os.Remove(path)
+		case <-ticker.C:
+		}
+	}
+}
+
+func writeSnapshot(path string) error {
+	families, err := Registry.Gather()
+	if err != nil {
+		return err
+	}
+
+	// Write then rename, so a reader never sees a partial snapshot
+	tmp, err := os.CreateTemp(filepath.Dir(path), "snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	encoder := expfmt.NewEncoder(tmp, snapshotFormat)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+type snapshotGatherer struct {
+	dir string
+}
+
+func (g *snapshotGatherer) Gather() ([]*dto.MetricFamily, error) {
+	live, err := Registry.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	sources := [][]*dto.MetricFamily{live}
+
+	entries, err := os.ReadDir(g.dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), snapshotExt))
+		if err != nil || !strings.HasSuffix(entry.Name(), snapshotExt) || pid == os.Getpid() {
+			continue
+		}
+
+		path := filepath.Join(g.dir, entry.Name())
+		if !processAlive(pid) {
+			_ = os.Remove(path)
+			continue
+		}
+
+		families, err := readSnapshot(path)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, families)
+	}
+
+	return merge(sources...), nil
+}
+
+func readSnapshot(path string) ([]*dto.MetricFamily, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var families []*dto.MetricFamily
+	decoder := expfmt.NewDecoder(file, snapshotFormat)
+	for {
+		family := new(dto.MetricFamily)
+		if err := decoder.Decode(family); err != nil {
+			if errors.Is(err, io.EOF) {
+				return families, nil
+			}
+			return nil, err
+		}
+		families = append(families, family)
+	}
+}
+
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+// merge sums the series with the same name and labels. Every process runs the
+// same code, so histograms share their buckets.
+func merge(sources ...[]*dto.MetricFamily) []*dto.MetricFamily {
+	families := make(map[string]*dto.MetricFamily)
+	series := make(map[string]*dto.Metric)
+
+	for _, source := range sources {
+		for _, family := range source {
+			merged, ok := families[family.GetName()]
+			if !ok {
+				merged = &dto.MetricFamily{Name: family.Name, Help: family.Help, Type: family.Type}
+				families[family.GetName()] = merged
+			}
+
+			for _, metric := range family.Metric {
+				key := seriesKey(family.GetName(), metric.Label)
+				if existing, ok := series[key]; ok {
+					add(existing, metric)
+					continue
+				}
+
+				clone := proto.Clone(metric).(*dto.Metric)
+				series[key] = clone
+				merged.Metric = append(merged.Metric, clone)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]*dto.MetricFamily, 0, len(names))
+	for _, name := range names {
+		result = append(result, families[name])
+	}
+
+	return result
+}
+
+func seriesKey(name string, labels []*dto.LabelPair) string {
+	var key strings.Builder
+	key.WriteString(name)
+	for _, label := range labels {
+		fmt.Fprintf(&key, "\xff%s=%s", label.GetName(), label.GetValue())
+	}
+	return key.String()
+}
+
+func add(dst, src *dto.Metric) {
+	switch {
+	case dst.Counter != nil && src.Counter != nil:
+		dst.Counter.Value = proto.Float64(dst.Counter.GetValue() + src.Counter.GetValue())
+	case dst.Gauge != nil && src.Gauge != nil:
+		dst.Gauge.Value = proto.Float64(dst.Gauge.GetValue() + src.Gauge.GetValue())
+	case dst.Untyped != nil && src.Untyped != nil:
+		dst.Untyped.Value = proto.Float64(dst.Untyped.GetValue() + src.Untyped.GetValue())
+	case dst.Histogram != nil && src.Histogram != nil:
+		dst.Histogram.SampleCount = proto.Uint64(dst.Histogram.GetSampleCount() + src.Histogram.GetSampleCount())
+		dst.Histogram.SampleSum = proto.Float64(dst.Histogram.GetSampleSum() + src.Histogram.GetSampleSum())
+		for i, bucket := range dst.Histogram.Bucket {
+			if i < len(src.Histogram.Bucket) {
+				count := bucket.GetCumulativeCount() + src.Histogram.Bucket[i].GetCumulativeCount()
+				bucket.CumulativeCount = proto.Uint64(count)
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"app/src/config"
+	"app/src/problem"
 	"app/src/service"
 	"app/src/utils"
 	"strings"
@@ -9,31 +10,35 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func Auth(userService service.UserService, requiredRights ...string) fiber.Handler {
+func Auth(userService service.UserService, tokenService service.TokenService, requiredRights ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		token := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
 
 		if token == "" {
-			return fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+			return problem.ErrUnauthenticated
 		}
 
-		userID, err := utils.VerifyToken(token, config.JWTSecret, config.TokenTypeAccess)
+		userID, err := tokenService.ParseToken(token, config.TokenTypeAccess)
 		if err != nil {
-			return fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+			return problem.ErrUnauthenticated
 		}
 
-		user, err := userService.GetUserByID(c, userID)
+		user, err := userService.GetUserByID(c.UserContext(), userID)
 		if err != nil || user == nil {
-			return fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+			return problem.ErrUnauthenticated
 		}
 
 		c.Locals("user", user)
+		c.SetUserContext(utils.WithLogger(c.UserContext(), utils.Logger(c.UserContext()).WithField("user_id", userID)))
+		if user.Language != "" {
+			setLanguage(c, user.Language)
+		}
 
 		if len(requiredRights) > 0 {
 			userRights, hasRights := config.RoleRights[user.Role]
 			if (!hasRights || !hasAllRights(userRights, requiredRights)) && c.Params("userId") != userID {
-				return fiber.NewError(fiber.StatusForbidden, "You don't have permission to access this resource")
+				return problem.ErrForbidden
 			}
 		}
 
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"app/src/model"
+	"app/src/problem"
+	"app/src/service"
+	"app/src/utils"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotencyReplayed = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLength bounds the key, clients usually send a UUID
+	maxIdempotencyKeyLength = 255
+)
+
+// skippedIdempotencyHeaders describe a single response, they aren't replayed,
+// nor are the RateLimit-* headers
+var skippedIdempotencyHeaders = []string{
+	fiber.HeaderDate, fiber.HeaderContentLength, fiber.HeaderServer, fiber.HeaderSetCookie,
+	fiber.HeaderXRequestID, fiber.HeaderRetryAfter,
+}
+
+// Idempotency replays the response of the first request sent with the same
+// Idempotency-Key header by the same user, instead of running the handler
+// again. Requests without the header are handled as usual.
+//
+// A key reused with a different method, path or body is rejected with 422,
+// and a retry arriving while the first request is in progress with 409.
+// Responses with a 5xx status aren't kept, so the request can be retried.
+func Idempotency(idempotencyService service.IdempotencyService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(HeaderIdempotencyKey)
+		if key == "" {
+			return c.Next()
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			return problem.ErrIdempotencyKeyTooLong
+		}
+
+		ctx := c.UserContext()
+		scope := "anonymous"
+		if user, ok := c.Locals("user").(*model.User); ok {
+			scope = user.ID.String()
+		}
+		fingerprint := utils.HashToken(c.Method() + " " + c.Path() + "\n" + string(c.Body()))
+
+		stored, err := idempotencyService.Begin(ctx, scope, key, fingerprint)
+		if err != nil {
+			return err
+		}
+
+		if stored != nil {
+			for name, value := range stored.Headers {
+				c.Set(name, value)
+			}
+			c.Set(HeaderIdempotencyReplayed, "true")
+
+			return c.Status(stored.Status).Send(stored.Body)
+		}
+
+		// The response is kept once complete, errors included
+		handleError(c, c.Next())
+
+		res := &model.IdempotentResponse{
+			Status:  c.Response().StatusCode(),
+			Headers: make(map[string]string),
+			Body:    append([]byte(nil), c.Response().Body()...),
+		}
+
+		if res.Status >= fiber.StatusInternalServerError {
+			_ = idempotencyService.Release(ctx, scope, key)
+			return nil
+		}
+
+		c.Response().Header.VisitAll(func(name, value []byte) {
+			if replayedHeader(string(name)) {
+				res.Headers[string(name)] = string(value)
+			}
+		})
+
+		if err := idempotencyService.Complete(ctx, scope, key, res); err != nil {
+			// The response was sent, a retry runs the request again
+			_ = idempotencyService.Release(ctx, scope, key)
+		}
+
+		return nil
+	}
+}
+
+func replayedHeader(name string) bool {
+	if strings.HasPrefix(strings.ToLower(name), "ratelimit-") {
+		return false
+	}
+
+	for _, skipped := range skippedIdempotencyHeaders {
+		if strings.EqualFold(name, skipped) {
+			return false
+		}
+	}
+	return true
+}
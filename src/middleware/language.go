@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"app/src/i18n"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Language speaks the language the Accept-Language header prefers, Auth
+// switches to the one the user chose if any
+func Language() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Vary(fiber.HeaderAcceptLanguage)
+		setLanguage(c, i18n.Match(c.Get(fiber.HeaderAcceptLanguage)))
+
+		return c.Next()
+	}
+}
+
+func setLanguage(c *fiber.Ctx, lang string) {
+	c.SetUserContext(i18n.WithLanguage(c.UserContext(), lang))
+	c.Set(fiber.HeaderContentLanguage, lang)
+}
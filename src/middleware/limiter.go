@@ -1,25 +1,131 @@
 package middleware
 
 import (
-	"app/src/response"
+	"app/src/config"
+	"app/src/metrics"
+	"app/src/model"
+	"app/src/problem"
+	"app/src/ratelimit"
+	"app/src/utils"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/limiter"
 )
 
-func LimiterConfig() fiber.Handler {
-	return limiter.New(limiter.Config{
-		Max:        20,
-		Expiration: 15 * time.Minute,
-		LimitReached: func(c *fiber.Ctx) error {
-			return c.Status(fiber.StatusTooManyRequests).
-				JSON(response.Common{
-					Code:    fiber.StatusTooManyRequests,
-					Status:  "error",
-					Message: "Too many requests, please try again later",
-				})
-		},
-		SkipSuccessfulRequests: true,
-	})
+// RateLimiter enforces the rate limit policies of the config, counting the requests in Store
+type RateLimiter struct {
+	Store  ratelimit.Store
+	Config *config.Live
+}
+
+func NewRateLimiter(store ratelimit.Store, cfg *config.Live) *RateLimiter {
+	return &RateLimiter{
+		Store:  store,
+		Config: cfg,
+	}
+}
+
+// Limit enforces the named policy. Its limit is read on every request, so a
+// reloaded limit applies at once to the current windows.
+//
+// The RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy
+// headers describe the policy closest to its limit when several apply.
+func (l *RateLimiter) Limit(name string) fiber.Handler {
+	if _, ok := l.Config.Get().RateLimit.Policies[name]; !ok {
+		panic(fmt.Sprintf("unknown rate limit policy %q", name))
+	}
+
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		policy := l.Config.Get().RateLimit.Policies[name]
+		key := name + ":" + utils.HashToken(rateLimitKey(c, policy.Key))
+
+		window, err := l.Store.Hit(ctx, key, policy.Window)
+		if err != nil {
+			// A rate limit store that is down doesn't take the API down with it
+			utils.Logger(ctx).Errorf("Failed to count the request for rate limit %s: %v", name, err)
+			return c.Next()
+		}
+
+		if window.Hits > policy.Max {
+			setRateLimitHeaders(c, policy, window)
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(secondsUntil(window.ResetAt)))
+			metrics.ObserveRateLimited(name)
+
+			return problem.ErrRateLimited
+		}
+
+		err = c.Next()
+
+		if policy.FailedOnly && !failed(c, err) {
+			if err := l.Store.Undo(ctx, key); err != nil {
+				utils.Logger(ctx).Errorf("Failed to undo the request for rate limit %s: %v", name, err)
+			} else {
+				window.Hits--
+			}
+		}
+		setRateLimitHeaders(c, policy, window)
+
+		return err
+	}
+}
+
+// rateLimitKey identifies the client the requests are counted for. The IP is
+// used when the request has no user or no email.
+func rateLimitKey(c *fiber.Ctx, by string) string {
+	switch by {
+	case config.RateLimitByUser:
+		if user, ok := c.Locals("user").(*model.User); ok {
+			return "user:" + user.ID.String()
+		}
+	case config.RateLimitByEmail:
+		var body struct {
+			Email string `json:"email" form:"email"`
+		}
+		if err := c.BodyParser(&body); err == nil && strings.TrimSpace(body.Email) != "" {
+			return "email:" + strings.ToLower(strings.TrimSpace(body.Email))
+		}
+	}
+
+	return "ip:" + c.IP()
+}
+
+// failed tells whether the request ends with an error status. A returned error
+// is turned into the response by the error handler, after the middleware.
+func failed(c *fiber.Ctx, err error) bool {
+	if err == nil {
+		return c.Response().StatusCode() >= fiber.StatusBadRequest
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code >= fiber.StatusBadRequest
+	}
+
+	return true
+}
+
+func setRateLimitHeaders(c *fiber.Ctx, policy config.RateLimitPolicy, window ratelimit.Window) {
+	remaining := max(policy.Max-window.Hits, 0)
+
+	// Another policy of the route is closer to its limit
+	if current := c.GetRespHeader("RateLimit-Remaining"); current != "" {
+		if other, err := strconv.Atoi(current); err == nil && other < remaining {
+			return
+		}
+	}
+
+	c.Set("RateLimit-Limit", strconv.Itoa(policy.Max))
+	c.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	c.Set("RateLimit-Reset", strconv.Itoa(secondsUntil(window.ResetAt)))
+	c.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Max, int(policy.Window.Seconds())))
+}
+
+func secondsUntil(t time.Time) int {
+	return max(int(math.Ceil(time.Until(t).Seconds())), 0)
 }
@@ -1,13 +1,50 @@
 package middleware
 
 import (
+	"app/src/model"
+	"app/src/utils"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/sirupsen/logrus"
 )
 
+// LoggerConfig attaches a request scoped logger to the user context and writes
+// one structured line per request once the response is known
 func LoggerConfig() fiber.Handler {
-	return logger.New(logger.Config{
-		Format:     "${time} ${method} ${status} ${path} in ${latency}\n",
-		TimeFormat: "15:04:05.00",
-	})
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		entry := utils.Log.WithFields(logrus.Fields{
+			"request_id": GetRequestID(c),
+			"method":     c.Method(),
+			"path":       c.Path(),
+		})
+		c.SetUserContext(utils.WithLogger(c.UserContext(), entry))
+
+		handleError(c, c.Next())
+
+		status := c.Response().StatusCode()
+		fields := logrus.Fields{
+			"route":      c.Route().Path,
+			"status":     status,
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"ip":         c.IP(),
+		}
+		if user, ok := c.Locals("user").(*model.User); ok {
+			fields["user_id"] = user.ID.String()
+		}
+
+		log := utils.Logger(c.UserContext()).WithFields(fields)
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			log.Error("Request completed")
+		case status >= fiber.StatusBadRequest:
+			log.Warn("Request completed")
+		default:
+			log.Info("Request completed")
+		}
+
+		return nil
+	}
 }
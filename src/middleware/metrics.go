@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"app/src/metrics"
+	"app/src/problem"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Metrics records the count and latency of requests by route template and status
+func Metrics() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Path() == metrics.Path {
+			return c.Next()
+		}
+
+		start := time.Now()
+
+		handleError(c, c.Next())
+
+		metrics.ObserveRequest(c.Method(), c.Route().Path, c.Response().StatusCode(), time.Since(start))
+
+		return nil
+	}
+}
+
+// MetricsAuth requires the token as a bearer token, when it is set
+func MetricsAuth(metricsToken string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if metricsToken == "" {
+			return c.Next()
+		}
+
+		token := strings.TrimSpace(strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "))
+		if subtle.ConstantTimeCompare([]byte(token), []byte(metricsToken)) != 1 {
+			return problem.ErrUnauthenticated
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+const requestIDKey = "requestid"
+
+// maxRequestIDLength bounds the incoming X-Request-ID, it ends up in every log line
+const maxRequestIDLength = 128
+
+// RequestID reuses a well formed incoming X-Request-ID or generates one,
+// and echoes it in the response
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(fiber.HeaderXRequestID)
+		if !validRequestID(id) {
+			id = utils.UUIDv4()
+		}
+
+		c.Locals(requestIDKey, id)
+		c.Set(fiber.HeaderXRequestID, id)
+
+		return c.Next()
+	}
+}
+
+// GetRequestID returns the id assigned by RequestID, empty when it isn't used
+func GetRequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals(requestIDKey).(string)
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DataRequestExport  = "export"
+	DataRequestErasure = "erasure"
+
+	// DataRequestUnconfirmed is an erasure waiting for the link sent by email
+	DataRequestUnconfirmed = "unconfirmed"
+	DataRequestPending     = "pending"
+	// DataRequestProcessing is a request claimed by a run of the job
+	DataRequestProcessing = "processing"
+	DataRequestCompleted  = "completed"
+	DataRequestFailed     = "failed"
+	// DataRequestExpired is an export whose archive was deleted
+	DataRequestExpired   = "expired"
+	DataRequestCancelled = "cancelled"
+)
+
+// DataRequest is an export of the data of a user, or the erasure of the user,
+// run by the process-data-requests job once ScheduledAt is reached. It only
+// holds the id of the user, so it is kept after the erasure as its record.
+type DataRequest struct {
+	ID     uuid.UUID `gorm:"primaryKey;not null" json:"id"`
+	UserID uuid.UUID `gorm:"not null" json:"-"`
+	Type   string    `gorm:"not null" json:"type"`
+	Status string    `gorm:"not null" json:"status"`
+	// Archive is the storage key of the export, until it expires
+	Archive     string     `gorm:"default:null" json:"-"`
+	Error       string     `gorm:"default:null" json:"-"`
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime:milli" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+}
+
+func (request *DataRequest) BeforeCreate(_ *gorm.DB) error {
+	request.ID = uuid.New()
+	return nil
+}
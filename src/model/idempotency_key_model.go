@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// IdempotencyKey holds the response of the first request sent with a key, to
+// replay it to the retries. ID hashes the key with its user, Response is
+// encrypted with the key, so neither can be read from the database.
+type IdempotencyKey struct {
+	ID          string `gorm:"primaryKey;not null"`
+	Fingerprint string `gorm:"not null"`
+	// Status is 0 while the first request is in progress
+	Status    int `gorm:"not null"`
+	Response  []byte
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime:milli"`
+}
+
+// IdempotentResponse is the response replayed for an idempotency key
+type IdempotentResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    []byte            `json:"body"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+)
+
+// JobLease is held by the instance running a job, until LockedUntil
+type JobLease struct {
+	Name        string    `gorm:"primaryKey;not null"`
+	Owner       string    `gorm:"not null"`
+	LockedUntil time.Time `gorm:"not null"`
+	UpdatedAt   time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli"`
+}
+
+type JobRun struct {
+	ID         uuid.UUID  `gorm:"primaryKey;not null" json:"id"`
+	Name       string     `gorm:"not null" json:"name"`
+	Owner      string     `gorm:"not null" json:"owner"`
+	Status     string     `gorm:"not null" json:"status"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `gorm:"not null" json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime:milli" json:"-"`
+}
+
+func (run *JobRun) BeforeCreate(_ *gorm.DB) error {
+	run.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// RateLimit counts the requests of a rate limit key in the window ending at ResetAt
+type RateLimit struct {
+	Key     string    `gorm:"primaryKey;column:rate_key;not null"`
+	Hits    int       `gorm:"not null"`
+	ResetAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"app/src/validation"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	UserImportPending = "pending"
+	// UserImportProcessing is an import claimed by a run of the job
+	UserImportProcessing = "processing"
+	UserImportCompleted  = "completed"
+	UserImportFailed     = "failed"
+
+	UserImportRowCreated = "created"
+	UserImportRowFailed  = "failed"
+)
+
+// UserImport is a file of users uploaded by an admin. Small files are
+// imported by the request, the others by the import-users job.
+type UserImport struct {
+	ID        uuid.UUID `gorm:"primaryKey;not null" json:"id"`
+	CreatedBy uuid.UUID `gorm:"not null" json:"created_by"`
+	Format    string    `gorm:"not null" json:"format"`
+	Invite    bool      `gorm:"not null" json:"invite"`
+	// Language is the language of the messages of the results
+	Language string `gorm:"default:null" json:"-"`
+	Status   string `gorm:"not null" json:"status"`
+	// File is the storage key of the uploaded file, until it is imported
+	File        string          `gorm:"default:null" json:"-"`
+	Error       string          `gorm:"default:null" json:"-"`
+	Total       int             `gorm:"not null" json:"total"`
+	Created     int             `gorm:"not null" json:"created"`
+	Failed      int             `gorm:"not null" json:"failed"`
+	Results     []UserImportRow `gorm:"serializer:json" json:"results,omitempty"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	CreatedAt   time.Time       `gorm:"autoCreateTime:milli" json:"created_at"`
+	UpdatedAt   time.Time       `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+}
+
+// UserImportRow is the result of a row, Line is where it starts in the file.
+// A failed row has the code of the problem, and the errors of its fields
+// when it isn't valid. It keeps no email: the imports are kept after the
+// users they created are erased, and the line finds the row in the file.
+type UserImportRow struct {
+	Line    int                              `json:"line"`
+	Status  string                           `json:"status"`
+	UserID  *uuid.UUID                       `json:"user_id,omitempty"`
+	Invited bool                             `json:"invited,omitempty"`
+	Code    string                           `json:"code,omitempty"`
+	Title   string                           `json:"title,omitempty"`
+	Errors  map[string]validation.FieldError `json:"errors,omitempty"`
+}
+
+func (userImport *UserImport) BeforeCreate(_ *gorm.DB) error {
+	userImport.ID = uuid.New()
+	return nil
+}
@@ -14,12 +14,32 @@ type User struct {
 	Password      string    `gorm:"not null" json:"-"`
 	Role          string    `gorm:"default:user;not null" json:"role"`
 	VerifiedEmail bool      `gorm:"default:false;not null" json:"verified_email"`
-	CreatedAt     time.Time `gorm:"autoCreateTime:milli" json:"-"`
-	UpdatedAt     time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
-	Token         []Token   `gorm:"foreignKey:user_id;references:id" json:"-"`
+	// Language is the language the user chose, empty to follow Accept-Language
+	Language string `gorm:"default:null" json:"language,omitempty"`
+	// Avatar is the file name of the thumbnails of the avatar, served at AvatarURL
+	Avatar    string `gorm:"default:null" json:"-"`
+	AvatarURL string `gorm:"-" json:"avatar_url,omitempty"`
+	// Version is bumped by every update, it backs the ETag of the user
+	Version   int64          `gorm:"default:1;not null" json:"-"`
+	CreatedAt time.Time      `gorm:"autoCreateTime:milli" json:"-"`
+	UpdatedAt time.Time      `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	Token     []Token        `gorm:"foreignKey:user_id;references:id" json:"-"`
 }
 
+// AvatarPath serves the avatars, by file name
+const AvatarPath = "/v1/avatars/"
+
 func (user *User) BeforeCreate(_ *gorm.DB) error {
 	user.ID = uuid.New() // Generate UUID before create
+	user.Version = 1
+	return nil
+}
+
+func (user *User) AfterFind(_ *gorm.DB) error {
+	user.AvatarURL = ""
+	if user.Avatar != "" {
+		user.AvatarURL = AvatarPath + user.Avatar
+	}
 	return nil
 }
@@ -0,0 +1,74 @@
+package problem
+
+import "github.com/gofiber/fiber/v2"
+
+// The codes are part of the API, once released they are never renamed
+var (
+	ErrValidation            = New(fiber.StatusBadRequest, "validation-failed", "Validation failed")
+	ErrInvalidRequestBody    = New(fiber.StatusBadRequest, "invalid-request-body", "Invalid request body")
+	ErrInvalidRequest        = New(fiber.StatusBadRequest, "invalid-request", "Invalid Request")
+	ErrInvalidUserID         = New(fiber.StatusBadRequest, "invalid-user-id", "Invalid user ID")
+	ErrInvalidCursor         = New(fiber.StatusBadRequest, "invalid-cursor", "Invalid cursor")
+	ErrInvalidSort           = New(fiber.StatusBadRequest, "invalid-sort", "Invalid sort")
+	ErrAvatarMissing         = New(fiber.StatusBadRequest, "avatar-missing", "The avatar file is missing")
+	ErrImportMissing         = New(fiber.StatusBadRequest, "import-missing", "The import file is missing")
+	ErrIdempotencyKeyTooLong = New(fiber.StatusBadRequest, "idempotency-key-too-long",
+		"Idempotency-Key must be at most 255 characters")
+
+	ErrUnauthenticated     = New(fiber.StatusUnauthorized, "unauthenticated", "Please authenticate")
+	ErrInvalidCredentials  = New(fiber.StatusUnauthorized, "invalid-credentials", "Invalid email or password")
+	ErrInvalidToken        = New(fiber.StatusUnauthorized, "invalid-token", "Invalid Token")
+	ErrPasswordResetFailed = New(fiber.StatusUnauthorized, "password-reset-failed", "Password reset failed")
+	ErrVerifyEmailFailed   = New(fiber.StatusUnauthorized, "verify-email-failed", "Verify email failed")
+	ErrOAuthStateMismatch  = New(fiber.StatusUnauthorized, "oauth-state-mismatch", "States don't Match!")
+	ErrForbidden           = New(fiber.StatusForbidden, "forbidden",
+		"You don't have permission to access this resource")
+	ErrCurrentPasswordInvalid = New(fiber.StatusForbidden, "current-password-invalid", "Current password is incorrect")
+	ErrReauthRequired         = New(fiber.StatusForbidden, "reauth-required",
+		"The account has no password, confirm it is you with the link sent by POST /v1/me/reauth")
+
+	ErrEndpointNotFound    = New(fiber.StatusNotFound, "endpoint-not-found", "Endpoint Not Found")
+	ErrUserNotFound        = New(fiber.StatusNotFound, "user-not-found", "User not found")
+	ErrDeletedUserNotFound = New(fiber.StatusNotFound, "deleted-user-not-found", "Deleted user not found")
+	ErrTokenNotFound       = New(fiber.StatusNotFound, "token-not-found", "Token not found")
+	ErrAvatarNotFound      = New(fiber.StatusNotFound, "avatar-not-found", "Avatar not found")
+	ErrProblemNotFound     = New(fiber.StatusNotFound, "problem-not-found", "Problem type not found")
+	ErrExportNotFound      = New(fiber.StatusNotFound, "export-not-found", "Export not found")
+	ErrErasureNotFound     = New(fiber.StatusNotFound, "erasure-not-found", "No erasure of the account was requested")
+	ErrImportNotFound      = New(fiber.StatusNotFound, "import-not-found", "Import not found")
+
+	ErrEmailTaken               = New(fiber.StatusConflict, "email-taken", "Email is already in use")
+	ErrIdempotencyKeyInProgress = New(fiber.StatusConflict, "idempotency-key-in-progress",
+		"A request with this Idempotency-Key is in progress")
+	ErrExportNotReady   = New(fiber.StatusConflict, "export-not-ready", "The export is not ready yet")
+	ErrErasureScheduled = New(fiber.StatusConflict, "erasure-scheduled", "The erasure of the account is already scheduled")
+	ErrErasureRunning   = New(fiber.StatusConflict, "erasure-running", "The erasure of the account is running")
+
+	ErrUserModified    = New(fiber.StatusPreconditionFailed, "user-modified", "User was modified since it was read")
+	ErrIfMatchMismatch = New(fiber.StatusPreconditionFailed, "if-match-mismatch",
+		"If-Match doesn't match the current version")
+
+	ErrAvatarTooLarge        = New(fiber.StatusRequestEntityTooLarge, "avatar-too-large", "Avatar is too large")
+	ErrAvatarUnsupportedType = New(fiber.StatusUnsupportedMediaType, "avatar-unsupported-type",
+		"Avatar must be a JPEG, PNG, GIF or WebP picture")
+	ErrImportTooLarge        = New(fiber.StatusRequestEntityTooLarge, "import-too-large", "The import file is too large")
+	ErrImportUnsupportedType = New(fiber.StatusUnsupportedMediaType, "import-unsupported-type",
+		"The import file must be a CSV or NDJSON file")
+
+	ErrIdempotencyKeyReused = New(fiber.StatusUnprocessableEntity, "idempotency-key-reused",
+		"Idempotency-Key was already used with a different request")
+	ErrConfirmationMismatch = New(fiber.StatusUnprocessableEntity, "confirmation-mismatch",
+		"Confirm must be the email of the account")
+	ErrAvatarInvalid       = New(fiber.StatusUnprocessableEntity, "avatar-invalid", "Avatar is not a valid picture")
+	ErrAvatarTooManyPixels = New(fiber.StatusUnprocessableEntity, "avatar-too-many-pixels", "Avatar has too many pixels")
+	ErrImportInvalid       = New(fiber.StatusUnprocessableEntity, "import-invalid",
+		"The import file has no users, a CSV file needs an email column")
+	ErrImportTooManyRows = New(fiber.StatusUnprocessableEntity, "import-too-many-rows",
+		"The import file has too many rows")
+	ErrImportRowInvalid = New(fiber.StatusUnprocessableEntity, "import-row-invalid",
+		"The row of the import can't be read")
+
+	ErrRateLimited = New(fiber.StatusTooManyRequests, "rate-limited", "Too many requests, please try again later")
+
+	ErrInternal = New(fiber.StatusInternalServerError, "internal-error", "Internal Server Error")
+)
@@ -0,0 +1,109 @@
+// Package problem holds the catalogue of the errors returned by the API. Each
+// error has a stable code clients can match on, and is rendered as an RFC 7807
+// problem by utils.ErrorHandler.
+package problem
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TypePrefix is the path serving the description of a code, it prefixes the
+// type of the problems
+const TypePrefix = "/v1/problems/"
+
+// Error is an error of the catalogue. Title describes every occurrence of the
+// code, detail this one.
+type Error struct {
+	Status int
+	Code   string
+	Title  string
+	detail string
+}
+
+var catalogue = make(map[string]*Error)
+
+// New adds an error to the catalogue, it panics when the code is already used
+func New(status int, code, title string) *Error {
+	if _, exists := catalogue[code]; exists {
+		panic("duplicate problem code " + code)
+	}
+
+	e := &Error{Status: status, Code: code, Title: title}
+	catalogue[code] = e
+
+	return e
+}
+
+// Lookup returns the error of the catalogue with the code
+func Lookup(code string) (*Error, bool) {
+	e, ok := catalogue[code]
+	return e, ok
+}
+
+// All returns the errors of the catalogue by status, then code
+func All() []*Error {
+	all := make([]*Error, 0, len(catalogue))
+	for _, e := range catalogue {
+		all = append(all, e)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Status != all[j].Status {
+			return all[i].Status < all[j].Status
+		}
+		return all[i].Code < all[j].Code
+	})
+
+	return all
+}
+
+// FromStatus describes an error of the framework, e.g. a 405, which isn't in
+// the catalogue. The code is derived from the status text.
+func FromStatus(status int, message string) *Error {
+	code := strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "-"))
+	if code == "" {
+		code = "http-error"
+	}
+
+	return &Error{Status: status, Code: code, Title: message}
+}
+
+// WithDetail returns a copy of the error explaining this occurrence
+func (e *Error) WithDetail(detail string) *Error {
+	c := *e
+	c.detail = detail
+
+	return &c
+}
+
+// Detail returns the explanation of this occurrence, empty if there is none
+func (e *Error) Detail() string {
+	return e.detail
+}
+
+// Type is the URI reference identifying the code
+func (e *Error) Type() string {
+	return TypePrefix + e.Code
+}
+
+func (e *Error) Error() string {
+	if e.detail != "" {
+		return e.detail
+	}
+	return e.Title
+}
+
+// Is matches the errors with the same code, whatever their detail
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Unwrap exposes the status as a *fiber.Error, for the code that only knows those
+func (e *Error) Unwrap() error {
+	return fiber.NewError(e.Status, e.Error())
+}
@@ -0,0 +1,91 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	kindString = "string"
+	kindNumber = "number"
+	kindBool   = "bool"
+	kindTime   = "time"
+)
+
+// cursor points after the last item of a page. It keeps the sort it was
+// created for, so it can't be replayed against a different ordering.
+type cursor struct {
+	SortBy string      `json:"s"`
+	Order  string      `json:"o"`
+	Kind   string      `json:"k"`
+	Value  interface{} `json:"v"`
+	ID     string      `json:"id"`
+}
+
+func encodeCursor(sortBy, order string, value interface{}, id string) (string, error) {
+	c := cursor{SortBy: sortBy, Order: order, ID: id}
+
+	switch v := value.(type) {
+	case time.Time:
+		c.Kind, c.Value = kindTime, v.Format(time.RFC3339Nano)
+	case string:
+		c.Kind, c.Value = kindString, v
+	case bool:
+		c.Kind, c.Value = kindBool, v
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		c.Kind, c.Value = kindNumber, v
+	default:
+		c.Kind, c.Value = kindString, fmt.Sprint(v)
+	}
+
+	bytes, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+func decodeCursor(encoded string) (*cursor, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	c := new(cursor)
+	if err := json.Unmarshal(bytes, c); err != nil {
+		return nil, err
+	}
+
+	// Restore the Go type of the value so drivers compare it correctly
+	switch c.Kind {
+	case kindTime:
+		raw, ok := c.Value.(string)
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, err
+		}
+		c.Value = t
+	case kindString:
+		if _, ok := c.Value.(string); !ok {
+			return nil, ErrInvalidCursor
+		}
+	case kindBool:
+		if _, ok := c.Value.(bool); !ok {
+			return nil, ErrInvalidCursor
+		}
+	case kindNumber:
+		if _, ok := c.Value.(float64); !ok {
+			return nil, ErrInvalidCursor
+		}
+	default:
+		return nil, ErrInvalidCursor
+	}
+
+	return c, nil
+}
@@ -0,0 +1,241 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+
+	// SortRelevance orders by Params.Rank, best match first
+	SortRelevance = "relevance"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort column")
+	ErrInvalidFilter = errors.New("invalid filter")
+	ErrInvalidLimit  = errors.New("invalid limit")
+)
+
+// Options describes what a resource allows to be sorted and filtered on.
+// The maps translate public parameter names into column names, so only
+// whitelisted columns ever reach the SQL.
+type Options struct {
+	Sortable     map[string]string
+	Filterable   map[string]string
+	DefaultSort  string
+	DefaultOrder string
+	// PrimaryKey breaks ties between equal sort values, defaults to "id"
+	PrimaryKey string
+}
+
+// Params are the list parameters of a single request.
+type Params struct {
+	Cursor       string
+	Page         int
+	Limit        int
+	SortBy       string
+	Order        string
+	Filters      map[string]interface{}
+	IncludeTotal bool
+	// Rank is a relevance score, used when sorting by SortRelevance
+	// or when no sort is given
+	Rank *clause.Expr
+}
+
+// Result is one page of items. Total is only set when it was requested.
+type Result[T any] struct {
+	Items      []T
+	NextCursor string
+	Total      *int64
+}
+
+// Paginate runs the list query for T on db, which may already carry
+// additional conditions such as a search scope. A cursor continues after
+// the previous page (keyset pagination), otherwise Page is used as an offset.
+// Limit must be positive.
+func Paginate[T any](ctx context.Context, db *gorm.DB, params Params, opts Options) (*Result[T], error) {
+	if params.Limit <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidLimit, params.Limit)
+	}
+
+	sortBy, order, err := resolveSort(params, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	primaryKey := opts.PrimaryKey
+	if primaryKey == "" {
+		primaryKey = "id"
+	}
+
+	base := db.WithContext(ctx).Model(new(T))
+	for key, value := range params.Filters {
+		column, ok := opts.Filterable[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFilter, key)
+		}
+		base = base.Where(map[string]interface{}{column: value})
+	}
+	base = base.Session(&gorm.Session{})
+
+	result := new(Result[T])
+
+	if params.IncludeTotal {
+		var total int64
+		if err := base.Count(&total).Error; err != nil {
+			return nil, err
+		}
+		result.Total = &total
+	}
+
+	if sortBy == SortRelevance {
+		return paginateByRank[T](base, params, primaryKey, result)
+	}
+
+	column := opts.Sortable[sortBy]
+	find := base.Order(fmt.Sprintf("%s %s, %s %s", column, order, primaryKey, order))
+
+	if params.Cursor != "" {
+		c, err := decodeCursor(params.Cursor)
+		if err != nil || c.SortBy != sortBy || c.Order != order {
+			return nil, ErrInvalidCursor
+		}
+
+		operator := ">"
+		if order == OrderDesc {
+			operator = "<"
+		}
+
+		find = find.Where(
+			fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", column, operator, column, primaryKey, operator),
+			c.Value, c.Value, c.ID,
+		)
+	} else if params.Page > 1 {
+		find = find.Offset((params.Page - 1) * params.Limit)
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	var items []T
+	if err := find.Limit(params.Limit + 1).Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	if len(items) > params.Limit {
+		items = items[:params.Limit]
+
+		next, err := nextCursor(db, items[len(items)-1], column, primaryKey, sortBy, order)
+		if err != nil {
+			return nil, err
+		}
+		result.NextCursor = next
+	}
+
+	result.Items = items
+
+	return result, nil
+}
+
+// paginateByRank orders by a computed score, which has no stable keyset,
+// so its cursor carries the offset of the next page instead.
+func paginateByRank[T any](base *gorm.DB, params Params, primaryKey string, result *Result[T]) (*Result[T], error) {
+	offset := 0
+	if params.Cursor != "" {
+		c, err := decodeCursor(params.Cursor)
+		if err != nil || c.SortBy != SortRelevance || c.Kind != kindNumber {
+			return nil, ErrInvalidCursor
+		}
+		value := c.Value.(float64)
+		if value < 0 || value != math.Trunc(value) {
+			return nil, ErrInvalidCursor
+		}
+		offset = int(value)
+	} else if params.Page > 1 {
+		offset = (params.Page - 1) * params.Limit
+	}
+
+	// An expression is used since order by columns can't bind the rank variables
+	find := base.Clauses(clause.OrderBy{Expression: clause.Expr{
+		SQL:                "(" + params.Rank.SQL + ") DESC, " + primaryKey,
+		Vars:               params.Rank.Vars,
+		WithoutParentheses: true,
+	}})
+
+	var items []T
+	if err := find.Offset(offset).Limit(params.Limit + 1).Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	if len(items) > params.Limit {
+		items = items[:params.Limit]
+
+		next, err := encodeCursor(SortRelevance, OrderDesc, offset+params.Limit, "")
+		if err != nil {
+			return nil, err
+		}
+		result.NextCursor = next
+	}
+
+	result.Items = items
+
+	return result, nil
+}
+
+func resolveSort(params Params, opts Options) (string, string, error) {
+	sortBy := params.SortBy
+	if params.Rank != nil && (sortBy == "" || sortBy == SortRelevance) {
+		return SortRelevance, OrderDesc, nil
+	}
+
+	if sortBy == "" {
+		sortBy = opts.DefaultSort
+	}
+
+	if _, ok := opts.Sortable[sortBy]; !ok {
+		return "", "", fmt.Errorf("%w: %s", ErrInvalidSort, sortBy)
+	}
+
+	order := params.Order
+	if order == "" {
+		order = opts.DefaultOrder
+	}
+
+	switch order {
+	case OrderAsc, OrderDesc:
+		return sortBy, order, nil
+	case "":
+		return sortBy, OrderAsc, nil
+	default:
+		return "", "", fmt.Errorf("%w: %s", ErrInvalidSort, order)
+	}
+}
+
+// nextCursor reads the sort and primary key values of the last item
+// through the GORM schema, so it works for any model.
+func nextCursor[T any](db *gorm.DB, last T, column, primaryKey, sortBy, order string) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&last); err != nil {
+		return "", err
+	}
+
+	sortField := stmt.Schema.LookUpField(column)
+	keyField := stmt.Schema.LookUpField(primaryKey)
+	if sortField == nil || keyField == nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidSort, column)
+	}
+
+	value := reflect.ValueOf(&last).Elem()
+	sortValue, _ := sortField.ValueOf(context.Background(), value)
+	keyValue, _ := keyField.ValueOf(context.Background(), value)
+
+	return encodeCursor(sortBy, order, sortValue, fmt.Sprint(keyValue))
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the expired windows are deleted
+const sweepInterval = time.Minute
+
+// MemoryStore counts the requests in the process, each prefork child and each
+// instance of the app has its own counters
+type MemoryStore struct {
+	mu        sync.Mutex
+	windows   map[string]*Window
+	nextSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{windows: make(map[string]*Window)}
+}
+
+func (s *MemoryStore) Hit(_ context.Context, key string, window time.Duration) (Window, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.After(s.nextSweep) {
+		s.sweep(now)
+	}
+
+	current, ok := s.windows[key]
+	if !ok || !now.Before(current.ResetAt) {
+		current = &Window{ResetAt: now.Add(window)}
+		s.windows[key] = current
+	}
+	current.Hits++
+
+	return *current, nil
+}
+
+func (s *MemoryStore) Undo(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.windows[key]; ok && current.Hits > 0 {
+		current.Hits--
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, window := range s.windows {
+		if !now.Before(window.ResetAt) {
+			delete(s.windows, key)
+		}
+	}
+	s.nextSweep = now.Add(sweepInterval)
+}
@@ -0,0 +1,21 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Store counts the requests of a key in fixed windows, a window starts with
+// its first request. Stores shared by the instances of the app, like the
+// database or Redis, enforce a limit across all of them.
+type Store interface {
+	// Hit counts a request and returns the window it falls in
+	Hit(ctx context.Context, key string, window time.Duration) (Window, error)
+	// Undo takes back a request counted by Hit, e.g. one that turned out not to count
+	Undo(ctx context.Context, key string) error
+}
+
+type Window struct {
+	Hits    int
+	ResetAt time.Time
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"app/src/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+type DataRequestRepository interface {
+	Create(ctx context.Context, request *model.DataRequest) error
+	Update(ctx context.Context, request *model.DataRequest) error
+	Transition(ctx context.Context, request *model.DataRequest, from ...string) (bool, error)
+	Claim(ctx context.Context, request *model.DataRequest, staleBefore time.Time) (bool, error)
+	FindByID(ctx context.Context, id, userID string) (*model.DataRequest, error)
+	FindOpen(ctx context.Context, requestType, userID string) (*model.DataRequest, error)
+	ListByUser(ctx context.Context, userID string) ([]model.DataRequest, error)
+	ListDue(
+		ctx context.Context, requestType string, before, staleBefore time.Time, limit int,
+	) ([]model.DataRequest, error)
+	ListExpired(ctx context.Context, before time.Time, limit int) ([]model.DataRequest, error)
+}
+
+type dataRequestRepository struct {
+	DB *gorm.DB
+}
+
+func NewDataRequestRepository(db *gorm.DB) DataRequestRepository {
+	return &dataRequestRepository{
+		DB: db,
+	}
+}
+
+func (r *dataRequestRepository) Create(ctx context.Context, request *model.DataRequest) error {
+	return translateError(conn(ctx, r.DB).Create(request).Error)
+}
+
+// dataRequestColumns are the columns saved by Update and Transition
+var dataRequestColumns = []string{"status", "archive", "error", "scheduled_at", "expires_at", "completed_at"}
+
+// Update saves the progress of the request, its status and what comes with it
+func (r *dataRequestRepository) Update(ctx context.Context, request *model.DataRequest) error {
+	return translateError(conn(ctx, r.DB).Model(request).Select(dataRequestColumns).Updates(request).Error)
+}
+
+// Transition saves the request like Update if its status is still one of from,
+// it reports whether it was. The requests changed by the users and by the job
+// are saved with it, so neither overwrites the other.
+func (r *dataRequestRepository) Transition(
+	ctx context.Context, request *model.DataRequest, from ...string,
+) (bool, error) {
+	result := conn(ctx, r.DB).Model(request).Where("status IN ?", from).
+		Select(dataRequestColumns).Updates(request)
+
+	return result.RowsAffected > 0, translateError(result.Error)
+}
+
+// Claim marks a due request processing for a run of the job, it reports false
+// when another run claimed it first or the user cancelled it since it was
+// listed. A request claimed before staleBefore was left by a run which died,
+// it is claimed again.
+func (r *dataRequestRepository) Claim(
+	ctx context.Context, request *model.DataRequest, staleBefore time.Time,
+) (bool, error) {
+	now := time.Now().UTC()
+
+	result := conn(ctx, r.DB).Model(&model.DataRequest{}).
+		Where("id = ? AND (status = ? OR (status = ? AND updated_at < ?))", request.ID,
+			model.DataRequestPending, model.DataRequestProcessing, staleBefore).
+		Updates(map[string]any{"status": model.DataRequestProcessing, "updated_at": now})
+
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, translateError(result.Error)
+	}
+
+	request.Status = model.DataRequestProcessing
+	request.UpdatedAt = now
+
+	return true, nil
+}
+
+func (r *dataRequestRepository) FindByID(ctx context.Context, id, userID string) (*model.DataRequest, error) {
+	request := new(model.DataRequest)
+
+	if err := conn(ctx, r.DB).First(request, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return request, nil
+}
+
+// FindOpen returns the request of the type not run yet or running, a user has
+// at most one. It reads from the primary so a request just made is found.
+func (r *dataRequestRepository) FindOpen(ctx context.Context, requestType, userID string) (*model.DataRequest, error) {
+	request := new(model.DataRequest)
+
+	err := conn(ctx, r.DB).Clauses(dbresolver.Write).
+		Where("type = ? AND user_id = ? AND status IN ?", requestType, userID,
+			[]string{model.DataRequestUnconfirmed, model.DataRequestPending, model.DataRequestProcessing}).
+		Order("created_at desc").
+		First(request).Error
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return request, nil
+}
+
+// ListByUser returns the requests of the user, the newest first
+func (r *dataRequestRepository) ListByUser(ctx context.Context, userID string) ([]model.DataRequest, error) {
+	var requests []model.DataRequest
+
+	err := conn(ctx, r.DB).Where("user_id = ?", userID).Order("created_at desc").Find(&requests).Error
+
+	return requests, translateError(err)
+}
+
+// ListDue returns at most limit requests of the type scheduled before the given time, the
+// oldest first. They are pending, or processing since before staleBefore, see Claim.
+func (r *dataRequestRepository) ListDue(
+	ctx context.Context, requestType string, before, staleBefore time.Time, limit int,
+) ([]model.DataRequest, error) {
+	var requests []model.DataRequest
+
+	err := conn(ctx, r.DB).
+		Where("type = ? AND scheduled_at <= ? AND (status = ? OR (status = ? AND updated_at < ?))", requestType,
+			before, model.DataRequestPending, model.DataRequestProcessing, staleBefore).
+		Order("scheduled_at").
+		Limit(limit).
+		Find(&requests).Error
+
+	return requests, translateError(err)
+}
+
+// ListExpired returns at most limit completed exports which expired before the given time
+func (r *dataRequestRepository) ListExpired(
+	ctx context.Context, before time.Time, limit int,
+) ([]model.DataRequest, error) {
+	var requests []model.DataRequest
+
+	err := conn(ctx, r.DB).
+		Where("type = ? AND status = ? AND expires_at < ?", model.DataRequestExport, model.DataRequestCompleted, before).
+		Limit(limit).
+		Find(&requests).Error
+
+	return requests, translateError(err)
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"app/src/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository interface {
+	Lock(ctx context.Context, key *model.IdempotencyKey) (bool, error)
+	GetByID(ctx context.Context, id string) (*model.IdempotencyKey, error)
+	Save(ctx context.Context, key *model.IdempotencyKey) error
+	Delete(ctx context.Context, id string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type idempotencyRepository struct {
+	DB *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{
+		DB: db,
+	}
+}
+
+// Lock creates the key, or takes it over once it expired. It fails when the
+// key is held by a request in progress or keeps a response.
+func (r *idempotencyRepository) Lock(ctx context.Context, key *model.IdempotencyKey) (bool, error) {
+	result := conn(ctx, r.DB).Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+	if result.Error != nil {
+		return false, translateError(result.Error)
+	}
+
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	result = conn(ctx, r.DB).Model(&model.IdempotencyKey{}).
+		Where("id = ? AND expires_at <= ?", key.ID, time.Now().UTC()).
+		Updates(map[string]interface{}{
+			"fingerprint": key.Fingerprint,
+			"status":      key.Status,
+			"response":    key.Response,
+			"expires_at":  key.ExpiresAt,
+		})
+
+	return result.RowsAffected == 1, translateError(result.Error)
+}
+
+func (r *idempotencyRepository) GetByID(ctx context.Context, id string) (*model.IdempotencyKey, error) {
+	key := new(model.IdempotencyKey)
+	if err := conn(ctx, r.DB).Where("id = ?", id).Take(key).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return key, nil
+}
+
+func (r *idempotencyRepository) Save(ctx context.Context, key *model.IdempotencyKey) error {
+	return translateError(conn(ctx, r.DB).Model(key).
+		Select("status", "response", "expires_at").
+		Updates(key).Error)
+}
+
+func (r *idempotencyRepository) Delete(ctx context.Context, id string) error {
+	return translateError(conn(ctx, r.DB).Where("id = ?", id).Delete(&model.IdempotencyKey{}).Error)
+}
+
+// DeleteExpired deletes the keys expired at now, with their responses
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := conn(ctx, r.DB).Where("expires_at <= ?", now.UTC()).Delete(&model.IdempotencyKey{})
+
+	return result.RowsAffected, translateError(result.Error)
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"app/src/model"
+	"app/src/query"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepository interface {
+	AcquireLease(ctx context.Context, name, owner string, until time.Time) (bool, error)
+	RenewLease(ctx context.Context, name, owner string, until time.Time) (bool, error)
+	CreateRun(ctx context.Context, run *model.JobRun) error
+	FinishRun(ctx context.Context, run *model.JobRun) error
+	ListRuns(ctx context.Context, params query.Params) (*query.Result[model.JobRun], error)
+	CountStuckRuns(ctx context.Context, startedBefore time.Time) (int64, error)
+}
+
+var jobRunQueryOptions = query.Options{
+	Sortable: map[string]string{
+		"started_at": "started_at",
+	},
+	Filterable: map[string]string{
+		"name":   "name",
+		"status": "status",
+	},
+	DefaultSort:  "started_at",
+	DefaultOrder: query.OrderDesc,
+}
+
+type jobRepository struct {
+	DB *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) JobRepository {
+	return &jobRepository{
+		DB: db,
+	}
+}
+
+// AcquireLease takes the lease of a job until the given time. It succeeds when
+// nobody holds the lease, the previous lease expired or owner already holds it.
+func (r *jobRepository) AcquireLease(ctx context.Context, name, owner string, until time.Time) (bool, error) {
+	lease := &model.JobLease{Name: name, Owner: owner, LockedUntil: until}
+
+	result := conn(ctx, r.DB).Clauses(clause.OnConflict{DoNothing: true}).Create(lease)
+	if result.Error != nil {
+		return false, translateError(result.Error)
+	}
+
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	result = conn(ctx, r.DB).Model(&model.JobLease{}).
+		Where("name = ? AND (locked_until <= ? OR owner = ?)", name, time.Now().UTC(), owner).
+		Updates(map[string]interface{}{"owner": owner, "locked_until": until})
+
+	return result.RowsAffected == 1, translateError(result.Error)
+}
+
+// RenewLease moves the end of a lease owner still holds, it fails once another owner took it over
+func (r *jobRepository) RenewLease(ctx context.Context, name, owner string, until time.Time) (bool, error) {
+	result := conn(ctx, r.DB).Model(&model.JobLease{}).
+		Where("name = ? AND owner = ?", name, owner).
+		Update("locked_until", until)
+
+	return result.RowsAffected == 1, translateError(result.Error)
+}
+
+func (r *jobRepository) CreateRun(ctx context.Context, run *model.JobRun) error {
+	return translateError(conn(ctx, r.DB).Create(run).Error)
+}
+
+func (r *jobRepository) FinishRun(ctx context.Context, run *model.JobRun) error {
+	return translateError(conn(ctx, r.DB).Model(run).
+		Select("status", "error", "finished_at").
+		Updates(run).Error)
+}
+
+func (r *jobRepository) ListRuns(ctx context.Context, params query.Params) (*query.Result[model.JobRun], error) {
+	return query.Paginate[model.JobRun](ctx, conn(ctx, r.DB), params, jobRunQueryOptions)
+}
+
+// CountStuckRuns counts the runs still running that started before the given time
+func (r *jobRepository) CountStuckRuns(ctx context.Context, startedBefore time.Time) (int64, error) {
+	var count int64
+	err := conn(ctx, r.DB).Model(&model.JobRun{}).
+		Where("status = ? AND started_at < ?", model.JobStatusRunning, startedBefore.UTC()).
+		Count(&count).Error
+
+	return count, translateError(err)
+}
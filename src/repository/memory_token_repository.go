@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"app/src/model"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// memoryTokenRepository keeps tokens in a map, for unit tests and tools
+// that don't need a database.
+type memoryTokenRepository struct {
+	mu     sync.RWMutex
+	tokens map[uuid.UUID]model.Token
+}
+
+func NewMemoryTokenRepository() TokenRepository {
+	return &memoryTokenRepository{
+		tokens: make(map[uuid.UUID]model.Token),
+	}
+}
+
+func (r *memoryTokenRepository) Create(_ context.Context, token *model.Token) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	token.ID = uuid.New()
+	token.CreatedAt = now
+	token.UpdatedAt = now
+	r.tokens[token.ID] = *token
+
+	return nil
+}
+
+func (r *memoryTokenRepository) FindByType(_ context.Context, tokenType, userID string) (*model.Token, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, tokenDoc := range r.tokens {
+		if tokenDoc.Type == tokenType && tokenDoc.UserID.String() == userID {
+			return &tokenDoc, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (r *memoryTokenRepository) FindByHash(
+	_ context.Context, tokenType, userID, hash string,
+) (*model.Token, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, tokenDoc := range r.tokens {
+		if tokenDoc.Type == tokenType && tokenDoc.UserID.String() == userID && tokenDoc.Token == hash {
+			return &tokenDoc, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (r *memoryTokenRepository) ListByType(
+	_ context.Context, tokenType, userID string, expiresAfter time.Time,
+) ([]model.Token, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tokens []model.Token
+	for _, tokenDoc := range r.tokens {
+		if tokenDoc.Type == tokenType && tokenDoc.UserID.String() == userID && tokenDoc.Expires.After(expiresAfter) {
+			tokens = append(tokens, tokenDoc)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.After(tokens[j].CreatedAt) })
+
+	return tokens, nil
+}
+
+func (r *memoryTokenRepository) ListByUser(_ context.Context, userID string) ([]model.Token, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tokens []model.Token
+	for _, tokenDoc := range r.tokens {
+		if tokenDoc.UserID.String() == userID {
+			tokens = append(tokens, tokenDoc)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.After(tokens[j].CreatedAt) })
+
+	return tokens, nil
+}
+
+func (r *memoryTokenRepository) DeleteByID(_ context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tokenID, err := uuid.Parse(id)
+	if err != nil {
+		return false, nil
+	}
+
+	_, ok := r.tokens[tokenID]
+	delete(r.tokens, tokenID)
+
+	return ok, nil
+}
+
+func (r *memoryTokenRepository) DeleteByType(_ context.Context, tokenType, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, tokenDoc := range r.tokens {
+		if tokenDoc.Type == tokenType && tokenDoc.UserID.String() == userID {
+			delete(r.tokens, id)
+		}
+	}
+
+	return nil
+}
+
+func (r *memoryTokenRepository) DeleteByUser(_ context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, tokenDoc := range r.tokens {
+		if tokenDoc.UserID.String() == userID {
+			delete(r.tokens, id)
+		}
+	}
+
+	return nil
+}
+
+func (r *memoryTokenRepository) DeleteExpired(_ context.Context, expiredBefore time.Time, limit int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, tokenDoc := range r.tokens {
+		if deleted >= int64(limit) {
+			break
+		}
+
+		if tokenDoc.Expires.Before(expiredBefore) {
+			delete(r.tokens, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
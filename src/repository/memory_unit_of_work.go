@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"app/src/model"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// memoryUnitOfWork restores the memory repositories when fn fails. Unlike a
+// database transaction it doesn't isolate concurrent callers.
+type memoryUnitOfWork struct {
+	users  *memoryUserRepository
+	tokens *memoryTokenRepository
+}
+
+// NewMemoryUnitOfWork covers the repositories created by NewMemoryUserRepository
+// and NewMemoryTokenRepository, either may be nil.
+func NewMemoryUnitOfWork(users UserRepository, tokens TokenRepository) UnitOfWork {
+	u := new(memoryUnitOfWork)
+	u.users, _ = users.(*memoryUserRepository)
+	u.tokens, _ = tokens.(*memoryTokenRepository)
+
+	return u
+}
+
+func (u *memoryUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+
+	var users map[uuid.UUID]model.User
+	if u.users != nil {
+		u.users.mu.RLock()
+		users = copyMap(u.users.users)
+		u.users.mu.RUnlock()
+	}
+
+	var tokens map[uuid.UUID]model.Token
+	if u.tokens != nil {
+		u.tokens.mu.RLock()
+		tokens = copyMap(u.tokens.tokens)
+		u.tokens.mu.RUnlock()
+	}
+
+	err := fn(context.WithValue(ctx, txKey{}, u))
+	if err == nil {
+		return nil
+	}
+
+	if u.users != nil {
+		u.users.mu.Lock()
+		u.users.users = users
+		u.users.mu.Unlock()
+	}
+
+	if u.tokens != nil {
+		u.tokens.mu.Lock()
+		u.tokens.tokens = tokens
+		u.tokens.mu.Unlock()
+	}
+
+	return err
+}
+
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	copied := make(map[K]V, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
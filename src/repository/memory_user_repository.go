@@ -0,0 +1,399 @@
+package repository
+
+import (
+	"app/src/model"
+	"app/src/query"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// memoryUserRepository keeps users in a map, for unit tests and tools
+// that don't need a database. Cursors are not supported by List.
+type memoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[uuid.UUID]model.User
+}
+
+func NewMemoryUserRepository() UserRepository {
+	return &memoryUserRepository{
+		users: make(map[uuid.UUID]model.User),
+	}
+}
+
+func (r *memoryUserRepository) List(
+	_ context.Context, term string, params query.Params,
+) (*query.Result[model.User], error) {
+	if params.Cursor != "" {
+		return nil, query.ErrInvalidCursor
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	term = strings.ToLower(term)
+	items := make([]model.User, 0, len(r.users))
+	for _, user := range r.users {
+		if user.DeletedAt.Valid || !matchesUser(user, term, params.Filters) {
+			continue
+		}
+		items = append(items, user)
+	}
+
+	sortBy := params.SortBy
+	if sortBy == "" || sortBy == query.SortRelevance {
+		sortBy = userQueryOptions.DefaultSort
+	}
+	if _, ok := userQueryOptions.Sortable[sortBy]; !ok {
+		return nil, query.ErrInvalidSort
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if params.Order == query.OrderDesc {
+			return lessUser(items[j], items[i], sortBy)
+		}
+		return lessUser(items[i], items[j], sortBy)
+	})
+
+	result := new(query.Result[model.User])
+	if params.IncludeTotal {
+		total := int64(len(items))
+		result.Total = &total
+	}
+
+	offset := 0
+	if params.Page > 1 {
+		offset = (params.Page - 1) * params.Limit
+	}
+	end := offset + params.Limit
+	if offset > len(items) {
+		offset = len(items)
+	}
+	if end > len(items) {
+		end = len(items)
+	}
+	result.Items = items[offset:end]
+
+	return result, nil
+}
+
+func matchesUser(user model.User, term string, filters map[string]interface{}) bool {
+	if term != "" &&
+		!strings.Contains(strings.ToLower(user.Name), term) &&
+		!strings.Contains(strings.ToLower(user.Email), term) &&
+		!strings.Contains(strings.ToLower(user.Role), term) {
+		return false
+	}
+
+	if role, ok := filters["role"]; ok && user.Role != role {
+		return false
+	}
+
+	if verified, ok := filters["verified_email"]; ok && user.VerifiedEmail != verified {
+		return false
+	}
+
+	return true
+}
+
+func lessUser(a, b model.User, sortBy string) bool {
+	switch sortBy {
+	case "name":
+		return a.Name < b.Name
+	case "email":
+		return a.Email < b.Email
+	case "role":
+		return a.Role < b.Role
+	default:
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+}
+
+func (r *memoryUserRepository) FindByID(_ context.Context, id string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	user, ok := r.users[userID]
+	if !ok || user.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+
+	return &user, nil
+}
+
+func (r *memoryUserRepository) FindWithDeleted(_ context.Context, id string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &user, nil
+}
+
+func (r *memoryUserRepository) FindByEmail(_ context.Context, email string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Email == email && !user.DeletedAt.Valid {
+			return &user, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (r *memoryUserRepository) Create(_ context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.emailTaken(user.Email, uuid.Nil) {
+		return ErrDuplicateKey
+	}
+
+	now := time.Now()
+	user.ID = uuid.New()
+	user.Version = 1
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	if user.Role == "" {
+		user.Role = "user"
+	}
+
+	r.users[user.ID] = *user
+
+	return nil
+}
+
+func (r *memoryUserRepository) Update(
+	_ context.Context, id string, fields *model.User, version int64,
+) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	user, ok := r.users[userID]
+	if !ok || user.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+
+	if version != 0 && user.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	// Mirror GORM Updates, only non-zero fields are written
+	if fields.Email != "" {
+		if r.emailTaken(fields.Email, userID) {
+			return nil, ErrDuplicateKey
+		}
+		user.Email = fields.Email
+	}
+	if fields.Name != "" {
+		user.Name = fields.Name
+	}
+	if fields.Password != "" {
+		user.Password = fields.Password
+	}
+	if fields.Role != "" {
+		user.Role = fields.Role
+	}
+	if fields.Language != "" {
+		user.Language = fields.Language
+	}
+	if fields.VerifiedEmail {
+		user.VerifiedEmail = true
+	}
+	user.Version++
+	user.UpdatedAt = time.Now()
+
+	r.users[userID] = user
+
+	return &user, nil
+}
+
+func (r *memoryUserRepository) SetAvatar(
+	_ context.Context, id, avatar string, version int64,
+) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	user, ok := r.users[userID]
+	if !ok || user.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+
+	if version != 0 && user.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	user.Avatar = avatar
+	_ = user.AfterFind(nil)
+	user.Version++
+	user.UpdatedAt = time.Now()
+	r.users[userID] = user
+
+	return &user, nil
+}
+
+func (r *memoryUserRepository) SetEmail(
+	_ context.Context, id, email string, version int64,
+) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	user, ok := r.users[userID]
+	if !ok || user.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+
+	if version != 0 && user.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	if r.emailTaken(email, userID) {
+		return nil, ErrDuplicateKey
+	}
+
+	user.Email = email
+	user.VerifiedEmail = false
+	user.Version++
+	user.UpdatedAt = time.Now()
+	r.users[userID] = user
+
+	return &user, nil
+}
+
+func (r *memoryUserRepository) Delete(_ context.Context, id string, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrNotFound
+	}
+
+	user, ok := r.users[userID]
+	if !ok || user.DeletedAt.Valid {
+		return ErrNotFound
+	}
+
+	if version != 0 && user.Version != version {
+		return ErrVersionConflict
+	}
+
+	user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.users[userID] = user
+
+	return nil
+}
+
+func (r *memoryUserRepository) Restore(_ context.Context, id string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	user, ok := r.users[userID]
+	if !ok || !user.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+
+	user.DeletedAt = gorm.DeletedAt{}
+	user.Version++
+	r.users[userID] = user
+
+	return &user, nil
+}
+
+func (r *memoryUserRepository) ListDeletedAvatars(_ context.Context, deletedBefore time.Time) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var avatars []string
+	for _, user := range r.users {
+		if user.DeletedAt.Valid && user.DeletedAt.Time.Before(deletedBefore) && user.Avatar != "" {
+			avatars = append(avatars, user.Avatar)
+		}
+	}
+
+	return avatars, nil
+}
+
+func (r *memoryUserRepository) PurgeDeleted(_ context.Context, deletedBefore time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for id, user := range r.users {
+		if user.DeletedAt.Valid && user.DeletedAt.Time.Before(deletedBefore) {
+			delete(r.users, id)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+func (r *memoryUserRepository) Purge(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrNotFound
+	}
+
+	if _, ok := r.users[userID]; !ok {
+		return ErrNotFound
+	}
+
+	delete(r.users, userID)
+
+	return nil
+}
+
+// emailTaken reports whether another user, deleted or not, has the email,
+// like the unique index on the users table
+func (r *memoryUserRepository) emailTaken(email string, except uuid.UUID) bool {
+	for id, user := range r.users {
+		if id != except && user.Email == email {
+			return true
+		}
+	}
+	return false
+}
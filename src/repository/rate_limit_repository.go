@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"app/src/model"
+	"app/src/ratelimit"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RateLimitRepository is a ratelimit.Store sharing the counters through the database
+type RateLimitRepository interface {
+	Hit(ctx context.Context, key string, window time.Duration) (ratelimit.Window, error)
+	Undo(ctx context.Context, key string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type rateLimitRepository struct {
+	DB *gorm.DB
+}
+
+func NewRateLimitRepository(db *gorm.DB) RateLimitRepository {
+	return &rateLimitRepository{
+		DB: db,
+	}
+}
+
+// Hit inserts the counter or increments it in a single statement, starting a new
+// window when the current one is over. The column values in the expressions are
+// the ones before the update with every driver.
+func (r *rateLimitRepository) Hit(ctx context.Context, key string, window time.Duration) (ratelimit.Window, error) {
+	now := time.Now().UTC()
+	counter := &model.RateLimit{Key: key, Hits: 1, ResetAt: now.Add(window)}
+
+	err := conn(ctx, r.DB).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "rate_key"}},
+		DoUpdates: clause.Set{
+			{
+				Column: clause.Column{Name: "hits"},
+				Value:  gorm.Expr("CASE WHEN rate_limits.reset_at <= ? THEN 1 ELSE rate_limits.hits + 1 END", now),
+			},
+			{
+				Column: clause.Column{Name: "reset_at"},
+				Value: gorm.Expr("CASE WHEN rate_limits.reset_at <= ? THEN ? ELSE rate_limits.reset_at END",
+					now, counter.ResetAt),
+			},
+		},
+	}).Create(counter).Error
+	if err != nil {
+		return ratelimit.Window{}, translateError(err)
+	}
+
+	if err := conn(ctx, r.DB).Where("rate_key = ?", key).Take(counter).Error; err != nil {
+		return ratelimit.Window{}, translateError(err)
+	}
+
+	return ratelimit.Window{Hits: counter.Hits, ResetAt: counter.ResetAt}, nil
+}
+
+func (r *rateLimitRepository) Undo(ctx context.Context, key string) error {
+	return translateError(conn(ctx, r.DB).Model(&model.RateLimit{}).
+		Where("rate_key = ? AND hits > 0", key).
+		Update("hits", gorm.Expr("hits - 1")).Error)
+}
+
+// DeleteExpired deletes the counters of the windows over at now
+func (r *rateLimitRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := conn(ctx, r.DB).Where("reset_at <= ?", now.UTC()).Delete(&model.RateLimit{})
+
+	return result.RowsAffected, translateError(result.Error)
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotFound     = errors.New("record not found")
+	ErrDuplicateKey = errors.New("duplicated key")
+	// ErrVersionConflict is returned when a row changed since the version the caller read
+	ErrVersionConflict = errors.New("version conflict")
+)
+
+// translateError maps GORM errors to the repository errors services check against
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicateKey
+	default:
+		return err
+	}
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"app/src/model"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+type TokenRepository interface {
+	Create(ctx context.Context, token *model.Token) error
+	FindByType(ctx context.Context, tokenType, userID string) (*model.Token, error)
+	FindByHash(ctx context.Context, tokenType, userID, hash string) (*model.Token, error)
+	ListByType(ctx context.Context, tokenType, userID string, expiresAfter time.Time) ([]model.Token, error)
+	ListByUser(ctx context.Context, userID string) ([]model.Token, error)
+	DeleteByID(ctx context.Context, id string) (bool, error)
+	DeleteByType(ctx context.Context, tokenType, userID string) error
+	DeleteByUser(ctx context.Context, userID string) error
+	DeleteExpired(ctx context.Context, expiredBefore time.Time, limit int) (int64, error)
+}
+
+type tokenRepository struct {
+	DB *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) TokenRepository {
+	return &tokenRepository{
+		DB: db,
+	}
+}
+
+func (r *tokenRepository) Create(ctx context.Context, token *model.Token) error {
+	return translateError(conn(ctx, r.DB).Create(token).Error)
+}
+
+// FindByType always reads from the primary so a fresh token is found.
+// A user has at most one token of each type but the refresh tokens.
+func (r *tokenRepository) FindByType(ctx context.Context, tokenType, userID string) (*model.Token, error) {
+	tokenDoc := new(model.Token)
+
+	err := conn(ctx, r.DB).Clauses(dbresolver.Write).
+		Where("type = ? AND user_id = ?", tokenType, userID).
+		First(tokenDoc).Error
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return tokenDoc, nil
+}
+
+// FindByHash finds the token with the given hash, from the primary like FindByType
+func (r *tokenRepository) FindByHash(ctx context.Context, tokenType, userID, hash string) (*model.Token, error) {
+	tokenDoc := new(model.Token)
+
+	err := conn(ctx, r.DB).Clauses(dbresolver.Write).
+		Where("type = ? AND user_id = ? AND token = ?", tokenType, userID, hash).
+		First(tokenDoc).Error
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return tokenDoc, nil
+}
+
+// ListByType returns the tokens of the type expiring after the given time, the newest first
+func (r *tokenRepository) ListByType(
+	ctx context.Context, tokenType, userID string, expiresAfter time.Time,
+) ([]model.Token, error) {
+	var tokens []model.Token
+
+	err := conn(ctx, r.DB).
+		Where("type = ? AND user_id = ? AND expires > ?", tokenType, userID, expiresAfter).
+		Order("created_at desc").
+		Find(&tokens).Error
+
+	return tokens, translateError(err)
+}
+
+// ListByUser returns every token of the user, the newest first
+func (r *tokenRepository) ListByUser(ctx context.Context, userID string) ([]model.Token, error) {
+	var tokens []model.Token
+
+	err := conn(ctx, r.DB).Where("user_id = ?", userID).Order("created_at desc").Find(&tokens).Error
+
+	return tokens, translateError(err)
+}
+
+// DeleteByID reports whether the token was still there, so only one of two
+// concurrent requests using it succeeds
+func (r *tokenRepository) DeleteByID(ctx context.Context, id string) (bool, error) {
+	result := conn(ctx, r.DB).Where("id = ?", id).Delete(&model.Token{})
+
+	return result.RowsAffected > 0, translateError(result.Error)
+}
+
+func (r *tokenRepository) DeleteByType(ctx context.Context, tokenType, userID string) error {
+	return translateError(conn(ctx, r.DB).
+		Where("type = ? AND user_id = ?", tokenType, userID).
+		Delete(&model.Token{}).Error)
+}
+
+func (r *tokenRepository) DeleteByUser(ctx context.Context, userID string) error {
+	return translateError(conn(ctx, r.DB).Where("user_id = ?", userID).Delete(&model.Token{}).Error)
+}
+
+// DeleteExpired removes at most limit tokens that expired before the given time.
+// The ids are selected first since not every driver supports DELETE ... LIMIT.
+func (r *tokenRepository) DeleteExpired(ctx context.Context, expiredBefore time.Time, limit int) (int64, error) {
+	var ids []uuid.UUID
+
+	err := conn(ctx, r.DB).Model(&model.Token{}).
+		Where("expires < ?", expiredBefore).
+		Limit(limit).
+		Pluck("id", &ids).Error
+
+	if err != nil || len(ids) == 0 {
+		return 0, translateError(err)
+	}
+
+	result := conn(ctx, r.DB).Where("id IN ?", ids).Delete(&model.Token{})
+
+	return result.RowsAffected, translateError(result.Error)
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"app/src/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UserImportRepository interface {
+	Create(ctx context.Context, userImport *model.UserImport) error
+	Update(ctx context.Context, userImport *model.UserImport) error
+	FindByID(ctx context.Context, id string) (*model.UserImport, error)
+	Claim(ctx context.Context, userImport *model.UserImport, staleBefore time.Time) (bool, error)
+	ListPending(ctx context.Context, staleBefore time.Time, limit int) ([]model.UserImport, error)
+}
+
+type userImportRepository struct {
+	DB *gorm.DB
+}
+
+func NewUserImportRepository(db *gorm.DB) UserImportRepository {
+	return &userImportRepository{
+		DB: db,
+	}
+}
+
+func (r *userImportRepository) Create(ctx context.Context, userImport *model.UserImport) error {
+	return translateError(conn(ctx, r.DB).Create(userImport).Error)
+}
+
+// Update saves the outcome of the import, its status, counts and results
+func (r *userImportRepository) Update(ctx context.Context, userImport *model.UserImport) error {
+	return translateError(conn(ctx, r.DB).Model(userImport).
+		Select("status", "file", "error", "total", "created", "failed", "results", "completed_at").
+		Updates(userImport).Error)
+}
+
+func (r *userImportRepository) FindByID(ctx context.Context, id string) (*model.UserImport, error) {
+	userImport := new(model.UserImport)
+
+	if err := conn(ctx, r.DB).First(userImport, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return userImport, nil
+}
+
+// Claim marks a pending import processing for a run of the job, it reports
+// false when another run claimed it first. An import claimed before
+// staleBefore was left by a run which died, it is claimed again.
+func (r *userImportRepository) Claim(
+	ctx context.Context, userImport *model.UserImport, staleBefore time.Time,
+) (bool, error) {
+	now := time.Now().UTC()
+
+	result := conn(ctx, r.DB).Model(&model.UserImport{}).
+		Where("id = ? AND (status = ? OR (status = ? AND updated_at < ?))", userImport.ID,
+			model.UserImportPending, model.UserImportProcessing, staleBefore).
+		Updates(map[string]any{"status": model.UserImportProcessing, "updated_at": now})
+
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, translateError(result.Error)
+	}
+
+	userImport.Status = model.UserImportProcessing
+	userImport.UpdatedAt = now
+
+	return true, nil
+}
+
+// ListPending returns at most limit imports left to the job, the oldest first.
+// They are pending, or processing since before staleBefore, see Claim.
+func (r *userImportRepository) ListPending(
+	ctx context.Context, staleBefore time.Time, limit int,
+) ([]model.UserImport, error) {
+	var userImports []model.UserImport
+
+	err := conn(ctx, r.DB).
+		Where("status = ? OR (status = ? AND updated_at < ?)",
+			model.UserImportPending, model.UserImportProcessing, staleBefore).
+		Order("created_at").
+		Limit(limit).
+		Find(&userImports).Error
+
+	return userImports, translateError(err)
+}
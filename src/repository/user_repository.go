@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"app/src/model"
+	"app/src/query"
+	"app/src/search"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+type UserRepository interface {
+	List(ctx context.Context, term string, params query.Params) (*query.Result[model.User], error)
+	FindByID(ctx context.Context, id string) (*model.User, error)
+	FindWithDeleted(ctx context.Context, id string) (*model.User, error)
+	FindByEmail(ctx context.Context, email string) (*model.User, error)
+	Create(ctx context.Context, user *model.User) error
+	Update(ctx context.Context, id string, fields *model.User, version int64) (*model.User, error)
+	SetAvatar(ctx context.Context, id, avatar string, version int64) (*model.User, error)
+	SetEmail(ctx context.Context, id, email string, version int64) (*model.User, error)
+	Delete(ctx context.Context, id string, version int64) error
+	Restore(ctx context.Context, id string) (*model.User, error)
+	ListDeletedAvatars(ctx context.Context, deletedBefore time.Time) ([]string, error)
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
+	Purge(ctx context.Context, id string) error
+}
+
+var userQueryOptions = query.Options{
+	Sortable: map[string]string{
+		"created_at": "created_at",
+		"name":       "name",
+		"email":      "email",
+		"role":       "role",
+	},
+	Filterable: map[string]string{
+		"role":           "role",
+		"verified_email": "verified_email",
+	},
+	DefaultSort:  "created_at",
+	DefaultOrder: query.OrderAsc,
+}
+
+type userRepository struct {
+	DB       *gorm.DB
+	Searcher search.Searcher
+}
+
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepository{
+		DB: db,
+		Searcher: search.New(db, search.Spec{
+			Vector:  "search_vector",
+			Columns: []string{"name", "email", "role"},
+		}),
+	}
+}
+
+func (r *userRepository) List(
+	ctx context.Context, term string, params query.Params,
+) (*query.Result[model.User], error) {
+	db := conn(ctx, r.DB)
+	if term != "" {
+		db = r.Searcher.Match(db, term)
+		params.Rank = r.Searcher.Rank(term)
+	}
+
+	return query.Paginate[model.User](ctx, db, params, userQueryOptions)
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id string) (*model.User, error) {
+	user := new(model.User)
+
+	if err := conn(ctx, r.DB).First(user, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return user, nil
+}
+
+// FindWithDeleted finds the user even if it is soft deleted
+func (r *userRepository) FindWithDeleted(ctx context.Context, id string) (*model.User, error) {
+	user := new(model.User)
+
+	if err := conn(ctx, r.DB).Unscoped().First(user, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	user := new(model.User)
+
+	if err := conn(ctx, r.DB).Where("email = ?", email).First(user).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	return translateError(conn(ctx, r.DB).Create(user).Error)
+}
+
+// Update sets the non-zero fields, bumps the version and returns the updated
+// user. Unless version is 0, the user must still be at that version.
+func (r *userRepository) Update(
+	ctx context.Context, id string, fields *model.User, version int64,
+) (*model.User, error) {
+	result := atVersion(conn(ctx, r.DB).Model(&model.User{}).Where("id = ?", id), version).
+		Updates(userUpdates(fields))
+	if result.Error != nil {
+		return nil, translateError(result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, r.notUpdated(ctx, id, version)
+	}
+
+	return r.findOnPrimary(ctx, id)
+}
+
+// SetAvatar replaces the avatar of the user, an empty avatar removes it. Unless
+// version is 0, the user must still be at that version.
+func (r *userRepository) SetAvatar(ctx context.Context, id, avatar string, version int64) (*model.User, error) {
+	values := map[string]interface{}{"avatar": nil, "version": gorm.Expr("version + 1")}
+	if avatar != "" {
+		values["avatar"] = avatar
+	}
+
+	result := atVersion(conn(ctx, r.DB).Model(&model.User{}).Where("id = ?", id), version).Updates(values)
+	if result.Error != nil {
+		return nil, translateError(result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, r.notUpdated(ctx, id, version)
+	}
+
+	return r.findOnPrimary(ctx, id)
+}
+
+// SetEmail changes the email of the user, which isn't verified anymore. Unless
+// version is 0, the user must still be at that version.
+func (r *userRepository) SetEmail(ctx context.Context, id, email string, version int64) (*model.User, error) {
+	values := map[string]interface{}{"email": email, "verified_email": false, "version": gorm.Expr("version + 1")}
+
+	result := atVersion(conn(ctx, r.DB).Model(&model.User{}).Where("id = ?", id), version).Updates(values)
+	if result.Error != nil {
+		return nil, translateError(result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, r.notUpdated(ctx, id, version)
+	}
+
+	return r.findOnPrimary(ctx, id)
+}
+
+// Delete soft deletes the user. Unless version is 0, the user must still be at that version.
+// The email stays taken until the user is purged, so that Restore can't conflict.
+func (r *userRepository) Delete(ctx context.Context, id string, version int64) error {
+	result := atVersion(conn(ctx, r.DB).Where("id = ?", id), version).Delete(&model.User{})
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return r.notUpdated(ctx, id, version)
+	}
+
+	return nil
+}
+
+func (r *userRepository) Restore(ctx context.Context, id string) (*model.User, error) {
+	result := conn(ctx, r.DB).Unscoped().Model(&model.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+
+	if result.Error != nil {
+		return nil, translateError(result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	return r.findOnPrimary(ctx, id)
+}
+
+// ListDeletedAvatars returns the avatars of the users PurgeDeleted removes
+func (r *userRepository) ListDeletedAvatars(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	var avatars []string
+
+	err := conn(ctx, r.DB).Unscoped().Model(&model.User{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND avatar <> ''", deletedBefore).
+		Pluck("avatar", &avatars).Error
+
+	return avatars, translateError(err)
+}
+
+// PurgeDeleted permanently removes users soft deleted before the given time,
+// their tokens are removed with them by the foreign key cascade.
+func (r *userRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	result := conn(ctx, r.DB).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+		Delete(&model.User{})
+
+	return result.RowsAffected, translateError(result.Error)
+}
+
+// Purge permanently removes the user, deleted or not, with its tokens
+func (r *userRepository) Purge(ctx context.Context, id string) error {
+	result := conn(ctx, r.DB).Unscoped().Where("id = ?", id).Delete(&model.User{})
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// userUpdates lists the non-zero fields like Updates does with a struct, a
+// map being the only way to set the version from its column
+func userUpdates(fields *model.User) map[string]interface{} {
+	values := map[string]interface{}{"version": gorm.Expr("version + 1")}
+	if fields.Name != "" {
+		values["name"] = fields.Name
+	}
+	if fields.Email != "" {
+		values["email"] = fields.Email
+	}
+	if fields.Password != "" {
+		values["password"] = fields.Password
+	}
+	if fields.Role != "" {
+		values["role"] = fields.Role
+	}
+	if fields.Language != "" {
+		values["language"] = fields.Language
+	}
+	if fields.VerifiedEmail {
+		values["verified_email"] = true
+	}
+
+	return values
+}
+
+func atVersion(db *gorm.DB, version int64) *gorm.DB {
+	if version == 0 {
+		return db
+	}
+	return db.Where("version = ?", version)
+}
+
+// notUpdated tells a missing user from one changed since version
+func (r *userRepository) notUpdated(ctx context.Context, id string, version int64) error {
+	if version == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := r.findOnPrimary(ctx, id); err != nil {
+		return err
+	}
+
+	return ErrVersionConflict
+}
+
+// findOnPrimary reads a row just written, a replica may lag behind
+func (r *userRepository) findOnPrimary(ctx context.Context, id string) (*model.User, error) {
+	user := new(model.User)
+
+	if err := conn(ctx, r.DB).Clauses(dbresolver.Write).First(user, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return user, nil
+}
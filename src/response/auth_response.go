@@ -1,6 +1,10 @@
 package response
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Tokens struct {
 	Access  TokenExpires `json:"access"`
@@ -17,3 +21,10 @@ type RefreshToken struct {
 	Status string `json:"status"`
 	Tokens Tokens `json:"tokens"`
 }
+
+// Session is a signed in client of the user, its refresh token isn't exposed
+type Session struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -28,3 +28,16 @@ func Error(c *fiber.Ctx, statusCode int, message string, details interface{}) er
 
 	return errRes
 }
+
+// MIMEApplicationProblemJSON is the media type of the problems
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// SendProblem sends an RFC 7807 problem
+func SendProblem(c *fiber.Ctx, problem Problem) error {
+	errRes := c.Status(problem.Status).JSON(problem, MIMEApplicationProblemJSON)
+	if errRes != nil {
+		logrus.Errorf("Failed to send error response : %+v", errRes)
+	}
+
+	return errRes
+}
@@ -0,0 +1,44 @@
+package example
+
+import "github.com/google/uuid"
+
+type DataRequest struct {
+	ID          uuid.UUID `json:"id" example:"0c1d2e3f-4a5b-4c6d-8e7f-9a0b1c2d3e4f"`
+	Type        string    `json:"type" example:"export"`
+	Status      string    `json:"status" example:"completed"`
+	ScheduledAt string    `json:"scheduled_at,omitempty" example:"2024-10-29T09:00:00Z"`
+	ExpiresAt   string    `json:"expires_at,omitempty" example:"2024-11-05T09:01:00Z"`
+	CompletedAt string    `json:"completed_at,omitempty" example:"2024-10-29T09:01:00Z"`
+	CreatedAt   string    `json:"created_at" example:"2024-10-29T09:00:00Z"`
+}
+
+type PendingExport struct {
+	ID          uuid.UUID `json:"id" example:"0c1d2e3f-4a5b-4c6d-8e7f-9a0b1c2d3e4f"`
+	Type        string    `json:"type" example:"export"`
+	Status      string    `json:"status" example:"pending"`
+	ScheduledAt string    `json:"scheduled_at" example:"2024-10-29T09:00:00Z"`
+	CreatedAt   string    `json:"created_at" example:"2024-10-29T09:00:00Z"`
+}
+
+type UnconfirmedErasure struct {
+	ID        uuid.UUID `json:"id" example:"5e6f7a8b-9c0d-4e1f-a2b3-c4d5e6f7a8b9"`
+	Type      string    `json:"type" example:"erasure"`
+	Status    string    `json:"status" example:"unconfirmed"`
+	CreatedAt string    `json:"created_at" example:"2024-10-29T09:00:00Z"`
+}
+
+type PendingErasure struct {
+	ID          uuid.UUID `json:"id" example:"5e6f7a8b-9c0d-4e1f-a2b3-c4d5e6f7a8b9"`
+	Type        string    `json:"type" example:"erasure"`
+	Status      string    `json:"status" example:"pending"`
+	ScheduledAt string    `json:"scheduled_at" example:"2024-11-12T09:05:00Z"`
+	CreatedAt   string    `json:"created_at" example:"2024-10-29T09:00:00Z"`
+}
+
+type CancelledErasure struct {
+	ID          uuid.UUID `json:"id" example:"5e6f7a8b-9c0d-4e1f-a2b3-c4d5e6f7a8b9"`
+	Type        string    `json:"type" example:"erasure"`
+	Status      string    `json:"status" example:"cancelled"`
+	ScheduledAt string    `json:"scheduled_at,omitempty" example:"2024-11-12T09:05:00Z"`
+	CreatedAt   string    `json:"created_at" example:"2024-10-29T09:00:00Z"`
+}
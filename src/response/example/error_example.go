@@ -1,43 +1,197 @@
 package example
 
 type Unauthorized struct {
-	Code    int    `json:"code" example:"401"`
-	Status  string `json:"status" example:"error"`
-	Message string `json:"message" example:"Please authenticate"`
+	Type   string `json:"type" example:"/v1/problems/unauthenticated"`
+	Title  string `json:"title" example:"Please authenticate"`
+	Status int    `json:"status" example:"401"`
+	Code   string `json:"code" example:"unauthenticated"`
 }
 
 type FailedLogin struct {
-	Code    int    `json:"code" example:"401"`
-	Status  string `json:"status" example:"error"`
-	Message string `json:"message" example:"Invalid email or password"`
+	Type   string `json:"type" example:"/v1/problems/invalid-credentials"`
+	Title  string `json:"title" example:"Invalid email or password"`
+	Status int    `json:"status" example:"401"`
+	Code   string `json:"code" example:"invalid-credentials"`
 }
 
 type FailedResetPassword struct {
-	Code    int    `json:"code" example:"401"`
-	Status  string `json:"status" example:"error"`
-	Message string `json:"message" example:"Password reset failed"`
+	Type   string `json:"type" example:"/v1/problems/password-reset-failed"`
+	Title  string `json:"title" example:"Password reset failed"`
+	Status int    `json:"status" example:"401"`
+	Code   string `json:"code" example:"password-reset-failed"`
 }
 
 type FailedVerifyEmail struct {
-	Code    int    `json:"code" example:"401"`
-	Status  string `json:"status" example:"error"`
-	Message string `json:"message" example:"Verify email failed"`
+	Type   string `json:"type" example:"/v1/problems/verify-email-failed"`
+	Title  string `json:"title" example:"Verify email failed"`
+	Status int    `json:"status" example:"401"`
+	Code   string `json:"code" example:"verify-email-failed"`
 }
 
 type Forbidden struct {
-	Code    int    `json:"code" example:"403"`
-	Status  string `json:"status" example:"error"`
-	Message string `json:"message" example:"You don't have permission to access this resource"`
+	Type   string `json:"type" example:"/v1/problems/forbidden"`
+	Title  string `json:"title" example:"You don't have permission to access this resource"`
+	Status int    `json:"status" example:"403"`
+	Code   string `json:"code" example:"forbidden"`
 }
 
 type NotFound struct {
-	Code    int    `json:"code" example:"404"`
-	Status  string `json:"status" example:"error"`
-	Message string `json:"message" example:"Not found"`
+	Type   string `json:"type" example:"/v1/problems/user-not-found"`
+	Title  string `json:"title" example:"User not found"`
+	Status int    `json:"status" example:"404"`
+	Code   string `json:"code" example:"user-not-found"`
 }
 
 type DuplicateEmail struct {
-	Code    int    `json:"code" example:"409"`
-	Status  string `json:"status" example:"error"`
-	Message string `json:"message" example:"Email already taken"`
+	Type   string `json:"type" example:"/v1/problems/email-taken"`
+	Title  string `json:"title" example:"Email is already in use"`
+	Status int    `json:"status" example:"409"`
+	Code   string `json:"code" example:"email-taken"`
+}
+
+type IdempotencyKeyReused struct {
+	Type   string `json:"type" example:"/v1/problems/idempotency-key-reused"`
+	Title  string `json:"title" example:"Idempotency-Key was already used with a different request"`
+	Status int    `json:"status" example:"422"`
+	Code   string `json:"code" example:"idempotency-key-reused"`
+}
+
+type PreconditionFailed struct {
+	Type   string `json:"type" example:"/v1/problems/user-modified"`
+	Title  string `json:"title" example:"User was modified since it was read"`
+	Status int    `json:"status" example:"412"`
+	Code   string `json:"code" example:"user-modified"`
+}
+
+type ProblemTypeNotFound struct {
+	Type   string `json:"type" example:"/v1/problems/problem-not-found"`
+	Title  string `json:"title" example:"Problem type not found"`
+	Status int    `json:"status" example:"404"`
+	Code   string `json:"code" example:"problem-not-found"`
+}
+
+type AvatarMissing struct {
+	Type   string `json:"type" example:"/v1/problems/avatar-missing"`
+	Title  string `json:"title" example:"The avatar file is missing"`
+	Status int    `json:"status" example:"400"`
+	Code   string `json:"code" example:"avatar-missing"`
+}
+
+type AvatarNotFound struct {
+	Type   string `json:"type" example:"/v1/problems/avatar-not-found"`
+	Title  string `json:"title" example:"Avatar not found"`
+	Status int    `json:"status" example:"404"`
+	Code   string `json:"code" example:"avatar-not-found"`
+}
+
+type AvatarTooLarge struct {
+	Type   string `json:"type" example:"/v1/problems/avatar-too-large"`
+	Title  string `json:"title" example:"Avatar is too large"`
+	Status int    `json:"status" example:"413"`
+	Code   string `json:"code" example:"avatar-too-large"`
+}
+
+type AvatarUnsupportedType struct {
+	Type   string `json:"type" example:"/v1/problems/avatar-unsupported-type"`
+	Title  string `json:"title" example:"Avatar must be a JPEG, PNG, GIF or WebP picture"`
+	Status int    `json:"status" example:"415"`
+	Code   string `json:"code" example:"avatar-unsupported-type"`
+}
+
+type AvatarInvalid struct {
+	Type   string `json:"type" example:"/v1/problems/avatar-invalid"`
+	Title  string `json:"title" example:"Avatar is not a valid picture"`
+	Status int    `json:"status" example:"422"`
+	Code   string `json:"code" example:"avatar-invalid"`
+}
+
+type CurrentPasswordInvalid struct {
+	Type   string `json:"type" example:"/v1/problems/current-password-invalid"`
+	Title  string `json:"title" example:"Current password is incorrect"`
+	Status int    `json:"status" example:"403"`
+	Code   string `json:"code" example:"current-password-invalid"`
+}
+
+type ConfirmationMismatch struct {
+	Type   string `json:"type" example:"/v1/problems/confirmation-mismatch"`
+	Title  string `json:"title" example:"Confirm must be the email of the account"`
+	Status int    `json:"status" example:"422"`
+	Code   string `json:"code" example:"confirmation-mismatch"`
+}
+
+type InvalidToken struct {
+	Type   string `json:"type" example:"/v1/problems/invalid-token"`
+	Title  string `json:"title" example:"Invalid Token"`
+	Status int    `json:"status" example:"401"`
+	Code   string `json:"code" example:"invalid-token"`
+}
+
+type ExportNotFound struct {
+	Type   string `json:"type" example:"/v1/problems/export-not-found"`
+	Title  string `json:"title" example:"Export not found"`
+	Status int    `json:"status" example:"404"`
+	Code   string `json:"code" example:"export-not-found"`
+}
+
+type ErasureNotFound struct {
+	Type   string `json:"type" example:"/v1/problems/erasure-not-found"`
+	Title  string `json:"title" example:"No erasure of the account was requested"`
+	Status int    `json:"status" example:"404"`
+	Code   string `json:"code" example:"erasure-not-found"`
+}
+
+type ExportNotReady struct {
+	Type   string `json:"type" example:"/v1/problems/export-not-ready"`
+	Title  string `json:"title" example:"The export is not ready yet"`
+	Status int    `json:"status" example:"409"`
+	Code   string `json:"code" example:"export-not-ready"`
+}
+
+type ErasureScheduled struct {
+	Type   string `json:"type" example:"/v1/problems/erasure-scheduled"`
+	Title  string `json:"title" example:"The erasure of the account is already scheduled"`
+	Status int    `json:"status" example:"409"`
+	Code   string `json:"code" example:"erasure-scheduled"`
+}
+
+type ErasureRunning struct {
+	Type   string `json:"type" example:"/v1/problems/erasure-running"`
+	Title  string `json:"title" example:"The erasure of the account is running"`
+	Status int    `json:"status" example:"409"`
+	Code   string `json:"code" example:"erasure-running"`
+}
+
+type ImportMissing struct {
+	Type   string `json:"type" example:"/v1/problems/import-missing"`
+	Title  string `json:"title" example:"The import file is missing"`
+	Status int    `json:"status" example:"400"`
+	Code   string `json:"code" example:"import-missing"`
+}
+
+type ImportNotFound struct {
+	Type   string `json:"type" example:"/v1/problems/import-not-found"`
+	Title  string `json:"title" example:"Import not found"`
+	Status int    `json:"status" example:"404"`
+	Code   string `json:"code" example:"import-not-found"`
+}
+
+type ImportTooLarge struct {
+	Type   string `json:"type" example:"/v1/problems/import-too-large"`
+	Title  string `json:"title" example:"The import file is too large"`
+	Status int    `json:"status" example:"413"`
+	Code   string `json:"code" example:"import-too-large"`
+}
+
+type ImportUnsupportedType struct {
+	Type   string `json:"type" example:"/v1/problems/import-unsupported-type"`
+	Title  string `json:"title" example:"The import file must be a CSV or NDJSON file"`
+	Status int    `json:"status" example:"415"`
+	Code   string `json:"code" example:"import-unsupported-type"`
+}
+
+type ImportInvalid struct {
+	Type   string `json:"type" example:"/v1/problems/import-invalid"`
+	Title  string `json:"title" example:"The import file has no users, a CSV file needs an email column"`
+	Status int    `json:"status" example:"422"`
+	Code   string `json:"code" example:"import-invalid"`
 }
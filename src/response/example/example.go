@@ -67,6 +67,7 @@ type GetAllUserResponse struct {
 	Results      []User `json:"results"`
 	Page         int    `json:"page" example:"1"`
 	Limit        int    `json:"limit" example:"10"`
+	NextCursor   string `json:"next_cursor" example:"eyJzIjoiY3JlYXRlZF9hdCIsIm8iOiJhc2MiLCJrIjoidGltZSJ9"`
 	TotalPages   int64  `json:"total_pages" example:"1"`
 	TotalResults int64  `json:"total_results" example:"1"`
 }
@@ -97,3 +98,139 @@ type DeleteUserResponse struct {
 	Status  string `json:"status" example:"success"`
 	Message string `json:"message" example:"Delete user successfully"`
 }
+
+type RestoreUserResponse struct {
+	Code    int    `json:"code" example:"200"`
+	Status  string `json:"status" example:"success"`
+	Message string `json:"message" example:"Restore user successfully"`
+	User    User   `json:"user"`
+}
+
+type ChangePasswordResponse struct {
+	Code    int    `json:"code" example:"200"`
+	Status  string `json:"status" example:"success"`
+	Message string `json:"message" example:"Change password successfully"`
+	User    User   `json:"user"`
+	Tokens  Tokens `json:"tokens"`
+}
+
+type ChangeEmailResponse struct {
+	Code    int    `json:"code" example:"200"`
+	Status  string `json:"status" example:"success"`
+	Message string `json:"message" example:"Change email successfully, please check your email for a link to verify it"`
+	User    User   `json:"user"`
+}
+
+type ReauthResponse struct {
+	Code    int    `json:"code" example:"200"`
+	Status  string `json:"status" example:"success"`
+	Message string `json:"message" example:"Please check your email for a link confirming it is you"`
+}
+
+type GetSessionsResponse struct {
+	Code    int       `json:"code" example:"200"`
+	Status  string    `json:"status" example:"success"`
+	Message string    `json:"message" example:"Get sessions successfully"`
+	Results []Session `json:"results"`
+}
+
+type RequestExportResponse struct {
+	Code        int           `json:"code" example:"202"`
+	Status      string        `json:"status" example:"success"`
+	Message     string        `json:"message" example:"Export requested, a link to download it will be emailed once it is ready"`
+	DataRequest PendingExport `json:"data_request"`
+}
+
+type GetDataRequestsResponse struct {
+	Code    int           `json:"code" example:"200"`
+	Status  string        `json:"status" example:"success"`
+	Message string        `json:"message" example:"Get data requests successfully"`
+	Results []DataRequest `json:"results"`
+}
+
+type RequestErasureResponse struct {
+	Code        int                `json:"code" example:"202"`
+	Status      string             `json:"status" example:"success"`
+	Message     string             `json:"message" example:"Erasure requested, please check your email for a link to confirm it"`
+	DataRequest UnconfirmedErasure `json:"data_request"`
+}
+
+type ConfirmErasureResponse struct {
+	Code        int            `json:"code" example:"200"`
+	Status      string         `json:"status" example:"success"`
+	Message     string         `json:"message" example:"Erasure confirmed successfully"`
+	DataRequest PendingErasure `json:"data_request"`
+}
+
+type CancelErasureResponse struct {
+	Code        int              `json:"code" example:"200"`
+	Status      string           `json:"status" example:"success"`
+	Message     string           `json:"message" example:"Erasure cancelled successfully"`
+	DataRequest CancelledErasure `json:"data_request"`
+}
+
+type UpdateAvatarResponse struct {
+	Code    int    `json:"code" example:"200"`
+	Status  string `json:"status" example:"success"`
+	Message string `json:"message" example:"Update avatar successfully"`
+	User    User   `json:"user"`
+}
+
+type DeleteAvatarResponse struct {
+	Code    int    `json:"code" example:"200"`
+	Status  string `json:"status" example:"success"`
+	Message string `json:"message" example:"Delete avatar successfully"`
+	User    User   `json:"user"`
+}
+
+type GetJobRunsResponse struct {
+	Code       int      `json:"code" example:"200"`
+	Status     string   `json:"status" example:"success"`
+	Message    string   `json:"message" example:"Get job runs successfully"`
+	Results    []JobRun `json:"results"`
+	Page       int      `json:"page" example:"1"`
+	Limit      int      `json:"limit" example:"20"`
+	NextCursor string   `json:"next_cursor" example:"eyJzIjoic3RhcnRlZF9hdCIsIm8iOiJkZXNjIiwiayI6InRpbWUifQ"`
+}
+
+type ProblemType struct {
+	Type   string `json:"type" example:"/v1/problems/user-not-found"`
+	Code   string `json:"code" example:"user-not-found"`
+	Title  string `json:"title" example:"User not found"`
+	Status int    `json:"status" example:"404"`
+}
+
+type GetProblemTypesResponse struct {
+	Code    int           `json:"code" example:"200"`
+	Status  string        `json:"status" example:"success"`
+	Message string        `json:"message" example:"Get problem types successfully"`
+	Results []ProblemType `json:"results"`
+}
+
+type GetProblemTypeResponse struct {
+	Code    int         `json:"code" example:"200"`
+	Status  string      `json:"status" example:"success"`
+	Message string      `json:"message" example:"Get problem type successfully"`
+	Problem ProblemType `json:"problem"`
+}
+
+type ImportUsersResponse struct {
+	Code       int        `json:"code" example:"200"`
+	Status     string     `json:"status" example:"success"`
+	Message    string     `json:"message" example:"Import users successfully"`
+	UserImport UserImport `json:"import"`
+}
+
+type ImportUsersAcceptedResponse struct {
+	Code       int               `json:"code" example:"202"`
+	Status     string            `json:"status" example:"success"`
+	Message    string            `json:"message" example:"Import accepted, get it to follow its progress"`
+	UserImport PendingUserImport `json:"import"`
+}
+
+type GetImportResponse struct {
+	Code       int        `json:"code" example:"200"`
+	Status     string     `json:"status" example:"success"`
+	Message    string     `json:"message" example:"Get import successfully"`
+	UserImport UserImport `json:"import"`
+}
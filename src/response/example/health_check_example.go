@@ -1,7 +1,7 @@
 package example
 
 type HealthCheck struct {
-	Name   string `json:"name" example:"Postgre"`
+	Name   string `json:"name" example:"Database"`
 	Status string `json:"status" example:"Up"`
 	IsUp   bool   `json:"is_up" example:"true"`
 }
@@ -15,7 +15,7 @@ type HealthCheckResponse struct {
 }
 
 type HealthCheckError struct {
-	Name    string  `json:"name" example:"Postgre"`
+	Name    string  `json:"name" example:"Database"`
 	Status  string  `json:"status" example:"Down"`
 	IsUp    bool    `json:"is_up" example:"false"`
 	Message *string `json:"message,omitempty" example:"failed to connect to 'host=localhost user=postgres database=wrongdb': server error (FATAL: database \"wrongdb\" does not exist (SQLSTATE 3D000))"`
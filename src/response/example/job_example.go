@@ -0,0 +1,13 @@
+package example
+
+import "github.com/google/uuid"
+
+type JobRun struct {
+	ID         uuid.UUID `json:"id" example:"e088d183-9eea-4a11-8d5d-74d7ec91bdf5"`
+	Name       string    `json:"name" example:"purge-expired-tokens"`
+	Owner      string    `json:"owner" example:"api-1:4211:5f1c2a9b"`
+	Status     string    `json:"status" example:"succeeded"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  string    `json:"started_at" example:"2024-10-22T03:00:00Z"`
+	FinishedAt string    `json:"finished_at" example:"2024-10-22T03:00:01Z"`
+}
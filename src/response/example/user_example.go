@@ -8,6 +8,8 @@ type User struct {
 	Email         string    `json:"email" example:"fake@example.com"`
 	Role          string    `json:"role" example:"user"`
 	VerifiedEmail bool      `json:"verified_email" example:"false"`
+	Language      string    `json:"language,omitempty" example:"en"`
+	AvatarURL     string    `json:"avatar_url,omitempty" example:"/v1/avatars/0b7f5c3e-7d35-4c5e-9a51-3f1a2c3d4e5f.jpg"`
 }
 
 type GoogleUser struct {
@@ -16,4 +18,5 @@ type GoogleUser struct {
 	Email         string    `json:"email" example:"fake@example.com"`
 	Role          string    `json:"role" example:"user"`
 	VerifiedEmail bool      `json:"verified_email" example:"true"`
+	Language      string    `json:"language,omitempty" example:"en"`
 }
@@ -0,0 +1,43 @@
+package example
+
+import (
+	"app/src/validation"
+
+	"github.com/google/uuid"
+)
+
+type UserImport struct {
+	ID          uuid.UUID       `json:"id" example:"7a8b9c0d-1e2f-4a3b-8c4d-5e6f7a8b9c0d"`
+	CreatedBy   uuid.UUID       `json:"created_by" example:"e088d183-9eea-4a11-8d5d-74d7ec91bdf5"`
+	Format      string          `json:"format" example:"csv"`
+	Invite      bool            `json:"invite" example:"true"`
+	Status      string          `json:"status" example:"completed"`
+	Total       int             `json:"total" example:"2"`
+	Created     int             `json:"created" example:"1"`
+	Failed      int             `json:"failed" example:"1"`
+	Results     []UserImportRow `json:"results"`
+	CompletedAt string          `json:"completed_at" example:"2024-10-30T09:00:01Z"`
+	CreatedAt   string          `json:"created_at" example:"2024-10-30T09:00:00Z"`
+}
+
+type PendingUserImport struct {
+	ID        uuid.UUID `json:"id" example:"7a8b9c0d-1e2f-4a3b-8c4d-5e6f7a8b9c0d"`
+	CreatedBy uuid.UUID `json:"created_by" example:"e088d183-9eea-4a11-8d5d-74d7ec91bdf5"`
+	Format    string    `json:"format" example:"ndjson"`
+	Invite    bool      `json:"invite" example:"false"`
+	Status    string    `json:"status" example:"pending"`
+	Total     int       `json:"total" example:"2500"`
+	Created   int       `json:"created" example:"0"`
+	Failed    int       `json:"failed" example:"0"`
+	CreatedAt string    `json:"created_at" example:"2024-10-30T09:00:00Z"`
+}
+
+type UserImportRow struct {
+	Line    int                              `json:"line" example:"3"`
+	Status  string                           `json:"status" example:"failed"`
+	UserID  string                           `json:"user_id,omitempty" example:"3c4d5e6f-7a8b-4c9d-8e0f-1a2b3c4d5e6f"`
+	Invited bool                             `json:"invited,omitempty" example:"false"`
+	Code    string                           `json:"code,omitempty" example:"validation-failed"`
+	Title   string                           `json:"title,omitempty" example:"Validation failed"`
+	Errors  map[string]validation.FieldError `json:"errors,omitempty"`
+}
@@ -1,5 +1,7 @@
 package response
 
+import "app/src/health"
+
 type HealthCheck struct {
 	Name    string  `json:"name"`
 	Status  string  `json:"status"`
@@ -14,3 +16,8 @@ type HealthCheckResponse struct {
 	IsHealthy bool          `json:"is_healthy"`
 	Result    []HealthCheck `json:"result"`
 }
+
+type ProbeResponse struct {
+	Status string          `json:"status"`
+	Checks []health.Result `json:"checks"`
+}
@@ -1,6 +1,9 @@
 package response
 
-import "app/src/model"
+import (
+	"app/src/model"
+	"app/src/validation"
+)
 
 type Common struct {
 	Code    int    `json:"code"`
@@ -23,15 +26,44 @@ type SuccessWithTokens struct {
 	Tokens  Tokens     `json:"tokens"`
 }
 
+type SuccessWithSessions struct {
+	Code    int       `json:"code"`
+	Status  string    `json:"status"`
+	Message string    `json:"message"`
+	Results []Session `json:"results"`
+}
+
+type SuccessWithDataRequest struct {
+	Code        int               `json:"code"`
+	Status      string            `json:"status"`
+	Message     string            `json:"message"`
+	DataRequest model.DataRequest `json:"data_request"`
+}
+
+type SuccessWithDataRequests struct {
+	Code    int                 `json:"code"`
+	Status  string              `json:"status"`
+	Message string              `json:"message"`
+	Results []model.DataRequest `json:"results"`
+}
+
+type SuccessWithUserImport struct {
+	Code       int              `json:"code"`
+	Status     string           `json:"status"`
+	Message    string           `json:"message"`
+	UserImport model.UserImport `json:"import"`
+}
+
 type SuccessWithPaginate[T any] struct {
 	Code         int    `json:"code"`
 	Status       string `json:"status"`
 	Message      string `json:"message"`
 	Results      []T    `json:"results"`
-	Page         int    `json:"page"`
+	Page         int    `json:"page,omitempty"`
 	Limit        int    `json:"limit"`
-	TotalPages   int64  `json:"total_pages"`
-	TotalResults int64  `json:"total_results"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+	TotalPages   int64  `json:"total_pages,omitempty"`
+	TotalResults int64  `json:"total_results,omitempty"`
 }
 
 type ErrorDetails struct {
@@ -40,3 +72,37 @@ type ErrorDetails struct {
 	Message string      `json:"message"`
 	Errors  interface{} `json:"errors"`
 }
+
+// ProblemType describes a code of the catalogue
+type ProblemType struct {
+	Type   string `json:"type"`
+	Code   string `json:"code"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+}
+
+type SuccessWithProblemTypes struct {
+	Code    int           `json:"code"`
+	Status  string        `json:"status"`
+	Message string        `json:"message"`
+	Results []ProblemType `json:"results"`
+}
+
+type SuccessWithProblemType struct {
+	Code    int         `json:"code"`
+	Status  string      `json:"status"`
+	Message string      `json:"message"`
+	Problem ProblemType `json:"problem"`
+}
+
+// Problem is an RFC 7807 problem, extended with the stable code of the error
+type Problem struct {
+	Type      string                           `json:"type"`
+	Title     string                           `json:"title"`
+	Status    int                              `json:"status"`
+	Detail    string                           `json:"detail,omitempty"`
+	Instance  string                           `json:"instance,omitempty"`
+	Code      string                           `json:"code"`
+	RequestID string                           `json:"request_id,omitempty"`
+	Errors    map[string]validation.FieldError `json:"errors,omitempty"`
+}
@@ -0,0 +1,30 @@
+package router
+
+import (
+	"app/src/config"
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func AccountRoutes(
+	v1 fiber.Router, a service.AccountService, u service.UserService,
+	t service.TokenService, e service.EmailService, limiter *m.RateLimiter,
+) {
+	accountController := controller.NewAccountController(a, t, e)
+
+	me := v1.Group("/me")
+
+	// The routes checking the current password count the failures like the auth routes
+	checked := limiter.Limit(config.RateLimitAuth)
+
+	me.Get("/", m.Auth(u, t), accountController.GetMe)
+	me.Patch("/", m.Auth(u, t), accountController.UpdateMe)
+	me.Delete("/", m.Auth(u, t), checked, accountController.DeleteMe)
+	me.Post("/password", m.Auth(u, t), checked, accountController.ChangePassword)
+	me.Post("/email", m.Auth(u, t), checked, accountController.ChangeEmail)
+	me.Post("/reauth", m.Auth(u, t), checked, accountController.Reauth)
+	me.Get("/sessions", m.Auth(u, t), accountController.GetSessions)
+}
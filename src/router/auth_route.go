@@ -11,20 +11,23 @@ import (
 
 func AuthRoutes(
 	v1 fiber.Router, a service.AuthService, u service.UserService,
-	t service.TokenService, e service.EmailService,
+	t service.TokenService, e service.EmailService, i service.IdempotencyService,
+	limiter *m.RateLimiter, google config.Google,
 ) {
-	authController := controller.NewAuthController(a, u, t, e)
-	config.GoogleConfig()
+	authController := controller.NewAuthController(a, u, t, e, google)
 
-	auth := v1.Group("/auth")
+	auth := v1.Group("/auth", limiter.Limit(config.RateLimitAuth))
 
-	auth.Post("/register", authController.Register)
-	auth.Post("/login", authController.Login)
+	auth.Post("/register", m.Idempotency(i), authController.Register)
+	auth.Post("/login", limiter.Limit(config.RateLimitLogin), authController.Login)
 	auth.Post("/logout", authController.Logout)
 	auth.Post("/refresh-tokens", authController.RefreshTokens)
-	auth.Post("/forgot-password", authController.ForgotPassword)
+	auth.Post("/forgot-password", limiter.Limit(config.RateLimitForgotPassword), authController.ForgotPassword)
 	auth.Post("/reset-password", authController.ResetPassword)
-	auth.Post("/send-verification-email", m.Auth(u), authController.SendVerificationEmail)
+	auth.Post(
+		"/send-verification-email",
+		m.Auth(u, t), limiter.Limit(config.RateLimitVerificationEmail), authController.SendVerificationEmail,
+	)
 	auth.Post("/verify-email", authController.VerifyEmail)
 	auth.Get("/google", authController.GoogleLogin)
 	auth.Get("/google-callback", authController.GoogleCallback)
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"app/src/controller"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func AvatarRoutes(v1 fiber.Router, a service.AvatarService) {
+	avatarController := controller.NewAvatarController(a)
+
+	avatar := v1.Group("/avatars")
+
+	avatar.Get("/:name", avatarController.GetAvatar)
+}
@@ -7,9 +7,13 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func HealthCheckRoutes(v1 fiber.Router, h service.HealthCheckService) {
+func HealthCheckRoutes(app *fiber.App, v1 fiber.Router, h service.HealthCheckService) {
 	healthCheckController := controller.NewHealthCheckController(h)
 
+	// Probes live at the root, so orchestrators don't depend on the API version
+	app.Get("/livez", healthCheckController.Livez)
+	app.Get("/readyz", healthCheckController.Readyz)
+
 	healthCheck := v1.Group("/health-check")
 	healthCheck.Get("/", healthCheckController.Check)
 }
@@ -0,0 +1,17 @@
+package router
+
+import (
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func JobRoutes(v1 fiber.Router, j service.JobService, u service.UserService, t service.TokenService) {
+	jobController := controller.NewJobController(j)
+
+	job := v1.Group("/jobs")
+
+	job.Get("/runs", m.Auth(u, t, "manageJobs"), jobController.GetJobRuns)
+}
@@ -0,0 +1,45 @@
+package router
+
+import (
+	"app/src/config"
+	"app/src/database"
+	"app/src/metrics"
+	m "app/src/middleware"
+	"app/src/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+// MetricsRoutes serves the Prometheus metrics outside of /v1, when they are enabled
+func MetricsRoutes(app *fiber.App, db *gorm.DB, cfg config.Metrics) {
+	if !cfg.Enabled {
+		return
+	}
+
+	registerDBStats(db)
+
+	dir := ""
+	if app.Config().Prefork {
+		dir = metrics.Dir(fiber.IsChild())
+	}
+
+	handler := promhttp.HandlerFor(metrics.Gatherer(dir), promhttp.HandlerOpts{})
+	app.Get(metrics.Path, m.MetricsAuth(cfg.Token), adaptor.HTTPHandler(handler))
+}
+
+func registerDBStats(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		if err := metrics.RegisterDB(sqlDB, "primary"); err != nil {
+			utils.Log.Warnf("Failed to register database metrics: %v", err)
+		}
+	}
+
+	for _, replica := range database.Replicas(db) {
+		if err := metrics.RegisterDB(replica.DB, replica.Host); err != nil {
+			utils.Log.Warnf("Failed to register metrics of database replica %s: %v", replica.Host, err)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package router
+
+import (
+	"app/src/config"
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func PrivacyRoutes(
+	v1 fiber.Router, p service.PrivacyService, u service.UserService, t service.TokenService, limiter *m.RateLimiter,
+) {
+	privacyController := controller.NewPrivacyController(p)
+
+	me := v1.Group("/me")
+
+	me.Post("/exports", m.Auth(u, t), privacyController.RequestExport)
+	me.Get("/exports/:exportId", m.Auth(u, t), privacyController.GetExport)
+	me.Get("/data-requests", m.Auth(u, t), privacyController.GetDataRequests)
+	me.Post("/erasure", m.Auth(u, t), limiter.Limit(config.RateLimitAuth), privacyController.RequestErasure)
+	me.Post("/erasure/confirm", m.Auth(u, t), limiter.Limit(config.RateLimitAuth), privacyController.ConfirmErasure)
+	me.Delete("/erasure", m.Auth(u, t), privacyController.CancelErasure)
+}
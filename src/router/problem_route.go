@@ -0,0 +1,16 @@
+package router
+
+import (
+	"app/src/controller"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func ProblemRoutes(v1 fiber.Router) {
+	problemController := controller.NewProblemController()
+
+	problem := v1.Group("/problems")
+
+	problem.Get("/", problemController.GetProblemTypes)
+	problem.Get("/:code", problemController.GetProblemType)
+}
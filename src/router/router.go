@@ -2,30 +2,66 @@ package router
 
 import (
 	"app/src/config"
+	m "app/src/middleware"
+	"app/src/ratelimit"
+	"app/src/repository"
 	"app/src/service"
+	"app/src/storage"
 	"app/src/validation"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
-func Routes(app *fiber.App, db *gorm.DB) {
+// Routes registers the routes, the services read the reloadable settings from live,
+// the rate limits are counted in rateLimits and the uploads are kept in blobs
+func Routes(app *fiber.App, db *gorm.DB, live *config.Live, rateLimits ratelimit.Store, blobs storage.Store) {
+	cfg := live.Get()
 	validate := validation.Validator()
 
-	healthCheckService := service.NewHealthCheckService(db)
-	emailService := service.NewEmailService()
-	userService := service.NewUserService(db, validate)
-	tokenService := service.NewTokenService(db, validate, userService)
-	authService := service.NewAuthService(db, validate, userService, tokenService)
+	userRepository := repository.NewUserRepository(db)
+	tokenRepository := repository.NewTokenRepository(db)
+	jobRepository := repository.NewJobRepository(db)
+	idempotencyRepository := repository.NewIdempotencyRepository(db)
+	dataRequestRepository := repository.NewDataRequestRepository(db)
+	userImportRepository := repository.NewUserImportRepository(db)
+	unitOfWork := repository.NewUnitOfWork(db)
+
+	healthCheckService := service.NewHealthCheckService(db, jobRepository, rateLimits, cfg.Health, cfg.SMTP)
+	emailService := service.NewEmailService(live)
+	userService := service.NewUserService(userRepository, validate)
+	tokenService := service.NewTokenService(tokenRepository, unitOfWork, validate, userService, live)
+	authService := service.NewAuthService(userRepository, unitOfWork, validate, userService, tokenService)
+	jobService := service.NewJobService(jobRepository, validate)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepository, cfg.Idempotency)
+	accountService := service.NewAccountService(
+		userRepository, tokenRepository, unitOfWork, validate, userService, tokenService,
+	)
+	avatarService := service.NewAvatarService(userRepository, blobs, cfg.Avatar)
+	privacyService := service.NewPrivacyService(
+		userRepository, tokenRepository, dataRequestRepository, unitOfWork, validate,
+		tokenService, emailService, avatarService, blobs, cfg.Privacy,
+	)
+	bulkUserService := service.NewBulkUserService(
+		userRepository, userImportRepository, validate, userService, tokenService, emailService, blobs, cfg.Import,
+	)
+	rateLimiter := m.NewRateLimiter(rateLimits, live)
 
 	v1 := app.Group("/v1")
 
-	HealthCheckRoutes(v1, healthCheckService)
-	AuthRoutes(v1, authService, userService, tokenService, emailService)
-	UserRoutes(v1, userService, tokenService)
+	HealthCheckRoutes(app, v1, healthCheckService)
+	AuthRoutes(v1, authService, userService, tokenService, emailService, idempotencyService, rateLimiter, cfg.Google)
+	UserRoutes(v1, userService, tokenService, idempotencyService, avatarService, bulkUserService)
+	AccountRoutes(v1, accountService, userService, tokenService, emailService, rateLimiter)
+	PrivacyRoutes(v1, privacyService, userService, tokenService, rateLimiter)
+	AvatarRoutes(v1, avatarService)
+	JobRoutes(v1, jobService, userService, tokenService)
+	ProblemRoutes(v1)
 	// TODO: add another routes here...
 
-	if !config.IsProd {
+	MetricsRoutes(app, db, cfg.Metrics)
+
+	if !cfg.App.IsProd() {
 		DocsRoutes(v1)
 	}
 }
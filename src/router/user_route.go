@@ -8,14 +8,26 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func UserRoutes(v1 fiber.Router, u service.UserService, t service.TokenService) {
+func UserRoutes(
+	v1 fiber.Router, u service.UserService, t service.TokenService, i service.IdempotencyService, a service.AvatarService,
+	b service.BulkUserService,
+) {
 	userController := controller.NewUserController(u, t)
+	avatarController := controller.NewAvatarController(a)
+	bulkUserController := controller.NewBulkUserController(b)
 
 	user := v1.Group("/users")
 
-	user.Get("/", m.Auth(u, "getUsers"), userController.GetUsers)
-	user.Post("/", m.Auth(u, "manageUsers"), userController.CreateUser)
-	user.Get("/:userId", m.Auth(u, "getUsers"), userController.GetUserByID)
-	user.Patch("/:userId", m.Auth(u, "manageUsers"), userController.UpdateUser)
-	user.Delete("/:userId", m.Auth(u, "manageUsers"), userController.DeleteUser)
+	user.Get("/", m.Auth(u, t, "getUsers"), userController.GetUsers)
+	user.Post("/", m.Auth(u, t, "manageUsers"), m.Idempotency(i), userController.CreateUser)
+	// Registered before /:userId, which would match them
+	user.Get("/export", m.Auth(u, t, "getUsers"), bulkUserController.ExportUsers)
+	user.Post("/import", m.Auth(u, t, "manageUsers"), m.Idempotency(i), bulkUserController.ImportUsers)
+	user.Get("/imports/:importId", m.Auth(u, t, "manageUsers"), bulkUserController.GetImport)
+	user.Get("/:userId", m.Auth(u, t, "getUsers"), userController.GetUserByID)
+	user.Patch("/:userId", m.Auth(u, t, "manageUsers"), userController.UpdateUser)
+	user.Delete("/:userId", m.Auth(u, t, "manageUsers"), userController.DeleteUser)
+	user.Post("/:userId/restore", m.Auth(u, t, "manageUsers"), userController.RestoreUser)
+	user.Put("/:userId/avatar", m.Auth(u, t, "manageUsers"), avatarController.UpdateAvatar)
+	user.Delete("/:userId/avatar", m.Auth(u, t, "manageUsers"), avatarController.DeleteAvatar)
 }
@@ -0,0 +1,39 @@
+package search
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// postgresSearcher combines full-text search on a tsvector column with
+// pg_trgm substring matching, so both whole words and partial emails match
+// and both can use a GIN index.
+type postgresSearcher struct {
+	spec Spec
+}
+
+func (s *postgresSearcher) Match(db *gorm.DB, term string) *gorm.DB {
+	pattern := "%" + escapeLike(term) + "%"
+
+	conditions := []string{s.spec.Vector + " @@ websearch_to_tsquery('simple', ?)"}
+	vars := []interface{}{term}
+	for _, column := range s.spec.Columns {
+		conditions = append(conditions, column+" ILIKE ? ESCAPE '!'")
+		vars = append(vars, pattern)
+	}
+
+	return db.Where(strings.Join(conditions, " OR "), vars...)
+}
+
+func (s *postgresSearcher) Rank(term string) *clause.Expr {
+	parts := []string{"ts_rank(" + s.spec.Vector + ", websearch_to_tsquery('simple', ?))"}
+	vars := []interface{}{term}
+	for _, column := range s.spec.Columns {
+		parts = append(parts, "similarity("+column+", ?)")
+		vars = append(vars, term)
+	}
+
+	return &clause.Expr{SQL: strings.Join(parts, " + "), Vars: vars}
+}
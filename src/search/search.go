@@ -0,0 +1,60 @@
+package search
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Searcher matches rows against a free text term. Backends that can
+// score matches also return a rank expression to order results by.
+type Searcher interface {
+	Match(db *gorm.DB, term string) *gorm.DB
+	Rank(term string) *clause.Expr
+}
+
+// Spec describes the searchable columns of a table.
+type Spec struct {
+	// Vector is a tsvector column kept up to date by the database (Postgres only)
+	Vector string
+	// Columns are matched by substring, backed by trigram indexes on Postgres
+	Columns []string
+}
+
+// New returns the best searcher for the database dialect of db.
+func New(db *gorm.DB, spec Spec) Searcher {
+	if db.Dialector.Name() == "postgres" && spec.Vector != "" {
+		return &postgresSearcher{spec: spec}
+	}
+
+	return &likeSearcher{spec: spec}
+}
+
+// likeSearcher is the portable fallback, a case-insensitive substring match without ranking.
+type likeSearcher struct {
+	spec Spec
+}
+
+func (s *likeSearcher) Match(db *gorm.DB, term string) *gorm.DB {
+	pattern := "%" + escapeLike(strings.ToLower(term)) + "%"
+
+	conditions := make([]string, 0, len(s.spec.Columns))
+	vars := make([]interface{}, 0, len(s.spec.Columns))
+	for _, column := range s.spec.Columns {
+		conditions = append(conditions, "LOWER("+column+") LIKE ? ESCAPE '!'")
+		vars = append(vars, pattern)
+	}
+
+	return db.Where(strings.Join(conditions, " OR "), vars...)
+}
+
+func (s *likeSearcher) Rank(_ string) *clause.Expr {
+	return nil
+}
+
+// escapeLike escapes the LIKE wildcards with '!', which is a valid
+// ESCAPE character on every supported database.
+func escapeLike(term string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(term)
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/problem"
+	"app/src/repository"
+	"app/src/utils"
+	"app/src/validation"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// AccountService lets the users manage their own account, the sensitive
+// changes require their current password, or a reauth token for the accounts
+// created with Google
+type AccountService interface {
+	UpdateProfile(ctx context.Context, user *model.User, req *validation.UpdateProfile, version int64) (*model.User, error)
+	ChangePassword(ctx context.Context, user *model.User, req *validation.ChangePassword) error
+	ChangeEmail(ctx context.Context, user *model.User, req *validation.ChangeEmail, version int64) (*model.User, error)
+	DeleteAccount(ctx context.Context, user *model.User, req *validation.DeleteAccount, version int64) error
+	GetSessions(ctx context.Context, user *model.User) ([]model.Token, error)
+}
+
+type accountService struct {
+	Users        repository.UserRepository
+	Tokens       repository.TokenRepository
+	UnitOfWork   repository.UnitOfWork
+	Validate     *validator.Validate
+	UserService  UserService
+	TokenService TokenService
+}
+
+func NewAccountService(
+	users repository.UserRepository, tokens repository.TokenRepository, unitOfWork repository.UnitOfWork,
+	validate *validator.Validate, userService UserService, tokenService TokenService,
+) AccountService {
+	return &accountService{
+		Users:        users,
+		Tokens:       tokens,
+		UnitOfWork:   unitOfWork,
+		Validate:     validate,
+		UserService:  userService,
+		TokenService: tokenService,
+	}
+}
+
+func (s *accountService) UpdateProfile(
+	ctx context.Context, user *model.User, req *validation.UpdateProfile, version int64,
+) (*model.User, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	updateBody := &validation.UpdateUser{
+		Name:     req.Name,
+		Language: req.Language,
+	}
+
+	return s.UserService.UpdateUser(ctx, updateBody, user.ID.String(), version)
+}
+
+// ChangePassword replaces the password and signs out every session, the
+// caller hands out new tokens to the current one
+func (s *accountService) ChangePassword(ctx context.Context, user *model.User, req *validation.ChangePassword) error {
+	if err := s.Validate.Struct(req); err != nil {
+		return err
+	}
+
+	// An account created with Google has no password, it sets one with forgot-password
+	if !utils.CheckPasswordHash(req.CurrentPassword, user.Password) {
+		return problem.ErrCurrentPasswordInvalid
+	}
+
+	updateBody := &validation.UpdatePassOrVerify{
+		Password: req.NewPassword,
+	}
+
+	return s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := s.UserService.UpdatePassOrVerify(ctx, updateBody, user.ID.String()); err != nil {
+			return err
+		}
+
+		return s.TokenService.DeleteAllToken(ctx, user.ID.String())
+	})
+}
+
+// ChangeEmail moves the account to a new email, which must be verified again
+func (s *accountService) ChangeEmail(
+	ctx context.Context, user *model.User, req *validation.ChangeEmail, version int64,
+) (*model.User, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	reauth, err := checkReauth(ctx, s.TokenService, user, req.CurrentPassword, req.ReauthToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *model.User
+	err = s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := useReauth(ctx, s.TokenService, reauth); err != nil {
+			return err
+		}
+
+		var err error
+		updated, err = s.Users.SetEmail(ctx, user.ID.String(), req.Email, version)
+		return err
+	})
+
+	if errors.Is(err, problem.ErrReauthRequired) {
+		return nil, err
+	}
+
+	if errors.Is(err, repository.ErrDuplicateKey) {
+		return nil, problem.ErrEmailTaken
+	}
+
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, problem.ErrUserNotFound
+	}
+
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, problem.ErrUserModified
+	}
+
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to change email: %+v", err)
+	}
+
+	return updated, err
+}
+
+// DeleteAccount deletes the user once confirmed by its email, like an admin
+// deleting it the account can still be restored until it is purged
+func (s *accountService) DeleteAccount(
+	ctx context.Context, user *model.User, req *validation.DeleteAccount, version int64,
+) error {
+	if err := s.Validate.Struct(req); err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(req.Confirm), user.Email) {
+		return problem.ErrConfirmationMismatch
+	}
+
+	reauth, err := checkReauth(ctx, s.TokenService, user, req.CurrentPassword, req.ReauthToken)
+	if err != nil {
+		return err
+	}
+
+	return s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := useReauth(ctx, s.TokenService, reauth); err != nil {
+			return err
+		}
+
+		if err := s.UserService.DeleteUser(ctx, user.ID.String(), version); err != nil {
+			return err
+		}
+
+		return s.TokenService.DeleteAllToken(ctx, user.ID.String())
+	})
+}
+
+// GetSessions returns the refresh tokens of the user which haven't expired
+func (s *accountService) GetSessions(ctx context.Context, user *model.User) ([]model.Token, error) {
+	tokens, err := s.Tokens.ListByType(ctx, config.TokenTypeRefresh, user.ID.String(), time.Now().UTC())
+
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to get sessions: %+v", err)
+	}
+
+	return tokens, err
+}
+
+// checkReauth requires the password of the accounts which have one, the others
+// were created with Google and need a reauth token emailed to them. It returns
+// the reauth token, to be used up with useReauth.
+func checkReauth(
+	ctx context.Context, tokens TokenService, user *model.User, password, reauthToken string,
+) (*model.Token, error) {
+	if user.Password != "" {
+		if !utils.CheckPasswordHash(password, user.Password) {
+			return nil, problem.ErrCurrentPasswordInvalid
+		}
+		return nil, nil
+	}
+
+	token, err := tokens.VerifyToken(ctx, reauthToken, config.TokenTypeReauth)
+	if err != nil || token.UserID != user.ID {
+		return nil, problem.ErrReauthRequired
+	}
+
+	return token, nil
+}
+
+// useReauth deletes the reauth token so it confirms a single change
+func useReauth(ctx context.Context, tokens TokenService, token *model.Token) error {
+	if token == nil {
+		return nil
+	}
+
+	err := tokens.RevokeToken(ctx, token)
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.ErrReauthRequired
+	}
+
+	return err
+}
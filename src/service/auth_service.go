@@ -2,55 +2,57 @@ package service
 
 import (
 	"app/src/config"
+	"app/src/metrics"
 	"app/src/model"
+	"app/src/problem"
+	"app/src/repository"
 	"app/src/response"
 	"app/src/utils"
 	"app/src/validation"
+	"context"
 	"errors"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/gofiber/fiber/v2"
-	"github.com/sirupsen/logrus"
-	"gorm.io/gorm"
 )
 
 type AuthService interface {
-	Register(c *fiber.Ctx, req *validation.Register) (*model.User, error)
-	Login(c *fiber.Ctx, req *validation.Login) (*model.User, error)
-	Logout(c *fiber.Ctx, req *validation.Logout) error
-	RefreshAuth(c *fiber.Ctx, req *validation.RefreshToken) (*response.Tokens, error)
-	ResetPassword(c *fiber.Ctx, query *validation.Token, req *validation.UpdatePassOrVerify) error
-	VerifyEmail(c *fiber.Ctx, query *validation.Token) error
+	Register(ctx context.Context, req *validation.Register) (*model.User, error)
+	Login(ctx context.Context, req *validation.Login) (*model.User, error)
+	Logout(ctx context.Context, req *validation.Logout) error
+	RefreshAuth(ctx context.Context, req *validation.RefreshToken) (*response.Tokens, error)
+	ResetPassword(ctx context.Context, query *validation.Token, req *validation.UpdatePassOrVerify) error
+	VerifyEmail(ctx context.Context, query *validation.Token) error
 }
 
 type authService struct {
-	Log          *logrus.Logger
-	DB           *gorm.DB
+	Users        repository.UserRepository
+	UnitOfWork   repository.UnitOfWork
 	Validate     *validator.Validate
 	UserService  UserService
 	TokenService TokenService
 }
 
 func NewAuthService(
-	db *gorm.DB, validate *validator.Validate, userService UserService, tokenService TokenService,
+	users repository.UserRepository, unitOfWork repository.UnitOfWork,
+	validate *validator.Validate, userService UserService, tokenService TokenService,
 ) AuthService {
 	return &authService{
-		Log:          utils.Log,
-		DB:           db,
+		Users:        users,
+		UnitOfWork:   unitOfWork,
 		Validate:     validate,
 		UserService:  userService,
 		TokenService: tokenService,
 	}
 }
 
-func (s *authService) Register(c *fiber.Ctx, req *validation.Register) (*model.User, error) {
+func (s *authService) Register(ctx context.Context, req *validation.Register) (*model.User, error) {
 	if err := s.Validate.Struct(req); err != nil {
 		return nil, err
 	}
 
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
-		s.Log.Errorf("Failed hash password: %+v", err)
+		utils.Logger(ctx).Errorf("Failed hash password: %+v", err)
 		return nil, err
 	}
 
@@ -58,127 +60,145 @@ func (s *authService) Register(c *fiber.Ctx, req *validation.Register) (*model.U
 		Name:     req.Name,
 		Email:    req.Email,
 		Password: hashedPassword,
+		Language: req.Language,
 	}
 
-	result := s.DB.WithContext(c.Context()).Create(user)
-	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
-		return nil, fiber.NewError(fiber.StatusConflict, "Email already taken")
+	err = s.Users.Create(ctx, user)
+	if errors.Is(err, repository.ErrDuplicateKey) {
+		return nil, problem.ErrEmailTaken
 	}
 
-	if result.Error != nil {
-		s.Log.Errorf("Failed create user: %+v", result.Error)
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed create user: %+v", err)
 	}
 
-	return user, result.Error
+	return user, err
 }
 
-func (s *authService) Login(c *fiber.Ctx, req *validation.Login) (*model.User, error) {
+func (s *authService) Login(ctx context.Context, req *validation.Login) (user *model.User, err error) {
+	defer func() { metrics.ObserveLogin(metrics.LoginPassword, err) }()
+
 	if err := s.Validate.Struct(req); err != nil {
 		return nil, err
 	}
 
-	user, err := s.UserService.GetUserByEmail(c, req.Email)
+	user, err = s.UserService.GetUserByEmail(ctx, req.Email)
 	if err != nil {
-		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid email or password")
+		return nil, problem.ErrInvalidCredentials
 	}
 
 	if !utils.CheckPasswordHash(req.Password, user.Password) {
-		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid email or password")
+		return nil, problem.ErrInvalidCredentials
 	}
 
 	return user, nil
 }
 
-func (s *authService) Logout(c *fiber.Ctx, req *validation.Logout) error {
+func (s *authService) Logout(ctx context.Context, req *validation.Logout) error {
 	if err := s.Validate.Struct(req); err != nil {
 		return err
 	}
 
-	token, err := s.TokenService.GetTokenByUserID(c, req.RefreshToken)
+	token, err := s.TokenService.GetTokenByUserID(ctx, req.RefreshToken)
 	if err != nil {
-		return fiber.NewError(fiber.StatusNotFound, "Token not found")
+		return problem.ErrTokenNotFound
 	}
 
-	err = s.TokenService.DeleteToken(c, config.TokenTypeRefresh, token.UserID.String())
+	// Only this session ends, the other devices stay signed in
+	err = s.TokenService.RevokeToken(ctx, token)
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.ErrTokenNotFound
+	}
 
 	return err
 }
 
-func (s *authService) RefreshAuth(c *fiber.Ctx, req *validation.RefreshToken) (*response.Tokens, error) {
+func (s *authService) RefreshAuth(ctx context.Context, req *validation.RefreshToken) (*response.Tokens, error) {
 	if err := s.Validate.Struct(req); err != nil {
 		return nil, err
 	}
 
-	token, err := s.TokenService.GetTokenByUserID(c, req.RefreshToken)
+	token, err := s.TokenService.GetTokenByUserID(ctx, req.RefreshToken)
 	if err != nil {
-		return nil, fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+		return nil, problem.ErrUnauthenticated
 	}
 
-	user, err := s.UserService.GetUserByID(c, token.UserID.String())
+	user, err := s.UserService.GetUserByID(ctx, token.UserID.String())
 	if err != nil {
-		return nil, fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+		return nil, problem.ErrUnauthenticated
 	}
 
-	newTokens, err := s.TokenService.GenerateAuthTokens(c, user)
+	// The used refresh token is replaced, when it is used twice at once only one wins
+	var newTokens *response.Tokens
+	err = s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := s.TokenService.RevokeToken(ctx, token); err != nil {
+			return err
+		}
+
+		var err error
+		newTokens, err = s.TokenService.GenerateAuthTokens(ctx, user)
+		return err
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, problem.ErrUnauthenticated
+	}
 	if err != nil {
-		return nil, fiber.ErrInternalServerError
+		return nil, problem.ErrInternal
 	}
 
-	return newTokens, err
+	return newTokens, nil
 }
 
-func (s *authService) ResetPassword(c *fiber.Ctx, query *validation.Token, req *validation.UpdatePassOrVerify) error {
+func (s *authService) ResetPassword(
+	ctx context.Context, query *validation.Token, req *validation.UpdatePassOrVerify,
+) error {
 	if err := s.Validate.Struct(query); err != nil {
 		return err
 	}
 
-	userID, err := utils.VerifyToken(query.Token, config.JWTSecret, config.TokenTypeResetPassword)
+	token, err := s.TokenService.VerifyToken(ctx, query.Token, config.TokenTypeResetPassword)
 	if err != nil {
-		return fiber.NewError(fiber.StatusUnauthorized, "Invalid Token")
+		return problem.ErrInvalidToken
 	}
 
-	user, err := s.UserService.GetUserByID(c, userID)
+	user, err := s.UserService.GetUserByID(ctx, token.UserID.String())
 	if err != nil {
-		return fiber.NewError(fiber.StatusUnauthorized, "Password reset failed")
+		return problem.ErrPasswordResetFailed
 	}
 
-	if errUpdate := s.UserService.UpdatePassOrVerify(c, req, user.ID.String()); errUpdate != nil {
-		return errUpdate
-	}
-
-	if errToken := s.TokenService.DeleteToken(c, config.TokenTypeResetPassword, user.ID.String()); errToken != nil {
-		return errToken
-	}
+	return s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if errUpdate := s.UserService.UpdatePassOrVerify(ctx, req, user.ID.String()); errUpdate != nil {
+			return errUpdate
+		}
 
-	return nil
+		return s.TokenService.DeleteToken(ctx, config.TokenTypeResetPassword, user.ID.String())
+	})
 }
 
-func (s *authService) VerifyEmail(c *fiber.Ctx, query *validation.Token) error {
+func (s *authService) VerifyEmail(ctx context.Context, query *validation.Token) error {
 	if err := s.Validate.Struct(query); err != nil {
 		return err
 	}
 
-	userID, err := utils.VerifyToken(query.Token, config.JWTSecret, config.TokenTypeVerifyEmail)
+	token, err := s.TokenService.VerifyToken(ctx, query.Token, config.TokenTypeVerifyEmail)
 	if err != nil {
-		return fiber.NewError(fiber.StatusUnauthorized, "Invalid Token")
+		return problem.ErrInvalidToken
 	}
 
-	user, err := s.UserService.GetUserByID(c, userID)
+	user, err := s.UserService.GetUserByID(ctx, token.UserID.String())
 	if err != nil {
-		return fiber.NewError(fiber.StatusUnauthorized, "Verify email failed")
-	}
-
-	if errToken := s.TokenService.DeleteToken(c, config.TokenTypeVerifyEmail, user.ID.String()); errToken != nil {
-		return errToken
+		return problem.ErrVerifyEmailFailed
 	}
 
 	updateBody := &validation.UpdatePassOrVerify{
 		VerifiedEmail: true,
 	}
 
-	if errUpdate := s.UserService.UpdatePassOrVerify(c, updateBody, user.ID.String()); errUpdate != nil {
-		return errUpdate
-	}
+	return s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if errToken := s.TokenService.DeleteToken(ctx, config.TokenTypeVerifyEmail, user.ID.String()); errToken != nil {
+			return errToken
+		}
 
-	return nil
+		return s.UserService.UpdatePassOrVerify(ctx, updateBody, user.ID.String())
+	})
 }
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"app/src/avatar"
+	"app/src/config"
+	"app/src/model"
+	"app/src/problem"
+	"app/src/repository"
+	"app/src/storage"
+	"app/src/utils"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+type AvatarService interface {
+	SetAvatar(ctx context.Context, userID string, file io.Reader, version int64) (*model.User, error)
+	DeleteAvatar(ctx context.Context, userID string, version int64) (*model.User, error)
+	GetAvatar(ctx context.Context, name string, size int) (io.ReadCloser, string, error)
+	PurgeAvatar(ctx context.Context, name string) error
+}
+
+// avatarName matches the file names given to the avatars
+var avatarName = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\.(jpg|png)$`)
+
+var avatarTypes = map[string]string{".jpg": "image/jpeg", ".png": "image/png"}
+
+type avatarService struct {
+	Users  repository.UserRepository
+	Blobs  storage.Store
+	Config config.Avatar
+}
+
+func NewAvatarService(users repository.UserRepository, blobs storage.Store, cfg config.Avatar) AvatarService {
+	return &avatarService{
+		Users:  users,
+		Blobs:  blobs,
+		Config: cfg,
+	}
+}
+
+// avatarKey is the key of the thumbnail of the avatar at size
+func avatarKey(name string, size int) string {
+	return "avatars/" + strconv.Itoa(size) + "/" + name
+}
+
+// SetAvatar stores the thumbnails of the picture under a new name, then points
+// the user to them. The thumbnails of the previous avatar are deleted.
+func (s *avatarService) SetAvatar(
+	ctx context.Context, userID string, file io.Reader, version int64,
+) (*model.User, error) {
+	user, err := s.Users.FindByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, problem.ErrUserNotFound
+	}
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed get user by id: %+v", err)
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, s.Config.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.Config.MaxSize {
+		return nil, problem.ErrAvatarTooLarge
+	}
+
+	processed, err := avatar.Process(data, s.Config.Sizes)
+	switch {
+	case errors.Is(err, avatar.ErrUnsupportedType):
+		return nil, problem.ErrAvatarUnsupportedType
+	case errors.Is(err, avatar.ErrTooManyPixels):
+		return nil, problem.ErrAvatarTooManyPixels
+	case err != nil:
+		return nil, problem.ErrAvatarInvalid
+	}
+
+	name := uuid.NewString() + processed.Ext
+	for _, thumbnail := range processed.Thumbnails {
+		key := avatarKey(name, thumbnail.Size)
+		if err := s.Blobs.Put(ctx, key, bytes.NewReader(thumbnail.Data), processed.ContentType); err != nil {
+			utils.Logger(ctx).Errorf("Failed to store avatar: %+v", err)
+			s.deleteBlobs(ctx, name)
+			return nil, err
+		}
+	}
+
+	updated, err := s.Users.SetAvatar(ctx, userID, name, version)
+	if err != nil {
+		s.deleteBlobs(ctx, name)
+		return nil, s.setAvatarError(ctx, err)
+	}
+
+	if user.Avatar != "" {
+		s.deleteBlobs(ctx, user.Avatar)
+	}
+
+	return updated, nil
+}
+
+func (s *avatarService) DeleteAvatar(ctx context.Context, userID string, version int64) (*model.User, error) {
+	user, err := s.Users.FindByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, problem.ErrUserNotFound
+	}
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed get user by id: %+v", err)
+		return nil, err
+	}
+
+	if user.Avatar == "" {
+		return nil, problem.ErrAvatarNotFound
+	}
+
+	updated, err := s.Users.SetAvatar(ctx, userID, "", version)
+	if err != nil {
+		return nil, s.setAvatarError(ctx, err)
+	}
+
+	s.deleteBlobs(ctx, user.Avatar)
+
+	return updated, nil
+}
+
+// GetAvatar opens the thumbnail of the avatar at size, the largest one when size is 0
+func (s *avatarService) GetAvatar(ctx context.Context, name string, size int) (io.ReadCloser, string, error) {
+	if size == 0 {
+		size = s.Config.Sizes[len(s.Config.Sizes)-1]
+	}
+
+	if !avatarName.MatchString(name) || !slices.Contains(s.Config.Sizes, size) {
+		return nil, "", problem.ErrAvatarNotFound
+	}
+
+	body, err := s.Blobs.Get(ctx, avatarKey(name, size))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, "", problem.ErrAvatarNotFound
+	}
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to get avatar: %+v", err)
+		return nil, "", err
+	}
+
+	return body, avatarTypes[path.Ext(name)], nil
+}
+
+func (s *avatarService) setAvatarError(ctx context.Context, err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.ErrUserNotFound
+	}
+
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return problem.ErrUserModified
+	}
+
+	utils.Logger(ctx).Errorf("Failed to set avatar: %+v", err)
+
+	return err
+}
+
+// PurgeAvatar removes the thumbnails of the avatar, unlike deleteBlobs it
+// fails if one of them is left behind
+func (s *avatarService) PurgeAvatar(ctx context.Context, name string) error {
+	var errs []error
+	for _, size := range s.Config.Sizes {
+		errs = append(errs, s.Blobs.Delete(ctx, avatarKey(name, size)))
+	}
+
+	return errors.Join(errs...)
+}
+
+// deleteBlobs removes the thumbnails of the avatar, a blob left behind is only logged
+func (s *avatarService) deleteBlobs(ctx context.Context, name string) {
+	for _, size := range s.Config.Sizes {
+		if err := s.Blobs.Delete(ctx, avatarKey(name, size)); err != nil {
+			utils.Logger(ctx).Errorf("Failed to delete avatar %s: %+v", avatarKey(name, size), err)
+		}
+	}
+}
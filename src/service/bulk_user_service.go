@@ -0,0 +1,409 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/i18n"
+	"app/src/model"
+	"app/src/problem"
+	"app/src/query"
+	"app/src/repository"
+	"app/src/storage"
+	"app/src/userfile"
+	"app/src/utils"
+	"app/src/validation"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// exportPageSize is the number of users read at once by an export
+const exportPageSize = 500
+
+// importClaimTimeout is how long a run of the job may hold an import, one
+// claimed longer ago was left by a run which died and is claimed again
+const importClaimTimeout = time.Hour
+
+// BulkUserService imports the users of CSV and NDJSON files, and exports them in the same formats
+type BulkUserService interface {
+	ImportUsers(
+		ctx context.Context, admin *model.User, req *validation.ImportUsers, filename string, file io.Reader,
+	) (*model.UserImport, error)
+	GetImport(ctx context.Context, id string) (*model.UserImport, error)
+	ProcessImports(ctx context.Context, limit int) (int, error)
+	ExportUsers(ctx context.Context, params *validation.ExportUsers) (io.ReadCloser, string, error)
+}
+
+type bulkUserService struct {
+	Users        repository.UserRepository
+	Imports      repository.UserImportRepository
+	Validate     *validator.Validate
+	UserService  UserService
+	TokenService TokenService
+	EmailService EmailService
+	Blobs        storage.Store
+	Config       config.Import
+}
+
+func NewBulkUserService(
+	users repository.UserRepository, imports repository.UserImportRepository, validate *validator.Validate,
+	userService UserService, tokenService TokenService, emailService EmailService, blobs storage.Store,
+	cfg config.Import,
+) BulkUserService {
+	return &bulkUserService{
+		Users:        users,
+		Imports:      imports,
+		Validate:     validate,
+		UserService:  userService,
+		TokenService: tokenService,
+		EmailService: emailService,
+		Blobs:        blobs,
+		Config:       cfg,
+	}
+}
+
+// ImportUsers creates the users of the file at once when it has at most
+// SyncRows rows. The larger files are kept in the storage and the returned
+// import is pending until the import-users job is done with it.
+func (s *bulkUserService) ImportUsers(
+	ctx context.Context, admin *model.User, req *validation.ImportUsers, filename string, file io.Reader,
+) (*model.UserImport, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	format := req.Format
+	if format == "" {
+		format = userfile.FormatOf(filename)
+	}
+	if format == "" {
+		return nil, problem.ErrImportUnsupportedType
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, s.Config.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.Config.MaxSize {
+		return nil, problem.ErrImportTooLarge
+	}
+
+	rows, err := userfile.Read(data, format)
+	if errors.Is(err, userfile.ErrInvalidFile) {
+		return nil, problem.ErrImportInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > s.Config.MaxRows {
+		return nil, problem.ErrImportTooManyRows
+	}
+
+	userImport := &model.UserImport{
+		CreatedBy: admin.ID,
+		Format:    format,
+		Invite:    req.Invite,
+		Language:  i18n.Language(ctx),
+		Status:    model.UserImportPending,
+		Total:     len(rows),
+	}
+
+	if len(rows) > s.Config.SyncRows {
+		return userImport, s.createPending(ctx, userImport, data)
+	}
+
+	if err := s.Imports.Create(ctx, userImport); err != nil {
+		utils.Logger(ctx).Errorf("Failed to create user import: %+v", err)
+		return nil, err
+	}
+
+	s.importRows(ctx, userImport, rows)
+
+	if err := s.Imports.Update(ctx, userImport); err != nil {
+		utils.Logger(ctx).Errorf("Failed to update user import: %+v", err)
+		return nil, err
+	}
+
+	return userImport, nil
+}
+
+// createPending keeps the file in the storage for the job
+func (s *bulkUserService) createPending(ctx context.Context, userImport *model.UserImport, data []byte) error {
+	userImport.File = "imports/" + uuid.NewString() + "." + userImport.Format
+	contentType := userfile.ContentType(userImport.Format)
+
+	if err := s.Blobs.Put(ctx, userImport.File, bytes.NewReader(data), contentType); err != nil {
+		utils.Logger(ctx).Errorf("Failed to store user import: %+v", err)
+		return err
+	}
+
+	if err := s.Imports.Create(ctx, userImport); err != nil {
+		utils.Logger(ctx).Errorf("Failed to create user import: %+v", err)
+		return errors.Join(err, s.Blobs.Delete(ctx, userImport.File))
+	}
+
+	return nil
+}
+
+func (s *bulkUserService) GetImport(ctx context.Context, id string) (*model.UserImport, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, problem.ErrImportNotFound
+	}
+
+	userImport, err := s.Imports.FindByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, problem.ErrImportNotFound
+	}
+
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to get user import: %+v", err)
+	}
+
+	return userImport, err
+}
+
+// ProcessImports runs at most limit pending imports, the oldest first. Each
+// is claimed first, so the imports run by another instance are left to it.
+func (s *bulkUserService) ProcessImports(ctx context.Context, limit int) (int, error) {
+	staleBefore := time.Now().UTC().Add(-importClaimTimeout)
+
+	userImports, err := s.Imports.ListPending(ctx, staleBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	var errs []error
+	for i := range userImports {
+		claimed, err := s.Imports.Claim(ctx, &userImports[i], staleBefore)
+		if !claimed {
+			errs = append(errs, err)
+			continue
+		}
+
+		processed++
+		errs = append(errs, s.process(ctx, &userImports[i]))
+	}
+
+	return processed, errors.Join(errs...)
+}
+
+// process imports the rows of the stored file, then deletes it. A file which
+// can't be read anymore fails the import, it is never imported twice.
+func (s *bulkUserService) process(ctx context.Context, userImport *model.UserImport) error {
+	file := userImport.File
+
+	rows, err := s.readFile(ctx, file, userImport.Format)
+	if err != nil {
+		now := time.Now().UTC()
+		userImport.Status = model.UserImportFailed
+		userImport.Error = err.Error()
+		userImport.CompletedAt = &now
+	} else {
+		s.importRows(ctx, userImport, rows)
+	}
+
+	userImport.File = ""
+	if err := s.Imports.Update(ctx, userImport); err != nil {
+		return err
+	}
+
+	return s.Blobs.Delete(ctx, file)
+}
+
+func (s *bulkUserService) readFile(ctx context.Context, key, format string) ([]userfile.Row, error) {
+	body, err := s.Blobs.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return userfile.Read(data, format)
+}
+
+// importRows creates the user of each row, the rows are independent so a
+// failed row doesn't stop the others. The results are in the language of the
+// import.
+func (s *bulkUserService) importRows(ctx context.Context, userImport *model.UserImport, rows []userfile.Row) {
+	if userImport.Language != "" {
+		ctx = i18n.WithLanguage(ctx, userImport.Language)
+	}
+
+	userImport.Results = make([]model.UserImportRow, 0, len(rows))
+	for i := range rows {
+		result := s.importRow(ctx, userImport, &rows[i])
+		if result.Status == model.UserImportRowCreated {
+			userImport.Created++
+		} else {
+			userImport.Failed++
+		}
+		userImport.Results = append(userImport.Results, result)
+	}
+
+	now := time.Now().UTC()
+	userImport.Total = len(rows)
+	userImport.Status = model.UserImportCompleted
+	userImport.CompletedAt = &now
+}
+
+func (s *bulkUserService) importRow(
+	ctx context.Context, userImport *model.UserImport, row *userfile.Row,
+) model.UserImportRow {
+	result := model.UserImportRow{Line: row.Line, Status: model.UserImportRowFailed}
+	if row.Err != nil {
+		return rowFailure(ctx, result, problem.ErrImportRowInvalid)
+	}
+
+	req := row.User
+	if req.Password == "" && userImport.Invite {
+		password, err := invitationPassword()
+		if err != nil {
+			return rowFailure(ctx, result, err)
+		}
+		req.Password = password
+	}
+
+	user, err := s.UserService.CreateUser(ctx, &req)
+	if err != nil {
+		return rowFailure(ctx, result, err)
+	}
+
+	result.Status = model.UserImportRowCreated
+	result.UserID = &user.ID
+	if userImport.Invite {
+		result.Invited = s.invite(ctx, user)
+	}
+
+	return result
+}
+
+// rowFailure describes why the row failed like the problem the API would answer
+func rowFailure(ctx context.Context, result model.UserImportRow, err error) model.UserImportRow {
+	lang := i18n.Language(ctx)
+
+	var problemErr *problem.Error
+	if !errors.As(err, &problemErr) {
+		problemErr = problem.ErrValidation
+		if result.Errors = validation.FieldErrors(err, lang); len(result.Errors) == 0 {
+			utils.Logger(ctx).Errorf("Failed to import user: %+v", err)
+			problemErr = problem.ErrInternal
+		}
+	}
+
+	result.Code = problemErr.Code
+	result.Title = problemErr.Title
+	if translated, ok := i18n.Lookup(lang, "problem."+problemErr.Code); ok {
+		result.Title = translated
+	}
+
+	return result
+}
+
+// invite emails the user a link to set their password, in their language
+// when the row has one. A failed invitation leaves the user created.
+func (s *bulkUserService) invite(ctx context.Context, user *model.User) bool {
+	if user.Language != "" {
+		ctx = i18n.WithLanguage(ctx, user.Language)
+	}
+
+	token, err := s.TokenService.GenerateInvitationToken(ctx, user)
+	if err == nil {
+		err = s.EmailService.SendInvitationEmail(ctx, user.Email, token, s.Config.InvitationExp)
+	}
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to invite user: %+v", err)
+		return false
+	}
+
+	return true
+}
+
+// invitationPassword is set on the invited users whose row has no password,
+// they never know it and set theirs from the invitation
+func invitationPassword() (string, error) {
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	// The letter and the digit satisfy the password rule whatever the random part is
+	return "a1" + base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// ExportUsers streams the users matching the params, page by page, as CSV
+// by default or NDJSON. The first page is read before answering so an
+// invalid query gets its error, a later failure cuts the stream short.
+func (s *bulkUserService) ExportUsers(
+	ctx context.Context, params *validation.ExportUsers,
+) (io.ReadCloser, string, error) {
+	if err := s.Validate.Struct(params); err != nil {
+		return nil, "", err
+	}
+
+	format := params.Format
+	if format == "" {
+		format = userfile.FormatCSV
+	}
+
+	listParams := userListParams(&validation.QueryUser{
+		SortBy:        params.SortBy,
+		Order:         params.Order,
+		Role:          params.Role,
+		VerifiedEmail: params.VerifiedEmail,
+	})
+	listParams.Limit = exportPageSize
+
+	page, err := s.Users.List(ctx, params.Search, listParams)
+	if err != nil {
+		return nil, "", listUsersError(ctx, err)
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		err := s.writeUsers(ctx, writer, format, params.Search, listParams, page)
+		if err != nil && !errors.Is(err, io.ErrClosedPipe) {
+			utils.Logger(ctx).Errorf("Failed to export users: %+v", err)
+		}
+		writer.CloseWithError(err)
+	}()
+
+	return reader, userfile.ContentType(format), nil
+}
+
+// writeUsers writes the first page, then follows the cursors until the last one
+func (s *bulkUserService) writeUsers(
+	ctx context.Context, w io.Writer, format, search string, listParams query.Params, page *query.Result[model.User],
+) error {
+	writer, err := userfile.NewWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	for {
+		for i := range page.Items {
+			if err := writer.Write(&page.Items[i]); err != nil {
+				return err
+			}
+		}
+
+		if page.NextCursor == "" {
+			return writer.Flush()
+		}
+
+		listParams.Cursor = page.NextCursor
+		if page, err = s.Users.List(ctx, search, listParams); err != nil {
+			return err
+		}
+	}
+}
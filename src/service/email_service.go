@@ -2,73 +2,231 @@ package service
 
 import (
 	"app/src/config"
+	"app/src/i18n"
+	"app/src/metrics"
+	"app/src/tracing"
 	"app/src/utils"
+	"context"
+	"embed"
 	"fmt"
-
-	"github.com/sirupsen/logrus"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/gomail.v2"
 )
 
+// EmailService sends the emails in the language of the context
 type EmailService interface {
-	SendEmail(to, subject, body string) error
-	SendResetPasswordEmail(to, token string) error
-	SendVerificationEmail(to, token string) error
+	SendEmail(ctx context.Context, to, subject, body string) error
+	SendResetPasswordEmail(ctx context.Context, to, token string) error
+	SendVerificationEmail(ctx context.Context, to, token string) error
+	SendDataExportEmail(ctx context.Context, to, exportID string, expires time.Time) error
+	SendConfirmErasureEmail(ctx context.Context, to, token string, grace time.Duration) error
+	SendReauthEmail(ctx context.Context, to, token string) error
+	SendInvitationEmail(ctx context.Context, to, token string, expires time.Duration) error
 }
 
+//go:embed templates/*.tmpl
+var emailTemplates embed.FS
+
 type emailService struct {
-	Log    *logrus.Logger
-	Dialer *gomail.Dialer
+	Dialer    *gomail.Dialer
+	From      string
+	templates atomic.Pointer[template.Template]
 }
 
-func NewEmailService() EmailService {
-	return &emailService{
-		Log: utils.Log,
+// NewEmailService sends the built-in emails, or those of the templates dir,
+// which are parsed again when the config is reloaded
+func NewEmailService(cfg *config.Live) EmailService {
+	smtp := cfg.Get().SMTP
+	s := &emailService{
 		Dialer: gomail.NewDialer(
-			config.SMTPHost,
-			config.SMTPPort,
-			config.SMTPUsername,
-			config.SMTPPassword,
+			smtp.Host,
+			smtp.Port,
+			smtp.Username,
+			smtp.Password,
 		),
+		From: smtp.From,
+	}
+
+	s.loadTemplates(smtp.TemplatesDir)
+	cfg.OnReload(func(cfg *config.Config) { s.loadTemplates(cfg.SMTP.TemplatesDir) })
+
+	return s
+}
+
+// loadTemplates swaps in the templates of dir, the current ones are kept if they don't parse
+func (s *emailService) loadTemplates(dir string) {
+	templates, err := parseEmailTemplates(dir)
+	if err != nil {
+		utils.Log.Errorf("Failed to parse email templates, keeping the current ones: %v", err)
+		if s.templates.Load() != nil {
+			return
+		}
+		templates = template.Must(parseEmailTemplates(""))
+	}
+
+	s.templates.Store(templates)
+}
+
+// emailFuncs are available to the templates, t translates a message of the
+// catalogs, e.g. {{t .Lang "email.greeting"}}
+var emailFuncs = template.FuncMap{"t": i18n.T}
+
+// parseEmailTemplates parses the built-in templates, then the *.tmpl files of dir
+// replacing those with the same name
+func parseEmailTemplates(dir string) (*template.Template, error) {
+	templates, err := template.New("").Funcs(emailFuncs).ParseFS(emailTemplates, "templates/*.tmpl")
+	if err != nil || dir == "" {
+		return templates, err
+	}
+
+	overrides, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil || len(overrides) == 0 {
+		return templates, err
 	}
+
+	return templates.ParseFiles(overrides...)
 }
 
-func (s *emailService) SendEmail(to, subject, body string) error {
+// render executes the template of an email and its subject, name-subject
+func (s *emailService) render(name string, data any) (subject, body string, err error) {
+	templates := s.templates.Load()
+
+	var buf strings.Builder
+	if err := templates.ExecuteTemplate(&buf, name+"-subject", data); err != nil {
+		return "", "", err
+	}
+	subject = buf.String()
+
+	buf.Reset()
+	if err := templates.ExecuteTemplate(&buf, name+".tmpl", data); err != nil {
+		return "", "", err
+	}
+
+	return subject, buf.String(), nil
+}
+
+func (s *emailService) SendEmail(ctx context.Context, to, subject, body string) error {
 	mailer := gomail.NewMessage()
-	mailer.SetHeader("From", config.EmailFrom)
+	mailer.SetHeader("From", s.From)
 	mailer.SetHeader("To", to)
 	mailer.SetHeader("Subject", subject)
 	mailer.SetBody("text/plain", body)
 
-	if err := s.Dialer.DialAndSend(mailer); err != nil {
-		s.Log.Errorf("Failed to send email: %v", err)
+	ctx, span := tracing.Tracer().Start(ctx, "smtp.send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.ServerAddress(s.Dialer.Host), semconv.ServerPort(s.Dialer.Port)),
+	)
+	defer span.End()
+
+	err := s.Dialer.DialAndSend(mailer)
+	metrics.ObserveEmail(err)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		utils.Logger(ctx).WithField("to", to).Errorf("Failed to send email: %v", err)
 		return err
 	}
 
 	return nil
 }
 
-func (s *emailService) SendResetPasswordEmail(to, token string) error {
-	subject := "Reset password"
-
+func (s *emailService) SendResetPasswordEmail(ctx context.Context, to, token string) error {
 	// TODO: replace this url with the link to the reset password page of your front-end app
 	resetPasswordURL := fmt.Sprintf("http://link-to-app/reset-password?token=%s", token)
-	body := fmt.Sprintf(`Dear user,
 
-To reset your password, click on this link: %s
+	subject, body, err := s.render("reset-password", map[string]string{
+		"URL":  resetPasswordURL,
+		"Lang": i18n.Language(ctx),
+	})
+	if err != nil {
+		return err
+	}
 
-If you did not request any password resets, then ignore this email.`, resetPasswordURL)
-	return s.SendEmail(to, subject, body)
+	return s.SendEmail(ctx, to, subject, body)
 }
 
-func (s *emailService) SendVerificationEmail(to, token string) error {
-	subject := "Email Verification"
-
+func (s *emailService) SendVerificationEmail(ctx context.Context, to, token string) error {
 	// TODO: replace this url with the link to the email verification page of your front-end app
 	verificationEmailURL := fmt.Sprintf("http://link-to-app/verify-email?token=%s", token)
-	body := fmt.Sprintf(`Dear user,
 
-To verify your email, click on this link: %s
+	subject, body, err := s.render("verify-email", map[string]string{
+		"URL":  verificationEmailURL,
+		"Lang": i18n.Language(ctx),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.SendEmail(ctx, to, subject, body)
+}
+
+func (s *emailService) SendDataExportEmail(ctx context.Context, to, exportID string, expires time.Time) error {
+	// TODO: replace this url with the link to the page of your front-end app downloading the export
+	dataExportURL := fmt.Sprintf("http://link-to-app/account/exports/%s", exportID)
+
+	subject, body, err := s.render("data-export", map[string]string{
+		"URL":     dataExportURL,
+		"Expires": expires.UTC().Format(time.DateOnly),
+		"Lang":    i18n.Language(ctx),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.SendEmail(ctx, to, subject, body)
+}
+
+func (s *emailService) SendConfirmErasureEmail(ctx context.Context, to, token string, grace time.Duration) error {
+	// TODO: replace this url with the link to the erasure confirmation page of your front-end app
+	confirmErasureURL := fmt.Sprintf("http://link-to-app/confirm-erasure?token=%s", token)
+
+	subject, body, err := s.render("confirm-erasure", map[string]string{
+		"URL":  confirmErasureURL,
+		"Days": fmt.Sprint(int(grace.Hours() / 24)),
+		"Lang": i18n.Language(ctx),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.SendEmail(ctx, to, subject, body)
+}
+
+func (s *emailService) SendReauthEmail(ctx context.Context, to, token string) error {
+	// TODO: replace this url with the link to the page of your front-end app sending the reauth_token
+	reauthURL := fmt.Sprintf("http://link-to-app/reauth?token=%s", token)
+
+	subject, body, err := s.render("reauth", map[string]string{
+		"URL":  reauthURL,
+		"Lang": i18n.Language(ctx),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.SendEmail(ctx, to, subject, body)
+}
+
+func (s *emailService) SendInvitationEmail(ctx context.Context, to, token string, expires time.Duration) error {
+	// TODO: replace this url with the link to the reset password page of your front-end app
+	invitationURL := fmt.Sprintf("http://link-to-app/reset-password?token=%s", token)
+
+	subject, body, err := s.render("invitation", map[string]string{
+		"URL":  invitationURL,
+		"Days": fmt.Sprint(int(expires.Hours() / 24)),
+		"Lang": i18n.Language(ctx),
+	})
+	if err != nil {
+		return err
+	}
 
-If you did not create an account, then ignore this email.`, verificationEmailURL)
-	return s.SendEmail(to, subject, body)
+	return s.SendEmail(ctx, to, subject, body)
 }
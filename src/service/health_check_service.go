@@ -1,61 +1,90 @@
 package service
 
 import (
+	"app/src/config"
+	"app/src/database"
+	"app/src/health"
+	"app/src/ratelimit"
+	"app/src/repository"
 	"app/src/utils"
-	"errors"
-	"runtime"
+	"context"
 
-	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 type HealthCheckService interface {
-	GormCheck() error
-	MemoryHeapCheck() error
+	Liveness(ctx context.Context) health.Report
+	Readiness(ctx context.Context) health.Report
 }
 
 type healthCheckService struct {
-	Log *logrus.Logger
-	DB  *gorm.DB
+	Registry *health.Registry
 }
 
-func NewHealthCheckService(db *gorm.DB) HealthCheckService {
-	return &healthCheckService{
-		Log: utils.Log,
-		DB:  db,
+// NewHealthCheckService registers the checks of the app's dependencies.
+// Only the heap check decides liveness, a dependency being down doesn't
+// mean the process must be restarted.
+func NewHealthCheckService(
+	db *gorm.DB, jobs repository.JobRepository, rateLimits ratelimit.Store, cfg config.Health, smtp config.SMTP,
+) HealthCheckService {
+	registry := health.NewRegistry(cfg.CheckTimeout)
+
+	if sqlDB, err := db.DB(); err == nil {
+		registry.Register(health.Check{Checker: health.Database("Database", sqlDB), CacheFor: cfg.CacheFor})
+	} else {
+		utils.Log.Errorf("failed to access the database connection pool: %v", err)
 	}
-}
 
-func (s *healthCheckService) GormCheck() error {
-	sqlDB, errDB := s.DB.DB()
-	if errDB != nil {
-		s.Log.Errorf("failed to access the database connection pool: %v", errDB)
-		return errDB
+	for _, replica := range database.Replicas(db) {
+		registry.Register(health.Check{Checker: health.Database("Replica "+replica.Host, replica.DB), CacheFor: cfg.CacheFor})
 	}
 
-	if err := sqlDB.Ping(); err != nil {
-		s.Log.Errorf("failed to ping the database: %v", err)
-		return err
+	registry.Register(health.Check{
+		Checker: health.Memory(cfg.HeapThreshold),
+		Live:    true,
+	})
+	registry.Register(health.Check{
+		Checker:  health.Disk(cfg.DiskPath, cfg.DiskMinFree),
+		CacheFor: cfg.CacheFor,
+	})
+	registry.Register(health.Check{
+		Checker:  health.SMTP(smtp.Host, smtp.Port),
+		Optional: true,
+		CacheFor: cfg.CacheFor,
+	})
+	// Requests aren't limited while the rate limit store is down, but they are served
+	if redis, ok := rateLimits.(*ratelimit.RedisStore); ok {
+		registry.Register(health.Check{
+			Checker:  health.NewChecker("Redis", redis.Ping),
+			Optional: true,
+			CacheFor: cfg.CacheFor,
+		})
 	}
+	registry.Register(health.Check{
+		Checker:  health.Jobs(jobs.CountStuckRuns, cfg.JobStuckAfter),
+		Optional: true,
+		CacheFor: cfg.CacheFor,
+	})
 
-	return nil
+	return &healthCheckService{
+		Registry: registry,
+	}
 }
 
-// MemoryHeapCheck checks if heap memory usage exceeds a threshold
-func (s *healthCheckService) MemoryHeapCheck() error {
-	var memStats runtime.MemStats
-	runtime.ReadMemStats(&memStats) // Collect memory statistics
-
-	heapAlloc := memStats.HeapAlloc            // Heap memory currently allocated
-	heapThreshold := uint64(300 * 1024 * 1024) // Example threshold: 300 MB
+func (s *healthCheckService) Liveness(ctx context.Context) health.Report {
+	return s.logFailures(ctx, s.Registry.Liveness(ctx))
+}
 
-	s.Log.Infof("Heap Memory Allocation: %v bytes", heapAlloc)
+func (s *healthCheckService) Readiness(ctx context.Context) health.Report {
+	return s.logFailures(ctx, s.Registry.Readiness(ctx))
+}
 
-	// If the heap allocation exceeds the threshold, return an error
-	if heapAlloc > heapThreshold {
-		s.Log.Errorf("Heap memory usage exceeds threshold: %v bytes", heapAlloc)
-		return errors.New("heap memory usage too high")
+func (s *healthCheckService) logFailures(ctx context.Context, report health.Report) health.Report {
+	for _, result := range report.Results {
+		if !result.Up {
+			utils.Logger(ctx).WithField("check", result.Name).Warnf("Health check failed: %s", result.Error)
+		}
 	}
 
-	return nil
+	return report
 }
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/problem"
+	"app/src/repository"
+	"app/src/utils"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// idempotencyLockTimeout bounds how long a request in progress holds its key,
+// so a key stays usable when the process handling it dies
+const idempotencyLockTimeout = time.Minute
+
+type IdempotencyService interface {
+	Begin(ctx context.Context, scope, key, fingerprint string) (*model.IdempotentResponse, error)
+	Complete(ctx context.Context, scope, key string, res *model.IdempotentResponse) error
+	Release(ctx context.Context, scope, key string) error
+	DeleteExpiredKeys(ctx context.Context, now time.Time) (int64, error)
+}
+
+type idempotencyService struct {
+	Keys repository.IdempotencyRepository
+	TTL  time.Duration
+}
+
+func NewIdempotencyService(keys repository.IdempotencyRepository, cfg config.Idempotency) IdempotencyService {
+	return &idempotencyService{
+		Keys: keys,
+		TTL:  cfg.TTL,
+	}
+}
+
+// Begin locks the key of a scope for a new request, or returns the response
+// of the first request sent with it. The fingerprint tells the requests apart.
+func (s *idempotencyService) Begin(
+	ctx context.Context, scope, key, fingerprint string,
+) (*model.IdempotentResponse, error) {
+	id := idempotencyKeyID(scope, key)
+
+	locked, err := s.Keys.Lock(ctx, &model.IdempotencyKey{
+		ID:          id,
+		Fingerprint: fingerprint,
+		ExpiresAt:   time.Now().UTC().Add(idempotencyLockTimeout),
+	})
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to lock idempotency key: %+v", err)
+		return nil, err
+	}
+
+	if locked {
+		return nil, nil
+	}
+
+	stored, err := s.Keys.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		// Released by the first request since, it can be retried
+		return nil, problem.ErrIdempotencyKeyInProgress
+	}
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to get idempotency key: %+v", err)
+		return nil, err
+	}
+
+	if stored.Fingerprint != fingerprint {
+		return nil, problem.ErrIdempotencyKeyReused
+	}
+
+	if stored.Status == 0 {
+		return nil, problem.ErrIdempotencyKeyInProgress
+	}
+
+	res := new(model.IdempotentResponse)
+	if err := openIdempotentResponse(scope, key, stored.Response, res); err != nil {
+		utils.Logger(ctx).Errorf("Failed to read idempotent response: %+v", err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Complete keeps the response of the request holding the key until the key expires
+func (s *idempotencyService) Complete(ctx context.Context, scope, key string, res *model.IdempotentResponse) error {
+	sealed, err := sealIdempotentResponse(scope, key, res)
+	if err != nil {
+		return err
+	}
+
+	err = s.Keys.Save(ctx, &model.IdempotencyKey{
+		ID:        idempotencyKeyID(scope, key),
+		Status:    res.Status,
+		Response:  sealed,
+		ExpiresAt: time.Now().UTC().Add(s.TTL),
+	})
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to save idempotent response: %+v", err)
+	}
+
+	return err
+}
+
+// Release frees the key of a request that failed, so it can be retried
+func (s *idempotencyService) Release(ctx context.Context, scope, key string) error {
+	err := s.Keys.Delete(ctx, idempotencyKeyID(scope, key))
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to release idempotency key: %+v", err)
+	}
+
+	return err
+}
+
+func (s *idempotencyService) DeleteExpiredKeys(ctx context.Context, now time.Time) (int64, error) {
+	deleted, err := s.Keys.DeleteExpired(ctx, now)
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to delete expired idempotency keys: %+v", err)
+	}
+
+	return deleted, err
+}
+
+func idempotencyKeyID(scope, key string) string {
+	return utils.HashToken(scope + ":" + key)
+}
+
+// idempotencyCipher encrypts the responses with a key derived from the
+// idempotency key, which only the client knows
+func idempotencyCipher(scope, key string) (cipher.AEAD, error) {
+	secret := sha256.Sum256([]byte("idempotent-response:" + scope + ":" + key))
+
+	block, err := aes.NewCipher(secret[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func sealIdempotentResponse(scope, key string, res *model.IdempotentResponse) ([]byte, error) {
+	plaintext, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := idempotencyCipher(scope, key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func openIdempotentResponse(scope, key string, sealed []byte, res *model.IdempotentResponse) error {
+	aead, err := idempotencyCipher(scope, key)
+	if err != nil {
+		return err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return errors.New("idempotent response is too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(plaintext, res)
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"app/src/model"
+	"app/src/problem"
+	"app/src/query"
+	"app/src/repository"
+	"app/src/utils"
+	"app/src/validation"
+	"context"
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type JobService interface {
+	GetJobRuns(ctx context.Context, params *validation.QueryJobRun) (*query.Result[model.JobRun], error)
+}
+
+type jobService struct {
+	Jobs     repository.JobRepository
+	Validate *validator.Validate
+}
+
+func NewJobService(jobs repository.JobRepository, validate *validator.Validate) JobService {
+	return &jobService{
+		Jobs:     jobs,
+		Validate: validate,
+	}
+}
+
+func (s *jobService) GetJobRuns(
+	ctx context.Context, params *validation.QueryJobRun,
+) (*query.Result[model.JobRun], error) {
+	if err := s.Validate.Struct(params); err != nil {
+		return nil, err
+	}
+
+	filters := make(map[string]interface{})
+	if params.Name != "" {
+		filters["name"] = params.Name
+	}
+	if params.Status != "" {
+		filters["status"] = params.Status
+	}
+
+	result, err := s.Jobs.ListRuns(ctx, query.Params{
+		Cursor:  params.Cursor,
+		Page:    params.Page,
+		Limit:   params.Limit,
+		Filters: filters,
+	})
+
+	if errors.Is(err, query.ErrInvalidCursor) {
+		return nil, problem.ErrInvalidCursor
+	}
+
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to get job runs: %+v", err)
+	}
+
+	return result, err
+}
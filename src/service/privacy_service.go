@@ -0,0 +1,635 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/i18n"
+	"app/src/model"
+	"app/src/problem"
+	"app/src/repository"
+	"app/src/storage"
+	"app/src/utils"
+	"app/src/validation"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// PrivacyService exports the data of the users and erases them, on their
+// request. The requests are run later by the process-data-requests job.
+type PrivacyService interface {
+	RequestExport(ctx context.Context, user *model.User) (*model.DataRequest, error)
+	GetDataRequests(ctx context.Context, user *model.User) ([]model.DataRequest, error)
+	GetExport(ctx context.Context, user *model.User, id string) (io.ReadCloser, error)
+	RequestErasure(ctx context.Context, user *model.User, req *validation.RequestErasure) (*model.DataRequest, error)
+	ConfirmErasure(ctx context.Context, user *model.User, query *validation.Token) (*model.DataRequest, error)
+	CancelErasure(ctx context.Context, user *model.User) (*model.DataRequest, error)
+	ProcessExports(ctx context.Context, limit int) (int, error)
+	ProcessErasures(ctx context.Context, limit int) (int, error)
+	PurgeExpiredExports(ctx context.Context, limit int) (int, error)
+}
+
+type privacyService struct {
+	Users         repository.UserRepository
+	Tokens        repository.TokenRepository
+	DataRequests  repository.DataRequestRepository
+	UnitOfWork    repository.UnitOfWork
+	Validate      *validator.Validate
+	TokenService  TokenService
+	EmailService  EmailService
+	AvatarService AvatarService
+	Blobs         storage.Store
+	Config        config.Privacy
+}
+
+func NewPrivacyService(
+	users repository.UserRepository, tokens repository.TokenRepository, dataRequests repository.DataRequestRepository,
+	unitOfWork repository.UnitOfWork, validate *validator.Validate, tokenService TokenService,
+	emailService EmailService, avatarService AvatarService, blobs storage.Store, cfg config.Privacy,
+) PrivacyService {
+	return &privacyService{
+		Users:         users,
+		Tokens:        tokens,
+		DataRequests:  dataRequests,
+		UnitOfWork:    unitOfWork,
+		Validate:      validate,
+		TokenService:  tokenService,
+		EmailService:  emailService,
+		AvatarService: avatarService,
+		Blobs:         blobs,
+		Config:        cfg,
+	}
+}
+
+// exportFile is the export.json file of the archives, it holds everything the
+// app stores about the user. There is no audit event store, and no linked
+// identity: the Google login finds the account by its email and keeps nothing
+// from Google, so these have no section.
+type exportFile struct {
+	ExportedAt   time.Time           `json:"exported_at"`
+	User         exportedUser        `json:"user"`
+	Tokens       []exportedToken     `json:"tokens"`
+	DataRequests []model.DataRequest `json:"data_requests"`
+}
+
+type exportedUser struct {
+	ID            uuid.UUID `json:"id"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	Role          string    `json:"role"`
+	VerifiedEmail bool      `json:"verified_email"`
+	Language      string    `json:"language,omitempty"`
+	Avatar        string    `json:"avatar,omitempty"`
+	HasPassword   bool      `json:"has_password"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// exportedToken leaves out the hash of the token
+type exportedToken struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// dataRequestClaimTimeout is how long a run of the job may hold a request, one
+// claimed longer ago was left by a run which died and is claimed again
+const dataRequestClaimTimeout = time.Hour
+
+var errErasureNotClaimed = errors.New("the erasure is no longer claimed by this run")
+
+func exportKey(id uuid.UUID) string {
+	return "exports/" + id.String() + ".zip"
+}
+
+// RequestExport schedules an export of the data of the user, or returns the one not built yet
+func (s *privacyService) RequestExport(ctx context.Context, user *model.User) (*model.DataRequest, error) {
+	open, err := s.DataRequests.FindOpen(ctx, model.DataRequestExport, user.ID.String())
+	if err == nil {
+		return open, nil
+	}
+
+	if !errors.Is(err, repository.ErrNotFound) {
+		utils.Logger(ctx).Errorf("Failed to find export: %+v", err)
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	request := &model.DataRequest{
+		UserID:      user.ID,
+		Type:        model.DataRequestExport,
+		Status:      model.DataRequestPending,
+		ScheduledAt: &now,
+	}
+
+	if err := s.DataRequests.Create(ctx, request); err != nil {
+		utils.Logger(ctx).Errorf("Failed to request export: %+v", err)
+		return nil, err
+	}
+
+	return request, nil
+}
+
+func (s *privacyService) GetDataRequests(ctx context.Context, user *model.User) ([]model.DataRequest, error) {
+	requests, err := s.DataRequests.ListByUser(ctx, user.ID.String())
+
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to get data requests: %+v", err)
+	}
+
+	return requests, err
+}
+
+// GetExport opens the archive of a built export of the user, the caller closes it
+func (s *privacyService) GetExport(ctx context.Context, user *model.User, id string) (io.ReadCloser, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, problem.ErrExportNotFound
+	}
+
+	request, err := s.DataRequests.FindByID(ctx, id, user.ID.String())
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && request.Type != model.DataRequestExport) {
+		return nil, problem.ErrExportNotFound
+	}
+
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to get export: %+v", err)
+		return nil, err
+	}
+
+	if request.Status == model.DataRequestPending || request.Status == model.DataRequestProcessing {
+		return nil, problem.ErrExportNotReady
+	}
+
+	// The archive of an expired export may not be deleted yet
+	expired := request.ExpiresAt == nil || request.ExpiresAt.Before(time.Now())
+	if request.Status != model.DataRequestCompleted || expired {
+		return nil, problem.ErrExportNotFound
+	}
+
+	body, err := s.Blobs.Get(ctx, request.Archive)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, problem.ErrExportNotFound
+	}
+
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to get export archive: %+v", err)
+	}
+
+	return body, err
+}
+
+// RequestErasure emails a link confirming the erasure of the account, the
+// erasure is scheduled once confirmed
+func (s *privacyService) RequestErasure(
+	ctx context.Context, user *model.User, req *validation.RequestErasure,
+) (*model.DataRequest, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(req.Confirm), user.Email) {
+		return nil, problem.ErrConfirmationMismatch
+	}
+
+	reauth, err := checkReauth(ctx, s.TokenService, user, req.CurrentPassword, req.ReauthToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := useReauth(ctx, s.TokenService, reauth); err != nil {
+		return nil, err
+	}
+
+	request, err := s.DataRequests.FindOpen(ctx, model.DataRequestErasure, user.ID.String())
+	if err == nil && request.Status != model.DataRequestUnconfirmed {
+		return nil, problem.ErrErasureScheduled
+	}
+
+	// A request not confirmed yet gets a new link
+	if errors.Is(err, repository.ErrNotFound) {
+		request = &model.DataRequest{
+			UserID: user.ID,
+			Type:   model.DataRequestErasure,
+			Status: model.DataRequestUnconfirmed,
+		}
+		err = s.DataRequests.Create(ctx, request)
+	}
+
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to request erasure: %+v", err)
+		return nil, err
+	}
+
+	token, err := s.TokenService.GenerateConfirmErasureToken(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.EmailService.SendConfirmErasureEmail(ctx, user.Email, token, s.Config.ErasureGrace); err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// ConfirmErasure schedules the erasure requested by the user after the grace period
+func (s *privacyService) ConfirmErasure(
+	ctx context.Context, user *model.User, query *validation.Token,
+) (*model.DataRequest, error) {
+	if err := s.Validate.Struct(query); err != nil {
+		return nil, err
+	}
+
+	token, err := s.TokenService.VerifyToken(ctx, query.Token, config.TokenTypeConfirmErasure)
+	if err != nil || token.UserID != user.ID {
+		return nil, problem.ErrInvalidToken
+	}
+
+	request, err := s.findOpenErasure(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Status != model.DataRequestUnconfirmed {
+		return nil, problem.ErrErasureScheduled
+	}
+
+	scheduledAt := time.Now().UTC().Add(s.Config.ErasureGrace)
+	request.Status = model.DataRequestPending
+	request.ScheduledAt = &scheduledAt
+
+	err = s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := s.TokenService.DeleteToken(ctx, config.TokenTypeConfirmErasure, user.ID.String()); err != nil {
+			return err
+		}
+
+		confirmed, err := s.DataRequests.Transition(ctx, request, model.DataRequestUnconfirmed)
+		if err == nil && !confirmed {
+			// Cancelled or confirmed by another request in the meantime
+			return problem.ErrErasureNotFound
+		}
+
+		return err
+	})
+
+	if errors.Is(err, problem.ErrErasureNotFound) {
+		return nil, err
+	}
+
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to confirm erasure: %+v", err)
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// CancelErasure cancels the erasure of the account, confirmed or not, until it runs
+func (s *privacyService) CancelErasure(ctx context.Context, user *model.User) (*model.DataRequest, error) {
+	request, err := s.findOpenErasure(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Status == model.DataRequestProcessing {
+		return nil, problem.ErrErasureRunning
+	}
+
+	request.Status = model.DataRequestCancelled
+
+	err = s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := s.TokenService.DeleteToken(ctx, config.TokenTypeConfirmErasure, user.ID.String()); err != nil {
+			return err
+		}
+
+		// The job may have claimed it since it was read
+		cancelled, err := s.DataRequests.Transition(ctx, request,
+			model.DataRequestUnconfirmed, model.DataRequestPending)
+		if err == nil && !cancelled {
+			return problem.ErrErasureRunning
+		}
+
+		return err
+	})
+
+	if errors.Is(err, problem.ErrErasureRunning) {
+		return nil, err
+	}
+
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to cancel erasure: %+v", err)
+		return nil, err
+	}
+
+	return request, nil
+}
+
+func (s *privacyService) findOpenErasure(ctx context.Context, user *model.User) (*model.DataRequest, error) {
+	request, err := s.DataRequests.FindOpen(ctx, model.DataRequestErasure, user.ID.String())
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, problem.ErrErasureNotFound
+	}
+
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to find erasure: %+v", err)
+	}
+
+	return request, err
+}
+
+// ProcessExports builds at most limit requested exports and emails the users a link to them
+func (s *privacyService) ProcessExports(ctx context.Context, limit int) (int, error) {
+	requests, err := s.claimDue(ctx, model.DataRequestExport, limit)
+
+	errs := []error{err}
+	for _, request := range requests {
+		errs = append(errs, s.export(ctx, request))
+	}
+
+	return len(requests), errors.Join(errs...)
+}
+
+// claimDue returns the due requests of the type this run claimed, the others
+// are run by another instance or were cancelled since they were listed
+func (s *privacyService) claimDue(ctx context.Context, requestType string, limit int) ([]*model.DataRequest, error) {
+	now := time.Now().UTC()
+	staleBefore := now.Add(-dataRequestClaimTimeout)
+
+	due, err := s.DataRequests.ListDue(ctx, requestType, now, staleBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var claimed []*model.DataRequest
+	var errs []error
+	for i := range due {
+		ok, err := s.DataRequests.Claim(ctx, &due[i], staleBefore)
+		if ok {
+			claimed = append(claimed, &due[i])
+		}
+		errs = append(errs, err)
+	}
+
+	return claimed, errors.Join(errs...)
+}
+
+// export builds the archive of the request, a request which can't be built fails
+// and the user requests a new one
+func (s *privacyService) export(ctx context.Context, request *model.DataRequest) error {
+	user, err := s.Users.FindByID(ctx, request.UserID.String())
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	if user == nil {
+		err = errors.New("user not found")
+	} else {
+		var archive []byte
+		if archive, err = s.buildArchive(ctx, user); err == nil {
+			err = s.Blobs.Put(ctx, exportKey(request.ID), bytes.NewReader(archive), "application/zip")
+		}
+	}
+
+	now := time.Now().UTC()
+	if err != nil {
+		request.Status = model.DataRequestFailed
+		request.Error = err.Error()
+		return errors.Join(err, s.DataRequests.Update(ctx, request))
+	}
+
+	expiresAt := now.Add(s.Config.ExportRetention)
+	request.Status = model.DataRequestCompleted
+	request.Archive = exportKey(request.ID)
+	request.CompletedAt = &now
+	request.ExpiresAt = &expiresAt
+
+	if err := s.DataRequests.Update(ctx, request); err != nil {
+		return err
+	}
+
+	// The export stays listed in the data requests of the user if the email is lost
+	if user.Language != "" {
+		ctx = i18n.WithLanguage(ctx, user.Language)
+	}
+	if err := s.EmailService.SendDataExportEmail(ctx, user.Email, request.ID.String(), expiresAt); err != nil {
+		utils.Logger(ctx).Errorf("Failed to send the data export email: %+v", err)
+	}
+
+	return nil
+}
+
+// buildArchive zips the export.json file of the user, with its avatar
+func (s *privacyService) buildArchive(ctx context.Context, user *model.User) ([]byte, error) {
+	tokens, err := s.Tokens.ListByUser(ctx, user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := s.DataRequests.ListByUser(ctx, user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	file := exportFile{
+		ExportedAt: time.Now().UTC(),
+		User: exportedUser{
+			ID:            user.ID,
+			Name:          user.Name,
+			Email:         user.Email,
+			Role:          user.Role,
+			VerifiedEmail: user.VerifiedEmail,
+			Language:      user.Language,
+			Avatar:        user.Avatar,
+			HasPassword:   user.Password != "",
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+		},
+		Tokens:       make([]exportedToken, 0, len(tokens)),
+		DataRequests: requests,
+	}
+	for _, token := range tokens {
+		file.Tokens = append(file.Tokens, exportedToken{
+			ID:        token.ID,
+			Type:      token.Type,
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.Expires,
+		})
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	entry, err := archive.Create("export.json")
+	if err != nil {
+		return nil, err
+	}
+
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(file); err != nil {
+		return nil, err
+	}
+
+	if user.Avatar != "" {
+		if err := s.addAvatar(ctx, archive, user.Avatar); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// addAvatar adds the largest thumbnail of the avatar to the archive
+func (s *privacyService) addAvatar(ctx context.Context, archive *zip.Writer, name string) error {
+	body, _, err := s.AvatarService.GetAvatar(ctx, name, 0)
+	if errors.Is(err, problem.ErrAvatarNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	entry, err := archive.Create("avatar" + path.Ext(name))
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(entry, body)
+
+	return err
+}
+
+// ProcessErasures runs at most limit erasures whose grace period is over. An
+// erasure which fails is tried again by the next run.
+func (s *privacyService) ProcessErasures(ctx context.Context, limit int) (int, error) {
+	requests, err := s.claimDue(ctx, model.DataRequestErasure, limit)
+
+	errs := []error{err}
+	for _, request := range requests {
+		err := s.erase(ctx, request)
+		errs = append(errs, err)
+
+		// Released for the next run, unless another run holds it now
+		if err != nil && !errors.Is(err, errErasureNotClaimed) {
+			request.Status = model.DataRequestPending
+			request.CompletedAt = nil
+			_, err = s.DataRequests.Transition(ctx, request, model.DataRequestProcessing)
+			errs = append(errs, err)
+		}
+	}
+
+	return len(requests), errors.Join(errs...)
+}
+
+// erase deletes the avatar and the exports of the user, then the user with its
+// tokens. Only the data requests are kept, as the record of the erasure.
+func (s *privacyService) erase(ctx context.Context, request *model.DataRequest) error {
+	userID := request.UserID.String()
+
+	// A deleted user is erased too, it may not be purged yet
+	user, err := s.Users.FindWithDeleted(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	if user != nil && user.Avatar != "" {
+		if err := s.AvatarService.PurgeAvatar(ctx, user.Avatar); err != nil {
+			return err
+		}
+	}
+
+	requests, err := s.DataRequests.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range requests {
+		if other.Archive != "" {
+			if err := s.Blobs.Delete(ctx, other.Archive); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		// Checked again first, nothing is erased if the request was taken from this run
+		now := time.Now().UTC()
+		request.Status = model.DataRequestCompleted
+		request.CompletedAt = &now
+
+		completed, err := s.DataRequests.Transition(ctx, request, model.DataRequestProcessing)
+		if err != nil {
+			return err
+		}
+		if !completed {
+			return errErasureNotClaimed
+		}
+
+		for i := range requests {
+			other := &requests[i]
+			switch {
+			case other.ID == request.ID:
+				continue
+			case other.Archive != "":
+				other.Status = model.DataRequestExpired
+				other.Archive = ""
+			case other.Status == model.DataRequestPending || other.Status == model.DataRequestUnconfirmed ||
+				other.Status == model.DataRequestProcessing:
+				other.Status = model.DataRequestCancelled
+			default:
+				continue
+			}
+
+			if err := s.DataRequests.Update(ctx, other); err != nil {
+				return err
+			}
+		}
+
+		if err := s.Tokens.DeleteByUser(ctx, userID); err != nil {
+			return err
+		}
+
+		if err := s.Users.Purge(ctx, userID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// PurgeExpiredExports deletes the archives of at most limit expired exports
+func (s *privacyService) PurgeExpiredExports(ctx context.Context, limit int) (int, error) {
+	requests, err := s.DataRequests.ListExpired(ctx, time.Now().UTC(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	for i := range requests {
+		request := &requests[i]
+		if err := s.Blobs.Delete(ctx, request.Archive); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		request.Status = model.DataRequestExpired
+		request.Archive = ""
+		errs = append(errs, s.DataRequests.Update(ctx, request))
+	}
+
+	return len(requests), errors.Join(errs...)
+}
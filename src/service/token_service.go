@@ -2,44 +2,56 @@ package service
 
 import (
 	"app/src/config"
+	"app/src/metrics"
 	"app/src/model"
+	"app/src/repository"
 	res "app/src/response"
 	"app/src/utils"
 	"app/src/validation"
+	"context"
 	"time"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"github.com/sirupsen/logrus"
-	"gorm.io/gorm"
 )
 
 type TokenService interface {
 	GenerateToken(userID string, expires time.Time, tokenType string) (string, error)
-	SaveToken(c *fiber.Ctx, token, userID, tokenType string, expires time.Time) error
-	DeleteToken(c *fiber.Ctx, tokenType string, userID string) error
-	DeleteAllToken(c *fiber.Ctx, userID string) error
-	GetTokenByUserID(c *fiber.Ctx, tokenStr string) (*model.Token, error)
-	GenerateAuthTokens(c *fiber.Ctx, user *model.User) (*res.Tokens, error)
-	GenerateResetPasswordToken(c *fiber.Ctx, req *validation.ForgotPassword) (string, error)
-	GenerateVerifyEmailToken(c *fiber.Ctx, user *model.User) (*string, error)
+	ParseToken(tokenStr, tokenType string) (string, error)
+	SaveToken(ctx context.Context, token, userID, tokenType string, expires time.Time) error
+	DeleteToken(ctx context.Context, tokenType string, userID string) error
+	RevokeToken(ctx context.Context, tokenDoc *model.Token) error
+	DeleteAllToken(ctx context.Context, userID string) error
+	GetTokenByUserID(ctx context.Context, tokenStr string) (*model.Token, error)
+	VerifyToken(ctx context.Context, tokenStr, tokenType string) (*model.Token, error)
+	DeleteExpiredTokens(ctx context.Context, expiredBefore time.Time, limit int) (int64, error)
+	GenerateAuthTokens(ctx context.Context, user *model.User) (*res.Tokens, error)
+	GenerateResetPasswordToken(ctx context.Context, req *validation.ForgotPassword) (string, error)
+	GenerateVerifyEmailToken(ctx context.Context, user *model.User) (*string, error)
+	GenerateConfirmErasureToken(ctx context.Context, user *model.User) (string, error)
+	GenerateReauthToken(ctx context.Context, user *model.User) (string, error)
+	GenerateInvitationToken(ctx context.Context, user *model.User) (string, error)
 }
 
 type tokenService struct {
-	Log         *logrus.Logger
-	DB          *gorm.DB
+	Tokens      repository.TokenRepository
+	UnitOfWork  repository.UnitOfWork
 	Validate    *validator.Validate
 	UserService UserService
+	Config      *config.Live
 }
 
-func NewTokenService(db *gorm.DB, validate *validator.Validate, userService UserService) TokenService {
+func NewTokenService(
+	tokens repository.TokenRepository, unitOfWork repository.UnitOfWork,
+	validate *validator.Validate, userService UserService, cfg *config.Live,
+) TokenService {
 	return &tokenService{
-		Log:         utils.Log,
-		DB:          db,
+		Tokens:      tokens,
+		UnitOfWork:  unitOfWork,
 		Validate:    validate,
 		UserService: userService,
+		Config:      cfg,
 	}
 }
 
@@ -49,95 +61,137 @@ func (s *tokenService) GenerateToken(userID string, expires time.Time, tokenType
 		"iat":  time.Now().Unix(),
 		"exp":  expires.Unix(),
 		"type": tokenType,
+		"jti":  uuid.NewString(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	return token.SignedString([]byte(config.JWTSecret))
+	signed, err := token.SignedString([]byte(s.Config.Get().JWT.Secret))
+	if err == nil {
+		metrics.ObserveTokenIssued(tokenType)
+	}
+
+	return signed, err
 }
 
-func (s *tokenService) SaveToken(c *fiber.Ctx, token, userID, tokenType string, expires time.Time) error {
-	if err := s.DeleteToken(c, tokenType, userID); err != nil {
+// SaveToken replaces the token of the given type, the user never ends up without one.
+// The refresh tokens are kept, one per session.
+func (s *tokenService) SaveToken(ctx context.Context, token, userID, tokenType string, expires time.Time) error {
+	return s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if tokenType != config.TokenTypeRefresh {
+			if err := s.DeleteToken(ctx, tokenType, userID); err != nil {
+				return err
+			}
+		}
+
+		tokenDoc := &model.Token{
+			Token:   utils.HashToken(token),
+			UserID:  uuid.MustParse(userID),
+			Type:    tokenType,
+			Expires: expires,
+		}
+
+		err := s.Tokens.Create(ctx, tokenDoc)
+
+		if err != nil {
+			utils.Logger(ctx).Errorf("Failed save token: %+v", err)
+		}
+
 		return err
-	}
+	})
+}
+
+func (s *tokenService) DeleteToken(ctx context.Context, tokenType string, userID string) error {
+	err := s.Tokens.DeleteByType(ctx, tokenType, userID)
 
-	tokenDoc := &model.Token{
-		Token:   token,
-		UserID:  uuid.MustParse(userID),
-		Type:    tokenType,
-		Expires: expires,
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to delete token: %+v", err)
 	}
 
-	result := s.DB.WithContext(c.Context()).Create(tokenDoc)
+	return err
+}
+
+// RevokeToken deletes a single token, repository.ErrNotFound means another
+// request revoked it first
+func (s *tokenService) RevokeToken(ctx context.Context, tokenDoc *model.Token) error {
+	deleted, err := s.Tokens.DeleteByID(ctx, tokenDoc.ID.String())
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to revoke token: %+v", err)
+		return err
+	}
 
-	if result.Error != nil {
-		s.Log.Errorf("Failed save token: %+v", result.Error)
+	if !deleted {
+		return repository.ErrNotFound
 	}
 
-	return result.Error
+	return nil
 }
 
-func (s *tokenService) DeleteToken(c *fiber.Ctx, tokenType string, userID string) error {
-	tokenDoc := new(model.Token)
-
-	result := s.DB.WithContext(c.Context()).
-		Where("type = ? AND user_id = ?", tokenType, userID).
-		Delete(tokenDoc)
+func (s *tokenService) DeleteAllToken(ctx context.Context, userID string) error {
+	err := s.Tokens.DeleteByUser(ctx, userID)
 
-	if result.Error != nil {
-		s.Log.Errorf("Failed to delete token: %+v", result.Error)
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to delete all token: %+v", err)
 	}
 
-	return result.Error
+	return err
 }
 
-func (s *tokenService) DeleteAllToken(c *fiber.Ctx, userID string) error {
-	tokenDoc := new(model.Token)
+func (s *tokenService) GetTokenByUserID(ctx context.Context, tokenStr string) (*model.Token, error) {
+	return s.VerifyToken(ctx, tokenStr, config.TokenTypeRefresh)
+}
 
-	result := s.DB.WithContext(c.Context()).Where("user_id = ?", userID).Delete(tokenDoc)
+// ParseToken checks the signature and the type of a token and returns its user id
+func (s *tokenService) ParseToken(tokenStr, tokenType string) (string, error) {
+	return utils.VerifyToken(tokenStr, s.Config.Get().JWT.Secret, tokenType)
+}
 
-	if result.Error != nil {
-		s.Log.Errorf("Failed to delete all token: %+v", result.Error)
+// VerifyToken checks the signature of a token and that it is stored for its
+// user, so a used or replaced token is rejected
+func (s *tokenService) VerifyToken(ctx context.Context, tokenStr, tokenType string) (*model.Token, error) {
+	userID, err := s.ParseToken(tokenStr, tokenType)
+	if err != nil {
+		return nil, err
 	}
 
-	return result.Error
-}
-
-func (s *tokenService) GetTokenByUserID(c *fiber.Ctx, tokenStr string) (*model.Token, error) {
-	userID, err := utils.VerifyToken(tokenStr, config.JWTSecret, config.TokenTypeRefresh)
+	// An indexed equality on the hash rather than a constant time comparison:
+	// only tokens with a valid signature get here, and the timing can only
+	// tell about the SHA-256 of the token sent, not a stored token
+	tokenDoc, err := s.Tokens.FindByHash(ctx, tokenType, userID, utils.HashToken(tokenStr))
 	if err != nil {
+		utils.Logger(ctx).Errorf("Failed get token by user id: %+v", err)
 		return nil, err
 	}
 
-	tokenDoc := new(model.Token)
+	return tokenDoc, nil
+}
 
-	result := s.DB.WithContext(c.Context()).
-		Where("token = ? AND user_id = ?", tokenStr, userID).
-		First(tokenDoc)
+// DeleteExpiredTokens removes one batch of at most limit expired tokens
+func (s *tokenService) DeleteExpiredTokens(ctx context.Context, expiredBefore time.Time, limit int) (int64, error) {
+	deleted, err := s.Tokens.DeleteExpired(ctx, expiredBefore, limit)
 
-	if result.Error != nil {
-		s.Log.Errorf("Failed get token by user id: %+v", err)
-		return nil, result.Error
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to delete expired tokens: %+v", err)
 	}
 
-	return tokenDoc, nil
+	return deleted, err
 }
 
-func (s *tokenService) GenerateAuthTokens(c *fiber.Ctx, user *model.User) (*res.Tokens, error) {
-	accessTokenExpires := time.Now().UTC().Add(time.Minute * time.Duration(config.JWTAccessExp))
+func (s *tokenService) GenerateAuthTokens(ctx context.Context, user *model.User) (*res.Tokens, error) {
+	accessTokenExpires := time.Now().UTC().Add(s.Config.Get().JWT.AccessExp)
 	accessToken, err := s.GenerateToken(user.ID.String(), accessTokenExpires, config.TokenTypeAccess)
 	if err != nil {
-		s.Log.Errorf("Failed generate token: %+v", err)
+		utils.Logger(ctx).Errorf("Failed generate token: %+v", err)
 		return nil, err
 	}
 
-	refreshTokenExpires := time.Now().UTC().Add(time.Hour * 24 * time.Duration(config.JWTRefreshExp))
+	refreshTokenExpires := time.Now().UTC().Add(s.Config.Get().JWT.RefreshExp)
 	refreshToken, err := s.GenerateToken(user.ID.String(), refreshTokenExpires, config.TokenTypeRefresh)
 	if err != nil {
-		s.Log.Errorf("Failed generate token: %+v", err)
+		utils.Logger(ctx).Errorf("Failed generate token: %+v", err)
 		return nil, err
 	}
 
-	if err = s.SaveToken(c, refreshToken, user.ID.String(), config.TokenTypeRefresh, refreshTokenExpires); err != nil {
+	if err = s.SaveToken(ctx, refreshToken, user.ID.String(), config.TokenTypeRefresh, refreshTokenExpires); err != nil {
 		return nil, err
 	}
 
@@ -153,41 +207,88 @@ func (s *tokenService) GenerateAuthTokens(c *fiber.Ctx, user *model.User) (*res.
 	}, nil
 }
 
-func (s *tokenService) GenerateResetPasswordToken(c *fiber.Ctx, req *validation.ForgotPassword) (string, error) {
+func (s *tokenService) GenerateResetPasswordToken(ctx context.Context, req *validation.ForgotPassword) (string, error) {
 	if err := s.Validate.Struct(req); err != nil {
 		return "", err
 	}
 
-	user, err := s.UserService.GetUserByEmail(c, req.Email)
+	user, err := s.UserService.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		return "", err
 	}
 
-	expires := time.Now().UTC().Add(time.Minute * time.Duration(config.JWTResetPasswordExp))
+	expires := time.Now().UTC().Add(s.Config.Get().JWT.ResetPasswordExp)
 	resetPasswordToken, err := s.GenerateToken(user.ID.String(), expires, config.TokenTypeResetPassword)
 	if err != nil {
-		s.Log.Errorf("Failed generate token: %+v", err)
+		utils.Logger(ctx).Errorf("Failed generate token: %+v", err)
 		return "", err
 	}
 
-	if err = s.SaveToken(c, resetPasswordToken, user.ID.String(), config.TokenTypeResetPassword, expires); err != nil {
+	if err = s.SaveToken(ctx, resetPasswordToken, user.ID.String(), config.TokenTypeResetPassword, expires); err != nil {
 		return "", err
 	}
 
 	return resetPasswordToken, nil
 }
 
-func (s *tokenService) GenerateVerifyEmailToken(c *fiber.Ctx, user *model.User) (*string, error) {
-	expires := time.Now().UTC().Add(time.Minute * time.Duration(config.JWTVerifyEmailExp))
+func (s *tokenService) GenerateVerifyEmailToken(ctx context.Context, user *model.User) (*string, error) {
+	expires := time.Now().UTC().Add(s.Config.Get().JWT.VerifyEmailExp)
 	verifyEmailToken, err := s.GenerateToken(user.ID.String(), expires, config.TokenTypeVerifyEmail)
 	if err != nil {
-		s.Log.Errorf("Failed generate token: %+v", err)
+		utils.Logger(ctx).Errorf("Failed generate token: %+v", err)
 		return nil, err
 	}
 
-	if err = s.SaveToken(c, verifyEmailToken, user.ID.String(), config.TokenTypeVerifyEmail, expires); err != nil {
+	if err = s.SaveToken(ctx, verifyEmailToken, user.ID.String(), config.TokenTypeVerifyEmail, expires); err != nil {
 		return nil, err
 	}
 
 	return &verifyEmailToken, nil
 }
+
+func (s *tokenService) GenerateConfirmErasureToken(ctx context.Context, user *model.User) (string, error) {
+	expires := time.Now().UTC().Add(s.Config.Get().JWT.VerifyEmailExp)
+	confirmErasureToken, err := s.GenerateToken(user.ID.String(), expires, config.TokenTypeConfirmErasure)
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed generate token: %+v", err)
+		return "", err
+	}
+
+	if err = s.SaveToken(ctx, confirmErasureToken, user.ID.String(), config.TokenTypeConfirmErasure, expires); err != nil {
+		return "", err
+	}
+
+	return confirmErasureToken, nil
+}
+
+func (s *tokenService) GenerateReauthToken(ctx context.Context, user *model.User) (string, error) {
+	expires := time.Now().UTC().Add(s.Config.Get().JWT.VerifyEmailExp)
+	reauthToken, err := s.GenerateToken(user.ID.String(), expires, config.TokenTypeReauth)
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed generate token: %+v", err)
+		return "", err
+	}
+
+	if err = s.SaveToken(ctx, reauthToken, user.ID.String(), config.TokenTypeReauth, expires); err != nil {
+		return "", err
+	}
+
+	return reauthToken, nil
+}
+
+// GenerateInvitationToken is a reset password token which lasts long enough
+// for an invited user to set their password
+func (s *tokenService) GenerateInvitationToken(ctx context.Context, user *model.User) (string, error) {
+	expires := time.Now().UTC().Add(s.Config.Get().Import.InvitationExp)
+	invitationToken, err := s.GenerateToken(user.ID.String(), expires, config.TokenTypeResetPassword)
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed generate token: %+v", err)
+		return "", err
+	}
+
+	if err = s.SaveToken(ctx, invitationToken, user.ID.String(), config.TokenTypeResetPassword, expires); err != nil {
+		return "", err
+	}
+
+	return invitationToken, nil
+}
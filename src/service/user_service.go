@@ -2,112 +2,129 @@ package service
 
 import (
 	"app/src/model"
+	"app/src/problem"
+	"app/src/query"
+	"app/src/repository"
 	"app/src/utils"
 	"app/src/validation"
+	"context"
 	"errors"
+	"time"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/gofiber/fiber/v2"
-	"github.com/sirupsen/logrus"
-	"gorm.io/gorm"
 )
 
 type UserService interface {
-	GetUsers(c *fiber.Ctx, params *validation.QueryUser) ([]model.User, int64, error)
-	GetUserByID(c *fiber.Ctx, id string) (*model.User, error)
-	GetUserByEmail(c *fiber.Ctx, email string) (*model.User, error)
-	CreateUser(c *fiber.Ctx, req *validation.CreateUser) (*model.User, error)
-	UpdatePassOrVerify(c *fiber.Ctx, req *validation.UpdatePassOrVerify, id string) error
-	UpdateUser(c *fiber.Ctx, req *validation.UpdateUser, id string) (*model.User, error)
-	DeleteUser(c *fiber.Ctx, id string) error
-	CreateGoogleUser(c *fiber.Ctx, req *validation.GoogleLogin) (*model.User, error)
+	GetUsers(ctx context.Context, params *validation.QueryUser) (*query.Result[model.User], error)
+	GetUserByID(ctx context.Context, id string) (*model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	CreateUser(ctx context.Context, req *validation.CreateUser) (*model.User, error)
+	UpdatePassOrVerify(ctx context.Context, req *validation.UpdatePassOrVerify, id string) error
+	UpdateUser(ctx context.Context, req *validation.UpdateUser, id string, version int64) (*model.User, error)
+	DeleteUser(ctx context.Context, id string, version int64) error
+	RestoreUser(ctx context.Context, id string) (*model.User, error)
+	ListDeletedAvatars(ctx context.Context, deletedBefore time.Time) ([]string, error)
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error)
+	CreateGoogleUser(ctx context.Context, req *validation.GoogleLogin) (*model.User, error)
 }
 
 type userService struct {
-	Log      *logrus.Logger
-	DB       *gorm.DB
+	Users    repository.UserRepository
 	Validate *validator.Validate
 }
 
-func NewUserService(db *gorm.DB, validate *validator.Validate) UserService {
+func NewUserService(users repository.UserRepository, validate *validator.Validate) UserService {
 	return &userService{
-		Log:      utils.Log,
-		DB:       db,
+		Users:    users,
 		Validate: validate,
 	}
 }
 
-func (s *userService) GetUsers(c *fiber.Ctx, params *validation.QueryUser) ([]model.User, int64, error) {
-	var users []model.User
-	var totalResults int64
-
+func (s *userService) GetUsers(ctx context.Context, params *validation.QueryUser) (*query.Result[model.User], error) {
 	if err := s.Validate.Struct(params); err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+
+	result, err := s.Users.List(ctx, params.Search, userListParams(params))
+	if err != nil {
+		return nil, listUsersError(ctx, err)
 	}
 
-	offset := (params.Page - 1) * params.Limit
-	query := s.DB.WithContext(c.Context()).Order("created_at asc")
+	return result, nil
+}
 
-	if search := params.Search; search != "" {
-		query = query.Where("name LIKE ? OR email LIKE ? OR role LIKE ?",
-			"%"+search+"%", "%"+search+"%", "%"+search+"%")
+// userListParams are the list parameters of the query, with its filters
+func userListParams(params *validation.QueryUser) query.Params {
+	filters := make(map[string]interface{})
+	if params.Role != "" {
+		filters["role"] = params.Role
+	}
+	if params.VerifiedEmail != "" {
+		filters["verified_email"] = params.VerifiedEmail == "true" || params.VerifiedEmail == "1"
 	}
 
-	result := query.Find(&users).Count(&totalResults)
-	if result.Error != nil {
-		s.Log.Errorf("Failed to search users: %+v", result.Error)
-		return nil, 0, result.Error
+	return query.Params{
+		Cursor:       params.Cursor,
+		Page:         params.Page,
+		Limit:        params.Limit,
+		SortBy:       params.SortBy,
+		Order:        params.Order,
+		Filters:      filters,
+		IncludeTotal: params.IncludeTotal,
 	}
+}
 
-	result = query.Limit(params.Limit).Offset(offset).Find(&users)
-	if result.Error != nil {
-		s.Log.Errorf("Failed to get all users: %+v", result.Error)
-		return nil, 0, result.Error
+// listUsersError turns the errors of the list parameters into problems, and logs the others
+func listUsersError(ctx context.Context, err error) error {
+	if errors.Is(err, query.ErrInvalidCursor) {
+		return problem.ErrInvalidCursor
 	}
 
-	return users, totalResults, result.Error
-}
+	if errors.Is(err, query.ErrInvalidSort) {
+		return problem.ErrInvalidSort
+	}
+
+	utils.Logger(ctx).Errorf("Failed to get all users: %+v", err)
 
-func (s *userService) GetUserByID(c *fiber.Ctx, id string) (*model.User, error) {
-	user := new(model.User)
+	return err
+}
 
-	result := s.DB.WithContext(c.Context()).First(user, "id = ?", id)
+func (s *userService) GetUserByID(ctx context.Context, id string) (*model.User, error) {
+	user, err := s.Users.FindByID(ctx, id)
 
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, problem.ErrUserNotFound
 	}
 
-	if result.Error != nil {
-		s.Log.Errorf("Failed get user by id: %+v", result.Error)
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed get user by id: %+v", err)
 	}
 
-	return user, result.Error
+	return user, err
 }
 
-func (s *userService) GetUserByEmail(c *fiber.Ctx, email string) (*model.User, error) {
-	user := new(model.User)
-
-	result := s.DB.WithContext(c.Context()).Where("email = ?", email).First(user)
+func (s *userService) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	user, err := s.Users.FindByEmail(ctx, email)
 
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, problem.ErrUserNotFound
 	}
 
-	if result.Error != nil {
-		s.Log.Errorf("Failed get user by email: %+v", result.Error)
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed get user by email: %+v", err)
 	}
 
-	return user, result.Error
+	return user, err
 }
 
-func (s *userService) CreateUser(c *fiber.Ctx, req *validation.CreateUser) (*model.User, error) {
+func (s *userService) CreateUser(ctx context.Context, req *validation.CreateUser) (*model.User, error) {
 	if err := s.Validate.Struct(req); err != nil {
 		return nil, err
 	}
 
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
-		s.Log.Errorf("Failed hash password: %+v", err)
+		utils.Logger(ctx).Errorf("Failed hash password: %+v", err)
 		return nil, err
 	}
 
@@ -116,28 +133,32 @@ func (s *userService) CreateUser(c *fiber.Ctx, req *validation.CreateUser) (*mod
 		Email:    req.Email,
 		Password: hashedPassword,
 		Role:     req.Role,
+		Language: req.Language,
 	}
 
-	result := s.DB.WithContext(c.Context()).Create(user)
+	err = s.Users.Create(ctx, user)
 
-	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
-		return nil, fiber.NewError(fiber.StatusConflict, "Email is already in use")
+	if errors.Is(err, repository.ErrDuplicateKey) {
+		return nil, problem.ErrEmailTaken
 	}
 
-	if result.Error != nil {
-		s.Log.Errorf("Failed to create user: %+v", result.Error)
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to create user: %+v", err)
 	}
 
-	return user, result.Error
+	return user, err
 }
 
-func (s *userService) UpdateUser(c *fiber.Ctx, req *validation.UpdateUser, id string) (*model.User, error) {
+// UpdateUser updates the user, unless version is 0 only if it wasn't changed since that version
+func (s *userService) UpdateUser(
+	ctx context.Context, req *validation.UpdateUser, id string, version int64,
+) (*model.User, error) {
 	if err := s.Validate.Struct(req); err != nil {
 		return nil, err
 	}
 
-	if req.Email == "" && req.Name == "" && req.Password == "" {
-		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid Request")
+	if req.Email == "" && req.Name == "" && req.Password == "" && req.Language == "" {
+		return nil, problem.ErrInvalidRequest
 	}
 
 	if req.Password != "" {
@@ -152,37 +173,37 @@ func (s *userService) UpdateUser(c *fiber.Ctx, req *validation.UpdateUser, id st
 		Name:     req.Name,
 		Password: req.Password,
 		Email:    req.Email,
+		Language: req.Language,
 	}
 
-	result := s.DB.WithContext(c.Context()).Where("id = ?", id).Updates(updateBody)
+	user, err := s.Users.Update(ctx, id, updateBody, version)
 
-	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
-		return nil, fiber.NewError(fiber.StatusConflict, "Email is already in use")
+	if errors.Is(err, repository.ErrDuplicateKey) {
+		return nil, problem.ErrEmailTaken
 	}
 
-	if result.RowsAffected == 0 {
-		return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, problem.ErrUserNotFound
 	}
 
-	if result.Error != nil {
-		s.Log.Errorf("Failed to update user: %+v", result.Error)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, problem.ErrUserModified
 	}
 
-	user, err := s.GetUserByID(c, id)
 	if err != nil {
-		return nil, err
+		utils.Logger(ctx).Errorf("Failed to update user: %+v", err)
 	}
 
-	return user, result.Error
+	return user, err
 }
 
-func (s *userService) UpdatePassOrVerify(c *fiber.Ctx, req *validation.UpdatePassOrVerify, id string) error {
+func (s *userService) UpdatePassOrVerify(ctx context.Context, req *validation.UpdatePassOrVerify, id string) error {
 	if err := s.Validate.Struct(req); err != nil {
 		return err
 	}
 
 	if req.Password == "" && !req.VerifiedEmail {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid Request")
+		return problem.ErrInvalidRequest
 	}
 
 	if req.Password != "" {
@@ -198,51 +219,91 @@ func (s *userService) UpdatePassOrVerify(c *fiber.Ctx, req *validation.UpdatePas
 		VerifiedEmail: req.VerifiedEmail,
 	}
 
-	result := s.DB.WithContext(c.Context()).Where("id = ?", id).Updates(updateBody)
+	_, err := s.Users.Update(ctx, id, updateBody, 0)
+
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.ErrUserNotFound
+	}
+
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to update user password or verifiedEmail: %+v", err)
+	}
+
+	return err
+}
+
+// DeleteUser deletes the user, unless version is 0 only if it wasn't changed since that version
+func (s *userService) DeleteUser(ctx context.Context, id string, version int64) error {
+	err := s.Users.Delete(ctx, id, version)
 
-	if result.RowsAffected == 0 {
-		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.ErrUserNotFound
 	}
 
-	if result.Error != nil {
-		s.Log.Errorf("Failed to update user password or verifiedEmail: %+v", result.Error)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return problem.ErrUserModified
+	}
+
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to delete user: %+v", err)
 	}
 
-	return result.Error
+	return err
 }
 
-func (s *userService) DeleteUser(c *fiber.Ctx, id string) error {
-	user := new(model.User)
+func (s *userService) RestoreUser(ctx context.Context, id string) (*model.User, error) {
+	user, err := s.Users.Restore(ctx, id)
+
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, problem.ErrDeletedUserNotFound
+	}
+
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to restore user: %+v", err)
+	}
+
+	return user, err
+}
 
-	result := s.DB.WithContext(c.Context()).Delete(user, "id = ?", id)
+// ListDeletedAvatars returns the avatars of the users PurgeDeletedUsers removes,
+// their thumbnails are deleted first so none is left without its user
+func (s *userService) ListDeletedAvatars(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	avatars, err := s.Users.ListDeletedAvatars(ctx, deletedBefore)
 
-	if result.RowsAffected == 0 {
-		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to list avatars of deleted users: %+v", err)
 	}
 
-	if result.Error != nil {
-		s.Log.Errorf("Failed to delete user: %+v", result.Error)
+	return avatars, err
+}
+
+// PurgeDeletedUsers permanently removes users soft deleted before the given time
+func (s *userService) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	purged, err := s.Users.PurgeDeleted(ctx, deletedBefore)
+
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to purge deleted users: %+v", err)
 	}
 
-	return result.Error
+	return purged, err
 }
 
-func (s *userService) CreateGoogleUser(c *fiber.Ctx, req *validation.GoogleLogin) (*model.User, error) {
+func (s *userService) CreateGoogleUser(ctx context.Context, req *validation.GoogleLogin) (*model.User, error) {
 	if err := s.Validate.Struct(req); err != nil {
 		return nil, err
 	}
 
-	userFromDB, err := s.GetUserByEmail(c, req.Email)
+	userFromDB, err := s.Users.FindByEmail(ctx, req.Email)
 	if err != nil {
-		if err.Error() == "User not found" {
+		if errors.Is(err, repository.ErrNotFound) {
 			user := &model.User{
 				Name:          req.Name,
 				Email:         req.Email,
 				VerifiedEmail: req.VerifiedEmail,
 			}
 
-			if createErr := s.DB.WithContext(c.Context()).Create(user).Error; createErr != nil {
-				s.Log.Errorf("Failed to create user: %+v", createErr)
+			if createErr := s.Users.Create(ctx, user); createErr != nil {
+				utils.Logger(ctx).Errorf("Failed to create user: %+v", createErr)
 				return nil, createErr
 			}
 
@@ -252,11 +313,16 @@ func (s *userService) CreateGoogleUser(c *fiber.Ctx, req *validation.GoogleLogin
 		return nil, err
 	}
 
-	userFromDB.VerifiedEmail = req.VerifiedEmail
-	if updateErr := s.DB.WithContext(c.Context()).Save(userFromDB).Error; updateErr != nil {
-		s.Log.Errorf("Failed to update user: %+v", updateErr)
-		return nil, updateErr
+	// Google only ever confirms the email, a user already verified is left as is
+	if !req.VerifiedEmail || userFromDB.VerifiedEmail {
+		return userFromDB, nil
+	}
+
+	user, err := s.Users.Update(ctx, userFromDB.ID.String(), &model.User{VerifiedEmail: true}, 0)
+	if err != nil {
+		utils.Logger(ctx).Errorf("Failed to update user: %+v", err)
+		return nil, err
 	}
 
-	return userFromDB, nil
+	return user, nil
 }
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"app/src/config"
+	"app/src/tracing"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	s3Service    = "s3"
+	s3Algorithm  = "AWS4-HMAC-SHA256"
+	s3TimeFormat = "20060102T150405Z"
+	s3DateFormat = "20060102"
+)
+
+type s3Store struct {
+	cfg      config.S3
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3Store keeps the blobs in a bucket of S3 or of a compatible server like
+// MinIO or R2, the requests are signed with AWS Signature Version 4
+func NewS3Store(cfg config.S3) (Store, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+
+	return &s3Store{cfg: cfg, endpoint: endpoint, client: tracing.HTTPClient, now: time.Now}, nil
+}
+
+// objectURL addresses the object by path, bucket first, or by virtual host
+func (s *s3Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.cfg.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+
+	return &u
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	payload, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	res, err := s.do(req, payload)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return s3Error(res, http.StatusOK)
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.do(req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, ErrNotFound
+	}
+	if err := s3Error(res, http.StatusOK); err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+
+	return res.Body, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.do(req, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// S3 answers 204 whether the object existed or not
+	if res.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return s3Error(res, http.StatusNoContent, http.StatusOK)
+}
+
+func (s *s3Store) do(req *http.Request, payload []byte) (*http.Response, error) {
+	SignS3Request(req, payload, s.cfg, s.now().UTC())
+	return s.client.Do(req)
+}
+
+// SignS3Request adds the headers of Signature Version 4 at the given time, the
+// payload is signed too
+func SignS3Request(req *http.Request, payload []byte, cfg config.S3, now time.Time) {
+	payloadHash := sha256.Sum256(payload)
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	names := []string{"host"}
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+
+	var headers strings.Builder
+	for _, name := range names {
+		value := req.Host
+		if value == "" {
+			value = req.URL.Host
+		}
+		if name != "host" {
+			value = strings.Join(req.Header.Values(name), ",")
+		}
+		headers.WriteString(name + ":" + strings.Join(strings.Fields(value), " ") + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3Escape(req.URL.Path, true),
+		canonicalQuery(req.URL.Query()),
+		headers.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format(s3DateFormat), cfg.Region, s3Service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{s3Algorithm, now.Format(s3TimeFormat), scope,
+		hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + cfg.SecretAccessKey)
+	for _, part := range []string{now.Format(s3DateFormat), cfg.Region, s3Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, cfg.AccessKeyID, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+// canonicalQuery sorts the parameters by name then value, both escaped the way
+// of Signature Version 4
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return s3Escape(names[i], false) < s3Escape(names[j], false) })
+
+	var params []string
+	for _, name := range names {
+		values := make([]string, 0, len(query[name]))
+		for _, value := range query[name] {
+			values = append(values, s3Escape(value, false))
+		}
+		sort.Strings(values)
+
+		for _, value := range values {
+			params = append(params, s3Escape(name, false)+"="+value)
+		}
+	}
+
+	return strings.Join(params, "&")
+}
+
+// s3Escape percent-encodes every byte but the unreserved characters, and the
+// slashes when they separate the segments of a path
+func s3Escape(s string, path bool) string {
+	var escaped strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '.', b == '_', b == '~', path && b == '/':
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+
+	return escaped.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Error describes the response unless it has one of the expected statuses
+func s3Error(res *http.Response, expected ...int) error {
+	for _, status := range expected {
+		if res.StatusCode == status {
+			return nil
+		}
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	return fmt.Errorf("s3 %s %s: %s: %s", res.Request.Method, res.Request.URL.Path, res.Status,
+		strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,22 @@
+// Package storage keeps the uploaded files as blobs, on the local filesystem
+// or in an S3-compatible bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when no blob has the key
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps blobs by key, e.g. avatars/3f0c….jpg. Keys use / as separator.
+type Store interface {
+	// Put writes the blob, replacing the one with the same key
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Get opens the blob, the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob, it succeeds if there is none
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,146 @@
+// Package userfile reads the users imported from CSV and NDJSON files, and
+// writes the exported users in the same formats.
+package userfile
+
+import (
+	"app/src/validation"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+var (
+	// ErrUnknownFormat is returned for a format other than csv and ndjson
+	ErrUnknownFormat = errors.New("unknown format")
+	// ErrInvalidFile is returned when the file has no users, e.g. a CSV file without an email column or rows
+	ErrInvalidFile = errors.New("invalid file of users")
+	// ErrInvalidRow is the error of a row which can't be read
+	ErrInvalidRow = errors.New("invalid row")
+)
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// Row is a user read from a file, Line is where it starts in the file. Err
+// is set, and User empty, when the row can't be read.
+type Row struct {
+	Line int
+	User validation.CreateUser
+	Err  error
+}
+
+// FormatOf returns the format of a file from its extension, empty when it has neither
+func FormatOf(filename string) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	}
+	return ""
+}
+
+// Read returns the rows of the file. The CSV files have a header naming
+// their columns, among name, email, password, role and language, the other
+// columns are ignored. The NDJSON files have an object per line with the
+// fields of the same names.
+func Read(data []byte, format string) ([]Row, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+
+	switch format {
+	case FormatCSV:
+		return readCSV(data)
+	case FormatNDJSON:
+		return readNDJSON(data)
+	}
+	return nil, ErrUnknownFormat
+}
+
+func readCSV(data []byte) ([]Row, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, ErrInvalidFile
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, ErrInvalidFile
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) && len(rows) == 0 {
+			return nil, ErrInvalidFile
+		}
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, Row{Line: parseErr.StartLine, Err: ErrInvalidRow})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// The password is kept as is, spaces are allowed in it
+		field := func(name string, trim bool) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			if trim {
+				return strings.TrimSpace(record[i])
+			}
+			return record[i]
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, Row{Line: line, User: validation.CreateUser{
+			Name:     field("name", true),
+			Email:    field("email", true),
+			Password: field("password", false),
+			Role:     field("role", true),
+			Language: field("language", true),
+		}})
+	}
+}
+
+func readNDJSON(data []byte) ([]Row, error) {
+	var rows []Row
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		row := Row{Line: i + 1}
+		if err := json.Unmarshal(line, &row.User); err != nil {
+			row = Row{Line: i + 1, Err: ErrInvalidRow}
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrInvalidFile
+	}
+
+	return rows, nil
+}
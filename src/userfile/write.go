@@ -0,0 +1,85 @@
+package userfile
+
+import (
+	"app/src/model"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// exportColumns are the columns of the exported CSV files, the fields of the users returned by the API
+var exportColumns = []string{"id", "name", "email", "role", "verified_email", "language", "avatar_url"}
+
+// Writer writes the exported users, Flush writes what is still buffered
+type Writer interface {
+	Write(user *model.User) error
+	Flush() error
+}
+
+// NewWriter writes the users to w in the format, a CSV file starts with its header
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		writer := &csvWriter{writer: csv.NewWriter(w)}
+		return writer, writer.writer.Write(exportColumns)
+	case FormatNDJSON:
+		buffered := bufio.NewWriter(w)
+		return &ndjsonWriter{buffered: buffered, encoder: json.NewEncoder(buffered)}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ContentType is the media type of the files in the format
+func ContentType(format string) string {
+	if format == FormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvWriter) Write(user *model.User) error {
+	return w.writer.Write([]string{
+		user.ID.String(),
+		csvCell(user.Name),
+		csvCell(user.Email),
+		csvCell(user.Role),
+		strconv.FormatBool(user.VerifiedEmail),
+		csvCell(user.Language),
+		csvCell(user.AvatarURL),
+	})
+}
+
+// csvCell quotes a value which a spreadsheet would run as a formula, e.g. a
+// name starting with =, by prefixing it with an apostrophe
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (w *csvWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type ndjsonWriter struct {
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+// Write encodes the user like the API does, the encoder ends it with a newline
+func (w *ndjsonWriter) Write(user *model.User) error {
+	return w.encoder.Encode(user)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.buffered.Flush()
+}
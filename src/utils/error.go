@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"app/src/i18n"
+	"app/src/problem"
 	"app/src/response"
 	"app/src/validation"
 	"errors"
@@ -8,19 +10,70 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
+// ErrorHandler renders the errors as RFC 7807 problems
 func ErrorHandler(c *fiber.Ctx, err error) error {
-	if errorsMap := validation.CustomErrorMessages(err); len(errorsMap) > 0 {
+	lang := i18n.Language(c.UserContext())
+	res := response.Problem{
+		Instance:  c.Path(),
+		RequestID: c.GetRespHeader(fiber.HeaderXRequestID),
+	}
+
+	var problemErr *problem.Error
+	var fiberErr *fiber.Error
+	switch {
+	case errors.As(err, &problemErr):
+	case errors.As(err, &fiberErr):
+		problemErr = problem.FromStatus(fiberErr.Code, fiberErr.Message)
+	default:
+		if res.Errors = validation.FieldErrors(err, lang); len(res.Errors) > 0 {
+			problemErr = problem.ErrValidation
+			break
+		}
+
+		Logger(c.UserContext()).Errorf("Unhandled error: %v", err)
+		problemErr = problem.ErrInternal
+	}
+
+	res.Type = problemErr.Type()
+	res.Title = title(problemErr, lang)
+	res.Status = problemErr.Status
+	res.Detail = problemErr.Detail()
+	res.Code = problemErr.Code
+
+	return response.SendProblem(c, res)
+}
+
+// LegacyErrorHandler renders the errors in the format used before the
+// problems, for the clients which haven't moved yet
+func LegacyErrorHandler(c *fiber.Ctx, err error) error {
+	lang := i18n.Language(c.UserContext())
+	if errorsMap := validation.CustomErrorMessages(err, lang); len(errorsMap) > 0 {
 		return response.Error(c, fiber.StatusBadRequest, "Bad Request", errorsMap)
 	}
 
+	var problemErr *problem.Error
+	if errors.As(err, &problemErr) && problemErr.Detail() == "" {
+		return response.Error(c, problemErr.Status, title(problemErr, lang), nil)
+	}
+
 	var fiberErr *fiber.Error
 	if errors.As(err, &fiberErr) {
 		return response.Error(c, fiberErr.Code, fiberErr.Message, nil)
 	}
 
+	Logger(c.UserContext()).Errorf("Unhandled error: %v", err)
+
 	return response.Error(c, fiber.StatusInternalServerError, "Internal Server Error", nil)
 }
 
-func NotFoundHandler(c *fiber.Ctx) error {
-	return response.Error(c, fiber.StatusNotFound, "Endpoint Not Found", nil)
+// title translates the title of the error, the catalogue has it in English
+func title(e *problem.Error, lang string) string {
+	if translated, ok := i18n.Lookup(lang, "problem."+e.Code); ok {
+		return translated
+	}
+	return e.Title
+}
+
+func NotFoundHandler(*fiber.Ctx) error {
+	return problem.ErrEndpointNotFound
 }
@@ -1,28 +1,85 @@
 package utils
 
 import (
+	"context"
 	"os"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type CustomFormatter struct {
 	logrus.TextFormatter
 }
 
+type loggerKey struct{}
+
 var Log *logrus.Logger
 
 func init() {
 	Log = logrus.New()
+	Log.SetFormatter(jsonFormatter())
+	Log.SetOutput(os.Stdout)
+	Log.AddHook(traceHook{})
+}
 
-	// Set logger to use the custom text formatter
-	Log.SetFormatter(&CustomFormatter{
-		TextFormatter: logrus.TextFormatter{
-			TimestampFormat: "15:04:05.000",
-			FullTimestamp:   true,
-			ForceColors:     true,
-		},
-	})
+// ConfigureLog sets the output format ("json" or "text") and the level of the global logger
+func ConfigureLog(format, level string) {
+	if format == "text" {
+		Log.SetFormatter(&CustomFormatter{
+			TextFormatter: logrus.TextFormatter{
+				TimestampFormat: "15:04:05.000",
+				FullTimestamp:   true,
+				ForceColors:     true,
+			},
+		})
+	} else {
+		Log.SetFormatter(jsonFormatter())
+	}
 
-	Log.SetOutput(os.Stdout)
+	if parsed, err := logrus.ParseLevel(level); err == nil {
+		Log.SetLevel(parsed)
+	} else if level != "" {
+		Log.Warnf("Unknown log level %q, keeping %s", level, Log.GetLevel())
+	}
+}
+
+func jsonFormatter() logrus.Formatter {
+	return &logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano}
+}
+
+// WithLogger returns a copy of ctx carrying entry, so everything down the call chain
+// logs with its fields (request id, user id, job name...)
+func WithLogger(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey{}, entry)
+}
+
+// Logger returns the logger carried by ctx, or the global one
+func Logger(ctx context.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(loggerKey{}).(*logrus.Entry); ok {
+		return entry.WithContext(ctx)
+	}
+
+	return Log.WithContext(ctx)
+}
+
+// traceHook adds the ids of the span in the entry context, so logs can be joined with traces
+type traceHook struct{}
+
+func (traceHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (traceHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	if span := trace.SpanContextFromContext(entry.Context); span.IsValid() {
+		entry.Data["trace_id"] = span.TraceID().String()
+		entry.Data["span_id"] = span.SpanID().String()
+	}
+
+	return nil
 }
@@ -0,0 +1,23 @@
+package validation
+
+type UpdateProfile struct {
+	Name     string `json:"name,omitempty" validate:"omitempty,max=50" example:"fake name"`
+	Language string `json:"language,omitempty" validate:"omitempty,language" example:"en"`
+}
+
+type ChangePassword struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=20" example:"password1"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,max=20,password" example:"password2"`
+}
+
+type ChangeEmail struct {
+	Email           string `json:"email" validate:"required,email,max=50" example:"new@example.com"`
+	CurrentPassword string `json:"current_password,omitempty" validate:"omitempty,max=20" example:"password1"`
+	ReauthToken     string `json:"reauth_token,omitempty" validate:"omitempty,max=512"`
+}
+
+type DeleteAccount struct {
+	Confirm         string `json:"confirm" validate:"required,max=50" example:"fake@example.com"`
+	CurrentPassword string `json:"current_password,omitempty" validate:"omitempty,max=20" example:"password1"`
+	ReauthToken     string `json:"reauth_token,omitempty" validate:"omitempty,max=512"`
+}
@@ -4,6 +4,7 @@ type Register struct {
 	Name     string `json:"name" validate:"required,max=50" example:"fake name"`
 	Email    string `json:"email" validate:"required,email,max=50" example:"fake@example.com"`
 	Password string `json:"password" validate:"required,min=8,max=20,password" example:"password1"`
+	Language string `json:"language,omitempty" validate:"omitempty,language" example:"en"`
 }
 
 type Login struct {
@@ -18,11 +19,11 @@ type GoogleLogin struct {
 }
 
 type Logout struct {
-	RefreshToken string `json:"refresh_token" validate:"required,max=255"`
+	RefreshToken string `json:"refresh_token" validate:"required,max=512"`
 }
 
 type RefreshToken struct {
-	RefreshToken string `json:"refresh_token" validate:"required,max=255"`
+	RefreshToken string `json:"refresh_token" validate:"required,max=512"`
 }
 
 type ForgotPassword struct {
@@ -30,5 +31,5 @@ type ForgotPassword struct {
 }
 
 type Token struct {
-	Token string `json:"token" validate:"required,max=255"`
+	Token string `json:"token" validate:"required,max=512"`
 }
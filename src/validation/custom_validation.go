@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"app/src/i18n"
 	"regexp"
 
 	"github.com/go-playground/validator/v10"
@@ -19,3 +20,9 @@ func Password(field validator.FieldLevel) bool {
 
 	return true
 }
+
+// Language accepts the languages with a message catalog
+func Language(field validator.FieldLevel) bool {
+	value, ok := field.Field().Interface().(string)
+	return ok && i18n.Supported(value)
+}
@@ -0,0 +1,9 @@
+package validation
+
+type QueryJobRun struct {
+	Page   int    `query:"page" validate:"omitempty,number,min=1"`
+	Limit  int    `query:"limit" validate:"number,min=1,max=100"`
+	Cursor string `query:"cursor" validate:"omitempty,max=512"`
+	Name   string `query:"name" validate:"omitempty,max=100"`
+	Status string `query:"status" validate:"omitempty,oneof=running succeeded failed"`
+}
@@ -0,0 +1,7 @@
+package validation
+
+type RequestErasure struct {
+	Confirm         string `json:"confirm" validate:"required,max=50" example:"fake@example.com"`
+	CurrentPassword string `json:"current_password,omitempty" validate:"omitempty,max=20" example:"password1"`
+	ReauthToken     string `json:"reauth_token,omitempty" validate:"omitempty,max=512"`
+}
@@ -5,12 +5,14 @@ type CreateUser struct {
 	Email    string `json:"email" validate:"required,email,max=50" example:"fake@example.com"`
 	Password string `json:"password" validate:"required,min=8,max=20,password" example:"password1"`
 	Role     string `json:"role" validate:"required,oneof=user admin,max=50" example:"user"`
+	Language string `json:"language,omitempty" validate:"omitempty,language" example:"en"`
 }
 
 type UpdateUser struct {
 	Name     string `json:"name,omitempty" validate:"omitempty,max=50" example:"fake name"`
 	Email    string `json:"email" validate:"omitempty,email,max=50" example:"fake@example.com"`
 	Password string `json:"password,omitempty" validate:"omitempty,min=8,max=20,password" example:"password1"`
+	Language string `json:"language,omitempty" validate:"omitempty,language" example:"en"`
 }
 
 type UpdatePassOrVerify struct {
@@ -19,7 +21,28 @@ type UpdatePassOrVerify struct {
 }
 
 type QueryUser struct {
-	Page   int    `validate:"omitempty,number,max=50"`
-	Limit  int    `validate:"omitempty,number,max=50"`
-	Search string `validate:"omitempty,max=50"`
+	Page          int    `query:"page" validate:"omitempty,number,min=1"`
+	Limit         int    `query:"limit" validate:"number,min=1,max=50"`
+	Search        string `query:"search" validate:"omitempty,max=50"`
+	Cursor        string `query:"cursor" validate:"omitempty,max=512"`
+	SortBy        string `query:"sortBy" validate:"omitempty,oneof=relevance created_at name email role"`
+	Order         string `query:"order" validate:"omitempty,oneof=asc desc"`
+	Role          string `query:"role" validate:"omitempty,oneof=user admin"`
+	VerifiedEmail string `query:"verified_email" validate:"omitempty,boolean"`
+	IncludeTotal  bool   `query:"include_total"`
+}
+
+// ImportUsers are the fields sent with the file, its format is guessed from its extension when not given
+type ImportUsers struct {
+	Format string `json:"format,omitempty" form:"format" validate:"omitempty,oneof=csv ndjson" example:"csv"`
+	Invite bool   `json:"invite,omitempty" form:"invite" example:"true"`
+}
+
+type ExportUsers struct {
+	Format        string `query:"format" validate:"omitempty,oneof=csv ndjson"`
+	Search        string `query:"search" validate:"omitempty,max=50"`
+	SortBy        string `query:"sortBy" validate:"omitempty,oneof=relevance created_at name email role"`
+	Order         string `query:"order" validate:"omitempty,oneof=asc desc"`
+	Role          string `query:"role" validate:"omitempty,oneof=user admin"`
+	VerifiedEmail string `query:"verified_email" validate:"omitempty,boolean"`
 }
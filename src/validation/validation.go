@@ -1,66 +1,101 @@
 package validation
 
 import (
+	"app/src/i18n"
 	"errors"
-	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
 
-var customMessages = map[string]string{
-	"required": "Field %s must be filled",
-	"email":    "Invalid email address for field %s",
-	"min":      "Field %s must have a minimum length of %s characters",
-	"max":      "Field %s must have a maximum length of %s characters",
-	"len":      "Field %s must be exactly %s characters long",
-	"number":   "Field %s must be a number",
-	"positive": "Field %s must be a positive number",
-	"alphanum": "Field %s must contain only alphanumeric characters",
-	"oneof":    "Invalid value for field %s",
-	"password": "Field %s must contain at least 1 letter and 1 number",
+// FieldError describes why the value of a field was rejected
+type FieldError struct {
+	Code    string `json:"code" example:"required"`
+	Message string `json:"message" example:"Field email must be filled"`
 }
 
-func CustomErrorMessages(err error) map[string]string {
+// CustomErrorMessages returns the messages in lang keyed by the Go namespace of
+// the fields, e.g. Register.Email. It's the format of the legacy error responses.
+func CustomErrorMessages(err error, lang string) map[string]string {
 	var validationErrors validator.ValidationErrors
 	if errors.As(err, &validationErrors) {
-		return generateErrorMessages(validationErrors)
+		return generateErrorMessages(validationErrors, lang)
 	}
 	return nil
 }
 
-func generateErrorMessages(validationErrors validator.ValidationErrors) map[string]string {
-	errorsMap := make(map[string]string)
-	for _, err := range validationErrors {
-		fieldName := err.StructNamespace()
-		tag := err.Tag()
+// FieldErrors returns the errors keyed by the path of the fields in the
+// request, e.g. email, as named by their json or query tag, with messages in lang
+func FieldErrors(err error, lang string) map[string]FieldError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
 
-		customMessage := customMessages[tag]
-		if customMessage != "" {
-			errorsMap[fieldName] = formatErrorMessage(customMessage, err, tag)
-		} else {
-			errorsMap[fieldName] = defaultErrorMessage(err)
+	errorsMap := make(map[string]FieldError)
+	for _, err := range validationErrors {
+		errorsMap[fieldPath(err)] = FieldError{
+			Code:    err.Tag(),
+			Message: errorMessage(err, err.Field(), lang),
 		}
 	}
 	return errorsMap
 }
 
-func formatErrorMessage(customMessage string, err validator.FieldError, tag string) string {
-	if tag == "min" || tag == "max" || tag == "len" {
-		return fmt.Sprintf(customMessage, err.Field(), err.Param())
+func generateErrorMessages(validationErrors validator.ValidationErrors, lang string) map[string]string {
+	errorsMap := make(map[string]string)
+	for _, err := range validationErrors {
+		errorsMap[err.StructNamespace()] = errorMessage(err, err.StructField(), lang)
+	}
+	return errorsMap
+}
+
+// fieldPath drops the name of the validated struct from the namespace
+func fieldPath(err validator.FieldError) string {
+	namespace := err.Namespace()
+	if i := strings.IndexByte(namespace, '.'); i >= 0 {
+		return namespace[i+1:]
 	}
-	return fmt.Sprintf(customMessage, err.Field())
+	return namespace
 }
 
-func defaultErrorMessage(err validator.FieldError) string {
-	return fmt.Sprintf("Field validation for '%s' failed on the '%s' tag", err.Field(), err.Tag())
+// errorMessage formats the message of the tag, the catalogs get the field, the
+// param and the tag as %[1]s, %[2]s and %[3]s
+func errorMessage(err validator.FieldError, field, lang string) string {
+	key := "validation." + err.Tag()
+	if _, ok := i18n.Lookup(lang, key); !ok {
+		key = "validation.default"
+	}
+
+	return i18n.T(lang, key, field, err.Param(), err.Tag())
+}
+
+// fieldName names the fields after their json or query tag
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "query"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
 }
 
 func Validator() *validator.Validate {
 	validate := validator.New()
+	validate.RegisterTagNameFunc(fieldName)
 
 	if err := validate.RegisterValidation("password", Password); err != nil {
 		return nil
 	}
 
+	if err := validate.RegisterValidation("language", Language); err != nil {
+		return nil
+	}
+
 	return validate
 }
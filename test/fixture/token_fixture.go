@@ -3,17 +3,20 @@ package fixture
 import (
 	"app/src/config"
 	"app/src/model"
+	"app/test"
 	"app/test/helper"
 	"time"
 )
 
-var ExpiresAccessToken = time.Now().UTC().Add(time.Minute * time.Duration(config.JWTAccessExp))
-var ExpiresRefreshToken = time.Now().UTC().Add(time.Hour * 24 * time.Duration(config.JWTRefreshExp))
-var ExpiresResetPasswordToken = time.Now().UTC().Add(time.Minute * time.Duration(config.JWTResetPasswordExp))
-var ExpiresVerifyEmailToken = time.Now().UTC().Add(time.Minute * time.Duration(config.JWTVerifyEmailExp))
+var ExpiresAccessToken = time.Now().UTC().Add(test.Config.JWT.AccessExp)
+var ExpiresRefreshToken = time.Now().UTC().Add(test.Config.JWT.RefreshExp)
+var ExpiresResetPasswordToken = time.Now().UTC().Add(test.Config.JWT.ResetPasswordExp)
+var ExpiresVerifyEmailToken = time.Now().UTC().Add(test.Config.JWT.VerifyEmailExp)
 
 func AccessToken(user *model.User) (string, error) {
-	accessToken, err := helper.GenerateToken(user.ID.String(), ExpiresAccessToken, config.TokenTypeAccess)
+	accessToken, err := helper.GenerateToken(
+		test.Config.JWT.Secret, user.ID.String(), ExpiresAccessToken, config.TokenTypeAccess,
+	)
 	if err != nil {
 		return accessToken, err
 	}
@@ -21,7 +24,9 @@ func AccessToken(user *model.User) (string, error) {
 }
 
 func RefreshToken(user *model.User) (string, error) {
-	refreshToken, err := helper.GenerateToken(user.ID.String(), ExpiresRefreshToken, config.TokenTypeRefresh)
+	refreshToken, err := helper.GenerateToken(
+		test.Config.JWT.Secret, user.ID.String(), ExpiresRefreshToken, config.TokenTypeRefresh,
+	)
 	if err != nil {
 		return refreshToken, err
 	}
@@ -30,7 +35,7 @@ func RefreshToken(user *model.User) (string, error) {
 
 func ResetPasswordToken(user *model.User) (string, error) {
 	resetPasswordToken, err := helper.GenerateToken(
-		user.ID.String(), ExpiresResetPasswordToken, config.TokenTypeResetPassword,
+		test.Config.JWT.Secret, user.ID.String(), ExpiresResetPasswordToken, config.TokenTypeResetPassword,
 	)
 	if err != nil {
 		return resetPasswordToken, err
@@ -39,7 +44,9 @@ func ResetPasswordToken(user *model.User) (string, error) {
 }
 
 func VerifyEmailToken(user *model.User) (string, error) {
-	verifyEmailToken, err := helper.GenerateToken(user.ID.String(), ExpiresVerifyEmailToken, config.TokenTypeVerifyEmail)
+	verifyEmailToken, err := helper.GenerateToken(
+		test.Config.JWT.Secret, user.ID.String(), ExpiresVerifyEmailToken, config.TokenTypeVerifyEmail,
+	)
 	if err != nil {
 		return verifyEmailToken, err
 	}
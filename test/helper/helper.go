@@ -19,7 +19,7 @@ func ClearAll(db *gorm.DB) {
 }
 
 func ClearUsers(db *gorm.DB) {
-	err := db.Where("id is not null").Delete(&model.User{}).Error
+	err := db.Unscoped().Where("id is not null").Delete(&model.User{}).Error
 	if err != nil {
 		logrus.Fatalf("Failed clear user data : %+v", err)
 	}
@@ -32,6 +32,34 @@ func ClearToken(db *gorm.DB) {
 	}
 }
 
+func ClearJobs(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.JobRun{}).Error; err != nil {
+		logrus.Fatalf("Failed clear job runs : %+v", err)
+	}
+
+	if err := db.Where("name is not null").Delete(&model.JobLease{}).Error; err != nil {
+		logrus.Fatalf("Failed clear job leases : %+v", err)
+	}
+}
+
+func ClearRateLimits(db *gorm.DB) {
+	if err := db.Where("rate_key is not null").Delete(&model.RateLimit{}).Error; err != nil {
+		logrus.Fatalf("Failed clear rate limits : %+v", err)
+	}
+}
+
+func ClearIdempotencyKeys(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.IdempotencyKey{}).Error; err != nil {
+		logrus.Fatalf("Failed clear idempotency keys : %+v", err)
+	}
+}
+
+func ClearDataRequests(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.DataRequest{}).Error; err != nil {
+		logrus.Fatalf("Failed clear data requests : %+v", err)
+	}
+}
+
 func CreateUser(db *gorm.DB, email, password, name string) {
 	hashedPassword, err := utils.HashPassword(password)
 	if err != nil {
@@ -74,7 +102,7 @@ func SaveToken(db *gorm.DB, token, userID, tokenType string, expires time.Time)
 	}
 
 	tokenDoc := &model.Token{
-		Token:   token,
+		Token:   utils.HashToken(token),
 		UserID:  uuid.MustParse(userID),
 		Type:    tokenType,
 		Expires: expires,
@@ -94,7 +122,7 @@ func DeleteToken(db *gorm.DB, tokenType, userID string) error {
 }
 
 func GenerateToken(
-	userID string, expires time.Time, tokenType string,
+	secret, userID string, expires time.Time, tokenType string,
 ) (string, error) {
 	claims := jwt.MapClaims{
 		"sub":  userID,
@@ -104,7 +132,7 @@ func GenerateToken(
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	return token.SignedString([]byte(config.JWTSecret))
+	return token.SignedString([]byte(secret))
 }
 
 func GenerateInvalidToken(
@@ -121,14 +149,14 @@ func GenerateInvalidToken(
 	return token.SignedString([]byte("invalidSecret"))
 }
 
-func GetTokenByUserID(db *gorm.DB, tokenStr string) (*model.Token, error) {
-	userID, err := utils.VerifyToken(tokenStr, config.JWTSecret, config.TokenTypeRefresh)
+func GetTokenByUserID(db *gorm.DB, secret, tokenStr string) (*model.Token, error) {
+	userID, err := utils.VerifyToken(tokenStr, secret, config.TokenTypeRefresh)
 	if err != nil {
 		return nil, err
 	}
 
 	tokenDoc := new(model.Token)
-	result := db.Where("token = ? AND user_id = ?", tokenStr, userID).
+	result := db.Where("token = ? AND user_id = ?", utils.HashToken(tokenStr), userID).
 		First(tokenDoc)
 
 	if result.Error != nil {
@@ -165,3 +193,25 @@ func GetUserByID(db *gorm.DB, id string) (*model.User, error) {
 
 	return user, result.Error
 }
+
+func SoftDeleteUser(db *gorm.DB, id string) error {
+	return db.Delete(&model.User{}, "id = ?", id).Error
+}
+
+func GetDeletedUserByID(db *gorm.DB, id string) (*model.User, error) {
+	user := new(model.User)
+
+	result := db.Unscoped().Where("deleted_at IS NOT NULL").First(user, "id = ?", id)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return user, nil
+}
+
+func ClearUserImports(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.UserImport{}).Error; err != nil {
+		logrus.Fatalf("Failed clear user imports : %+v", err)
+	}
+}
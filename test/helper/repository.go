@@ -0,0 +1,45 @@
+package helper
+
+import (
+	"app/src/model"
+	"app/src/repository"
+	"context"
+	"errors"
+)
+
+var ErrInjected = errors.New("injected failure")
+
+// FailingUserRepository fails the methods named in Fail, to test what is
+// left behind when a multi-step operation breaks half way
+type FailingUserRepository struct {
+	repository.UserRepository
+	Fail map[string]bool
+}
+
+func (r *FailingUserRepository) Update(
+	ctx context.Context, id string, fields *model.User, version int64,
+) (*model.User, error) {
+	if r.Fail["Update"] {
+		return nil, ErrInjected
+	}
+	return r.UserRepository.Update(ctx, id, fields, version)
+}
+
+type FailingTokenRepository struct {
+	repository.TokenRepository
+	Fail map[string]bool
+}
+
+func (r *FailingTokenRepository) Create(ctx context.Context, token *model.Token) error {
+	if r.Fail["Create"] {
+		return ErrInjected
+	}
+	return r.TokenRepository.Create(ctx, token)
+}
+
+func (r *FailingTokenRepository) DeleteByType(ctx context.Context, tokenType, userID string) error {
+	if r.Fail["DeleteByType"] {
+		return ErrInjected
+	}
+	return r.TokenRepository.DeleteByType(ctx, tokenType, userID)
+}
@@ -0,0 +1,86 @@
+package helper
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// SMTPServer is a minimal SMTP server keeping the messages it receives
+type SMTPServer struct {
+	Addr *net.TCPAddr
+
+	mu       sync.Mutex
+	messages []string
+}
+
+// StartSMTPServer listens on a random local port until the test ends
+func StartSMTPServer(t *testing.T) *SMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start SMTP server: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &SMTPServer{Addr: listener.Addr().(*net.TCPAddr)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	return server
+}
+
+// Messages returns the raw messages received so far
+func (s *SMTPServer) Messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.messages...)
+}
+
+func (s *SMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	_ = text.PrintfLine("220 localhost ESMTP")
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		switch command := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			_ = text.PrintfLine("250 localhost")
+		case command == "DATA":
+			_ = text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+
+			data, err := textproto.NewReader(reader).ReadDotLines()
+			if err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			s.messages = append(s.messages, strings.Join(data, "\n"))
+			s.mu.Unlock()
+
+			_ = text.PrintfLine("250 OK")
+		case command == "QUIT":
+			_ = text.PrintfLine("221 Bye")
+			return
+		default:
+			_ = text.PrintfLine("250 OK")
+		}
+	}
+}
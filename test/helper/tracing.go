@@ -0,0 +1,36 @@
+package helper
+
+import (
+	"app/src/config"
+	"app/src/tracing"
+	"sync"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	spans     = tracetest.NewInMemoryExporter()
+	spansOnce sync.Once
+)
+
+// RecordSpans makes the global tracer provider export to memory and returns
+// the exporter, emptied. Spans are exported as soon as they end.
+func RecordSpans() *tracetest.InMemoryExporter {
+	spansOnce.Do(func() {
+		tracing.Install(config.Tracing{ServiceName: "test", SampleRatio: 1}, sdktrace.NewSimpleSpanProcessor(spans))
+	})
+	spans.Reset()
+
+	return spans
+}
+
+// FindSpan returns the first span with the given name
+func FindSpan(stubs tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range stubs {
+		if stubs[i].Name == name {
+			return &stubs[i]
+		}
+	}
+	return nil
+}
@@ -1,9 +1,15 @@
 package test
 
 import (
+	"app/src/config"
 	"app/src/database"
+	"app/src/middleware"
+	"app/src/ratelimit"
 	"app/src/router"
+	"app/src/storage"
 	"app/src/utils"
+	"math"
+	"os"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -13,12 +19,70 @@ var App = fiber.New(fiber.Config{
 	CaseSensitive: true,
 	ErrorHandler:  utils.ErrorHandler,
 })
+var Config = loadConfig()
+
+// Live holds Config for the services, it isn't reloaded in tests
+var Live = config.NewLive(Config, nil)
 var DB *gorm.DB
+
+// Blobs keeps the uploads of the tests in a temporary dir
+var Blobs storage.Store
 var Log = utils.Log
 
+// loadConfig reads the environment and the .env file of the project, pointed
+// to the test database
+func loadConfig() *config.Config {
+	cfg, err := config.Read(nil, "../../.env")
+	if err != nil {
+		Log.Fatalf("Failed to read test config: %+v", err)
+	}
+
+	// TODO: You can modify host and database configuration for tests
+	// The driver is taken from DB_DRIVER, e.g. `DB_DRIVER=sqlite make tests`
+	cfg.DB.Host = "localhost"
+	cfg.DB.Name = "testdb"
+	if cfg.DB.Driver == database.DriverSQLite {
+		cfg.DB.Name = "file:testdb?mode=memory&cache=shared"
+	}
+	cfg.DB.ReplicaHosts = nil
+
+	// The tests send many failing requests from the same client, the rate limits are tested on their own
+	for name, policy := range cfg.RateLimit.Policies {
+		policy.Max = math.MaxInt32
+		cfg.RateLimit.Policies[name] = policy
+	}
+
+	// Files of more than 3 rows are imported by the job, so the tests cover it without large files
+	cfg.Import.SyncRows = 3
+
+	if err := cfg.Validate(); err != nil {
+		Log.Fatalf("Invalid test config: %+v", err)
+	}
+	utils.ConfigureLog(cfg.Log.Format, cfg.Log.Level)
+
+	return cfg
+}
+
 func init() {
-	// TODO: You can modify host and database configuration for tests
-	DB = database.Connect("localhost", "testdb")
-	router.Routes(App, DB)
+	DB = database.Connect(Config.DB)
+	if err := database.Migrate(DB, Config.DB.Driver); err != nil {
+		Log.Fatalf("Failed to migrate test database: %+v", err)
+	}
+
+	App.Use(middleware.RequestID())
+	App.Use(middleware.Tracing())
+	App.Use(middleware.Metrics())
+	App.Use(middleware.LoggerConfig())
+	App.Use(middleware.Language())
+	dir, err := os.MkdirTemp("", "uploads")
+	if err != nil {
+		Log.Fatalf("Failed to create the test storage dir: %+v", err)
+	}
+	Blobs, err = storage.NewFileStore(dir)
+	if err != nil {
+		Log.Fatalf("Failed to set up the test storage: %+v", err)
+	}
+
+	router.Routes(App, DB, Live, ratelimit.NewMemoryStore(), Blobs)
 	App.Use(utils.NotFoundHandler)
 }
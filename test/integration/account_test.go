@@ -0,0 +1,420 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/response"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// accountUser inserts a user whose password is password1
+func accountUser(t *testing.T) (*model.User, string) {
+	t.Helper()
+
+	user := &model.User{Name: "Test1", Email: "test1@gmail.com", Password: "password1", Role: "user"}
+	helper.InsertUser(test.DB, user)
+
+	accessToken, err := fixture.AccessToken(user)
+	assert.Nil(t, err)
+
+	return user, accessToken
+}
+
+// googleAccountUser is created like a Google login does, without a password
+func googleAccountUser(t *testing.T) (*model.User, string) {
+	t.Helper()
+
+	user := &model.User{Name: "Test1", Email: "test1@gmail.com", Role: "user", VerifiedEmail: true}
+	assert.Nil(t, test.DB.Create(user).Error)
+
+	accessToken, err := fixture.AccessToken(user)
+	assert.Nil(t, err)
+
+	return user, accessToken
+}
+
+// reauthToken stores a reauth token of the user like POST /v1/me/reauth does
+func reauthToken(t *testing.T, user *model.User) string {
+	t.Helper()
+
+	expires := time.Now().UTC().Add(test.Config.JWT.VerifyEmailExp)
+	token, err := helper.GenerateToken(test.Config.JWT.Secret, user.ID.String(), expires, config.TokenTypeReauth)
+	assert.Nil(t, err)
+	assert.Nil(t, helper.SaveToken(test.DB, token, user.ID.String(), config.TokenTypeReauth, expires))
+
+	return token
+}
+
+func sendAccount(t *testing.T, method, path, token, body string, headers ...string) (*http.Response, []byte) {
+	t.Helper()
+
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		request.Header.Set(headers[i], headers[i+1])
+	}
+
+	apiResponse, err := test.App.Test(request, 10000)
+	assert.Nil(t, err)
+
+	bytes, err := io.ReadAll(apiResponse.Body)
+	assert.Nil(t, err)
+
+	return apiResponse, bytes
+}
+
+func TestAccountRoutes(t *testing.T) {
+	t.Run("GET /v1/me", func(t *testing.T) {
+		t.Run("should return 200 and the signed in user with its ETag", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			user, accessToken := accountUser(t)
+
+			apiResponse, body := sendAccount(t, http.MethodGet, "/v1/me", accessToken, "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, `"1"`, apiResponse.Header.Get("ETag"))
+
+			responseBody := new(response.SuccessWithUser)
+			assert.Nil(t, json.Unmarshal(body, responseBody))
+			assert.Equal(t, user.ID, responseBody.User.ID)
+			assert.Equal(t, user.Email, responseBody.User.Email)
+			assert.NotContains(t, string(body), "password")
+
+			apiResponse, _ = sendAccount(t, http.MethodGet, "/v1/me", accessToken, "", "If-None-Match", `"1"`)
+			assert.Equal(t, http.StatusNotModified, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 error if access token is missing", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+
+			apiResponse, _ := sendAccount(t, http.MethodGet, "/v1/me", "", "")
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("PATCH /v1/me", func(t *testing.T) {
+		t.Run("should update the name and the language only", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			user, accessToken := accountUser(t)
+
+			apiResponse, body := sendAccount(t, http.MethodPatch, "/v1/me", accessToken,
+				`{"name":"New name","language":"id","email":"other@gmail.com","role":"admin"}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, `"2"`, apiResponse.Header.Get("ETag"))
+
+			responseBody := new(response.SuccessWithUser)
+			assert.Nil(t, json.Unmarshal(body, responseBody))
+			assert.Equal(t, "New name", responseBody.User.Name)
+
+			stored, err := helper.GetUserByID(test.DB, user.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, "New name", stored.Name)
+			assert.Equal(t, "id", stored.Language)
+			assert.Equal(t, "test1@gmail.com", stored.Email)
+			assert.Equal(t, "user", stored.Role)
+		})
+
+		t.Run("should return 412 error if If-Match is not the current version", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			_, accessToken := accountUser(t)
+
+			apiResponse, _ := sendAccount(t, http.MethodPatch, "/v1/me", accessToken, `{"name":"New name"}`,
+				"If-Match", `"2"`)
+			assert.Equal(t, http.StatusPreconditionFailed, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/me/password", func(t *testing.T) {
+		t.Run("should change the password and sign out the other sessions", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			user, accessToken := accountUser(t)
+
+			// Expires earlier than the new token, or both would be the same JWT
+			expires := fixture.ExpiresRefreshToken.Add(-time.Hour)
+			oldRefreshToken, err := helper.GenerateToken(test.Config.JWT.Secret, user.ID.String(), expires,
+				config.TokenTypeRefresh)
+			assert.Nil(t, err)
+			assert.Nil(t, helper.SaveToken(test.DB, oldRefreshToken, user.ID.String(), config.TokenTypeRefresh, expires))
+
+			apiResponse, body := sendAccount(t, http.MethodPost, "/v1/me/password", accessToken,
+				`{"current_password":"password1","new_password":"password2"}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			responseBody := new(response.SuccessWithTokens)
+			assert.Nil(t, json.Unmarshal(body, responseBody))
+			assert.NotEmpty(t, responseBody.Tokens.Refresh.Token)
+
+			apiResponse, _ = sendAccount(t, http.MethodPost, "/v1/auth/refresh-tokens", "",
+				`{"refresh_token":"`+oldRefreshToken+`"}`)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+
+			apiResponse, _ = sendAccount(t, http.MethodPost, "/v1/auth/refresh-tokens", "",
+				`{"refresh_token":"`+responseBody.Tokens.Refresh.Token+`"}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse, _ = sendAccount(t, http.MethodPost, "/v1/auth/login", "",
+				`{"email":"test1@gmail.com","password":"password2"}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error if the current password is wrong", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			user, accessToken := accountUser(t)
+
+			apiResponse, body := sendAccount(t, http.MethodPost, "/v1/me/password", accessToken,
+				`{"current_password":"wrongPassword1","new_password":"password2"}`)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+			assert.Contains(t, string(body), "current-password-invalid")
+
+			stored, err := helper.GetUserByID(test.DB, user.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, user.Password, stored.Password)
+		})
+
+		t.Run("should return 400 error if the new password is invalid", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			_, accessToken := accountUser(t)
+
+			apiResponse, _ := sendAccount(t, http.MethodPost, "/v1/me/password", accessToken,
+				`{"current_password":"password1","new_password":"short"}`)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/me/email", func(t *testing.T) {
+		t.Run("should change the email, which must be verified again", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			user, accessToken := accountUser(t)
+			assert.Nil(t, test.DB.Model(user).Update("verified_email", true).Error)
+
+			apiResponse, body := sendAccount(t, http.MethodPost, "/v1/me/email", accessToken,
+				`{"email":"new@gmail.com","current_password":"password1"}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, `"2"`, apiResponse.Header.Get("ETag"))
+
+			responseBody := new(response.SuccessWithUser)
+			assert.Nil(t, json.Unmarshal(body, responseBody))
+			assert.Equal(t, "new@gmail.com", responseBody.User.Email)
+			assert.False(t, responseBody.User.VerifiedEmail)
+
+			verifyEmailToken, err := helper.GetTokenByType(test.DB, user.ID.String(), config.TokenTypeVerifyEmail)
+			assert.Nil(t, err)
+			assert.NotNil(t, verifyEmailToken)
+		})
+
+		t.Run("should return 409 error if the email is taken", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			_, accessToken := accountUser(t)
+			helper.CreateUser(test.DB, "taken@gmail.com", "password1", "Taken")
+
+			apiResponse, _ := sendAccount(t, http.MethodPost, "/v1/me/email", accessToken,
+				`{"email":"taken@gmail.com","current_password":"password1"}`)
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error if the current password is missing", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			user, accessToken := accountUser(t)
+
+			apiResponse, _ := sendAccount(t, http.MethodPost, "/v1/me/email", accessToken, `{"email":"new@gmail.com"}`)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			stored, err := helper.GetUserByID(test.DB, user.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, "test1@gmail.com", stored.Email)
+		})
+
+		t.Run("should require a reauth token from an account created with Google", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			user, accessToken := googleAccountUser(t)
+			other := &model.User{Name: "Test2", Email: "test2@gmail.com", Password: "password1", Role: "user"}
+			helper.InsertUser(test.DB, other)
+
+			apiResponse, body := sendAccount(t, http.MethodPost, "/v1/me/email", accessToken, `{"email":"new@gmail.com"}`)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+			assert.Contains(t, string(body), "reauth-required")
+
+			apiResponse, _ = sendAccount(t, http.MethodPost, "/v1/me/email", accessToken,
+				`{"email":"new@gmail.com","reauth_token":"`+reauthToken(t, other)+`"}`)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			stored, err := helper.GetUserByID(test.DB, user.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, "test1@gmail.com", stored.Email)
+
+			token := reauthToken(t, user)
+			apiResponse, _ = sendAccount(t, http.MethodPost, "/v1/me/email", accessToken,
+				`{"email":"new@gmail.com","reauth_token":"`+token+`"}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			// The token confirms a single change
+			apiResponse, _ = sendAccount(t, http.MethodPost, "/v1/me/email", accessToken,
+				`{"email":"newer@gmail.com","reauth_token":"`+token+`"}`)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			stored, err = helper.GetUserByID(test.DB, user.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, "new@gmail.com", stored.Email)
+		})
+	})
+
+	t.Run("POST /v1/me/reauth", func(t *testing.T) {
+		t.Run("should store a reauth token and email it", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			user, accessToken := googleAccountUser(t)
+
+			apiResponse, _ := sendAccount(t, http.MethodPost, "/v1/me/reauth", accessToken, "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			token, err := helper.GetTokenByType(test.DB, user.ID.String(), config.TokenTypeReauth)
+			assert.Nil(t, err)
+			assert.NotNil(t, token)
+		})
+	})
+
+	t.Run("DELETE /v1/me", func(t *testing.T) {
+		t.Run("should delete the account and its tokens once confirmed", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			user, accessToken := accountUser(t)
+
+			refreshToken, err := fixture.RefreshToken(user)
+			assert.Nil(t, err)
+			assert.Nil(t, helper.SaveToken(test.DB, refreshToken, user.ID.String(), config.TokenTypeRefresh,
+				fixture.ExpiresRefreshToken))
+
+			apiResponse, _ := sendAccount(t, http.MethodDelete, "/v1/me", accessToken,
+				`{"confirm":"TEST1@gmail.com","current_password":"password1"}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			_, err = helper.GetUserByID(test.DB, user.ID.String())
+			assert.NotNil(t, err)
+			deleted, err := helper.GetDeletedUserByID(test.DB, user.ID.String())
+			assert.Nil(t, err)
+			assert.NotNil(t, deleted)
+
+			tokenDoc, _ := helper.GetTokenByType(test.DB, user.ID.String(), config.TokenTypeRefresh)
+			assert.Nil(t, tokenDoc)
+
+			apiResponse, _ = sendAccount(t, http.MethodGet, "/v1/me", accessToken, "")
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 422 error if confirm isn't the email of the account", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			user, accessToken := accountUser(t)
+
+			apiResponse, body := sendAccount(t, http.MethodDelete, "/v1/me", accessToken,
+				`{"confirm":"other@gmail.com","current_password":"password1"}`)
+			assert.Equal(t, http.StatusUnprocessableEntity, apiResponse.StatusCode)
+			assert.Contains(t, string(body), "confirmation-mismatch")
+
+			_, err := helper.GetUserByID(test.DB, user.ID.String())
+			assert.Nil(t, err)
+		})
+
+		t.Run("should return 403 error if the current password is wrong", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			user, accessToken := accountUser(t)
+
+			apiResponse, _ := sendAccount(t, http.MethodDelete, "/v1/me", accessToken,
+				`{"confirm":"test1@gmail.com","current_password":"wrongPassword1"}`)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			_, err := helper.GetUserByID(test.DB, user.ID.String())
+			assert.Nil(t, err)
+		})
+	})
+
+	t.Run("DELETE /v1/me of an account created with Google", func(t *testing.T) {
+		t.Run("should delete the account with a reauth token only", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			user, accessToken := googleAccountUser(t)
+
+			apiResponse, _ := sendAccount(t, http.MethodDelete, "/v1/me", accessToken, `{"confirm":"test1@gmail.com"}`)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			_, err := helper.GetUserByID(test.DB, user.ID.String())
+			assert.Nil(t, err)
+
+			apiResponse, _ = sendAccount(t, http.MethodDelete, "/v1/me", accessToken,
+				`{"confirm":"test1@gmail.com","reauth_token":"`+reauthToken(t, user)+`"}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			_, err = helper.GetUserByID(test.DB, user.ID.String())
+			assert.NotNil(t, err)
+		})
+	})
+
+	t.Run("GET /v1/me/sessions", func(t *testing.T) {
+		t.Run("should list the sessions which haven't expired", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			_, _ = accountUser(t)
+
+			var logins []*response.SuccessWithTokens
+			for i := 0; i < 2; i++ {
+				apiResponse, body := sendAccount(t, http.MethodPost, "/v1/auth/login", "",
+					`{"email":"test1@gmail.com","password":"password1"}`)
+				assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+				login := new(response.SuccessWithTokens)
+				assert.Nil(t, json.Unmarshal(body, login))
+				logins = append(logins, login)
+			}
+
+			apiResponse, body := sendAccount(t, http.MethodGet, "/v1/me/sessions", logins[0].Tokens.Access.Token, "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			responseBody := new(response.SuccessWithSessions)
+			assert.Nil(t, json.Unmarshal(body, responseBody))
+			assert.Len(t, responseBody.Results, 2)
+			assert.WithinDuration(t, logins[1].Tokens.Refresh.Expires, responseBody.Results[0].ExpiresAt, time.Second)
+			for _, login := range logins {
+				assert.NotContains(t, string(body), login.Tokens.Refresh.Token)
+			}
+		})
+
+		t.Run("should keep the other sessions when one logs out", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			_, accessToken := accountUser(t)
+
+			var refreshTokens []string
+			for i := 0; i < 2; i++ {
+				apiResponse, body := sendAccount(t, http.MethodPost, "/v1/auth/login", "",
+					`{"email":"test1@gmail.com","password":"password1"}`)
+				assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+				login := new(response.SuccessWithTokens)
+				assert.Nil(t, json.Unmarshal(body, login))
+				refreshTokens = append(refreshTokens, login.Tokens.Refresh.Token)
+			}
+
+			apiResponse, _ := sendAccount(t, http.MethodPost, "/v1/auth/logout", "",
+				`{"refresh_token":"`+refreshTokens[0]+`"}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse, body := sendAccount(t, http.MethodGet, "/v1/me/sessions", accessToken, "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			responseBody := new(response.SuccessWithSessions)
+			assert.Nil(t, json.Unmarshal(body, responseBody))
+			assert.Len(t, responseBody.Results, 1)
+
+			apiResponse, _ = sendAccount(t, http.MethodPost, "/v1/auth/refresh-tokens", "",
+				`{"refresh_token":"`+refreshTokens[1]+`"}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+	})
+}
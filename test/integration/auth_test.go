@@ -2,6 +2,7 @@ package integration
 
 import (
 	"app/src/config"
+	"app/src/model"
 	"app/src/response"
 	"app/src/utils"
 	"app/src/validation"
@@ -106,6 +107,29 @@ func TestAuthRoutes(t *testing.T) {
 			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
 		})
 
+		t.Run("should return 409 error if email belongs to a deleted user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.CreateUser(test.DB, "test@gmail.com", "test1234", "Test")
+			assert.Nil(t, test.DB.Where("email = ?", "test@gmail.com").Delete(&model.User{}).Error)
+			requestBody.Email = "test@gmail.com"
+
+			bodyJSON, err := json.Marshal(requestBody)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/register", strings.NewReader(string(bodyJSON)))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Accept", "application/json")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			responseBody := make(map[string]interface{})
+			assert.Nil(t, json.NewDecoder(apiResponse.Body).Decode(&responseBody))
+
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+			assert.Equal(t, "email-taken", responseBody["code"])
+		})
+
 		t.Run("should return 400 error if password length is less than 8 characters", func(t *testing.T) {
 			helper.ClearAll(test.DB)
 			requestBody.Password = "passwo1"
@@ -189,6 +213,12 @@ func TestAuthRoutes(t *testing.T) {
 			assert.Equal(t, false, responseBody.User.VerifiedEmail)
 			assert.NotNil(t, responseBody.Tokens.Access.Token)
 			assert.NotNil(t, responseBody.Tokens.Refresh.Token)
+
+			// Only the hash of the refresh token is stored
+			dbRefreshTokenDoc, err := helper.GetTokenByType(test.DB, responseBody.User.ID.String(), config.TokenTypeRefresh)
+			assert.Nil(t, err)
+			assert.NotEqual(t, responseBody.Tokens.Refresh.Token, dbRefreshTokenDoc.Token)
+			assert.Equal(t, utils.HashToken(responseBody.Tokens.Refresh.Token), dbRefreshTokenDoc.Token)
 		})
 
 		t.Run("should return 401 error if there are no users with that email", func(t *testing.T) {
@@ -217,8 +247,32 @@ func TestAuthRoutes(t *testing.T) {
 			assert.Nil(t, err)
 
 			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
-			assert.Equal(t, "error", responseBody["status"])
-			assert.Equal(t, "Invalid email or password", responseBody["message"])
+			assert.Equal(t, "application/problem+json", apiResponse.Header.Get("Content-Type"))
+			assert.Equal(t, "invalid-credentials", responseBody["code"])
+			assert.Equal(t, "Invalid email or password", responseBody["title"])
+		})
+
+		t.Run("should return 401 error if the user is deleted", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.CreateUser(test.DB, "deleted@gmail.com", "test1234", "Deleted User")
+			assert.Nil(t, test.DB.Where("email = ?", "deleted@gmail.com").Delete(&model.User{}).Error)
+
+			loginCredentials := &validation.Login{
+				Email:    "deleted@gmail.com",
+				Password: "test1234",
+			}
+
+			bodyJSON, err := json.Marshal(loginCredentials)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/login", strings.NewReader(string(bodyJSON)))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Accept", "application/json")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
 		})
 
 		t.Run("should return 401 error if password is wrong", func(t *testing.T) {
@@ -247,8 +301,9 @@ func TestAuthRoutes(t *testing.T) {
 			assert.Nil(t, err)
 
 			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
-			assert.Equal(t, "error", responseBody["status"])
-			assert.Equal(t, "Invalid email or password", responseBody["message"])
+			assert.Equal(t, "application/problem+json", apiResponse.Header.Get("Content-Type"))
+			assert.Equal(t, "invalid-credentials", responseBody["code"])
+			assert.Equal(t, "Invalid email or password", responseBody["title"])
 		})
 	})
 	t.Run("POST /v1/auth/logout", func(t *testing.T) {
@@ -273,7 +328,7 @@ func TestAuthRoutes(t *testing.T) {
 			assert.Nil(t, err)
 			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
 
-			dbRefreshTokenDoc, _ := helper.GetTokenByUserID(test.DB, refreshToken)
+			dbRefreshTokenDoc, _ := helper.GetTokenByUserID(test.DB, test.Config.JWT.Secret, refreshToken)
 			assert.Nil(t, dbRefreshTokenDoc)
 		})
 
@@ -340,7 +395,7 @@ func TestAuthRoutes(t *testing.T) {
 			assert.NotNil(t, responseBody.Tokens.Access.Token)
 			assert.NotNil(t, responseBody.Tokens.Refresh.Token)
 
-			dbRefreshTokenDoc, err := helper.GetTokenByUserID(test.DB, responseBody.Tokens.Refresh.Token)
+			dbRefreshTokenDoc, err := helper.GetTokenByUserID(test.DB, test.Config.JWT.Secret, responseBody.Tokens.Refresh.Token)
 			assert.Nil(t, err)
 
 			assert.Equal(t, dbRefreshTokenDoc.UserID, fixture.UserOne.ID)
@@ -406,7 +461,7 @@ func TestAuthRoutes(t *testing.T) {
 			helper.InsertUser(test.DB, fixture.UserOne)
 
 			expires := time.Now().Add(time.Second * 1)
-			refreshToken, err := helper.GenerateToken(fixture.UserOne.ID.String(), expires, config.TokenTypeRefresh)
+			refreshToken, err := helper.GenerateToken(test.Config.JWT.Secret, fixture.UserOne.ID.String(), expires, config.TokenTypeRefresh)
 			assert.Nil(t, err)
 
 			err = helper.SaveToken(test.DB, refreshToken, fixture.UserOne.ID.String(), config.TokenTypeRefresh, fixture.ExpiresRefreshToken)
@@ -520,10 +575,37 @@ func TestAuthRoutes(t *testing.T) {
 			isPasswordMatch := utils.CheckPasswordHash("password2", user.Password)
 			assert.True(t, isPasswordMatch)
 
-			dbResetPasswordTokenDoc, _ := helper.GetTokenByUserID(test.DB, resetPasswordToken)
+			dbResetPasswordTokenDoc, _ := helper.GetTokenByUserID(test.DB, test.Config.JWT.Secret, resetPasswordToken)
 			assert.Nil(t, dbResetPasswordTokenDoc)
 		})
 
+		t.Run("should return 401 if reset password token was already used", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			resetPasswordToken, err := fixture.ResetPasswordToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			err = helper.SaveToken(test.DB, resetPasswordToken, fixture.UserOne.ID.String(),
+				config.TokenTypeResetPassword, fixture.ExpiresResetPasswordToken)
+			assert.Nil(t, err)
+
+			for _, expected := range []int{http.StatusOK, http.StatusUnauthorized} {
+				bodyJSON, err := json.Marshal(validation.UpdatePassOrVerify{Password: "password2"})
+				assert.Nil(t, err)
+
+				request := httptest.NewRequest(http.MethodPost, "/v1/auth/reset-password?token="+resetPasswordToken,
+					strings.NewReader(string(bodyJSON)))
+				request.Header.Set("Content-Type", "application/json")
+				request.Header.Set("Accept", "application/json")
+
+				apiResponse, err := test.App.Test(request)
+				assert.Nil(t, err)
+
+				assert.Equal(t, expected, apiResponse.StatusCode)
+			}
+		})
+
 		t.Run("should return 400 if reset password token is missing", func(t *testing.T) {
 			helper.ClearAll(test.DB)
 			helper.InsertUser(test.DB, fixture.UserOne)
@@ -550,7 +632,7 @@ func TestAuthRoutes(t *testing.T) {
 			helper.InsertUser(test.DB, fixture.UserOne)
 
 			expires := time.Now().Add(time.Second * 1)
-			resetPasswordToken, err := helper.GenerateToken(fixture.UserOne.ID.String(), expires, config.TokenTypeResetPassword)
+			resetPasswordToken, err := helper.GenerateToken(test.Config.JWT.Secret, fixture.UserOne.ID.String(), expires, config.TokenTypeResetPassword)
 			assert.Nil(t, err)
 
 			err = helper.SaveToken(test.DB, resetPasswordToken, fixture.UserOne.ID.String(), config.TokenTypeResetPassword, fixture.ExpiresResetPasswordToken)
@@ -690,7 +772,7 @@ func TestAuthRoutes(t *testing.T) {
 
 			assert.True(t, user.VerifiedEmail)
 
-			dbVerifyEmailTokenDoc, _ := helper.GetTokenByUserID(test.DB, verifyEmailToken)
+			dbVerifyEmailTokenDoc, _ := helper.GetTokenByUserID(test.DB, test.Config.JWT.Secret, verifyEmailToken)
 			assert.Nil(t, dbVerifyEmailTokenDoc)
 		})
 
@@ -713,7 +795,7 @@ func TestAuthRoutes(t *testing.T) {
 			helper.InsertUser(test.DB, fixture.UserOne)
 
 			expires := time.Now().Add(time.Second * 1)
-			verifyEmailToken, err := helper.GenerateToken(fixture.UserOne.ID.String(), expires, config.TokenTypeVerifyEmail)
+			verifyEmailToken, err := helper.GenerateToken(test.Config.JWT.Secret, fixture.UserOne.ID.String(), expires, config.TokenTypeVerifyEmail)
 			assert.Nil(t, err)
 
 			err = helper.SaveToken(test.DB, verifyEmailToken, fixture.UserOne.ID.String(), config.TokenTypeVerifyEmail, fixture.ExpiresVerifyEmailToken)
@@ -744,7 +826,7 @@ func TestAuthMiddleware(t *testing.T) {
 		request := httptest.NewRequest(http.MethodGet, "/v1/users", nil)
 		request.Header.Set("Authorization", "Bearer "+token)
 
-		userID, err := utils.VerifyToken(token, config.JWTSecret, config.TokenTypeAccess)
+		userID, err := utils.VerifyToken(token, test.Config.JWT.Secret, config.TokenTypeAccess)
 		assert.Nil(t, err)
 
 		assert.Equal(t, fixture.UserOne.ID.String(), userID)
@@ -805,7 +887,7 @@ func TestAuthMiddleware(t *testing.T) {
 		helper.InsertUser(test.DB, fixture.UserOne)
 
 		expires := time.Now().Add(time.Second * 1)
-		accessToken, err := helper.GenerateToken(fixture.UserOne.ID.String(), expires, config.TokenTypeAccess)
+		accessToken, err := helper.GenerateToken(test.Config.JWT.Secret, fixture.UserOne.ID.String(), expires, config.TokenTypeAccess)
 		assert.Nil(t, err)
 
 		time.Sleep(2 * time.Second)
@@ -0,0 +1,272 @@
+package integration
+
+import (
+	"app/src/response"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pngPicture(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, png.Encode(&buf, img))
+
+	return buf.Bytes()
+}
+
+func uploadAvatar(t *testing.T, userID, token, ifMatch string, picture []byte) (*http.Response, []byte) {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if picture != nil {
+		part, err := form.CreateFormFile("avatar", "avatar.png")
+		assert.Nil(t, err)
+		_, err = part.Write(picture)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, form.Close())
+
+	request := httptest.NewRequest(http.MethodPut, "/v1/users/"+userID+"/avatar", &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	request.Header.Set("Authorization", "Bearer "+token)
+	if ifMatch != "" {
+		request.Header.Set("If-Match", ifMatch)
+	}
+
+	apiResponse, err := test.App.Test(request, -1)
+	assert.Nil(t, err)
+
+	bytes, err := io.ReadAll(apiResponse.Body)
+	assert.Nil(t, err)
+
+	return apiResponse, bytes
+}
+
+func getAvatar(t *testing.T, path string) (*http.Response, []byte) {
+	t.Helper()
+
+	apiResponse, err := test.App.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Nil(t, err)
+
+	bytes, err := io.ReadAll(apiResponse.Body)
+	assert.Nil(t, err)
+
+	return apiResponse, bytes
+}
+
+func TestAvatarRoutes(t *testing.T) {
+	t.Run("PUT /v1/users/:userId/avatar", func(t *testing.T) {
+		t.Run("should return 200 and serve the thumbnails of the avatar", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse, body := uploadAvatar(t, fixture.UserOne.ID.String(), userOneAccessToken, "", pngPicture(t, 400, 300))
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, `"2"`, apiResponse.Header.Get("ETag"))
+
+			responseBody := new(response.SuccessWithUser)
+			assert.Nil(t, json.Unmarshal(body, responseBody))
+			assert.Equal(t, "Update avatar successfully", responseBody.Message)
+			assert.Regexp(t, `^/v1/avatars/[0-9a-f-]{36}\.jpg$`, responseBody.User.AvatarURL)
+
+			user, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, responseBody.User.AvatarURL, user.AvatarURL)
+
+			apiResponse, picture := getAvatar(t, responseBody.User.AvatarURL)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, "image/jpeg", apiResponse.Header.Get("Content-Type"))
+			assert.Contains(t, apiResponse.Header.Get("Cache-Control"), "immutable")
+
+			config, format, err := image.DecodeConfig(bytes.NewReader(picture))
+			assert.Nil(t, err)
+			assert.Equal(t, "jpeg", format)
+			assert.Equal(t, 256, config.Width)
+			assert.Equal(t, 256, config.Height)
+
+			apiResponse, picture = getAvatar(t, responseBody.User.AvatarURL+"?size=64")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			config, _, err = image.DecodeConfig(bytes.NewReader(picture))
+			assert.Nil(t, err)
+			assert.Equal(t, 64, config.Width)
+
+			apiResponse, _ = getAvatar(t, responseBody.User.AvatarURL+"?size=100")
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+
+		t.Run("should delete the thumbnails of the previous avatar", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			_, body := uploadAvatar(t, fixture.UserOne.ID.String(), userOneAccessToken, "", pngPicture(t, 64, 64))
+			first := new(response.SuccessWithUser)
+			assert.Nil(t, json.Unmarshal(body, first))
+
+			apiResponse, body := uploadAvatar(t, fixture.UserOne.ID.String(), userOneAccessToken, "", pngPicture(t, 64, 64))
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			second := new(response.SuccessWithUser)
+			assert.Nil(t, json.Unmarshal(body, second))
+			assert.NotEqual(t, first.User.AvatarURL, second.User.AvatarURL)
+
+			apiResponse, _ = getAvatar(t, first.User.AvatarURL)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+			apiResponse, _ = getAvatar(t, second.User.AvatarURL)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 415 error if the file isn't a picture", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse, body := uploadAvatar(t, fixture.UserOne.ID.String(), userOneAccessToken, "",
+				[]byte("<html><body>not a picture</body></html>"))
+			assert.Equal(t, http.StatusUnsupportedMediaType, apiResponse.StatusCode)
+			assert.Contains(t, string(body), "avatar-unsupported-type")
+		})
+
+		t.Run("should return 422 error if the picture is corrupted", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			picture := pngPicture(t, 64, 64)
+			apiResponse, body := uploadAvatar(t, fixture.UserOne.ID.String(), userOneAccessToken, "", picture[:100])
+			assert.Equal(t, http.StatusUnprocessableEntity, apiResponse.StatusCode)
+			assert.Contains(t, string(body), "avatar-invalid")
+		})
+
+		t.Run("should return 413 error if the file is larger than AVATAR_MAX_SIZE_MB", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			picture := append(pngPicture(t, 64, 64), make([]byte, test.Config.Avatar.MaxSize)...)
+			apiResponse, body := uploadAvatar(t, fixture.UserOne.ID.String(), userOneAccessToken, "", picture)
+			assert.Equal(t, http.StatusRequestEntityTooLarge, apiResponse.StatusCode)
+			assert.Contains(t, string(body), "avatar-too-large")
+
+			user, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.Empty(t, user.Avatar)
+		})
+
+		t.Run("should return 400 error if the avatar file is missing", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse, _ := uploadAvatar(t, fixture.UserOne.ID.String(), userOneAccessToken, "", nil)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 412 error if If-Match is not the current version", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse, _ := uploadAvatar(t, fixture.UserOne.ID.String(), userOneAccessToken, `"2"`, pngPicture(t, 64, 64))
+			assert.Equal(t, http.StatusPreconditionFailed, apiResponse.StatusCode)
+
+			user, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.Empty(t, user.Avatar)
+		})
+
+		t.Run("should return 403 error if user is uploading the avatar of another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse, _ := uploadAvatar(t, fixture.UserTwo.ID.String(), userOneAccessToken, "", pngPicture(t, 64, 64))
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("DELETE /v1/users/:userId/avatar", func(t *testing.T) {
+		t.Run("should return 200 and delete the avatar", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			_, body := uploadAvatar(t, fixture.UserOne.ID.String(), userOneAccessToken, "", pngPicture(t, 64, 64))
+			uploaded := new(response.SuccessWithUser)
+			assert.Nil(t, json.Unmarshal(body, uploaded))
+
+			request := httptest.NewRequest(http.MethodDelete, "/v1/users/"+fixture.UserOne.ID.String()+"/avatar", nil)
+			request.Header.Set("Authorization", "Bearer "+userOneAccessToken)
+			request.Header.Set("If-Match", `"2"`)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, `"3"`, apiResponse.Header.Get("ETag"))
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+			assert.NotContains(t, string(bytes), "avatar_url")
+
+			apiResponse, _ = getAvatar(t, uploaded.User.AvatarURL)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+
+			request = httptest.NewRequest(http.MethodDelete, "/v1/users/"+fixture.UserOne.ID.String()+"/avatar", nil)
+			request.Header.Set("Authorization", "Bearer "+userOneAccessToken)
+
+			apiResponse, err = test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("GET /v1/avatars/:name", func(t *testing.T) {
+		t.Run("should return 404 error if the name isn't an avatar", func(t *testing.T) {
+			for _, name := range []string{"unknown.jpg", "..%2F..%2Fetc%2Fpasswd", strings.Repeat("a", 36) + ".jpg"} {
+				apiResponse, _ := getAvatar(t, "/v1/avatars/"+name)
+				assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode, name)
+			}
+		})
+	})
+}
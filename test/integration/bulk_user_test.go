@@ -0,0 +1,529 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/job"
+	"app/src/model"
+	"app/src/repository"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// importFile uploads the file to POST /v1/users/import, with the form fields given as name, value pairs
+func importFile(t *testing.T, token, filename, content string, fields ...string) (*http.Response, []byte) {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if filename != "" {
+		part, err := form.CreateFormFile("file", filename)
+		assert.Nil(t, err)
+		_, err = part.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		assert.Nil(t, form.WriteField(fields[i], fields[i+1]))
+	}
+	assert.Nil(t, form.Close())
+
+	request := httptest.NewRequest(http.MethodPost, "/v1/users/import", &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	apiResponse, err := test.App.Test(request, -1)
+	assert.Nil(t, err)
+
+	bytes, err := io.ReadAll(apiResponse.Body)
+	assert.Nil(t, err)
+
+	return apiResponse, bytes
+}
+
+func userImport(t *testing.T, body []byte) model.UserImport {
+	t.Helper()
+
+	responseBody := new(response.SuccessWithUserImport)
+	assert.Nil(t, json.Unmarshal(body, responseBody))
+
+	return responseBody.UserImport
+}
+
+// importUsers returns the import-users job, its emails are sent to the returned server
+func importUsers(t *testing.T) (func(ctx context.Context) error, *helper.SMTPServer) {
+	t.Helper()
+
+	return importUsersFrom(t, repository.NewUserImportRepository(test.DB))
+}
+
+// importUsersFrom returns the import-users job reading the imports from the given repository
+func importUsersFrom(
+	t *testing.T, imports repository.UserImportRepository,
+) (func(ctx context.Context) error, *helper.SMTPServer) {
+	t.Helper()
+
+	server := helper.StartSMTPServer(t)
+	cfg := *test.Config
+	cfg.SMTP.Host = server.Addr.IP.String()
+	cfg.SMTP.Port = server.Addr.Port
+	live := config.NewLive(&cfg, nil)
+
+	validate := validation.Validator()
+	users := repository.NewUserRepository(test.DB)
+	tokens := repository.NewTokenRepository(test.DB)
+	userService := service.NewUserService(users, validate)
+	bulkUserService := service.NewBulkUserService(
+		users, imports, validate, userService,
+		service.NewTokenService(tokens, repository.NewUnitOfWork(test.DB), validate, userService, live),
+		service.NewEmailService(live), test.Blobs, cfg.Import,
+	)
+
+	return job.ImportUsers(bulkUserService, 5), server
+}
+
+// listedTogether holds the imports listed by each instance until every instance listed them
+type listedTogether struct {
+	repository.UserImportRepository
+	listed *sync.WaitGroup
+}
+
+func (r listedTogether) ListPending(
+	ctx context.Context, staleBefore time.Time, limit int,
+) ([]model.UserImport, error) {
+	userImports, err := r.UserImportRepository.ListPending(ctx, staleBefore, limit)
+	r.listed.Done()
+	r.listed.Wait()
+
+	return userImports, err
+}
+
+func exportUsers(t *testing.T, token, query string) (*http.Response, []byte) {
+	t.Helper()
+
+	request := httptest.NewRequest(http.MethodGet, "/v1/users/export"+query, nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	apiResponse, err := test.App.Test(request, -1)
+	assert.Nil(t, err)
+
+	bytes, err := io.ReadAll(apiResponse.Body)
+	assert.Nil(t, err)
+
+	return apiResponse, bytes
+}
+
+func TestBulkUserRoutes(t *testing.T) {
+	t.Run("POST /v1/users/import", func(t *testing.T) {
+		t.Run("should create the valid rows and report every row of a CSV file", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearUserImports(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin, fixture.UserOne)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			file := "\xef\xbb\xbfName,Email,Password,Role,Team\n" +
+				"Jane,jane@gmail.com,password1,user,sales\n" +
+				"Bad,not-an-email,password1,user,sales\n" +
+				"Taken,test1@gmail.com,password1,admin,ops\n"
+
+			apiResponse, body := importFile(t, adminAccessToken, "users.csv", file)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			result := userImport(t, body)
+			assert.Equal(t, model.UserImportCompleted, result.Status)
+			assert.Equal(t, "csv", result.Format)
+			assert.Equal(t, fixture.Admin.ID, result.CreatedBy)
+			assert.Equal(t, 3, result.Total)
+			assert.Equal(t, 1, result.Created)
+			assert.Equal(t, 2, result.Failed)
+			assert.Len(t, result.Results, 3)
+
+			created := result.Results[0]
+			assert.Equal(t, 2, created.Line)
+			assert.Equal(t, model.UserImportRowCreated, created.Status)
+			assert.False(t, created.Invited)
+
+			user, err := helper.GetUserByID(test.DB, created.UserID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, "Jane", user.Name)
+			assert.Equal(t, "jane@gmail.com", user.Email)
+			assert.NotEqual(t, "password1", user.Password)
+
+			invalid := result.Results[1]
+			assert.Equal(t, 3, invalid.Line)
+			assert.Equal(t, model.UserImportRowFailed, invalid.Status)
+			assert.Equal(t, "validation-failed", invalid.Code)
+			assert.Equal(t, "email", invalid.Errors["email"].Code)
+			assert.Nil(t, invalid.UserID)
+
+			taken := result.Results[2]
+			assert.Equal(t, 4, taken.Line)
+			assert.Equal(t, "email-taken", taken.Code)
+			assert.Equal(t, "Email is already in use", taken.Title)
+
+			apiResponse, body = sendAccount(t, http.MethodGet, "/v1/users/imports/"+result.ID.String(), adminAccessToken, "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, result.Results, userImport(t, body).Results)
+
+			// The import is kept after the users are erased, it names them by id only
+			stored := new(model.UserImport)
+			assert.Nil(t, test.DB.First(stored, "id = ?", result.ID).Error)
+			results, err := json.Marshal(stored.Results)
+			assert.Nil(t, err)
+			assert.NotContains(t, string(results), "@gmail.com")
+		})
+
+		t.Run("should invite the users of a NDJSON file, which may leave out the password", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearUserImports(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			file := `{"name":"Jane","email":"jane@gmail.com","role":"user","language":"id"}` + "\n\n" +
+				`{"name":"John","email":"john@gmail.com","role":"user"` + "\n" +
+				`{"name":"Joe","email":"joe@gmail.com","role":"user"}` + "\n"
+
+			apiResponse, body := importFile(t, adminAccessToken, "users.txt", file, "format", "ndjson", "invite", "true")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			result := userImport(t, body)
+			assert.True(t, result.Invite)
+			assert.Equal(t, 2, result.Created)
+			assert.Equal(t, 1, result.Failed)
+
+			assert.Equal(t, 1, result.Results[0].Line)
+			assert.True(t, result.Results[0].Invited)
+			assert.Equal(t, 3, result.Results[1].Line)
+			assert.Equal(t, "import-row-invalid", result.Results[1].Code)
+			assert.Equal(t, 4, result.Results[2].Line)
+			assert.True(t, result.Results[2].Invited)
+
+			user, err := helper.GetUserByID(test.DB, result.Results[0].UserID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, "id", user.Language)
+
+			token, err := helper.GetTokenByType(test.DB, user.ID.String(), config.TokenTypeResetPassword)
+			assert.Nil(t, err)
+			assert.WithinDuration(t, time.Now().Add(test.Config.Import.InvitationExp), token.Expires, time.Minute)
+		})
+
+		t.Run("should import a large file in the background and email the invitations", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearUserImports(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			file := "name,email,role\n"
+			for i := 1; i <= test.Config.Import.SyncRows+1; i++ {
+				file += fmt.Sprintf("User %d,user%d@gmail.com,user\n", i, i)
+			}
+
+			apiResponse, body := importFile(t, adminAccessToken, "users.csv", file, "invite", "true")
+			assert.Equal(t, http.StatusAccepted, apiResponse.StatusCode)
+
+			pending := userImport(t, body)
+			assert.Equal(t, model.UserImportPending, pending.Status)
+			assert.Equal(t, test.Config.Import.SyncRows+1, pending.Total)
+			assert.Empty(t, pending.Results)
+			assert.Equal(t, "/v1/users/imports/"+pending.ID.String(), apiResponse.Header.Get("Location"))
+
+			stored := new(model.UserImport)
+			assert.Nil(t, test.DB.First(stored, "id = ?", pending.ID).Error)
+			assert.NotEmpty(t, stored.File)
+
+			process, server := importUsers(t)
+			assert.Nil(t, process(context.Background()))
+
+			apiResponse, body = sendAccount(t, http.MethodGet, "/v1/users/imports/"+pending.ID.String(), adminAccessToken, "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			completed := userImport(t, body)
+			assert.Equal(t, model.UserImportCompleted, completed.Status)
+			assert.Equal(t, test.Config.Import.SyncRows+1, completed.Created)
+			assert.Zero(t, completed.Failed)
+			assert.NotNil(t, completed.CompletedAt)
+
+			messages := server.Messages()
+			assert.Len(t, messages, test.Config.Import.SyncRows+1)
+			email, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(messages[0])))
+			assert.Nil(t, err)
+			assert.Contains(t, string(email), "To: user1@gmail.com")
+			assert.Contains(t, string(email), "/reset-password?token=")
+
+			_, err = test.Blobs.Get(context.Background(), stored.File)
+			assert.NotNil(t, err)
+
+			// The job doesn't import the file again
+			assert.Nil(t, process(context.Background()))
+			assert.Len(t, server.Messages(), test.Config.Import.SyncRows+1)
+		})
+
+		t.Run("should import a large file once when the job runs on several instances", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearUserImports(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			file := "name,email,role\n"
+			for i := 1; i <= test.Config.Import.SyncRows+1; i++ {
+				file += fmt.Sprintf("User %d,user%d@gmail.com,user\n", i, i)
+			}
+
+			apiResponse, body := importFile(t, adminAccessToken, "users.csv", file, "invite", "true")
+			assert.Equal(t, http.StatusAccepted, apiResponse.StatusCode)
+			pending := userImport(t, body)
+
+			// Both instances list the import before either runs it
+			var listed, wg sync.WaitGroup
+			servers := make([]*helper.SMTPServer, 2)
+			listed.Add(len(servers))
+			for i := range servers {
+				var process func(ctx context.Context) error
+				process, servers[i] = importUsersFrom(t, listedTogether{
+					UserImportRepository: repository.NewUserImportRepository(test.DB),
+					listed:               &listed,
+				})
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					assert.Nil(t, process(context.Background()))
+				}()
+			}
+			wg.Wait()
+
+			stored := new(model.UserImport)
+			assert.Nil(t, test.DB.First(stored, "id = ?", pending.ID).Error)
+			assert.Equal(t, model.UserImportCompleted, stored.Status)
+			assert.Equal(t, test.Config.Import.SyncRows+1, stored.Created)
+			assert.Zero(t, stored.Failed)
+
+			// Each user is invited once, by either instance
+			assert.Len(t, append(servers[0].Messages(), servers[1].Messages()...), test.Config.Import.SyncRows+1)
+		})
+
+		t.Run("should claim a pending import once", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearUserImports(test.DB)
+
+			pending := &model.UserImport{
+				CreatedBy: fixture.Admin.ID, Format: "csv", Status: model.UserImportPending, File: "imports/users.csv",
+			}
+			assert.Nil(t, test.DB.Create(pending).Error)
+
+			imports := repository.NewUserImportRepository(test.DB)
+			staleBefore := time.Now().UTC().Add(-time.Hour)
+			listed, err := imports.ListPending(context.Background(), staleBefore, 10)
+			assert.Nil(t, err)
+			assert.Len(t, listed, 1)
+			again := listed[0]
+
+			claimed, err := imports.Claim(context.Background(), &listed[0], staleBefore)
+			assert.Nil(t, err)
+			assert.True(t, claimed)
+			assert.Equal(t, model.UserImportProcessing, listed[0].Status)
+
+			claimed, err = imports.Claim(context.Background(), &again, staleBefore)
+			assert.Nil(t, err)
+			assert.False(t, claimed)
+
+			listed, err = imports.ListPending(context.Background(), staleBefore, 10)
+			assert.Nil(t, err)
+			assert.Empty(t, listed)
+
+			// Until the claim is stale
+			assert.Nil(t, test.DB.Model(&model.UserImport{}).Where("id = ?", pending.ID).
+				Update("updated_at", time.Now().UTC().Add(-2*time.Hour)).Error)
+			claimed, err = imports.Claim(context.Background(), &again, staleBefore)
+			assert.Nil(t, err)
+			assert.True(t, claimed)
+		})
+
+		t.Run("should return 400 error if the file is missing", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse, body := importFile(t, adminAccessToken, "", "", "invite", "true")
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+			assert.Contains(t, string(body), "import-missing")
+		})
+
+		t.Run("should return 415 error if the format can't be told", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse, body := importFile(t, adminAccessToken, "users.txt", "name,email\n")
+			assert.Equal(t, http.StatusUnsupportedMediaType, apiResponse.StatusCode)
+			assert.Contains(t, string(body), "import-unsupported-type")
+		})
+
+		t.Run("should return 422 error if the CSV file has no email column", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearUserImports(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse, body := importFile(t, adminAccessToken, "users.csv", "name,role\nJane,user\n")
+			assert.Equal(t, http.StatusUnprocessableEntity, apiResponse.StatusCode)
+			assert.Contains(t, string(body), "import-invalid")
+
+			var count int64
+			assert.Nil(t, test.DB.Model(&model.UserImport{}).Count(&count).Error)
+			assert.Zero(t, count)
+		})
+
+		t.Run("should return 422 error if the CSV file has a header only", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearUserImports(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse, body := importFile(t, adminAccessToken, "users.csv", "name,email,role\n")
+			assert.Equal(t, http.StatusUnprocessableEntity, apiResponse.StatusCode)
+			assert.Contains(t, string(body), "import-invalid")
+
+			var count int64
+			assert.Nil(t, test.DB.Model(&model.UserImport{}).Count(&count).Error)
+			assert.Zero(t, count)
+		})
+
+		t.Run("should return 403 error if user is not an admin", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse, _ := importFile(t, userOneAccessToken, "users.csv", "name,email\nJane,jane@gmail.com\n")
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("GET /v1/users/imports/:importId", func(t *testing.T) {
+		t.Run("should return 404 error if the import doesn't exist", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse, _ := sendAccount(t, http.MethodGet, "/v1/users/imports/"+fixture.UserOne.ID.String(),
+				adminAccessToken, "")
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+
+			apiResponse, _ = sendAccount(t, http.MethodGet, "/v1/users/imports/not-an-id", adminAccessToken, "")
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("GET /v1/users/export", func(t *testing.T) {
+		t.Run("should stream the users as CSV", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin, fixture.UserOne, fixture.UserTwo)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse, body := exportUsers(t, adminAccessToken, "?role=user")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, "text/csv; charset=utf-8", apiResponse.Header.Get("Content-Type"))
+			assert.Contains(t, apiResponse.Header.Get("Content-Disposition"), "users.csv")
+
+			records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+			assert.Nil(t, err)
+			assert.Equal(t, [][]string{
+				{"id", "name", "email", "role", "verified_email", "language", "avatar_url"},
+				{fixture.UserOne.ID.String(), "Test1", "test1@gmail.com", "user", "false", "", ""},
+				{fixture.UserTwo.ID.String(), "Test2", "test2@gmail.com", "user", "false", "", ""},
+			}, records)
+		})
+
+		t.Run("should stream every page of users as NDJSON", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			// More users than a page of the export, inserted at once since their password doesn't matter
+			users := make([]model.User, 520)
+			for i := range users {
+				users[i] = model.User{Name: "User", Email: fmt.Sprintf("user%d@gmail.com", i), Password: "x", Role: "user"}
+			}
+			assert.Nil(t, test.DB.CreateInBatches(users, 100).Error)
+
+			apiResponse, body := exportUsers(t, adminAccessToken, "?format=ndjson&role=user&sortBy=email")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, "application/x-ndjson", apiResponse.Header.Get("Content-Type"))
+			assert.NotContains(t, string(body), "password")
+
+			seen := make(map[string]bool)
+			scanner := bufio.NewScanner(bytes.NewReader(body))
+			for scanner.Scan() {
+				user := new(model.User)
+				assert.Nil(t, json.Unmarshal(scanner.Bytes(), user))
+				seen[user.Email] = true
+			}
+			assert.Len(t, seen, len(users))
+		})
+
+		t.Run("should return 400 error if the query is invalid", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse, body := exportUsers(t, adminAccessToken, "?format=xml")
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+			assert.Contains(t, string(body), "validation-failed")
+		})
+
+		t.Run("should return 403 error if user is not an admin", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse, _ := exportUsers(t, userOneAccessToken, "")
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+}
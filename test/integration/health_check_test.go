@@ -1,14 +1,20 @@
 package integration
 
 import (
+	"app/src/controller"
+	"app/src/health"
 	"app/src/response"
 	"app/test"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -36,49 +42,83 @@ func TestHealthCheckRoutes(t *testing.T) {
 			assert.Equal(t, "success", responseBody.Status)
 			assert.Equal(t, "Health check completed", responseBody.Message)
 			assert.Equal(t, true, responseBody.IsHealthy)
-			assert.Equal(t, []response.HealthCheck{
-				{
-					Name:   "Postgre",
-					Status: "Up",
-					IsUp:   true,
-				},
-				{
-					Name:   "Memory",
-					Status: "Up",
-					IsUp:   true,
-				},
-			}, responseBody.Result)
+			names := make([]string, 0, len(responseBody.Result))
+			for _, result := range responseBody.Result {
+				names = append(names, result.Name)
+			}
+			assert.Equal(t, []string{"Database", "Memory", "Disk", "SMTP", "Jobs"}, names)
+			assert.Equal(t, response.HealthCheck{Name: "Database", Status: "Up", IsUp: true}, responseBody.Result[0])
+			assert.Equal(t, response.HealthCheck{Name: "Memory", Status: "Up", IsUp: true}, responseBody.Result[1])
 		})
+	})
+
+	t.Run("GET /livez", func(t *testing.T) {
+		t.Run("should return 200 and only run the liveness checks", func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/livez", nil)
+
+			apiResponse, err := test.App.Test(request, 2000)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, "no-store", apiResponse.Header.Get(fiber.HeaderCacheControl))
+
+			responseBody := new(response.ProbeResponse)
+			assert.Nil(t, json.NewDecoder(apiResponse.Body).Decode(responseBody))
+
+			assert.Equal(t, "ok", responseBody.Status)
+			assert.Len(t, responseBody.Checks, 1)
+			assert.Equal(t, "Memory", responseBody.Checks[0].Name)
+			assert.True(t, responseBody.Checks[0].Up)
+		})
+	})
+
+	t.Run("GET /readyz", func(t *testing.T) {
+		t.Run("should return 200 when the required dependencies are up", func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+			apiResponse, err := test.App.Test(request, 2000)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
 
-		// t.Run("should return 500 and error response if request failed", func(t *testing.T) {
-		// 	request := httptest.NewRequest(http.MethodGet, "/v1/health-check", nil)
+			responseBody := new(response.ProbeResponse)
+			assert.Nil(t, json.NewDecoder(apiResponse.Body).Decode(responseBody))
 
-		// 	msTimeout := 2000
-		// 	apiResponse, err := test.App.Test(request, msTimeout)
-		// 	assert.Nil(t, err)
+			assert.Equal(t, "ok", responseBody.Status)
+			assert.Len(t, responseBody.Checks, 5)
+			assert.Equal(t, "Database", responseBody.Checks[0].Name)
+			assert.True(t, responseBody.Checks[0].Up)
+		})
 
-		// 	assert.Equal(t, http.StatusInternalServerError, apiResponse.StatusCode)
+		t.Run("should return 503 when a required dependency is down", func(t *testing.T) {
+			registry := health.NewRegistry(time.Second)
+			registry.Register(health.Check{Checker: health.NewChecker("Database", func(context.Context) error {
+				return errors.New("connection refused")
+			})})
 
-		// 	bytes, err := io.ReadAll(apiResponse.Body)
-		// 	assert.Nil(t, err)
+			app := fiber.New()
+			h := controller.NewHealthCheckController(registryService{registry})
+			app.Get("/readyz", h.Readyz)
 
-		// 	responseBody := new(response.HealthCheckResponse)
+			apiResponse, err := app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusServiceUnavailable, apiResponse.StatusCode)
 
-		// 	err = json.Unmarshal(bytes, responseBody)
-		// 	assert.Nil(t, err)
+			responseBody := new(response.ProbeResponse)
+			assert.Nil(t, json.NewDecoder(apiResponse.Body).Decode(responseBody))
 
-		// 	assert.Equal(t, http.StatusInternalServerError, apiResponse.StatusCode)
-		// 	assert.Equal(t, http.StatusInternalServerError, responseBody.Code)
-		// 	assert.Equal(t, "error", responseBody.Status)
-		// 	assert.Equal(t, "Health check completed", responseBody.Message)
-		// 	assert.Equal(t, false, responseBody.IsHealthy)
-		// 	assert.Equal(t, []response.HealthCheck{
-		// 		{
-		// 			Name:   "Postgre",
-		// 			Status: "Down",
-		// 			IsUp:   false,
-		// 		},
-		// 	}, responseBody.Result)
-		// })
+			assert.Equal(t, "fail", responseBody.Status)
+			assert.Equal(t, "connection refused", responseBody.Checks[0].Error)
+		})
 	})
 }
+
+type registryService struct {
+	registry *health.Registry
+}
+
+func (s registryService) Liveness(ctx context.Context) health.Report {
+	return s.registry.Liveness(ctx)
+}
+
+func (s registryService) Readiness(ctx context.Context) health.Report {
+	return s.registry.Readiness(ctx)
+}
@@ -0,0 +1,237 @@
+package integration
+
+import (
+	"app/src/job"
+	"app/src/model"
+	"app/src/repository"
+	"app/src/service"
+	"app/src/utils"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func sendIdempotent(t *testing.T, path, key, token, body string) (*http.Response, string) {
+	t.Helper()
+
+	request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Idempotency-Key", key)
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	apiResponse, err := test.App.Test(request)
+	assert.Nil(t, err)
+
+	bytes, err := io.ReadAll(apiResponse.Body)
+	assert.Nil(t, err)
+
+	return apiResponse, string(bytes)
+}
+
+func countUsers(t *testing.T, email string) int64 {
+	t.Helper()
+
+	var count int64
+	assert.Nil(t, test.DB.Model(&model.User{}).Where("email = ?", email).Count(&count).Error)
+
+	return count
+}
+
+func TestIdempotency(t *testing.T) {
+	registerBody := `{"name":"Test","email":"test@gmail.com","password":"password1"}`
+
+	t.Run("POST /v1/auth/register", func(t *testing.T) {
+		t.Run("should replay the response of the first request sent with the key", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearIdempotencyKeys(test.DB)
+
+			first, firstBody := sendIdempotent(t, "/v1/auth/register", "key-1", "", registerBody)
+			assert.Equal(t, http.StatusCreated, first.StatusCode)
+			assert.Empty(t, first.Header.Get("Idempotent-Replayed"))
+
+			retry, retryBody := sendIdempotent(t, "/v1/auth/register", "key-1", "", registerBody)
+			assert.Equal(t, http.StatusCreated, retry.StatusCode)
+			assert.Equal(t, "true", retry.Header.Get("Idempotent-Replayed"))
+			assert.Equal(t, first.Header.Get("Content-Type"), retry.Header.Get("Content-Type"))
+			assert.Equal(t, firstBody, retryBody)
+
+			assert.Equal(t, int64(1), countUsers(t, "test@gmail.com"))
+		})
+
+		t.Run("should return 422 error if the key is reused with a different body", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearIdempotencyKeys(test.DB)
+
+			first, _ := sendIdempotent(t, "/v1/auth/register", "key-1", "", registerBody)
+			assert.Equal(t, http.StatusCreated, first.StatusCode)
+
+			other := strings.Replace(registerBody, "test@gmail.com", "other@gmail.com", 1)
+			retry, retryBody := sendIdempotent(t, "/v1/auth/register", "key-1", "", other)
+			assert.Equal(t, http.StatusUnprocessableEntity, retry.StatusCode)
+			assert.Contains(t, retryBody, "Idempotency-Key was already used with a different request")
+			assert.Equal(t, int64(0), countUsers(t, "other@gmail.com"))
+		})
+
+		t.Run("should return 400 error if the key is too long", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+
+			apiResponse, _ := sendIdempotent(t, "/v1/auth/register", strings.Repeat("k", 256), "", registerBody)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+			assert.Equal(t, int64(0), countUsers(t, "test@gmail.com"))
+		})
+
+		t.Run("should handle the request again once the key expired", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearIdempotencyKeys(test.DB)
+
+			first, _ := sendIdempotent(t, "/v1/auth/register", "key-1", "", registerBody)
+			assert.Equal(t, http.StatusCreated, first.StatusCode)
+
+			assert.Nil(t, test.DB.Model(&model.IdempotencyKey{}).Where("id is not null").
+				Update("expires_at", time.Now().UTC().Add(-time.Second)).Error)
+			helper.ClearAll(test.DB)
+
+			retry, _ := sendIdempotent(t, "/v1/auth/register", "key-1", "", registerBody)
+			assert.Equal(t, http.StatusCreated, retry.StatusCode)
+			assert.Empty(t, retry.Header.Get("Idempotent-Replayed"))
+		})
+	})
+
+	t.Run("POST /v1/users", func(t *testing.T) {
+		createBody := `{"name":"Test","email":"test@gmail.com","password":"password1","role":"user"}`
+		otherAdmin := &model.User{
+			ID:       uuid.New(),
+			Name:     "Other Admin",
+			Email:    "other.admin@gmail.com",
+			Password: "password1",
+			Role:     "admin",
+		}
+
+		t.Run("should replay the response to the same user only", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearIdempotencyKeys(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin, otherAdmin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+			otherAccessToken, err := fixture.AccessToken(otherAdmin)
+			assert.Nil(t, err)
+
+			first, firstBody := sendIdempotent(t, "/v1/users", "key-1", adminAccessToken, createBody)
+			assert.Equal(t, http.StatusCreated, first.StatusCode)
+
+			retry, retryBody := sendIdempotent(t, "/v1/users", "key-1", adminAccessToken, createBody)
+			assert.Equal(t, http.StatusCreated, retry.StatusCode)
+			assert.Equal(t, "true", retry.Header.Get("Idempotent-Replayed"))
+			assert.Equal(t, firstBody, retryBody)
+
+			other, _ := sendIdempotent(t, "/v1/users", "key-1", otherAccessToken, createBody)
+			assert.Equal(t, http.StatusConflict, other.StatusCode)
+			assert.Empty(t, other.Header.Get("Idempotent-Replayed"))
+
+			assert.Equal(t, int64(1), countUsers(t, "test@gmail.com"))
+		})
+
+		t.Run("should replay the error response of the first request", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearIdempotencyKeys(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			duplicate := strings.Replace(createBody, "test@gmail.com", fixture.Admin.Email, 1)
+			first, firstBody := sendIdempotent(t, "/v1/users", "key-1", adminAccessToken, duplicate)
+			assert.Equal(t, http.StatusConflict, first.StatusCode)
+
+			retry, retryBody := sendIdempotent(t, "/v1/users", "key-1", adminAccessToken, duplicate)
+			assert.Equal(t, http.StatusConflict, retry.StatusCode)
+			assert.Equal(t, "true", retry.Header.Get("Idempotent-Replayed"))
+			assert.Equal(t, firstBody, retryBody)
+		})
+
+		t.Run("should return 401 error before reading the key if access token is missing", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearIdempotencyKeys(test.DB)
+
+			apiResponse, _ := sendIdempotent(t, "/v1/users", "key-1", "", createBody)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+
+			var count int64
+			assert.Nil(t, test.DB.Model(&model.IdempotencyKey{}).Count(&count).Error)
+			assert.Equal(t, int64(0), count)
+		})
+	})
+}
+
+func TestIdempotencyService(t *testing.T) {
+	ctx := context.Background()
+	idempotencyService := service.NewIdempotencyService(repository.NewIdempotencyRepository(test.DB),
+		test.Config.Idempotency)
+
+	t.Run("should return 409 error while the first request is in progress", func(t *testing.T) {
+		helper.ClearIdempotencyKeys(test.DB)
+
+		stored, err := idempotencyService.Begin(ctx, "user", "key-1", "fingerprint")
+		assert.Nil(t, err)
+		assert.Nil(t, stored)
+
+		_, err = idempotencyService.Begin(ctx, "user", "key-1", "fingerprint")
+		assert.EqualError(t, err, "A request with this Idempotency-Key is in progress")
+
+		assert.Nil(t, idempotencyService.Release(ctx, "user", "key-1"))
+		stored, err = idempotencyService.Begin(ctx, "user", "key-1", "fingerprint")
+		assert.Nil(t, err)
+		assert.Nil(t, stored)
+	})
+
+	t.Run("should not keep the response readable in the database", func(t *testing.T) {
+		helper.ClearIdempotencyKeys(test.DB)
+
+		_, err := idempotencyService.Begin(ctx, "user", "key-1", "fingerprint")
+		assert.Nil(t, err)
+		assert.Nil(t, idempotencyService.Complete(ctx, "user", "key-1", &model.IdempotentResponse{
+			Status: http.StatusCreated,
+			Body:   []byte(`{"token":"secret-token"}`),
+		}))
+
+		var key model.IdempotencyKey
+		assert.Nil(t, test.DB.Take(&key).Error)
+		assert.NotContains(t, key.ID, "key-1")
+		assert.NotContains(t, string(key.Response), "secret-token")
+
+		stored, err := idempotencyService.Begin(ctx, "user", "key-1", "fingerprint")
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusCreated, stored.Status)
+		assert.Equal(t, `{"token":"secret-token"}`, string(stored.Body))
+	})
+
+	t.Run("should purge the expired keys", func(t *testing.T) {
+		helper.ClearIdempotencyKeys(test.DB)
+
+		_, err := idempotencyService.Begin(ctx, "user", "key-1", "fingerprint")
+		assert.Nil(t, err)
+		_, err = idempotencyService.Begin(ctx, "user", "key-2", "fingerprint")
+		assert.Nil(t, err)
+		assert.Nil(t, test.DB.Model(&model.IdempotencyKey{}).Where("id = ?", utils.HashToken("user:key-2")).
+			Update("expires_at", time.Now().UTC().Add(-time.Second)).Error)
+
+		assert.Nil(t, job.PurgeIdempotencyKeys(idempotencyService)(ctx))
+
+		var count int64
+		assert.Nil(t, test.DB.Model(&model.IdempotencyKey{}).Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+	})
+}
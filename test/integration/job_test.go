@@ -0,0 +1,315 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/job"
+	"app/src/model"
+	"app/src/repository"
+	"app/src/response"
+	"app/src/service"
+	"app/src/utils"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobs(t *testing.T) {
+	t.Run("Purge expired tokens", func(t *testing.T) {
+		validate := validation.Validator()
+		userService := service.NewUserService(repository.NewUserRepository(test.DB), validate)
+		tokenService := service.NewTokenService(
+			repository.NewTokenRepository(test.DB), repository.NewUnitOfWork(test.DB), validate, userService, test.Live,
+		)
+
+		t.Run("should delete expired tokens of every type in batches", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo, fixture.Admin)
+
+			expired := time.Now().UTC().Add(-time.Minute)
+			for _, user := range []*model.User{fixture.UserOne, fixture.UserTwo, fixture.Admin} {
+				userID := user.ID.String()
+				assert.Nil(t, helper.SaveToken(test.DB, "refresh"+userID, userID, config.TokenTypeRefresh, expired))
+				assert.Nil(t, helper.SaveToken(test.DB, "reset"+userID, userID, config.TokenTypeResetPassword, expired))
+			}
+
+			validToken, err := fixture.VerifyEmailToken(fixture.UserOne)
+			assert.Nil(t, err)
+			assert.Nil(t, helper.SaveToken(test.DB, validToken, fixture.UserOne.ID.String(),
+				config.TokenTypeVerifyEmail, fixture.ExpiresVerifyEmailToken))
+
+			err = job.PurgeExpiredTokens(tokenService, 4)(context.Background())
+			assert.Nil(t, err)
+
+			var tokens []model.Token
+			assert.Nil(t, test.DB.Find(&tokens).Error)
+			assert.Len(t, tokens, 1)
+			assert.Equal(t, utils.HashToken(validToken), tokens[0].Token)
+		})
+
+		t.Run("should stop between batches when the context is cancelled", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := job.PurgeExpiredTokens(tokenService, 4)(ctx)
+			assert.ErrorIs(t, err, context.Canceled)
+		})
+	})
+	t.Run("Scheduler", func(t *testing.T) {
+		pastOccurrence := time.Date(2024, 10, 22, 3, 0, 0, 0, time.UTC)
+
+		t.Run("should run a scheduled job on only one instance", func(t *testing.T) {
+			helper.ClearJobs(test.DB)
+
+			var calls atomic.Int32
+			fn := func(_ context.Context) error {
+				calls.Add(1)
+				return nil
+			}
+
+			first := job.NewScheduler(repository.NewJobRepository(test.DB))
+			second := job.NewScheduler(repository.NewJobRepository(test.DB))
+			assert.Nil(t, first.Register("test-job", "@hourly", fn))
+			assert.Nil(t, second.Register("test-job", "@hourly", fn))
+
+			scheduledAt := time.Now()
+
+			ran, err := first.Run(context.Background(), "test-job", scheduledAt)
+			assert.Nil(t, err)
+			assert.True(t, ran)
+
+			ran, err = second.Run(context.Background(), "test-job", scheduledAt)
+			assert.Nil(t, err)
+			assert.False(t, ran)
+
+			assert.Equal(t, int32(1), calls.Load())
+
+			var runs []model.JobRun
+			assert.Nil(t, test.DB.Find(&runs).Error)
+			assert.Len(t, runs, 1)
+			assert.Equal(t, model.JobStatusSucceeded, runs[0].Status)
+			assert.Equal(t, first.Owner, runs[0].Owner)
+			assert.NotNil(t, runs[0].FinishedAt)
+		})
+
+		t.Run("should let another instance take over an expired lease", func(t *testing.T) {
+			helper.ClearJobs(test.DB)
+
+			fn := func(_ context.Context) error { return nil }
+
+			first := job.NewScheduler(repository.NewJobRepository(test.DB))
+			second := job.NewScheduler(repository.NewJobRepository(test.DB))
+			assert.Nil(t, first.Register("test-job", "@hourly", fn))
+			assert.Nil(t, second.Register("test-job", "@hourly", fn))
+
+			// The lease of a past occurrence ends at the following one, which is past too
+			ran, err := first.Run(context.Background(), "test-job", pastOccurrence)
+			assert.Nil(t, err)
+			assert.True(t, ran)
+
+			ran, err = second.Run(context.Background(), "test-job", time.Now())
+			assert.Nil(t, err)
+			assert.True(t, ran)
+		})
+
+		t.Run("should keep the lease while a run outlives the interval of its job", func(t *testing.T) {
+			helper.ClearJobs(test.DB)
+
+			started := make(chan struct{})
+			release := make(chan struct{})
+			var calls atomic.Int32
+			fn := func(_ context.Context) error {
+				if calls.Add(1) == 1 {
+					close(started)
+					<-release
+				}
+				return nil
+			}
+
+			first := job.NewScheduler(repository.NewJobRepository(test.DB))
+			second := job.NewScheduler(repository.NewJobRepository(test.DB))
+			first.LeaseTTL = 300 * time.Millisecond
+			second.LeaseTTL = 300 * time.Millisecond
+			assert.Nil(t, first.Register("test-job", "@every 1s", fn))
+			assert.Nil(t, second.Register("test-job", "@every 1s", fn))
+
+			scheduledAt := time.Now()
+			finished := make(chan error)
+			go func() {
+				_, err := first.Run(context.Background(), "test-job", scheduledAt)
+				finished <- err
+			}()
+			<-started
+
+			// The first lease would have ended at the next occurrence without the heartbeat
+			time.Sleep(1500 * time.Millisecond)
+			ran, err := second.Run(context.Background(), "test-job", scheduledAt.Add(time.Second))
+			assert.Nil(t, err)
+			assert.False(t, ran)
+
+			close(release)
+			assert.Nil(t, <-finished)
+
+			ran, err = second.Run(context.Background(), "test-job", scheduledAt.Add(2*time.Second))
+			assert.Nil(t, err)
+			assert.True(t, ran)
+			assert.Equal(t, int32(2), calls.Load())
+		})
+
+		t.Run("should cancel a run whose lease was taken over", func(t *testing.T) {
+			helper.ClearJobs(test.DB)
+
+			started := make(chan struct{})
+			scheduler := job.NewScheduler(repository.NewJobRepository(test.DB))
+			scheduler.LeaseTTL = 150 * time.Millisecond
+			assert.Nil(t, scheduler.Register("test-job", "@hourly", func(ctx context.Context) error {
+				close(started)
+				<-ctx.Done()
+				return ctx.Err()
+			}))
+
+			finished := make(chan error)
+			go func() {
+				_, err := scheduler.Run(context.Background(), "test-job", time.Now())
+				finished <- err
+			}()
+			<-started
+
+			assert.Nil(t, test.DB.Model(&model.JobLease{}).Where("name = ?", "test-job").
+				Update("owner", "another-instance").Error)
+
+			select {
+			case err := <-finished:
+				assert.ErrorIs(t, err, context.Canceled)
+			case <-time.After(5 * time.Second):
+				t.Fatal("the run wasn't cancelled")
+			}
+		})
+
+		t.Run("should record the error of a failed run", func(t *testing.T) {
+			helper.ClearJobs(test.DB)
+
+			scheduler := job.NewScheduler(repository.NewJobRepository(test.DB))
+			assert.Nil(t, scheduler.Register("test-job", "@hourly", func(_ context.Context) error {
+				return errors.New("something went wrong")
+			}))
+
+			ran, err := scheduler.Run(context.Background(), "test-job", time.Now())
+			assert.NotNil(t, err)
+			assert.True(t, ran)
+
+			var run model.JobRun
+			assert.Nil(t, test.DB.First(&run).Error)
+			assert.Equal(t, model.JobStatusFailed, run.Status)
+			assert.Equal(t, "something went wrong", run.Error)
+		})
+
+		t.Run("should reject an invalid cron spec", func(t *testing.T) {
+			scheduler := job.NewScheduler(repository.NewJobRepository(test.DB))
+
+			err := scheduler.Register("test-job", "every day", func(_ context.Context) error { return nil })
+			assert.NotNil(t, err)
+		})
+	})
+}
+
+func TestJobRoutes(t *testing.T) {
+	t.Run("GET /v1/jobs/runs", func(t *testing.T) {
+		t.Run("should return 200 and the runs of a job, latest first", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearJobs(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			scheduler := job.NewScheduler(repository.NewJobRepository(test.DB))
+			assert.Nil(t, scheduler.Register("test-job", "@hourly", func(_ context.Context) error { return nil }))
+			assert.Nil(t, scheduler.Register("other-job", "@hourly", func(_ context.Context) error { return nil }))
+
+			for _, name := range []string{"test-job", "other-job", "test-job"} {
+				_, err := scheduler.Run(context.Background(), name, time.Now())
+				assert.Nil(t, err)
+			}
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/jobs/runs?name=test-job", nil)
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithPaginate[model.JobRun])
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Len(t, responseBody.Results, 2)
+			for _, run := range responseBody.Results {
+				assert.Equal(t, "test-job", run.Name)
+				assert.Equal(t, model.JobStatusSucceeded, run.Status)
+			}
+			assert.False(t, responseBody.Results[0].StartedAt.Before(responseBody.Results[1].StartedAt))
+		})
+
+		t.Run("should return 403 error if logged in user is not an admin", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/jobs/runs", nil)
+			request.Header.Set("Authorization", "Bearer "+userOneAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if status is invalid", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/jobs/runs?status=unknown", nil)
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if limit is 0", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/jobs/runs?limit=0", nil)
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+}
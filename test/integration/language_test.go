@@ -0,0 +1,141 @@
+package integration
+
+import (
+	"app/src/model"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func sendInLanguage(t *testing.T, method, path, acceptLanguage, token, body string) (*http.Response, map[string]interface{}) {
+	t.Helper()
+
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept-Language", acceptLanguage)
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	apiResponse, err := test.App.Test(request)
+	assert.Nil(t, err)
+
+	bytes, err := io.ReadAll(apiResponse.Body)
+	assert.Nil(t, err)
+
+	responseBody := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(bytes, &responseBody))
+
+	return apiResponse, responseBody
+}
+
+func TestLanguage(t *testing.T) {
+	t.Run("should answer in the language of Accept-Language", func(t *testing.T) {
+		helper.ClearAll(test.DB)
+
+		apiResponse, responseBody := sendInLanguage(t, http.MethodPost, "/v1/auth/login", "id-ID,id;q=0.9,en;q=0.8", "",
+			`{"email":"test@gmail.com","password":"password1"}`)
+
+		assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		assert.Equal(t, "id", apiResponse.Header.Get("Content-Language"))
+		assert.Contains(t, apiResponse.Header.Get("Vary"), "Accept-Language")
+		assert.Equal(t, "invalid-credentials", responseBody["code"])
+		assert.Equal(t, "Email atau kata sandi salah", responseBody["title"])
+	})
+
+	t.Run("should translate the validation errors", func(t *testing.T) {
+		apiResponse, responseBody := sendInLanguage(t, http.MethodPost, "/v1/auth/register", "id", "",
+			`{"name":"Test","email":"invalid","password":"password1"}`)
+
+		assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		assert.Equal(t, map[string]interface{}{
+			"email": map[string]interface{}{"code": "email", "message": "Alamat email pada kolom email tidak valid"},
+		}, responseBody["errors"])
+	})
+
+	t.Run("should translate the problem of a taken email", func(t *testing.T) {
+		helper.ClearAll(test.DB)
+		helper.InsertUser(test.DB, fixture.UserOne)
+
+		apiResponse, responseBody := sendInLanguage(t, http.MethodPost, "/v1/auth/register", "id", "",
+			`{"name":"Test","email":"`+fixture.UserOne.Email+`","password":"password1"}`)
+
+		assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+		assert.Equal(t, "Email sudah digunakan", responseBody["title"])
+		assert.NotContains(t, responseBody, "detail")
+	})
+
+	t.Run("should fall back to English", func(t *testing.T) {
+		apiResponse, responseBody := sendInLanguage(t, http.MethodGet, "/v1/unknown", "fr-FR", "", "")
+
+		assert.Equal(t, "en", apiResponse.Header.Get("Content-Language"))
+		assert.Equal(t, "Endpoint Not Found", responseBody["title"])
+	})
+
+	t.Run("should prefer the language the user chose", func(t *testing.T) {
+		helper.ClearAll(test.DB)
+		user := &model.User{
+			ID:       uuid.New(),
+			Name:     "Test",
+			Email:    "test@gmail.com",
+			Password: "password1",
+			Role:     "user",
+			Language: "id",
+		}
+		helper.InsertUser(test.DB, user)
+
+		accessToken, err := fixture.AccessToken(user)
+		assert.Nil(t, err)
+
+		apiResponse, responseBody := sendInLanguage(t, http.MethodGet, "/v1/users/"+uuid.NewString(), "en", accessToken, "")
+
+		assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		assert.Equal(t, "id", apiResponse.Header.Get("Content-Language"))
+		assert.Equal(t, "Anda tidak memiliki izin untuk mengakses sumber daya ini", responseBody["title"])
+	})
+
+	t.Run("PATCH /v1/users/:userId", func(t *testing.T) {
+		t.Run("should update the language of the user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse, responseBody := sendInLanguage(t, http.MethodPatch, "/v1/users/"+fixture.UserOne.ID.String(), "en",
+				accessToken, `{"language":"id"}`)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, "id", responseBody["user"].(map[string]interface{})["language"])
+
+			user, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, "id", user.Language)
+		})
+
+		t.Run("should return 400 error if the language isn't supported", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse, responseBody := sendInLanguage(t, http.MethodPatch, "/v1/users/"+fixture.UserOne.ID.String(), "en",
+				accessToken, `{"language":"fr"}`)
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+			assert.Equal(t, map[string]interface{}{
+				"language": map[string]interface{}{"code": "language", "message": "Field language must be a supported language"},
+			}, responseBody["errors"])
+		})
+	})
+}
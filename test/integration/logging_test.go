@@ -0,0 +1,153 @@
+package integration
+
+import (
+	"app/src/utils"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// captureLogs collects the JSON log lines written while fn runs
+func captureLogs(t *testing.T, level logrus.Level, fn func()) []map[string]interface{} {
+	var buf bytes.Buffer
+
+	output, formatter, previous := utils.Log.Out, utils.Log.Formatter, utils.Log.GetLevel()
+	utils.Log.SetOutput(&buf)
+	utils.Log.SetFormatter(&logrus.JSONFormatter{})
+	utils.Log.SetLevel(level)
+	defer func() {
+		utils.Log.SetOutput(output)
+		utils.Log.SetFormatter(formatter)
+		utils.Log.SetLevel(previous)
+	}()
+
+	fn()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		line := make(map[string]interface{})
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func findLog(lines []map[string]interface{}, msg string) map[string]interface{} {
+	for _, line := range lines {
+		if line["msg"] == msg {
+			return line
+		}
+	}
+	return nil
+}
+
+func TestLogging(t *testing.T) {
+	t.Run("X-Request-ID", func(t *testing.T) {
+		t.Run("should echo a well formed incoming request id", func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/v1/health-check", nil)
+			request.Header.Set("X-Request-ID", "client-id-123")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, "client-id-123", apiResponse.Header.Get("X-Request-ID"))
+		})
+
+		t.Run("should generate a request id if none is given", func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/v1/health-check", nil)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Len(t, apiResponse.Header.Get("X-Request-ID"), 36)
+		})
+
+		t.Run("should replace a malformed request id", func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/v1/health-check", nil)
+			request.Header.Set("X-Request-ID", "bad id\twith spaces")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.NotEqual(t, "bad id\twith spaces", apiResponse.Header.Get("X-Request-ID"))
+			assert.Len(t, apiResponse.Header.Get("X-Request-ID"), 36)
+		})
+	})
+
+	t.Run("Request log", func(t *testing.T) {
+		t.Run("should log request id, user id, route, status and latency", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			lines := captureLogs(t, logrus.InfoLevel, func() {
+				request := httptest.NewRequest(http.MethodGet, "/v1/users/"+fixture.UserOne.ID.String(), nil)
+				request.Header.Set("Authorization", "Bearer "+accessToken)
+				request.Header.Set("X-Request-ID", "request-log-test")
+
+				_, err := test.App.Test(request)
+				assert.Nil(t, err)
+			})
+
+			line := findLog(lines, "Request completed")
+			if assert.NotNil(t, line) {
+				assert.Equal(t, "info", line["level"])
+				assert.Equal(t, "request-log-test", line["request_id"])
+				assert.Equal(t, fixture.UserOne.ID.String(), line["user_id"])
+				assert.Equal(t, "/v1/users/:userId", line["route"])
+				assert.Equal(t, float64(http.StatusOK), line["status"])
+				assert.Equal(t, http.MethodGet, line["method"])
+				assert.Contains(t, line, "latency_ms")
+			}
+		})
+
+		t.Run("should log the status set by the error handler", func(t *testing.T) {
+			lines := captureLogs(t, logrus.InfoLevel, func() {
+				request := httptest.NewRequest(http.MethodGet, "/v1/users/"+fixture.UserOne.ID.String(), nil)
+
+				_, err := test.App.Test(request)
+				assert.Nil(t, err)
+			})
+
+			line := findLog(lines, "Request completed")
+			if assert.NotNil(t, line) {
+				assert.Equal(t, "warning", line["level"])
+				assert.Equal(t, float64(http.StatusUnauthorized), line["status"])
+				assert.NotContains(t, line, "user_id")
+			}
+		})
+
+		t.Run("should tag SQL statements with the request id", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			lines := captureLogs(t, logrus.DebugLevel, func() {
+				request := httptest.NewRequest(http.MethodGet, "/v1/users/"+fixture.UserOne.ID.String(), nil)
+				request.Header.Set("Authorization", "Bearer "+accessToken)
+				request.Header.Set("X-Request-ID", "sql-log-test")
+
+				_, err := test.App.Test(request)
+				assert.Nil(t, err)
+			})
+
+			line := findLog(lines, "Query")
+			if assert.NotNil(t, line) {
+				assert.Equal(t, "sql-log-test", line["request_id"])
+				assert.Contains(t, line["sql"], "users")
+			}
+		})
+	})
+}
@@ -0,0 +1,162 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/router"
+	"app/src/utils"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMetricsApp(token string) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: utils.ErrorHandler})
+	router.MetricsRoutes(app, test.DB, config.Metrics{Enabled: true, Token: token})
+
+	return app
+}
+
+func scrape(t *testing.T, app *fiber.App, token string) map[string]*dto.MetricFamily {
+	request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	apiResponse, err := app.Test(request)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+	parser := expfmt.TextParser{}
+	families, err := parser.TextToMetricFamilies(apiResponse.Body)
+	assert.Nil(t, err)
+
+	return families
+}
+
+// sampleValue returns the value of the counter (or histogram count) with the given labels
+func sampleValue(families map[string]*dto.MetricFamily, name string, labels map[string]string) float64 {
+	family, ok := families[name]
+	if !ok {
+		return 0
+	}
+
+	for _, metric := range family.Metric {
+		if !hasLabels(metric, labels) {
+			continue
+		}
+		if metric.Histogram != nil {
+			return float64(metric.Histogram.GetSampleCount())
+		}
+		return metric.GetCounter().GetValue() + metric.GetGauge().GetValue()
+	}
+
+	return 0
+}
+
+func hasLabels(metric *dto.Metric, labels map[string]string) bool {
+	matched := 0
+	for _, pair := range metric.Label {
+		if value, ok := labels[pair.GetName()]; ok && value == pair.GetValue() {
+			matched++
+		}
+	}
+	return matched == len(labels)
+}
+
+func login(t *testing.T, email, password string) {
+	body := `{"email":"` + email + `","password":"` + password + `"}`
+	request := httptest.NewRequest(http.MethodPost, "/v1/auth/login", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+
+	_, err := test.App.Test(request)
+	assert.Nil(t, err)
+}
+
+func TestMetrics(t *testing.T) {
+	t.Run("GET /metrics", func(t *testing.T) {
+		t.Run("should count requests by route template and status", func(t *testing.T) {
+			app := newMetricsApp("")
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			labels := map[string]string{"method": "GET", "route": "/v1/users/:userId", "status": "200"}
+			before := scrape(t, app, "")
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/users/"+fixture.UserOne.ID.String(), nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+			_, err = test.App.Test(request)
+			assert.Nil(t, err)
+
+			after := scrape(t, app, "")
+			assert.Equal(t, 1.0,
+				sampleValue(after, "http_requests_total", labels)-sampleValue(before, "http_requests_total", labels))
+			assert.Equal(t, 1.0, sampleValue(after, "http_request_duration_seconds", labels)-
+				sampleValue(before, "http_request_duration_seconds", labels))
+		})
+
+		t.Run("should count logins and issued tokens", func(t *testing.T) {
+			app := newMetricsApp("")
+			helper.ClearAll(test.DB)
+			helper.CreateUser(test.DB, "metrics@gmail.com", "password1", "Metrics User")
+
+			succeeded := map[string]string{"method": "password", "result": "succeeded"}
+			failed := map[string]string{"method": "password", "result": "failed"}
+			refresh := map[string]string{"type": config.TokenTypeRefresh}
+			before := scrape(t, app, "")
+
+			login(t, "metrics@gmail.com", "password1")
+			login(t, "metrics@gmail.com", "wrongPassword1")
+
+			after := scrape(t, app, "")
+			assert.Equal(t, 1.0,
+				sampleValue(after, "auth_logins_total", succeeded)-sampleValue(before, "auth_logins_total", succeeded))
+			assert.Equal(t, 1.0,
+				sampleValue(after, "auth_logins_total", failed)-sampleValue(before, "auth_logins_total", failed))
+			assert.Equal(t, 1.0, sampleValue(after, "auth_tokens_issued_total", refresh)-
+				sampleValue(before, "auth_tokens_issued_total", refresh))
+		})
+
+		t.Run("should expose the database pool stats", func(t *testing.T) {
+			families := scrape(t, newMetricsApp(""), "")
+
+			assert.Contains(t, families, "go_sql_open_connections")
+			assert.Contains(t, families, "go_goroutines")
+		})
+
+		t.Run("should return 401 error if the token is missing or wrong", func(t *testing.T) {
+			app := newMetricsApp("metrics-secret")
+
+			for _, header := range []string{"", "Bearer wrong"} {
+				request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+				request.Header.Set("Authorization", header)
+
+				apiResponse, err := app.Test(request)
+				assert.Nil(t, err)
+				assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+			}
+
+			scrape(t, app, "metrics-secret")
+		})
+
+		t.Run("should return 404 error if metrics are disabled", func(t *testing.T) {
+			app := fiber.New()
+			router.MetricsRoutes(app, test.DB, config.Metrics{})
+
+			apiResponse, err := app.Test(httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+}
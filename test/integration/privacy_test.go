@@ -0,0 +1,554 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/job"
+	"app/src/model"
+	"app/src/repository"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/quotedprintable"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// processDataRequests returns the process-data-requests job, its emails are sent to the returned server
+func processDataRequests(t *testing.T) (func(ctx context.Context) error, *helper.SMTPServer) {
+	t.Helper()
+
+	return processDataRequestsFrom(t, repository.NewDataRequestRepository(test.DB))
+}
+
+// processDataRequestsFrom returns the process-data-requests job reading the requests from the given repository
+func processDataRequestsFrom(
+	t *testing.T, dataRequests repository.DataRequestRepository,
+) (func(ctx context.Context) error, *helper.SMTPServer) {
+	t.Helper()
+
+	server := helper.StartSMTPServer(t)
+	cfg := *test.Config
+	cfg.SMTP.Host = server.Addr.IP.String()
+	cfg.SMTP.Port = server.Addr.Port
+	live := config.NewLive(&cfg, nil)
+
+	validate := validation.Validator()
+	users := repository.NewUserRepository(test.DB)
+	tokens := repository.NewTokenRepository(test.DB)
+	unitOfWork := repository.NewUnitOfWork(test.DB)
+	userService := service.NewUserService(users, validate)
+	privacyService := service.NewPrivacyService(
+		users, tokens, dataRequests, unitOfWork, validate,
+		service.NewTokenService(tokens, unitOfWork, validate, userService, live), service.NewEmailService(live),
+		service.NewAvatarService(users, test.Blobs, cfg.Avatar), test.Blobs, cfg.Privacy,
+	)
+
+	return job.ProcessDataRequests(privacyService, 20), server
+}
+
+// erasuresListedTogether holds the erasures listed by each instance until
+// every instance listed them, and counts the erasures run
+type erasuresListedTogether struct {
+	repository.DataRequestRepository
+	listed *sync.WaitGroup
+	erased *atomic.Int32
+}
+
+func (r erasuresListedTogether) ListDue(
+	ctx context.Context, requestType string, before, staleBefore time.Time, limit int,
+) ([]model.DataRequest, error) {
+	requests, err := r.DataRequestRepository.ListDue(ctx, requestType, before, staleBefore, limit)
+	if requestType == model.DataRequestErasure {
+		r.listed.Done()
+		r.listed.Wait()
+	}
+
+	return requests, err
+}
+
+// ListByUser is called once by each erasure, before it erases anything
+func (r erasuresListedTogether) ListByUser(ctx context.Context, userID string) ([]model.DataRequest, error) {
+	r.erased.Add(1)
+
+	return r.DataRequestRepository.ListByUser(ctx, userID)
+}
+
+func dataRequest(t *testing.T, body []byte) model.DataRequest {
+	t.Helper()
+
+	responseBody := new(response.SuccessWithDataRequest)
+	assert.Nil(t, json.Unmarshal(body, responseBody))
+
+	return responseBody.DataRequest
+}
+
+func confirmErasureToken(t *testing.T, user *model.User) string {
+	t.Helper()
+
+	expires := time.Now().UTC().Add(test.Config.JWT.VerifyEmailExp)
+	token, err := helper.GenerateToken(test.Config.JWT.Secret, user.ID.String(), expires, config.TokenTypeConfirmErasure)
+	assert.Nil(t, err)
+	assert.Nil(t, helper.SaveToken(test.DB, token, user.ID.String(), config.TokenTypeConfirmErasure, expires))
+
+	return token
+}
+
+// dueErasure requests and confirms the erasure of the user, then ends its grace period
+func dueErasure(t *testing.T, user *model.User, accessToken string) model.DataRequest {
+	t.Helper()
+
+	sendAccount(t, http.MethodPost, "/v1/me/erasure", accessToken,
+		`{"confirm":"test1@gmail.com","current_password":"password1"}`)
+	apiResponse, body := sendAccount(t, http.MethodPost, "/v1/me/erasure/confirm?token="+
+		confirmErasureToken(t, user), accessToken, "")
+	assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+	erasure := dataRequest(t, body)
+
+	assert.Nil(t, test.DB.Model(&model.DataRequest{}).Where("id = ?", erasure.ID).
+		Update("scheduled_at", time.Now().UTC().Add(-time.Minute)).Error)
+
+	return erasure
+}
+
+func TestPrivacyRoutes(t *testing.T) {
+	t.Run("POST /v1/me/exports", func(t *testing.T) {
+		t.Run("should build the export in the background and email a link to it", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			user, accessToken := accountUser(t)
+
+			apiResponse, _ := uploadAvatar(t, user.ID.String(), accessToken, "", pngPicture(t, 64, 64))
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse, body := sendAccount(t, http.MethodPost, "/v1/me/exports", accessToken, "")
+			assert.Equal(t, http.StatusAccepted, apiResponse.StatusCode)
+			export := dataRequest(t, body)
+			assert.Equal(t, model.DataRequestExport, export.Type)
+			assert.Equal(t, model.DataRequestPending, export.Status)
+
+			apiResponse, body = sendAccount(t, http.MethodPost, "/v1/me/exports", accessToken, "")
+			assert.Equal(t, http.StatusAccepted, apiResponse.StatusCode)
+			assert.Equal(t, export.ID, dataRequest(t, body).ID)
+
+			apiResponse, body = sendAccount(t, http.MethodGet, "/v1/me/exports/"+export.ID.String(), accessToken, "")
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+			assert.Contains(t, string(body), "export-not-ready")
+
+			process, server := processDataRequests(t)
+			assert.Nil(t, process(context.Background()))
+
+			messages := server.Messages()
+			assert.Len(t, messages, 1)
+			email, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(messages[0])))
+			assert.Nil(t, err)
+			assert.Contains(t, string(email), "To: test1@gmail.com")
+			assert.Contains(t, string(email), "/account/exports/"+export.ID.String())
+
+			apiResponse, body = sendAccount(t, http.MethodGet, "/v1/me/data-requests", accessToken, "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			requests := new(response.SuccessWithDataRequests)
+			assert.Nil(t, json.Unmarshal(body, requests))
+			assert.Len(t, requests.Results, 1)
+			assert.Equal(t, model.DataRequestCompleted, requests.Results[0].Status)
+			assert.NotNil(t, requests.Results[0].ExpiresAt)
+
+			apiResponse, body = sendAccount(t, http.MethodGet, "/v1/me/exports/"+export.ID.String(), accessToken, "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, "application/zip", apiResponse.Header.Get("Content-Type"))
+			assert.Contains(t, apiResponse.Header.Get("Content-Disposition"), "export.zip")
+
+			archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+			assert.Nil(t, err)
+			assert.Len(t, archive.File, 2)
+			assert.Equal(t, "export.json", archive.File[0].Name)
+			assert.Equal(t, "avatar.jpg", archive.File[1].Name)
+
+			file, err := archive.File[0].Open()
+			assert.Nil(t, err)
+			content, err := io.ReadAll(file)
+			assert.Nil(t, err)
+			assert.Contains(t, string(content), `"email": "test1@gmail.com"`)
+			assert.Contains(t, string(content), `"has_password": true`)
+			assert.NotContains(t, string(content), user.Password)
+			assert.Contains(t, string(content), `"data_requests"`)
+		})
+
+		t.Run("should export an account created with Google as a user without a password", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			_, accessToken := googleAccountUser(t)
+
+			_, body := sendAccount(t, http.MethodPost, "/v1/me/exports", accessToken, "")
+			export := dataRequest(t, body)
+
+			process, _ := processDataRequests(t)
+			assert.Nil(t, process(context.Background()))
+
+			apiResponse, body := sendAccount(t, http.MethodGet, "/v1/me/exports/"+export.ID.String(), accessToken, "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+			assert.Nil(t, err)
+			assert.Len(t, archive.File, 1)
+
+			file, err := archive.File[0].Open()
+			assert.Nil(t, err)
+			exported := make(map[string]json.RawMessage)
+			assert.Nil(t, json.NewDecoder(file).Decode(&exported))
+			assert.Contains(t, string(exported["user"]), `"has_password": false`)
+			// Nothing but the user row and what hangs off it
+			assert.Len(t, exported, 4)
+			for _, key := range []string{"exported_at", "user", "tokens", "data_requests"} {
+				assert.Contains(t, exported, key)
+			}
+		})
+
+		t.Run("should delete the archive once the export expires", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			_, accessToken := accountUser(t)
+
+			_, body := sendAccount(t, http.MethodPost, "/v1/me/exports", accessToken, "")
+			export := dataRequest(t, body)
+
+			process, _ := processDataRequests(t)
+			assert.Nil(t, process(context.Background()))
+
+			assert.Nil(t, test.DB.Model(&model.DataRequest{}).Where("id = ?", export.ID).
+				Update("expires_at", time.Now().UTC().Add(-time.Minute)).Error)
+			assert.Nil(t, process(context.Background()))
+
+			stored := new(model.DataRequest)
+			assert.Nil(t, test.DB.First(stored, "id = ?", export.ID).Error)
+			assert.Equal(t, model.DataRequestExpired, stored.Status)
+			assert.Empty(t, stored.Archive)
+
+			_, err := test.Blobs.Get(context.Background(), "exports/"+export.ID.String()+".zip")
+			assert.NotNil(t, err)
+
+			apiResponse, _ := sendAccount(t, http.MethodGet, "/v1/me/exports/"+export.ID.String(), accessToken, "")
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 404 error if the export is another user's", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			_, accessToken := accountUser(t)
+
+			_, body := sendAccount(t, http.MethodPost, "/v1/me/exports", accessToken, "")
+			export := dataRequest(t, body)
+
+			other := &model.User{Name: "Test2", Email: "test2@gmail.com", Password: "password1", Role: "user"}
+			helper.InsertUser(test.DB, other)
+			otherAccessToken, err := fixture.AccessToken(other)
+			assert.Nil(t, err)
+
+			apiResponse, _ := sendAccount(t, http.MethodGet, "/v1/me/exports/"+export.ID.String(), otherAccessToken, "")
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+
+			apiResponse, _ = sendAccount(t, http.MethodGet, "/v1/me/exports/not-an-id", otherAccessToken, "")
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/me/erasure", func(t *testing.T) {
+		t.Run("should erase the account once confirmed and the grace period is over", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			user, accessToken := accountUser(t)
+
+			_, body := uploadAvatar(t, user.ID.String(), accessToken, "", pngPicture(t, 64, 64))
+			uploaded := new(response.SuccessWithUser)
+			assert.Nil(t, json.Unmarshal(body, uploaded))
+
+			_, body = sendAccount(t, http.MethodPost, "/v1/me/exports", accessToken, "")
+			export := dataRequest(t, body)
+			process, _ := processDataRequests(t)
+			assert.Nil(t, process(context.Background()))
+
+			apiResponse, body := sendAccount(t, http.MethodPost, "/v1/me/erasure", accessToken,
+				`{"confirm":"test1@gmail.com","current_password":"password1"}`)
+			assert.Equal(t, http.StatusAccepted, apiResponse.StatusCode)
+			erasure := dataRequest(t, body)
+			assert.Equal(t, model.DataRequestUnconfirmed, erasure.Status)
+			assert.Nil(t, erasure.ScheduledAt)
+
+			apiResponse, body = sendAccount(t, http.MethodPost, "/v1/me/erasure/confirm?token="+
+				confirmErasureToken(t, user), accessToken, "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			confirmed := dataRequest(t, body)
+			assert.Equal(t, erasure.ID, confirmed.ID)
+			assert.Equal(t, model.DataRequestPending, confirmed.Status)
+			assert.WithinDuration(t, time.Now().Add(test.Config.Privacy.ErasureGrace), *confirmed.ScheduledAt, time.Minute)
+
+			apiResponse, _ = sendAccount(t, http.MethodPost, "/v1/me/erasure", accessToken,
+				`{"confirm":"test1@gmail.com","current_password":"password1"}`)
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+
+			// Nothing is erased during the grace period
+			assert.Nil(t, process(context.Background()))
+			_, err := helper.GetUserByID(test.DB, user.ID.String())
+			assert.Nil(t, err)
+
+			assert.Nil(t, test.DB.Model(&model.DataRequest{}).Where("id = ?", erasure.ID).
+				Update("scheduled_at", time.Now().UTC().Add(-time.Minute)).Error)
+			assert.Nil(t, process(context.Background()))
+
+			var count int64
+			assert.Nil(t, test.DB.Unscoped().Model(&model.User{}).Where("id = ?", user.ID).Count(&count).Error)
+			assert.Zero(t, count)
+			assert.Nil(t, test.DB.Model(&model.Token{}).Where("user_id = ?", user.ID).Count(&count).Error)
+			assert.Zero(t, count)
+
+			apiResponse, _ = getAvatar(t, uploaded.User.AvatarURL)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+			_, err = test.Blobs.Get(context.Background(), "exports/"+export.ID.String()+".zip")
+			assert.NotNil(t, err)
+
+			var requests []model.DataRequest
+			assert.Nil(t, test.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&requests).Error)
+			assert.Len(t, requests, 2)
+			assert.Equal(t, model.DataRequestExpired, requests[0].Status)
+			assert.Equal(t, model.DataRequestCompleted, requests[1].Status)
+			assert.NotNil(t, requests[1].CompletedAt)
+
+			apiResponse, _ = sendAccount(t, http.MethodGet, "/v1/me", accessToken, "")
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 422 error if confirm isn't the email of the account", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			_, accessToken := accountUser(t)
+
+			apiResponse, body := sendAccount(t, http.MethodPost, "/v1/me/erasure", accessToken,
+				`{"confirm":"other@gmail.com","current_password":"password1"}`)
+			assert.Equal(t, http.StatusUnprocessableEntity, apiResponse.StatusCode)
+			assert.Contains(t, string(body), "confirmation-mismatch")
+		})
+
+		t.Run("should return 403 error if the current password is wrong", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			_, accessToken := accountUser(t)
+
+			apiResponse, _ := sendAccount(t, http.MethodPost, "/v1/me/erasure", accessToken,
+				`{"confirm":"test1@gmail.com","current_password":"wrongPassword1"}`)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/me/erasure of an account created with Google", func(t *testing.T) {
+		t.Run("should request the erasure with a reauth token only", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			user, accessToken := googleAccountUser(t)
+
+			apiResponse, body := sendAccount(t, http.MethodPost, "/v1/me/erasure", accessToken,
+				`{"confirm":"test1@gmail.com"}`)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+			assert.Contains(t, string(body), "reauth-required")
+
+			var count int64
+			assert.Nil(t, test.DB.Model(&model.DataRequest{}).Where("user_id = ?", user.ID).Count(&count).Error)
+			assert.Zero(t, count)
+
+			apiResponse, body = sendAccount(t, http.MethodPost, "/v1/me/erasure", accessToken,
+				`{"confirm":"test1@gmail.com","reauth_token":"`+reauthToken(t, user)+`"}`)
+			assert.Equal(t, http.StatusAccepted, apiResponse.StatusCode)
+			assert.Equal(t, model.DataRequestUnconfirmed, dataRequest(t, body).Status)
+		})
+	})
+
+	t.Run("POST /v1/me/erasure/confirm", func(t *testing.T) {
+		t.Run("should return 401 error if the token is another user's", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			_, accessToken := accountUser(t)
+
+			apiResponse, _ := sendAccount(t, http.MethodPost, "/v1/me/erasure", accessToken,
+				`{"confirm":"test1@gmail.com","current_password":"password1"}`)
+			assert.Equal(t, http.StatusAccepted, apiResponse.StatusCode)
+
+			other := &model.User{Name: "Test2", Email: "test2@gmail.com", Password: "password1", Role: "user"}
+			helper.InsertUser(test.DB, other)
+
+			apiResponse, _ = sendAccount(t, http.MethodPost, "/v1/me/erasure/confirm?token="+
+				confirmErasureToken(t, other), accessToken, "")
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+
+			// The tokens are guessed under the auth rate limit, which counts the failures
+			assert.NotEmpty(t, apiResponse.Header.Get("RateLimit-Policy"))
+		})
+
+		t.Run("should return 404 error if no erasure was requested", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			user, accessToken := accountUser(t)
+
+			apiResponse, _ := sendAccount(t, http.MethodPost, "/v1/me/erasure/confirm?token="+
+				confirmErasureToken(t, user), accessToken, "")
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("DELETE /v1/me/erasure", func(t *testing.T) {
+		t.Run("should cancel the erasure", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			user, accessToken := accountUser(t)
+
+			sendAccount(t, http.MethodPost, "/v1/me/erasure", accessToken,
+				`{"confirm":"test1@gmail.com","current_password":"password1"}`)
+			token := confirmErasureToken(t, user)
+			apiResponse, _ := sendAccount(t, http.MethodPost, "/v1/me/erasure/confirm?token="+token, accessToken, "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse, body := sendAccount(t, http.MethodDelete, "/v1/me/erasure", accessToken, "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, model.DataRequestCancelled, dataRequest(t, body).Status)
+
+			apiResponse, _ = sendAccount(t, http.MethodDelete, "/v1/me/erasure", accessToken, "")
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 409 error if the job is running the erasure", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			user, accessToken := accountUser(t)
+
+			erasure := dueErasure(t, user, accessToken)
+			assert.Nil(t, test.DB.Model(&model.DataRequest{}).Where("id = ?", erasure.ID).
+				Update("status", model.DataRequestProcessing).Error)
+
+			apiResponse, body := sendAccount(t, http.MethodDelete, "/v1/me/erasure", accessToken, "")
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+			assert.Contains(t, string(body), "erasure-running")
+		})
+
+		t.Run("should keep the account if cancelled after the job listed the erasure", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			user, accessToken := accountUser(t)
+			dueErasure(t, user, accessToken)
+
+			dataRequests := repository.NewDataRequestRepository(test.DB)
+			now := time.Now().UTC()
+			due, err := dataRequests.ListDue(context.Background(), model.DataRequestErasure, now, now.Add(-time.Hour), 10)
+			assert.Nil(t, err)
+			assert.Len(t, due, 1)
+
+			apiResponse, _ := sendAccount(t, http.MethodDelete, "/v1/me/erasure", accessToken, "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			claimed, err := dataRequests.Claim(context.Background(), &due[0], now.Add(-time.Hour))
+			assert.Nil(t, err)
+			assert.False(t, claimed)
+
+			process, _ := processDataRequests(t)
+			assert.Nil(t, process(context.Background()))
+			_, err = helper.GetUserByID(test.DB, user.ID.String())
+			assert.Nil(t, err)
+
+			stored := new(model.DataRequest)
+			assert.Nil(t, test.DB.First(stored, "id = ?", due[0].ID).Error)
+			assert.Equal(t, model.DataRequestCancelled, stored.Status)
+			assert.Nil(t, stored.CompletedAt)
+		})
+	})
+
+	t.Run("process-data-requests job", func(t *testing.T) {
+		t.Run("should claim a due request once", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			user, accessToken := accountUser(t)
+			dueErasure(t, user, accessToken)
+
+			dataRequests := repository.NewDataRequestRepository(test.DB)
+			now := time.Now().UTC()
+			due, err := dataRequests.ListDue(context.Background(), model.DataRequestErasure, now, now.Add(-time.Hour), 10)
+			assert.Nil(t, err)
+			assert.Len(t, due, 1)
+			again := due[0]
+
+			claimed, err := dataRequests.Claim(context.Background(), &due[0], now.Add(-time.Hour))
+			assert.Nil(t, err)
+			assert.True(t, claimed)
+			assert.Equal(t, model.DataRequestProcessing, due[0].Status)
+
+			claimed, err = dataRequests.Claim(context.Background(), &again, now.Add(-time.Hour))
+			assert.Nil(t, err)
+			assert.False(t, claimed)
+		})
+
+		t.Run("should erase the account once when run by several instances", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			user, accessToken := accountUser(t)
+			erasure := dueErasure(t, user, accessToken)
+
+			// Both instances list the erasure before either runs it
+			var listed, wg sync.WaitGroup
+			var erased atomic.Int32
+			listed.Add(2)
+			for i := 0; i < 2; i++ {
+				process, _ := processDataRequestsFrom(t, erasuresListedTogether{
+					DataRequestRepository: repository.NewDataRequestRepository(test.DB),
+					listed:                &listed,
+					erased:                &erased,
+				})
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					assert.Nil(t, process(context.Background()))
+				}()
+			}
+			wg.Wait()
+			assert.Equal(t, int32(1), erased.Load())
+
+			var count int64
+			assert.Nil(t, test.DB.Unscoped().Model(&model.User{}).Where("id = ?", user.ID).Count(&count).Error)
+			assert.Zero(t, count)
+
+			stored := new(model.DataRequest)
+			assert.Nil(t, test.DB.First(stored, "id = ?", erasure.ID).Error)
+			assert.Equal(t, model.DataRequestCompleted, stored.Status)
+		})
+
+		t.Run("should run an erasure left by a run which died", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearDataRequests(test.DB)
+			user, accessToken := accountUser(t)
+			erasure := dueErasure(t, user, accessToken)
+
+			assert.Nil(t, test.DB.Model(&model.DataRequest{}).Where("id = ?", erasure.ID).
+				Updates(map[string]any{
+					"status":     model.DataRequestProcessing,
+					"updated_at": time.Now().UTC().Add(-2 * time.Hour),
+				}).Error)
+
+			process, _ := processDataRequests(t)
+			assert.Nil(t, process(context.Background()))
+
+			_, err := helper.GetUserByID(test.DB, user.ID.String())
+			assert.NotNil(t, err)
+
+			stored := new(model.DataRequest)
+			assert.Nil(t, test.DB.First(stored, "id = ?", erasure.ID).Error)
+			assert.Equal(t, model.DataRequestCompleted, stored.Status)
+		})
+	})
+}
@@ -0,0 +1,98 @@
+package integration
+
+import (
+	"app/test"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sendForProblem(t *testing.T, method, path, body string) (*http.Response, map[string]interface{}) {
+	t.Helper()
+
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+
+	apiResponse, err := test.App.Test(request)
+	assert.Nil(t, err)
+
+	bytes, err := io.ReadAll(apiResponse.Body)
+	assert.Nil(t, err)
+
+	responseBody := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(bytes, &responseBody))
+
+	return apiResponse, responseBody
+}
+
+func TestProblems(t *testing.T) {
+	t.Run("should key the validation errors by the json fields of the request", func(t *testing.T) {
+		helper.ClearAll(test.DB)
+
+		apiResponse, responseBody := sendForProblem(t, http.MethodPost, "/v1/auth/register",
+			`{"name":"Test","email":"invalid","password":"short"}`)
+
+		assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		assert.Equal(t, "application/problem+json", apiResponse.Header.Get("Content-Type"))
+		assert.Equal(t, "validation-failed", responseBody["code"])
+		assert.Equal(t, "/v1/problems/validation-failed", responseBody["type"])
+		assert.Equal(t, "/v1/auth/register", responseBody["instance"])
+		assert.Equal(t, apiResponse.Header.Get("X-Request-ID"), responseBody["request_id"])
+
+		errors, ok := responseBody["errors"].(map[string]interface{})
+		assert.True(t, ok)
+		assert.Len(t, errors, 2)
+		assert.Equal(t, map[string]interface{}{
+			"code":    "email",
+			"message": "Invalid email address for field email",
+		}, errors["email"])
+		assert.Equal(t, "min", errors["password"].(map[string]interface{})["code"])
+	})
+
+	t.Run("should return a problem for the unknown endpoints", func(t *testing.T) {
+		apiResponse, responseBody := sendForProblem(t, http.MethodGet, "/v1/unknown", "")
+
+		assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		assert.Equal(t, "endpoint-not-found", responseBody["code"])
+		assert.Equal(t, "Endpoint Not Found", responseBody["title"])
+	})
+
+	t.Run("GET /v1/problems", func(t *testing.T) {
+		t.Run("should list the codes of the catalogue", func(t *testing.T) {
+			apiResponse, responseBody := sendForProblem(t, http.MethodGet, "/v1/problems", "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			codes := make(map[string]bool)
+			for _, result := range responseBody["results"].([]interface{}) {
+				codes[result.(map[string]interface{})["code"].(string)] = true
+			}
+			assert.True(t, codes["validation-failed"])
+			assert.True(t, codes["user-not-found"])
+		})
+
+		t.Run("should resolve the type of a problem", func(t *testing.T) {
+			apiResponse, responseBody := sendForProblem(t, http.MethodGet, "/v1/problems/email-taken", "")
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, map[string]interface{}{
+				"type":   "/v1/problems/email-taken",
+				"code":   "email-taken",
+				"title":  "Email is already in use",
+				"status": float64(http.StatusConflict),
+			}, responseBody["problem"])
+		})
+
+		t.Run("should return 404 error if the code is unknown", func(t *testing.T) {
+			apiResponse, responseBody := sendForProblem(t, http.MethodGet, "/v1/problems/unknown", "")
+
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+			assert.Equal(t, "problem-not-found", responseBody["code"])
+		})
+	})
+}
@@ -0,0 +1,128 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/job"
+	"app/src/middleware"
+	"app/src/model"
+	"app/src/repository"
+	"app/src/utils"
+	"app/test"
+	"app/test/helper"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitRepository(t *testing.T) {
+	ctx := context.Background()
+	rateLimits := repository.NewRateLimitRepository(test.DB)
+
+	t.Run("should count the requests of a key until its window is over", func(t *testing.T) {
+		helper.ClearRateLimits(test.DB)
+
+		first, err := rateLimits.Hit(ctx, "login:a", time.Minute)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, first.Hits)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), first.ResetAt, time.Second)
+
+		second, err := rateLimits.Hit(ctx, "login:a", time.Minute)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, second.Hits)
+		assert.WithinDuration(t, first.ResetAt, second.ResetAt, time.Millisecond)
+
+		assert.Nil(t, test.DB.Model(&model.RateLimit{}).Where("rate_key = ?", "login:a").
+			Update("reset_at", time.Now().UTC().Add(-time.Second)).Error)
+
+		next, err := rateLimits.Hit(ctx, "login:a", time.Minute)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, next.Hits)
+		assert.True(t, next.ResetAt.After(time.Now()))
+	})
+
+	t.Run("should count concurrent requests once each", func(t *testing.T) {
+		helper.ClearRateLimits(test.DB)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := rateLimits.Hit(ctx, "login:a", time.Minute)
+				assert.Nil(t, err)
+			}()
+		}
+		wg.Wait()
+
+		window, err := rateLimits.Hit(ctx, "login:a", time.Minute)
+		assert.Nil(t, err)
+		assert.Equal(t, 11, window.Hits)
+	})
+
+	t.Run("should take back a request", func(t *testing.T) {
+		helper.ClearRateLimits(test.DB)
+
+		_, _ = rateLimits.Hit(ctx, "login:a", time.Minute)
+		assert.Nil(t, rateLimits.Undo(ctx, "login:a"))
+		assert.Nil(t, rateLimits.Undo(ctx, "login:a"))
+
+		window, err := rateLimits.Hit(ctx, "login:a", time.Minute)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, window.Hits)
+	})
+
+	t.Run("should purge the counters of the windows that are over", func(t *testing.T) {
+		helper.ClearRateLimits(test.DB)
+
+		_, _ = rateLimits.Hit(ctx, "login:a", time.Minute)
+		_, _ = rateLimits.Hit(ctx, "login:b", time.Hour)
+		assert.Nil(t, test.DB.Model(&model.RateLimit{}).Where("rate_key = ?", "login:a").
+			Update("reset_at", time.Now().UTC().Add(-time.Second)).Error)
+
+		assert.Nil(t, job.PurgeRateLimits(rateLimits)(ctx))
+
+		var counters []model.RateLimit
+		assert.Nil(t, test.DB.Find(&counters).Error)
+		assert.Len(t, counters, 1)
+		assert.Equal(t, "login:b", counters[0].Key)
+	})
+
+	t.Run("should share the limit between the apps using the database", func(t *testing.T) {
+		helper.ClearRateLimits(test.DB)
+
+		live := config.NewLive(&config.Config{RateLimit: config.RateLimit{
+			Policies: map[string]config.RateLimitPolicy{
+				config.RateLimitLogin: {Key: config.RateLimitByEmail, Max: 2, Window: time.Minute, FailedOnly: true},
+			},
+		}}, nil)
+
+		// Two instances of the app, or two prefork children
+		apps := make([]*fiber.App, 2)
+		for i := range apps {
+			limiter := middleware.NewRateLimiter(repository.NewRateLimitRepository(test.DB), live)
+			apps[i] = fiber.New(fiber.Config{ErrorHandler: utils.ErrorHandler})
+			apps[i].Post("/v1/auth/login", limiter.Limit(config.RateLimitLogin), func(c *fiber.Ctx) error {
+				return fiber.NewError(fiber.StatusUnauthorized, "Invalid email or password")
+			})
+		}
+
+		statuses := make([]int, 3)
+		for i := range statuses {
+			request := httptest.NewRequest(fiber.MethodPost, "/v1/auth/login",
+				strings.NewReader(`{"email":"test1@gmail.com","password":"password1"}`))
+			request.Header.Set("Content-Type", "application/json")
+
+			res, err := apps[i%2].Test(request)
+			assert.Nil(t, err)
+			statuses[i] = res.StatusCode
+		}
+
+		assert.Equal(t, []int{fiber.StatusUnauthorized, fiber.StatusUnauthorized, fiber.StatusTooManyRequests}, statuses)
+	})
+}
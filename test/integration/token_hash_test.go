@@ -0,0 +1,74 @@
+package integration
+
+import (
+	"app/src/database"
+	"app/src/utils"
+	"app/test"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const (
+	// tokensVersion is the last migration storing the tokens in plain text
+	tokensVersion = 20241022090000
+	// hashTokensVersion converts the stored tokens to their hash
+	hashTokensVersion = 20241023090000
+)
+
+// migrationDB connects to an empty database of the driver of the tests, a
+// SQLite file or a database created for the test and dropped after it
+func migrationDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	cfg := test.Config.DB
+	cfg.ReplicaHosts = nil
+	if cfg.Driver == database.DriverSQLite {
+		cfg.Name = filepath.Join(t.TempDir(), "migrations.db")
+		return database.Connect(cfg)
+	}
+
+	cfg.Name += "_migrations"
+	require.NoError(t, test.DB.Exec("DROP DATABASE IF EXISTS "+cfg.Name).Error)
+	require.NoError(t, test.DB.Exec("CREATE DATABASE "+cfg.Name).Error)
+
+	db := database.Connect(cfg)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		test.DB.Exec("DROP DATABASE IF EXISTS " + cfg.Name)
+	})
+
+	return db
+}
+
+// The hash-stored-tokens migration converts the existing rows in SQL, its
+// hash has to match the one computed by the app
+func TestTokenHashMigration(t *testing.T) {
+	db := migrationDB(t)
+	driver := test.Config.DB.Driver
+	require.NoError(t, database.MigrateTo(db, driver, tokensVersion))
+
+	userID := "e088d183-9eea-4a11-8d5d-74d7ec91bdf5"
+	token := "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJzdWIiOiIxIn0.signature"
+	require.NoError(t, db.Exec("INSERT INTO users (id, name, email, password, role) VALUES (?, ?, ?, ?, ?)",
+		userID, "Test", "test@gmail.com", "password", "user").Error)
+	require.NoError(t, db.Exec("INSERT INTO tokens (id, token, user_id, type) VALUES (?, ?, ?, ?)",
+		"3c4d5e6f-7a8b-4c9d-8e0f-1a2b3c4d5e6f", token, userID, "refresh").Error)
+
+	require.NoError(t, database.MigrateTo(db, driver, hashTokensVersion))
+
+	var stored []string
+	require.NoError(t, db.Raw("SELECT token FROM tokens").Scan(&stored).Error)
+
+	if driver == database.DriverSQLite {
+		// SQLite has no SHA-256 function, the tokens are removed and users sign in again
+		assert.Empty(t, stored)
+		return
+	}
+	assert.Equal(t, []string{utils.HashToken(token)}, stored)
+}
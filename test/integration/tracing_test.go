@@ -0,0 +1,256 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/controller"
+	"app/src/middleware"
+	"app/src/repository"
+	"app/src/service"
+	"app/src/tracing"
+	"app/src/utils"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/oauth2"
+)
+
+const (
+	incomingTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	incomingSpanID  = "00f067aa0ba902b7"
+)
+
+func spanAttribute(span *tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing(t *testing.T) {
+	t.Run("HTTP requests", func(t *testing.T) {
+		t.Run("should continue the incoming trace with child spans for queries", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			spans := helper.RecordSpans()
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/users/"+fixture.UserOne.ID.String(), nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+			request.Header.Set("traceparent", "00-"+incomingTraceID+"-"+incomingSpanID+"-01")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			stubs := spans.GetSpans()
+			server := helper.FindSpan(stubs, "GET /v1/users/:userId")
+			if !assert.NotNil(t, server) {
+				return
+			}
+
+			assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+			assert.Equal(t, incomingTraceID, server.SpanContext.TraceID().String())
+			assert.Equal(t, incomingSpanID, server.Parent.SpanID().String())
+			assert.True(t, server.Parent.IsRemote())
+			assert.Equal(t, int64(http.StatusOK), spanAttribute(server, "http.response.status_code").AsInt64())
+
+			queries := 0
+			for i := range stubs {
+				if strings.HasPrefix(stubs[i].Name, "gorm.select users") {
+					queries++
+					assert.Equal(t, server.SpanContext.SpanID(), stubs[i].Parent.SpanID())
+					assert.Equal(t, trace.SpanKindClient, stubs[i].SpanKind)
+					assert.Contains(t, spanAttribute(&stubs[i], "db.query.text").AsString(), "users")
+				}
+			}
+			// One query in middleware.Auth, one in the controller
+			assert.Equal(t, 2, queries)
+		})
+
+		t.Run("should start a new trace without traceparent", func(t *testing.T) {
+			spans := helper.RecordSpans()
+
+			_, err := test.App.Test(httptest.NewRequest(http.MethodGet, "/v1/health-check", nil))
+			assert.Nil(t, err)
+
+			server := helper.FindSpan(spans.GetSpans(), "GET /v1/health-check/")
+			if assert.NotNil(t, server) {
+				assert.False(t, server.Parent.IsValid())
+			}
+		})
+
+		t.Run("should mark the span of a failed request as an error", func(t *testing.T) {
+			spans := helper.RecordSpans()
+
+			app := fiber.New(fiber.Config{ErrorHandler: utils.ErrorHandler})
+			app.Use(middleware.Tracing())
+			app.Get("/fail", func(_ *fiber.Ctx) error {
+				return errors.New("boom")
+			})
+
+			apiResponse, err := app.Test(httptest.NewRequest(http.MethodGet, "/fail", nil))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusInternalServerError, apiResponse.StatusCode)
+
+			server := helper.FindSpan(spans.GetSpans(), "GET /fail")
+			if assert.NotNil(t, server) {
+				assert.Equal(t, codes.Error, server.Status.Code)
+			}
+		})
+	})
+
+	t.Run("Logs", func(t *testing.T) {
+		t.Run("should carry the trace id", func(t *testing.T) {
+			helper.RecordSpans()
+
+			lines := captureLogs(t, logrus.InfoLevel, func() {
+				request := httptest.NewRequest(http.MethodGet, "/v1/health-check", nil)
+				request.Header.Set("traceparent", "00-"+incomingTraceID+"-"+incomingSpanID+"-01")
+
+				_, err := test.App.Test(request)
+				assert.Nil(t, err)
+			})
+
+			line := findLog(lines, "Request completed")
+			if assert.NotNil(t, line) {
+				assert.Equal(t, incomingTraceID, line["trace_id"])
+				assert.Len(t, line["span_id"], 16)
+			}
+		})
+	})
+
+	t.Run("SMTP", func(t *testing.T) {
+		t.Run("should add a span for each email sent", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.CreateUser(test.DB, "tracing@gmail.com", "password1", "Tracing User")
+
+			spans := helper.RecordSpans()
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/forgot-password",
+				strings.NewReader(`{"email":"tracing@gmail.com"}`))
+			request.Header.Set("Content-Type", "application/json")
+
+			_, err := test.App.Test(request, 5000)
+			assert.Nil(t, err)
+
+			stubs := spans.GetSpans()
+			server := helper.FindSpan(stubs, "POST /v1/auth/forgot-password")
+			smtp := helper.FindSpan(stubs, "smtp.send")
+			if assert.NotNil(t, server) && assert.NotNil(t, smtp) {
+				assert.Equal(t, server.SpanContext.SpanID(), smtp.Parent.SpanID())
+				assert.Equal(t, trace.SpanKindClient, smtp.SpanKind)
+			}
+		})
+	})
+
+	t.Run("Outbound HTTP", func(t *testing.T) {
+		t.Run("should add a client span and propagate traceparent", func(t *testing.T) {
+			spans := helper.RecordSpans()
+
+			var traceparent string
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				traceparent = r.Header.Get("traceparent")
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer upstream.Close()
+
+			ctx, parent := tracing.Tracer().Start(context.Background(), "parent")
+			request, err := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+			assert.Nil(t, err)
+
+			response, err := tracing.HTTPClient.Do(request)
+			assert.Nil(t, err)
+			response.Body.Close()
+			parent.End()
+
+			var client *tracetest.SpanStub
+			stubs := spans.GetSpans()
+			for i := range stubs {
+				if stubs[i].SpanKind == trace.SpanKindClient {
+					client = &stubs[i]
+				}
+			}
+
+			if assert.NotNil(t, client) {
+				assert.Equal(t, parent.SpanContext().SpanID(), client.Parent.SpanID())
+				assert.Contains(t, traceparent, parent.SpanContext().TraceID().String())
+				assert.Contains(t, traceparent, client.SpanContext.SpanID().String())
+			}
+		})
+
+		t.Run("should keep the Google access token out of the spans", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			spans := helper.RecordSpans()
+
+			const accessToken = "google-access-token"
+			var authorization string
+			google := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/token":
+					_, _ = w.Write([]byte(`{"access_token":"` + accessToken + `","token_type":"Bearer","expires_in":3600}`))
+				case "/userinfo":
+					authorization = r.Header.Get("Authorization")
+					_, _ = w.Write([]byte(`{"name":"Google","email":"google@gmail.com","verified_email":true}`))
+				}
+			}))
+			defer google.Close()
+
+			validate := validation.Validator()
+			users := repository.NewUserRepository(test.DB)
+			tokens := repository.NewTokenRepository(test.DB)
+			userService := service.NewUserService(users, validate)
+			tokenService := service.NewTokenService(tokens, repository.NewUnitOfWork(test.DB), validate, userService,
+				config.NewLive(test.Config, nil))
+
+			authController := controller.NewAuthController(nil, userService, tokenService, nil, test.Config.Google)
+			authController.GoogleConfig.Endpoint = oauth2.Endpoint{
+				TokenURL: google.URL + "/token", AuthStyle: oauth2.AuthStyleInParams,
+			}
+			authController.GoogleUserInfoURL = google.URL + "/userinfo"
+
+			app := fiber.New()
+			app.Get("/google-callback", authController.GoogleCallback)
+
+			request := httptest.NewRequest(http.MethodGet, "/google-callback?state=state&code=code", nil)
+			request.Header.Set("Cookie", "oauth_state=state")
+
+			apiResponse, err := app.Test(request, -1)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, "Bearer "+accessToken, authorization)
+
+			stubs := spans.GetSpans()
+			clients := 0
+			for _, stub := range stubs {
+				if stub.SpanKind == trace.SpanKindClient {
+					clients++
+				}
+				for _, kv := range stub.Attributes {
+					assert.NotContains(t, kv.Value.Emit(), accessToken, string(kv.Key))
+				}
+			}
+			assert.Equal(t, 2, clients)
+		})
+	})
+}
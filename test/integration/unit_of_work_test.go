@@ -0,0 +1,103 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/repository"
+	"app/src/service"
+	"app/src/utils"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitOfWork(t *testing.T) {
+	ctx := context.Background()
+	validate := validation.Validator()
+
+	users := &helper.FailingUserRepository{
+		UserRepository: repository.NewUserRepository(test.DB),
+		Fail:           make(map[string]bool),
+	}
+	tokens := &helper.FailingTokenRepository{
+		TokenRepository: repository.NewTokenRepository(test.DB),
+		Fail:            make(map[string]bool),
+	}
+	unitOfWork := repository.NewUnitOfWork(test.DB)
+
+	userService := service.NewUserService(users, validate)
+	tokenService := service.NewTokenService(tokens, unitOfWork, validate, userService, test.Live)
+	authService := service.NewAuthService(users, unitOfWork, validate, userService, tokenService)
+
+	setup := func(t *testing.T) *model.User {
+		users.Fail = make(map[string]bool)
+		tokens.Fail = make(map[string]bool)
+
+		helper.ClearAll(test.DB)
+		helper.CreateUser(test.DB, "test@gmail.com", "password1", "Test User")
+
+		user := new(model.User)
+		assert.Nil(t, test.DB.Where("email = ?", "test@gmail.com").First(user).Error)
+
+		return user
+	}
+
+	t.Run("should roll back the password update if deleting reset tokens fails", func(t *testing.T) {
+		user := setup(t)
+		token, err := fixture.ResetPasswordToken(user)
+		assert.Nil(t, err)
+		assert.Nil(t, helper.SaveToken(test.DB, token, user.ID.String(),
+			config.TokenTypeResetPassword, fixture.ExpiresResetPasswordToken))
+
+		tokens.Fail["DeleteByType"] = true
+		err = authService.ResetPassword(ctx, &validation.Token{Token: token},
+			&validation.UpdatePassOrVerify{Password: "newPassword1"})
+		assert.ErrorIs(t, err, helper.ErrInjected)
+
+		stored, err := helper.GetUserByID(test.DB, user.ID.String())
+		assert.Nil(t, err)
+		assert.True(t, utils.CheckPasswordHash("password1", stored.Password))
+
+		_, err = helper.GetTokenByType(test.DB, user.ID.String(), config.TokenTypeResetPassword)
+		assert.Nil(t, err)
+	})
+
+	t.Run("should roll back the token deletion if verifying the email fails", func(t *testing.T) {
+		user := setup(t)
+		token, err := fixture.VerifyEmailToken(user)
+		assert.Nil(t, err)
+		assert.Nil(t, helper.SaveToken(test.DB, token, user.ID.String(),
+			config.TokenTypeVerifyEmail, fixture.ExpiresVerifyEmailToken))
+
+		users.Fail["Update"] = true
+		err = authService.VerifyEmail(ctx, &validation.Token{Token: token})
+		assert.ErrorIs(t, err, helper.ErrInjected)
+
+		stored, err := helper.GetUserByID(test.DB, user.ID.String())
+		assert.Nil(t, err)
+		assert.False(t, stored.VerifiedEmail)
+
+		_, err = helper.GetTokenByType(test.DB, user.ID.String(), config.TokenTypeVerifyEmail)
+		assert.Nil(t, err)
+	})
+
+	t.Run("should keep the previous refresh token if saving the new one fails", func(t *testing.T) {
+		user := setup(t)
+		token, err := fixture.RefreshToken(user)
+		assert.Nil(t, err)
+		assert.Nil(t, helper.SaveToken(test.DB, token, user.ID.String(),
+			config.TokenTypeRefresh, fixture.ExpiresRefreshToken))
+
+		tokens.Fail["Create"] = true
+		_, err = tokenService.GenerateAuthTokens(ctx, user)
+		assert.ErrorIs(t, err, helper.ErrInjected)
+
+		_, err = helper.GetTokenByUserID(test.DB, test.Config.JWT.Secret, token)
+		assert.Nil(t, err)
+	})
+}
@@ -1,18 +1,24 @@
 package integration
 
 import (
+	"app/src/job"
 	"app/src/model"
+	"app/src/repository"
 	"app/src/response"
+	"app/src/service"
 	"app/src/validation"
 	"app/test"
 	"app/test/fixture"
 	"app/test/helper"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -187,6 +193,33 @@ func TestUserRoutes(t *testing.T) {
 			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
 		})
 
+		t.Run("should return 409 error if email belongs to a deleted user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin, fixture.UserOne)
+			assert.Nil(t, helper.SoftDeleteUser(test.DB, fixture.UserOne.ID.String()))
+			newUser.Email = fixture.UserOne.Email
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			bodyJSON, err := json.Marshal(newUser)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(string(bodyJSON)))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Accept", "application/json")
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			responseBody := make(map[string]interface{})
+			assert.Nil(t, json.NewDecoder(apiResponse.Body).Decode(&responseBody))
+
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+			assert.Equal(t, "email-taken", responseBody["code"])
+		})
+
 		t.Run("should return 400 error if password length is less than 8 characters", func(t *testing.T) {
 			helper.ClearAll(test.DB)
 			helper.InsertUser(test.DB, fixture.Admin)
@@ -417,6 +450,179 @@ func TestUserRoutes(t *testing.T) {
 			assert.Len(t, responseBody.Results, 1)
 			assert.Equal(t, fixture.Admin.ID, responseBody.Results[0].ID)
 		})
+
+		t.Run("should return the next page when following next_cursor", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/users?limit=2", nil)
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			firstPage := new(response.SuccessWithPaginate[model.User])
+
+			err = json.Unmarshal(bytes, firstPage)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.NotEmpty(t, firstPage.NextCursor)
+
+			request = httptest.NewRequest(http.MethodGet, "/v1/users?limit=2&cursor="+firstPage.NextCursor, nil)
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err = test.App.Test(request)
+			assert.Nil(t, err)
+
+			bytes, err = io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			secondPage := new(response.SuccessWithPaginate[model.User])
+
+			err = json.Unmarshal(bytes, secondPage)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Empty(t, secondPage.NextCursor)
+			assert.Equal(t, int64(0), secondPage.TotalResults)
+			assert.Len(t, secondPage.Results, 1)
+			assert.Equal(t, fixture.Admin.ID, secondPage.Results[0].ID)
+		})
+
+		t.Run("should return 400 error if limit is 0", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/users?limit=0&include_total=true", nil)
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should sort and filter users by the whitelisted columns", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/users?sortBy=name&order=desc&role=user", nil)
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithPaginate[model.User])
+
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, int64(2), responseBody.TotalResults)
+			assert.Len(t, responseBody.Results, 2)
+			assert.Equal(t, fixture.UserTwo.ID, responseBody.Results[0].ID)
+			assert.Equal(t, fixture.UserOne.ID, responseBody.Results[1].ID)
+		})
+
+		t.Run("should search users case-insensitively", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/users?search=TEST1", nil)
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithPaginate[model.User])
+
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, int64(1), responseBody.TotalResults)
+			assert.Len(t, responseBody.Results, 1)
+			assert.Equal(t, fixture.UserOne.ID, responseBody.Results[0].ID)
+		})
+
+		t.Run("should treat LIKE wildcards in the search term literally", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/users?search=%25", nil)
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithPaginate[model.User])
+
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Len(t, responseBody.Results, 0)
+		})
+
+		t.Run("should return 400 error if sortBy is not allowed", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/users?sortBy=password", nil)
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if cursor is invalid", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/users?cursor=invalid", nil)
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
 	})
 
 	t.Run("GET /v1/users/:userId", func(t *testing.T) {
@@ -523,6 +729,48 @@ func TestUserRoutes(t *testing.T) {
 
 			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
 		})
+		t.Run("should return the version of the user as ETag and 304 if it is current", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/users/"+fixture.UserOne.ID.String(), nil)
+			request.Header.Set("Authorization", "Bearer "+userOneAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, `"1"`, apiResponse.Header.Get("ETag"))
+
+			request = httptest.NewRequest(http.MethodGet, "/v1/users/"+fixture.UserOne.ID.String(), nil)
+			request.Header.Set("Authorization", "Bearer "+userOneAccessToken)
+			request.Header.Set("If-None-Match", `"1"`)
+
+			apiResponse, err = test.App.Test(request)
+			assert.Nil(t, err)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusNotModified, apiResponse.StatusCode)
+			assert.Empty(t, bytes)
+
+			assert.Nil(t, test.DB.Model(&model.User{}).Where("id = ?", fixture.UserOne.ID).
+				Update("version", 2).Error)
+
+			request = httptest.NewRequest(http.MethodGet, "/v1/users/"+fixture.UserOne.ID.String(), nil)
+			request.Header.Set("Authorization", "Bearer "+userOneAccessToken)
+			request.Header.Set("If-None-Match", `"1"`)
+
+			apiResponse, err = test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, `"2"`, apiResponse.Header.Get("ETag"))
+		})
 	})
 
 	t.Run("DELETE /v1/users/:userId", func(t *testing.T) {
@@ -543,6 +791,10 @@ func TestUserRoutes(t *testing.T) {
 
 			user, _ := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
 			assert.Nil(t, user)
+
+			deletedUser, err := helper.GetDeletedUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.NotNil(t, deletedUser)
 		})
 
 		t.Run("should return 401 error if access token is missing", func(t *testing.T) {
@@ -619,6 +871,200 @@ func TestUserRoutes(t *testing.T) {
 
 			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
 		})
+		t.Run("should return 412 error if If-Match is not the current version", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			for _, ifMatch := range []string{`"2"`, `W/"1"`, "invalid"} {
+				request := httptest.NewRequest(http.MethodDelete, "/v1/users/"+fixture.UserOne.ID.String(), nil)
+				request.Header.Set("Authorization", "Bearer "+userOneAccessToken)
+				request.Header.Set("If-Match", ifMatch)
+
+				apiResponse, err := test.App.Test(request)
+				assert.Nil(t, err)
+
+				assert.Equal(t, http.StatusPreconditionFailed, apiResponse.StatusCode, ifMatch)
+			}
+
+			user, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.NotNil(t, user)
+
+			request := httptest.NewRequest(http.MethodDelete, "/v1/users/"+fixture.UserOne.ID.String(), nil)
+			request.Header.Set("Authorization", "Bearer "+userOneAccessToken)
+			request.Header.Set("If-Match", `"1"`)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/users/:userId/restore", func(t *testing.T) {
+		t.Run("should return 200 and restore the user if admin is restoring a deleted user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+			assert.Nil(t, helper.SoftDeleteUser(test.DB, fixture.UserOne.ID.String()))
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/users/"+fixture.UserOne.ID.String()+"/restore", nil)
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithUser)
+
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, fixture.UserOne.ID, responseBody.User.ID)
+			assert.Equal(t, `"2"`, apiResponse.Header.Get("ETag"))
+
+			user, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.NotNil(t, user)
+			assert.Equal(t, int64(2), user.Version)
+		})
+
+		t.Run("should return 403 error if user is trying to restore another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			assert.Nil(t, helper.SoftDeleteUser(test.DB, fixture.UserTwo.ID.String()))
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/users/"+fixture.UserTwo.ID.String()+"/restore", nil)
+			request.Header.Set("Authorization", "Bearer "+userOneAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 404 error if user is not deleted", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/users/"+fixture.UserOne.ID.String()+"/restore", nil)
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 error if the deleted user tries to authenticate", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			assert.Nil(t, helper.SoftDeleteUser(test.DB, fixture.UserOne.ID.String()))
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/users/"+fixture.UserOne.ID.String(), nil)
+			request.Header.Set("Authorization", "Bearer "+userOneAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("Purge deleted users", func(t *testing.T) {
+		userService := service.NewUserService(repository.NewUserRepository(test.DB), validation.Validator())
+
+		t.Run("should permanently remove users deleted before the retention period", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			assert.Nil(t, helper.SoftDeleteUser(test.DB, fixture.UserOne.ID.String()))
+			assert.Nil(t, helper.SoftDeleteUser(test.DB, fixture.UserTwo.ID.String()))
+
+			purged, err := userService.PurgeDeletedUsers(context.Background(), time.Now().Add(time.Minute))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(2), purged)
+
+			user, _ := helper.GetDeletedUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, user)
+		})
+
+		t.Run("should free the emails of the purged users", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			assert.Nil(t, helper.SoftDeleteUser(test.DB, fixture.UserOne.ID.String()))
+
+			_, err := userService.PurgeDeletedUsers(context.Background(), time.Now().Add(time.Minute))
+			assert.Nil(t, err)
+
+			user, err := userService.CreateUser(context.Background(), &validation.CreateUser{
+				Name:     "Test",
+				Email:    fixture.UserOne.Email,
+				Password: "password1",
+				Role:     "user",
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, fixture.UserOne.Email, user.Email)
+		})
+
+		t.Run("should delete the avatars of the purged users", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			_, body := uploadAvatar(t, fixture.UserOne.ID.String(), accessToken, "", pngPicture(t, 64, 64))
+			uploaded := new(response.SuccessWithUser)
+			assert.Nil(t, json.Unmarshal(body, uploaded))
+			stored, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.NotEmpty(t, stored.Avatar)
+			assert.Nil(t, helper.SoftDeleteUser(test.DB, fixture.UserOne.ID.String()))
+
+			users := repository.NewUserRepository(test.DB)
+			avatarService := service.NewAvatarService(users, test.Blobs, test.Config.Avatar)
+			purge := job.PurgeDeletedUsers(userService, avatarService, -time.Minute)
+			assert.Nil(t, purge(context.Background()))
+
+			user, _ := helper.GetDeletedUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, user)
+			for _, size := range test.Config.Avatar.Sizes {
+				_, err := test.Blobs.Get(context.Background(), fmt.Sprintf("avatars/%d/%s", size, stored.Avatar))
+				assert.NotNil(t, err)
+			}
+			apiResponse, _ := getAvatar(t, uploaded.User.AvatarURL)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+
+		t.Run("should keep users deleted within the retention period", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			assert.Nil(t, helper.SoftDeleteUser(test.DB, fixture.UserOne.ID.String()))
+
+			purged, err := userService.PurgeDeletedUsers(context.Background(), time.Now().Add(-time.Hour))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(0), purged)
+
+			user, err := helper.GetDeletedUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.NotNil(t, user)
+		})
 	})
 
 	t.Run("PATCH /v1/users/:userId", func(t *testing.T) {
@@ -922,5 +1368,101 @@ func TestUserRoutes(t *testing.T) {
 
 			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
 		})
+		t.Run("should return 412 error if the user was updated since If-Match was read", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			update := func(name, ifMatch string) *http.Response {
+				request := httptest.NewRequest(http.MethodPatch, "/v1/users/"+fixture.UserOne.ID.String(),
+					strings.NewReader(`{"name":"`+name+`"}`))
+				request.Header.Set("Content-Type", "application/json")
+				request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+				request.Header.Set("If-Match", ifMatch)
+
+				apiResponse, err := test.App.Test(request)
+				assert.Nil(t, err)
+
+				return apiResponse
+			}
+
+			apiResponse := update("First Admin", `"1"`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, `"2"`, apiResponse.Header.Get("ETag"))
+
+			apiResponse = update("Second Admin", `"1"`)
+			assert.Equal(t, http.StatusPreconditionFailed, apiResponse.StatusCode)
+
+			user, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, "First Admin", user.Name)
+			assert.Equal(t, int64(2), user.Version)
+
+			apiResponse = update("Second Admin", `"2"`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, `"3"`, apiResponse.Header.Get("ETag"))
+		})
+
+		t.Run("should bump the version if If-Match accepts any version", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPatch, "/v1/users/"+fixture.UserOne.ID.String(),
+				strings.NewReader(`{"name":"Golang"}`))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Authorization", "Bearer "+userOneAccessToken)
+			request.Header.Set("If-Match", "*")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, `"2"`, apiResponse.Header.Get("ETag"))
+		})
+
+		t.Run("should return 404 error if If-Match is set and the user is not found", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPatch, "/v1/users/"+fixture.UserOne.ID.String(),
+				strings.NewReader(`{"name":"Golang"}`))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+			request.Header.Set("If-Match", `"1"`)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+}
+
+func TestCreateGoogleUser(t *testing.T) {
+	userService := service.NewUserService(repository.NewUserRepository(test.DB), validation.Validator())
+
+	t.Run("should verify the email of an existing user and bump its version once", func(t *testing.T) {
+		helper.ClearAll(test.DB)
+		helper.InsertUser(test.DB, fixture.UserOne)
+
+		req := &validation.GoogleLogin{Name: "Google", Email: fixture.UserOne.Email, VerifiedEmail: true}
+
+		user, err := userService.CreateGoogleUser(context.Background(), req)
+		assert.Nil(t, err)
+		assert.True(t, user.VerifiedEmail)
+		assert.Equal(t, int64(2), user.Version)
+		assert.Equal(t, fixture.UserOne.Name, user.Name)
+
+		user, err = userService.CreateGoogleUser(context.Background(), req)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), user.Version)
 	})
 }
@@ -0,0 +1,148 @@
+package avatar_test
+
+import (
+	"app/src/avatar"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	red  = color.NRGBA{R: 255, A: 255}
+	blue = color.NRGBA{B: 255, A: 255}
+)
+
+// halves is a picture red on its left half and blue on its right half
+func halves(width, height int, alpha uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			c := red
+			if x >= width/2 {
+				c = blue
+			}
+			c.A = alpha
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// encodeJPEG inserts an EXIF segment with the orientation after the start of image
+func encodeJPEG(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}))
+	data := buf.Bytes()
+
+	tiff := []byte("MM\x00\x2A\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00")
+	binary.BigEndian.PutUint16(tiff[18:], orientation)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+func decode(t *testing.T, data []byte) image.Image {
+	t.Helper()
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	assert.NoError(t, err)
+	return img
+}
+
+// near compares colors loosely, the thumbnails are resampled and compressed
+func near(t *testing.T, expected color.NRGBA, actual color.Color) {
+	t.Helper()
+
+	r, g, b, _ := actual.RGBA()
+	diff := func(a uint8, b uint32) int { return max(int(a)-int(b>>8), int(b>>8)-int(a)) }
+	assert.True(t, diff(expected.R, r) < 48 && diff(expected.G, g) < 48 && diff(expected.B, b) < 48,
+		"expected %v, got %v", expected, actual)
+}
+
+func TestProcess(t *testing.T) {
+	t.Run("should crop the center of the picture into each size", func(t *testing.T) {
+		result, err := avatar.Process(encodePNG(t, halves(300, 100, 255)), []int{32, 64})
+		assert.NoError(t, err)
+
+		assert.Equal(t, "image/jpeg", result.ContentType)
+		assert.Equal(t, ".jpg", result.Ext)
+		assert.Len(t, result.Thumbnails, 2)
+
+		for i, size := range []int{32, 64} {
+			assert.Equal(t, size, result.Thumbnails[i].Size)
+
+			img := decode(t, result.Thumbnails[i].Data)
+			assert.Equal(t, image.Rect(0, 0, size, size), img.Bounds())
+			near(t, red, img.At(2, size/2))
+			near(t, blue, img.At(size-3, size/2))
+		}
+	})
+
+	t.Run("should keep the transparency as PNG", func(t *testing.T) {
+		result, err := avatar.Process(encodePNG(t, halves(64, 64, 0)), []int{32})
+		assert.NoError(t, err)
+
+		assert.Equal(t, "image/png", result.ContentType)
+		assert.Equal(t, ".png", result.Ext)
+
+		_, _, _, alpha := decode(t, result.Thumbnails[0].Data).At(0, 0).RGBA()
+		assert.Equal(t, uint32(0), alpha)
+	})
+
+	t.Run("should turn the picture upright and drop its EXIF segment", func(t *testing.T) {
+		// Orientation 6 is displayed rotated 90° clockwise, the left half ends on top
+		data := encodeJPEG(t, halves(64, 64, 255), 6)
+
+		result, err := avatar.Process(data, []int{32})
+		assert.NoError(t, err)
+
+		img := decode(t, result.Thumbnails[0].Data)
+		near(t, red, img.At(16, 2))
+		near(t, blue, img.At(16, 29))
+		assert.NotContains(t, string(result.Thumbnails[0].Data), "Exif")
+	})
+
+	t.Run("should return ErrUnsupportedType if the content isn't a picture", func(t *testing.T) {
+		_, err := avatar.Process([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"), []int{32})
+		assert.ErrorIs(t, err, avatar.ErrUnsupportedType)
+	})
+
+	t.Run("should return ErrInvalidImage if the picture is truncated", func(t *testing.T) {
+		data := encodePNG(t, halves(64, 64, 255))
+
+		_, err := avatar.Process(data[:60], []int{32})
+		assert.ErrorIs(t, err, avatar.ErrInvalidImage)
+	})
+
+	t.Run("should return ErrTooManyPixels before decoding a huge picture", func(t *testing.T) {
+		// Only the header is read, it claims 10000x10000 pixels
+		data := encodePNG(t, halves(1, 1, 255))
+		binary.BigEndian.PutUint32(data[16:], 10000)
+		binary.BigEndian.PutUint32(data[20:], 10000)
+		binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+		_, err := avatar.Process(data, []int{32})
+		assert.ErrorIs(t, err, avatar.ErrTooManyPixels)
+	})
+}
@@ -0,0 +1,349 @@
+package config_test
+
+import (
+	"app/src/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile writes a config file in a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+// isolate clears every key the config reads, so the environment of the shell doesn't leak in
+func isolate(t *testing.T) {
+	for _, key := range config.Keys() {
+		t.Setenv(key, "")
+	}
+}
+
+const validEnv = `
+JWT_SECRET=thisisasamplesecret
+DB_DRIVER=sqlite
+DB_NAME=app
+APP_PORT=3000
+`
+
+func TestLoad(t *testing.T) {
+	t.Run("should read the first config file that exists", func(t *testing.T) {
+		isolate(t)
+		path := writeFile(t, ".env", validEnv)
+
+		cfg, err := config.Load(nil, filepath.Join(t.TempDir(), "missing.env"), path)
+		require.NoError(t, err)
+
+		assert.Equal(t, "thisisasamplesecret", cfg.JWT.Secret)
+		assert.Equal(t, "sqlite", cfg.DB.Driver)
+		assert.Equal(t, 30*time.Minute, cfg.JWT.AccessExp)
+		assert.Equal(t, 30*24*time.Hour, cfg.JWT.RefreshExp)
+		assert.Equal(t, uint64(300*1024*1024), cfg.Health.HeapThreshold)
+		assert.False(t, cfg.App.IsProd())
+	})
+
+	t.Run("should ignore the settings of the shell", func(t *testing.T) {
+		t.Setenv("DATA_REQUEST_SCHEDULE", "every day")
+		t.Setenv("USER_IMPORT_SYNC_ROWS", "-1")
+		t.Setenv("S3_BUCKET", "from-the-shell")
+		isolate(t)
+
+		cfg, err := config.Load(nil, writeFile(t, ".env", validEnv))
+		require.NoError(t, err)
+		assert.Equal(t, "@every 1m", cfg.Jobs.DataRequestSchedule)
+		assert.Empty(t, cfg.Storage.S3.Bucket)
+	})
+
+	t.Run("should read yaml files", func(t *testing.T) {
+		isolate(t)
+		path := writeFile(t, "config.yaml", "JWT_SECRET: fromyaml\nDB_DRIVER: sqlite\nDB_NAME: app\n")
+		t.Setenv("CONFIG_FILE", path)
+
+		cfg, err := config.Load(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "fromyaml", cfg.JWT.Secret)
+	})
+
+	t.Run("should prefer flags over env and env over the file", func(t *testing.T) {
+		isolate(t)
+		path := writeFile(t, ".env", validEnv+"APP_ENV=dev\nLOG_LEVEL=debug\n")
+		t.Setenv("APP_PORT", "4000")
+		t.Setenv("APP_ENV", "staging")
+
+		flags := config.NewFlagSet("app")
+		assert.NoError(t, flags.Parse([]string{"--config", path, "--env", "prod", "purge-expired-tokens"}))
+
+		cfg, err := config.Load(flags)
+		require.NoError(t, err)
+
+		assert.Equal(t, "prod", cfg.App.Env)
+		assert.Equal(t, 4000, cfg.App.Port)
+		assert.Equal(t, "debug", cfg.Log.Level)
+		assert.Equal(t, []string{"purge-expired-tokens"}, flags.Args())
+	})
+
+	t.Run("should fail if the given config file doesn't exist", func(t *testing.T) {
+		isolate(t)
+		t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.env"))
+
+		_, err := config.Load(nil)
+		assert.ErrorContains(t, err, "failed to read config file")
+	})
+
+	t.Run("should list every invalid setting", func(t *testing.T) {
+		isolate(t)
+		path := writeFile(t, ".env", "DB_DRIVER=oracle\nAPP_PORT=70000\nLOG_LEVEL=loud\n")
+
+		_, err := config.Load(nil, path)
+		assert.ErrorContains(t, err, "JWT_SECRET is required")
+		assert.ErrorContains(t, err, `DB_DRIVER must be one of postgres, mysql, sqlite, got "oracle"`)
+		assert.ErrorContains(t, err, "DB_NAME is required")
+		assert.ErrorContains(t, err, "APP_PORT must be between 1 and 65535, got 70000")
+		assert.ErrorContains(t, err, `LOG_LEVEL "loud" is not a log level`)
+	})
+}
+
+func TestDatabase(t *testing.T) {
+	t.Run("should read the replica hosts and the pool settings", func(t *testing.T) {
+		isolate(t)
+		t.Setenv("DB_REPLICA_HOSTS", " replica-1, replica-2 ,")
+		t.Setenv("DB_MAX_OPEN_CONNS", "20")
+		t.Setenv("DB_CONN_MAX_IDLE_TIME_MINUTES", "5")
+
+		cfg, err := config.Load(nil, writeFile(t, ".env", validEnv))
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"replica-1", "replica-2"}, cfg.DB.ReplicaHosts)
+		assert.Equal(t, 10, cfg.DB.MaxIdleConns)
+		assert.Equal(t, 20, cfg.DB.MaxOpenConns)
+		assert.Equal(t, time.Hour, cfg.DB.ConnMaxLifetime)
+		assert.Equal(t, 5*time.Minute, cfg.DB.ConnMaxIdleTime)
+	})
+
+	t.Run("should have no replicas by default", func(t *testing.T) {
+		isolate(t)
+
+		cfg, err := config.Load(nil, writeFile(t, ".env", validEnv))
+		require.NoError(t, err)
+		assert.Empty(t, cfg.DB.ReplicaHosts)
+	})
+}
+
+func TestRateLimitPolicies(t *testing.T) {
+	t.Run("should default every policy and read the overridden limits", func(t *testing.T) {
+		isolate(t)
+		t.Setenv("RATE_LIMIT_LOGIN", "10/1h30m")
+
+		cfg, err := config.Load(nil, writeFile(t, ".env", validEnv))
+		require.NoError(t, err)
+
+		assert.Equal(t, config.RateLimitStoreMemory, cfg.RateLimit.Store)
+		assert.Equal(t, config.RateLimitPolicy{
+			Key: config.RateLimitByIP, Max: 20, Window: 15 * time.Minute, FailedOnly: true,
+		}, cfg.RateLimit.Policies[config.RateLimitAuth])
+		assert.Equal(t, config.RateLimitPolicy{
+			Key: config.RateLimitByEmail, Max: 10, Window: 90 * time.Minute, FailedOnly: true,
+		}, cfg.RateLimit.Policies[config.RateLimitLogin])
+		assert.Contains(t, cfg.RateLimit.Policies, config.RateLimitForgotPassword)
+		assert.Contains(t, cfg.RateLimit.Policies, config.RateLimitVerificationEmail)
+	})
+
+	t.Run("should reject invalid limits and stores", func(t *testing.T) {
+		isolate(t)
+		env := validEnv + "RATE_LIMIT_AUTH=20\nRATE_LIMIT_LOGIN=5/100ms\nRATE_LIMIT_STORE=memcached\n"
+
+		_, err := config.Load(nil, writeFile(t, ".env", env))
+		assert.ErrorContains(t, err, `RATE_LIMIT_STORE must be one of memory, database, redis, got "memcached"`)
+		assert.ErrorContains(t, err, "RATE_LIMIT_AUTH must be a positive number of requests per window of at least 1s")
+		assert.ErrorContains(t, err, "RATE_LIMIT_LOGIN must be a positive number of requests per window of at least 1s")
+	})
+}
+
+func TestSecretFiles(t *testing.T) {
+	t.Run("should read a secret from its file without the trailing newline", func(t *testing.T) {
+		isolate(t)
+		path := writeFile(t, ".env", "DB_DRIVER=sqlite\nDB_NAME=app\n")
+		t.Setenv("JWT_SECRET_FILE", writeFile(t, "jwt_secret", "fromfile\n"))
+		t.Setenv("DB_PASSWORD_FILE", writeFile(t, "db_password", "pass word\r\n"))
+
+		cfg, err := config.Load(nil, path)
+		require.NoError(t, err)
+
+		assert.Equal(t, "fromfile", cfg.JWT.Secret)
+		assert.Equal(t, "pass word", cfg.DB.Password)
+	})
+
+	t.Run("should reject a secret set both directly and from a file", func(t *testing.T) {
+		isolate(t)
+		t.Setenv("JWT_SECRET_FILE", writeFile(t, "jwt_secret", "fromfile"))
+
+		_, err := config.Load(nil, writeFile(t, ".env", validEnv))
+		assert.EqualError(t, err, "JWT_SECRET and JWT_SECRET_FILE are both set, set only one")
+	})
+
+	t.Run("should fail if the secret file can't be read", func(t *testing.T) {
+		isolate(t)
+		t.Setenv("SMTP_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+
+		_, err := config.Load(nil, writeFile(t, ".env", validEnv))
+		assert.ErrorContains(t, err, "failed to read SMTP_PASSWORD_FILE")
+	})
+}
+
+func TestValidate(t *testing.T) {
+	isolate(t)
+	valid := func(t *testing.T) *config.Config {
+		cfg, err := config.Read(nil, writeFile(t, ".env", validEnv))
+		require.NoError(t, err)
+		assert.NoError(t, cfg.Validate())
+
+		return cfg
+	}
+
+	t.Run("should require the database host and port, except for sqlite", func(t *testing.T) {
+		cfg := valid(t)
+		cfg.DB.Driver = "postgres"
+
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "DB_HOST is required")
+		assert.ErrorContains(t, err, "DB_PORT must be between 1 and 65535")
+	})
+
+	t.Run("should require the sender when SMTP is configured", func(t *testing.T) {
+		cfg := valid(t)
+		cfg.SMTP.Host = "localhost"
+		cfg.SMTP.Port = 25
+
+		assert.EqualError(t, cfg.Validate(), "invalid configuration:\nEMAIL_FROM is required when SMTP_HOST is set")
+	})
+
+	t.Run("should require the whole Google client", func(t *testing.T) {
+		cfg := valid(t)
+		cfg.Google.ClientID = "client"
+
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "GOOGLE_CLIENT_SECRET is required")
+		assert.ErrorContains(t, err, "REDIRECT_URL is required")
+	})
+
+	t.Run("should require the URL of the redis rate limit store", func(t *testing.T) {
+		cfg := valid(t)
+		cfg.RateLimit.Store = config.RateLimitStoreRedis
+
+		err := cfg.Validate()
+		assert.EqualError(t, err,
+			"invalid configuration:\nRATE_LIMIT_REDIS_URL is required when RATE_LIMIT_STORE is redis")
+	})
+
+	t.Run("should reject a sample ratio above 1", func(t *testing.T) {
+		cfg := valid(t)
+		cfg.Tracing.SampleRatio = 2
+
+		assert.ErrorContains(t, cfg.Validate(), "TRACING_SAMPLE_RATIO must be between 0 and 1")
+	})
+
+	t.Run("should require a positive idempotency key TTL", func(t *testing.T) {
+		cfg := valid(t)
+		assert.Equal(t, 24*time.Hour, cfg.Idempotency.TTL)
+		cfg.Idempotency.TTL = 0
+
+		assert.EqualError(t, cfg.Validate(), "invalid configuration:\nIDEMPOTENCY_TTL_HOURS must be positive")
+	})
+	t.Run("should default to problem errors and reject unknown formats", func(t *testing.T) {
+		cfg := valid(t)
+		assert.Equal(t, config.ErrorFormatProblem, cfg.App.ErrorFormat)
+		cfg.App.ErrorFormat = "xml"
+
+		assert.EqualError(t, cfg.Validate(), "invalid configuration:\nERROR_FORMAT must be problem or legacy, got \"xml\"")
+	})
+
+	t.Run("should require the bucket and its keys when the storage is s3", func(t *testing.T) {
+		cfg := valid(t)
+		assert.Equal(t, config.StorageDriverLocal, cfg.Storage.Driver)
+		cfg.Storage.Driver = config.StorageDriverS3
+
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "S3_ENDPOINT is required")
+		assert.ErrorContains(t, err, "S3_BUCKET is required")
+		assert.ErrorContains(t, err, "S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required")
+	})
+}
+
+func TestAvatar(t *testing.T) {
+	t.Run("should read the sizes in ascending order", func(t *testing.T) {
+		isolate(t)
+		t.Setenv("AVATAR_SIZES", "256, 64,128,64")
+
+		cfg, err := config.Load(nil, writeFile(t, ".env", validEnv))
+		require.NoError(t, err)
+
+		assert.Equal(t, []int{64, 128, 256}, cfg.Avatar.Sizes)
+		assert.Equal(t, int64(2*1024*1024), cfg.Avatar.MaxSize)
+	})
+
+	t.Run("should reject the sizes which aren't between 16 and 1024 pixels", func(t *testing.T) {
+		isolate(t)
+		t.Setenv("AVATAR_SIZES", "64,big,2048")
+
+		_, err := config.Load(nil, writeFile(t, ".env", validEnv))
+		assert.ErrorContains(t, err, "AVATAR_SIZES must be between 16 and 1024 pixels, got 0")
+		assert.ErrorContains(t, err, "AVATAR_SIZES must be between 16 and 1024 pixels, got 2048")
+	})
+}
+
+func TestPrivacy(t *testing.T) {
+	t.Run("should read the retention and the grace period in days", func(t *testing.T) {
+		isolate(t)
+		t.Setenv("EXPORT_RETENTION_DAYS", "3")
+
+		cfg, err := config.Load(nil, writeFile(t, ".env", validEnv))
+		require.NoError(t, err)
+
+		assert.Equal(t, 3*24*time.Hour, cfg.Privacy.ExportRetention)
+		assert.Equal(t, 14*24*time.Hour, cfg.Privacy.ErasureGrace)
+	})
+
+	t.Run("should reject a retention which isn't positive and a negative grace period", func(t *testing.T) {
+		isolate(t)
+		t.Setenv("EXPORT_RETENTION_DAYS", "0")
+		t.Setenv("ERASURE_GRACE_DAYS", "-1")
+
+		_, err := config.Load(nil, writeFile(t, ".env", validEnv))
+		assert.ErrorContains(t, err, "EXPORT_RETENTION_DAYS must be positive")
+		assert.ErrorContains(t, err, "ERASURE_GRACE_DAYS must not be negative")
+	})
+}
+
+func TestImport(t *testing.T) {
+	t.Run("should read the file size in megabytes and the invitation expiry in days", func(t *testing.T) {
+		isolate(t)
+		t.Setenv("USER_IMPORT_MAX_SIZE_MB", "2")
+		t.Setenv("USER_INVITATION_EXP_DAYS", "3")
+
+		cfg, err := config.Load(nil, writeFile(t, ".env", validEnv))
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(2<<20), cfg.Import.MaxSize)
+		assert.Equal(t, 10000, cfg.Import.MaxRows)
+		assert.Equal(t, 10, cfg.Import.SyncRows)
+		assert.Equal(t, 3*24*time.Hour, cfg.Import.InvitationExp)
+	})
+
+	t.Run("should reject more rows imported by the request than in a file", func(t *testing.T) {
+		isolate(t)
+		t.Setenv("USER_IMPORT_MAX_ROWS", "10")
+		t.Setenv("USER_IMPORT_SYNC_ROWS", "20")
+		t.Setenv("USER_INVITATION_EXP_DAYS", "0")
+
+		_, err := config.Load(nil, writeFile(t, ".env", validEnv))
+		assert.ErrorContains(t, err, "USER_IMPORT_SYNC_ROWS must be between 0 and USER_IMPORT_MAX_ROWS")
+		assert.ErrorContains(t, err, "USER_INVITATION_EXP_DAYS must be positive")
+	})
+}
@@ -0,0 +1,76 @@
+package config_test
+
+import (
+	"app/src/config"
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLive(t *testing.T) {
+	newLive := func(t *testing.T, env string) (*config.Live, string) {
+		isolate(t)
+		path := writeFile(t, ".env", validEnv+env)
+		cfg, err := config.Load(nil, path)
+		require.NoError(t, err)
+
+		return config.NewLive(cfg, func() (*config.Config, error) { return config.Load(nil, path) }), path
+	}
+
+	t.Run("should swap in the reloadable settings only", func(t *testing.T) {
+		live, path := newLive(t, "RATE_LIMIT_LOGIN=5/15m\n")
+		var reloaded *config.Config
+		live.OnReload(func(cfg *config.Config) { reloaded = cfg })
+
+		env := validEnv + "RATE_LIMIT_LOGIN=3/1h\nJWT_ACCESS_EXP_MINUTES=10\nLOG_LEVEL=debug\nAPP_PORT=4000\n"
+		assert.NoError(t, os.WriteFile(path, []byte(env), 0o600))
+		assert.NoError(t, live.Reload())
+
+		cfg := live.Get()
+		assert.Same(t, cfg, reloaded)
+		assert.Equal(t, 3, cfg.RateLimit.Policies[config.RateLimitLogin].Max)
+		assert.Equal(t, time.Hour, cfg.RateLimit.Policies[config.RateLimitLogin].Window)
+		assert.Equal(t, 10*time.Minute, cfg.JWT.AccessExp)
+		assert.Equal(t, "debug", cfg.Log.Level)
+		assert.Equal(t, 3000, cfg.App.Port)
+	})
+
+	t.Run("should keep the current config if the new one is invalid", func(t *testing.T) {
+		live, path := newLive(t, "")
+		current := live.Get()
+
+		assert.NoError(t, os.WriteFile(path, []byte(validEnv+"RATE_LIMIT_LOGIN=0/15m\n"), 0o600))
+		assert.ErrorContains(t, live.Reload(), "RATE_LIMIT_LOGIN must be a positive number of requests")
+		assert.Same(t, current, live.Get())
+	})
+
+	t.Run("should fail without a way to load the config", func(t *testing.T) {
+		live := config.NewLive(&config.Config{}, nil)
+
+		assert.EqualError(t, live.Reload(), "config can't be reloaded")
+	})
+
+	t.Run("should reload when the file changes and on SIGHUP", func(t *testing.T) {
+		live, path := newLive(t, "")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		assert.NoError(t, live.Watch(ctx, path))
+
+		assert.NoError(t, os.WriteFile(path, []byte(validEnv+"RATE_LIMIT_LOGIN=7/15m\n"), 0o600))
+		assert.Eventually(t, func() bool { return loginMax(live) == 7 }, 5*time.Second, 20*time.Millisecond)
+
+		// The environment isn't watched, only the signal picks this up
+		t.Setenv("RATE_LIMIT_LOGIN", "9/15m")
+		assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+		assert.Eventually(t, func() bool { return loginMax(live) == 9 }, 5*time.Second, 20*time.Millisecond)
+	})
+}
+
+func loginMax(live *config.Live) int {
+	return live.Get().RateLimit.Policies[config.RateLimitLogin].Max
+}
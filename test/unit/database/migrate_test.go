@@ -0,0 +1,104 @@
+package database_test
+
+import (
+	"app/src/config"
+	"app/src/database"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	t.Run("should split on the semicolons ending the statements", func(t *testing.T) {
+		statements := database.SplitStatements(`
+-- Users; the first table
+CREATE TABLE users (id INT);
+
+/* the index; of the users */
+CREATE INDEX idx_users_id ON users (id);
+`)
+
+		assert.Equal(t, []string{
+			"-- Users; the first table\nCREATE TABLE users (id INT)",
+			"/* the index; of the users */\nCREATE INDEX idx_users_id ON users (id)",
+		}, statements)
+	})
+
+	t.Run("should keep the semicolons of quoted strings and identifiers", func(t *testing.T) {
+		statements := database.SplitStatements(
+			"INSERT INTO notes (body) VALUES ('a;b', 'it''s; here');\n" +
+				"CREATE TABLE `semi;colon` (\"a;b\" INT);",
+		)
+
+		assert.Equal(t, []string{
+			"INSERT INTO notes (body) VALUES ('a;b', 'it''s; here')",
+			"CREATE TABLE `semi;colon` (\"a;b\" INT)",
+		}, statements)
+	})
+
+	t.Run("should keep the bodies of functions and triggers whole", func(t *testing.T) {
+		function := `CREATE FUNCTION touch() RETURNS trigger AS $body$
+BEGIN
+  NEW.updated_at = now();
+  RETURN NEW;
+END;
+$body$ LANGUAGE plpgsql`
+		trigger := `CREATE TRIGGER users_touch AFTER UPDATE ON users
+BEGIN
+  UPDATE users SET role = CASE WHEN NEW.role = '' THEN 'user' ELSE NEW.role END WHERE id = NEW.id;
+  DELETE FROM tokens WHERE user_id = NEW.id;
+END`
+		procedure := `CREATE PROCEDURE purge()
+BEGIN
+  IF (SELECT COUNT(*) FROM users) > 0 THEN
+    DELETE FROM users;
+  END IF;
+END`
+
+		statements := database.SplitStatements(function + ";\n" + trigger + ";\n" + procedure + ";\nBEGIN;\nCOMMIT;")
+
+		assert.Equal(t, []string{function, trigger, procedure, "BEGIN", "COMMIT"}, statements)
+	})
+
+	t.Run("should drop the trailing comments", func(t *testing.T) {
+		statements := database.SplitStatements("DROP TABLE users;\n-- done\n/* really */\n")
+
+		assert.Equal(t, []string{"DROP TABLE users"}, statements)
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	t.Run("should apply every migration once", func(t *testing.T) {
+		cfg := config.Database{Driver: database.DriverSQLite, Name: filepath.Join(t.TempDir(), "app.db")}
+		db := database.Connect(cfg)
+
+		require.NoError(t, database.Migrate(db, cfg.Driver))
+		require.NoError(t, database.Migrate(db, cfg.Driver))
+
+		var version int64
+		var dirty bool
+		row := db.Raw("SELECT version, dirty FROM schema_migrations").Row()
+		require.NoError(t, row.Scan(&version, &dirty))
+		assert.Positive(t, version)
+		assert.False(t, dirty)
+		assert.True(t, db.Migrator().HasTable("users"))
+	})
+
+	t.Run("should stop at the given version", func(t *testing.T) {
+		cfg := config.Database{Driver: database.DriverSQLite, Name: filepath.Join(t.TempDir(), "app.db")}
+		db := database.Connect(cfg)
+
+		require.NoError(t, database.MigrateTo(db, cfg.Driver, 20240929085107))
+
+		var version int64
+		require.NoError(t, db.Raw("SELECT version FROM schema_migrations").Row().Scan(&version))
+		assert.Equal(t, int64(20240929085107), version)
+		assert.True(t, db.Migrator().HasTable("tokens"))
+		assert.False(t, db.Migrator().HasTable("job_runs"))
+
+		require.NoError(t, database.Migrate(db, cfg.Driver))
+		assert.True(t, db.Migrator().HasTable("job_runs"))
+	})
+}
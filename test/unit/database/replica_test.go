@@ -0,0 +1,94 @@
+package database_test
+
+import (
+	"app/src/config"
+	"app/src/database"
+	"app/src/health"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/plugin/dbresolver"
+)
+
+type note struct {
+	ID   int
+	Body string
+}
+
+// sqliteReplica connects to a primary and a replica, two SQLite files each with a note of its own
+func sqliteReplica(t *testing.T) (config.Database, string) {
+	dir := t.TempDir()
+	cfg := config.Database{
+		Driver:          database.DriverSQLite,
+		Name:            filepath.Join(dir, "primary.db"),
+		ReplicaHosts:    []string{filepath.Join(dir, "replica.db")},
+		MaxIdleConns:    2,
+		MaxOpenConns:    5,
+		ConnMaxLifetime: time.Hour,
+	}
+
+	for name, body := range map[string]string{cfg.Name: "primary", cfg.ReplicaHosts[0]: "replica"} {
+		db := database.Connect(config.Database{Driver: database.DriverSQLite, Name: name})
+		require.NoError(t, db.AutoMigrate(&note{}))
+		require.NoError(t, db.Create(&note{ID: 1, Body: body}).Error)
+		sqlDB, err := db.DB()
+		require.NoError(t, err)
+		require.NoError(t, sqlDB.Close())
+	}
+
+	return cfg, cfg.ReplicaHosts[0]
+}
+
+func TestReplicas(t *testing.T) {
+	t.Run("should send the queries to the replicas and the writes to the primary", func(t *testing.T) {
+		cfg, _ := sqliteReplica(t)
+		db := database.Connect(cfg)
+
+		read := new(note)
+		require.NoError(t, db.First(read, 1).Error)
+		assert.Equal(t, "replica", read.Body)
+
+		require.NoError(t, db.Model(&note{}).Where("id = ?", 1).Update("body", "written").Error)
+
+		require.NoError(t, db.First(read, 1).Error)
+		assert.Equal(t, "replica", read.Body)
+		require.NoError(t, db.Clauses(dbresolver.Write).First(read, 1).Error)
+		assert.Equal(t, "written", read.Body)
+	})
+
+	t.Run("should apply the pool settings to every replica", func(t *testing.T) {
+		cfg, host := sqliteReplica(t)
+		db := database.Connect(cfg)
+
+		replicas := database.Replicas(db)
+		require.Len(t, replicas, 1)
+		assert.Equal(t, host, replicas[0].Host)
+		assert.Equal(t, cfg.MaxOpenConns, replicas[0].DB.Stats().MaxOpenConnections)
+
+		primary, err := db.DB()
+		require.NoError(t, err)
+		assert.Equal(t, cfg.MaxOpenConns, primary.Stats().MaxOpenConnections)
+	})
+
+	t.Run("should report a replica down once its pool is closed", func(t *testing.T) {
+		cfg, _ := sqliteReplica(t)
+		replica := database.Replicas(database.Connect(cfg))[0]
+		checker := health.Database("Replica "+replica.Host, replica.DB)
+
+		assert.NoError(t, checker.Check(context.Background()))
+
+		require.NoError(t, replica.DB.Close())
+		assert.Error(t, checker.Check(context.Background()))
+	})
+
+	t.Run("should have no replicas unless configured", func(t *testing.T) {
+		cfg, _ := sqliteReplica(t)
+		cfg.ReplicaHosts = nil
+
+		assert.Empty(t, database.Replicas(database.Connect(cfg)))
+	})
+}
@@ -0,0 +1,153 @@
+package health_test
+
+import (
+	"app/src/health"
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func counting(name string, calls *atomic.Int32, err error) health.Checker {
+	return health.NewChecker(name, func(context.Context) error {
+		calls.Add(1)
+		return err
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("should report every check in registration order", func(t *testing.T) {
+		var calls atomic.Int32
+		registry := health.NewRegistry(time.Second)
+		registry.Register(health.Check{Checker: counting("Database", &calls, nil)})
+		registry.Register(health.Check{Checker: counting("Disk", &calls, nil)})
+
+		report := registry.Readiness(context.Background())
+
+		assert.True(t, report.Healthy)
+		assert.Len(t, report.Results, 2)
+		assert.Equal(t, "Database", report.Results[0].Name)
+		assert.Equal(t, "Disk", report.Results[1].Name)
+		assert.True(t, report.Results[0].Up)
+	})
+
+	t.Run("should only run the live checks for liveness", func(t *testing.T) {
+		var calls atomic.Int32
+		registry := health.NewRegistry(time.Second)
+		registry.Register(health.Check{Checker: counting("Database", &calls, errors.New("down"))})
+		registry.Register(health.Check{Checker: counting("Memory", &calls, nil), Live: true})
+
+		report := registry.Liveness(context.Background())
+
+		assert.True(t, report.Healthy)
+		assert.Len(t, report.Results, 1)
+		assert.Equal(t, "Memory", report.Results[0].Name)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("should stay healthy when an optional check fails", func(t *testing.T) {
+		var calls atomic.Int32
+		registry := health.NewRegistry(time.Second)
+		registry.Register(health.Check{Checker: counting("SMTP", &calls, errors.New("refused")), Optional: true})
+
+		report := registry.Readiness(context.Background())
+
+		assert.True(t, report.Healthy)
+		assert.False(t, report.Results[0].Up)
+		assert.True(t, report.Results[0].Optional)
+		assert.Equal(t, "refused", report.Results[0].Error)
+	})
+
+	t.Run("should fail when a required check fails", func(t *testing.T) {
+		var calls atomic.Int32
+		registry := health.NewRegistry(time.Second)
+		registry.Register(health.Check{Checker: counting("Database", &calls, errors.New("refused"))})
+
+		assert.False(t, registry.Readiness(context.Background()).Healthy)
+	})
+
+	t.Run("should reuse the result while it is cached", func(t *testing.T) {
+		var calls atomic.Int32
+		registry := health.NewRegistry(time.Second)
+		registry.Register(health.Check{Checker: counting("Database", &calls, nil), CacheFor: time.Minute})
+		registry.Register(health.Check{Checker: counting("Disk", &calls, nil)})
+
+		registry.Readiness(context.Background())
+		registry.Readiness(context.Background())
+
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("should give up on a check ignoring the timeout", func(t *testing.T) {
+		block := make(chan struct{})
+		defer close(block)
+
+		registry := health.NewRegistry(time.Second)
+		registry.Register(health.Check{
+			Checker: health.NewChecker("Slow", func(context.Context) error {
+				<-block
+				return nil
+			}),
+			Timeout: 50 * time.Millisecond,
+		})
+
+		start := time.Now()
+		report := registry.Readiness(context.Background())
+
+		assert.Less(t, time.Since(start), time.Second)
+		assert.False(t, report.Healthy)
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Results[0].Error)
+	})
+}
+
+func TestCheckers(t *testing.T) {
+	t.Run("Memory should fail above the threshold", func(t *testing.T) {
+		assert.NoError(t, health.Memory(1<<40).Check(context.Background()))
+		assert.Error(t, health.Memory(1).Check(context.Background()))
+	})
+
+	t.Run("Disk should fail below the free space", func(t *testing.T) {
+		assert.NoError(t, health.Disk(t.TempDir(), 0).Check(context.Background()))
+		assert.Error(t, health.Disk(t.TempDir(), 1<<62).Check(context.Background()))
+	})
+
+	t.Run("Jobs should fail when a run is stuck", func(t *testing.T) {
+		var startedBefore time.Time
+		stuck := int64(0)
+		countStuck := func(_ context.Context, before time.Time) (int64, error) {
+			startedBefore = before
+			return stuck, nil
+		}
+
+		checker := health.Jobs(countStuck, time.Hour)
+		assert.NoError(t, checker.Check(context.Background()))
+		assert.WithinDuration(t, time.Now().Add(-time.Hour), startedBefore, time.Second)
+
+		stuck = 2
+		assert.Error(t, checker.Check(context.Background()))
+	})
+}
+
+// TestDrain runs last, draining can't be undone in the process
+func TestDrain(t *testing.T) {
+	registry := health.NewRegistry(time.Second)
+	registry.Register(health.Check{Checker: health.NewChecker("Database", func(context.Context) error { return nil })})
+
+	path := filepath.Join(t.TempDir(), "app.draining")
+	health.ShareDrain(path)
+
+	assert.False(t, health.Draining())
+	assert.True(t, registry.Readiness(context.Background()).Healthy)
+
+	assert.NoError(t, health.Drain())
+	assert.FileExists(t, path)
+
+	report := registry.Readiness(context.Background())
+	assert.False(t, report.Healthy)
+	assert.Equal(t, "Shutdown", report.Results[len(report.Results)-1].Name)
+	assert.True(t, registry.Liveness(context.Background()).Healthy)
+}
@@ -0,0 +1,89 @@
+package i18n_test
+
+import (
+	"app/src/i18n"
+	"app/src/problem"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"", "en"},
+		{"id", "id"},
+		{"id-ID,id;q=0.9,en;q=0.8", "id"},
+		{"en-US,en;q=0.9,id;q=0.8", "en"},
+		{"fr-FR,fr;q=0.9,id;q=0.5", "id"},
+		{"en;q=0.5,id;q=0.7", "id"},
+		{"id;q=0,en", "en"},
+		{"fr, de", "en"},
+		{"*", "en"},
+		{"id;q=invalid", "en"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, i18n.Match(test.acceptLanguage), test.acceptLanguage)
+	}
+}
+
+func TestT(t *testing.T) {
+	t.Run("should format the message of the language", func(t *testing.T) {
+		assert.Equal(t, "Kolom email wajib diisi", i18n.T("id", "validation.required", "email"))
+		assert.Equal(t, "Field email must be filled", i18n.T("en", "validation.required", "email"))
+	})
+
+	t.Run("should fall back to the default language, then to the key", func(t *testing.T) {
+		assert.Equal(t, "Field email must be filled", i18n.T("fr", "validation.required", "email"))
+		assert.Equal(t, "unknown.key", i18n.T("id", "unknown.key"))
+	})
+
+	t.Run("should keep the language in the context", func(t *testing.T) {
+		assert.Equal(t, i18n.Default, i18n.Language(context.Background()))
+		assert.Equal(t, "id", i18n.Language(i18n.WithLanguage(context.Background(), "id")))
+	})
+}
+
+func TestCatalogs(t *testing.T) {
+	assert.Equal(t, []string{"en", "id"}, i18n.Languages())
+
+	t.Run("should translate every message of the default language", func(t *testing.T) {
+		keys := func(lang string) []string {
+			data, err := os.ReadFile(filepath.Join("../../../src/i18n/locales", lang+".json"))
+			assert.NoError(t, err)
+
+			messages := make(map[string]string)
+			assert.NoError(t, json.Unmarshal(data, &messages))
+
+			keys := make([]string, 0, len(messages))
+			for key := range messages {
+				if !strings.HasPrefix(key, "problem.") {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			return keys
+		}
+
+		for _, lang := range i18n.Languages() {
+			assert.Equal(t, keys(i18n.Default), keys(lang), lang)
+		}
+	})
+
+	t.Run("should translate the title of every problem", func(t *testing.T) {
+		for _, e := range problem.All() {
+			title, ok := i18n.Lookup("id", "problem."+e.Code)
+			assert.True(t, ok, e.Code)
+			assert.NotEqual(t, e.Title, title, e.Code)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package metrics_test
+
+import (
+	"app/src/metrics"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+// deadPID is above the highest pid Linux hands out
+const deadPID = 1 << 23
+
+func writeSnapshot(t *testing.T, dir string, pid int, families ...*dto.MetricFamily) {
+	file, err := os.Create(filepath.Join(dir, strconv.Itoa(pid)+".pb"))
+	assert.NoError(t, err)
+	defer file.Close()
+
+	encoder := expfmt.NewEncoder(file, expfmt.NewFormat(expfmt.TypeProtoDelim))
+	for _, family := range families {
+		assert.NoError(t, encoder.Encode(family))
+	}
+}
+
+func loginFamily(value float64) *dto.MetricFamily {
+	return &dto.MetricFamily{
+		Name: proto.String("auth_logins_total"),
+		Help: proto.String("Number of login attempts by method and result."),
+		Type: dto.MetricType_COUNTER.Enum(),
+		Metric: []*dto.Metric{{
+			Label: []*dto.LabelPair{
+				{Name: proto.String("method"), Value: proto.String("password")},
+				{Name: proto.String("result"), Value: proto.String("succeeded")},
+			},
+			Counter: &dto.Counter{Value: proto.Float64(value)},
+		}},
+	}
+}
+
+func loginsSucceeded(t *testing.T, gatherer interface {
+	Gather() ([]*dto.MetricFamily, error)
+}) float64 {
+	families, err := gatherer.Gather()
+	assert.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != "auth_logins_total" {
+			continue
+		}
+		for _, metric := range family.Metric {
+			for _, label := range metric.Label {
+				if label.GetName() == "result" && label.GetValue() == "succeeded" {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+
+	return 0
+}
+
+func TestPreforkGatherer(t *testing.T) {
+	t.Run("should sum the snapshots of the other processes with the live metrics", func(t *testing.T) {
+		dir := t.TempDir()
+		live := loginsSucceeded(t, metrics.Gatherer(""))
+
+		writeSnapshot(t, dir, os.Getppid(), loginFamily(3))
+		metrics.ObserveLogin(metrics.LoginPassword, nil)
+
+		assert.Equal(t, live+1+3, loginsSucceeded(t, metrics.Gatherer(dir)))
+	})
+
+	t.Run("should ignore and remove the snapshot of a process that is gone", func(t *testing.T) {
+		dir := t.TempDir()
+		live := loginsSucceeded(t, metrics.Gatherer(""))
+
+		writeSnapshot(t, dir, deadPID, loginFamily(5))
+
+		assert.Equal(t, live, loginsSucceeded(t, metrics.Gatherer(dir)))
+		assert.NoFileExists(t, filepath.Join(dir, strconv.Itoa(deadPID)+".pb"))
+	})
+
+	t.Run("should write snapshots readable by the other processes", func(t *testing.T) {
+		dir := t.TempDir()
+		metrics.ObserveLogin(metrics.LoginPassword, nil)
+
+		done := make(chan error)
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() { done <- metrics.WriteSnapshots(ctx, dir, time.Hour) }()
+
+		path := filepath.Join(dir, strconv.Itoa(os.Getpid())+".pb")
+		assert.Eventually(t, func() bool {
+			_, err := os.Stat(path)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+
+		// Read it back as if it came from another process
+		snapshot, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		other := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(other, strconv.Itoa(os.Getppid())+".pb"), snapshot, 0o600))
+
+		live := loginsSucceeded(t, metrics.Gatherer(""))
+		assert.Equal(t, 2*live, loginsSucceeded(t, metrics.Gatherer(other)))
+
+		cancel()
+		assert.NoError(t, <-done)
+		assert.NoFileExists(t, path)
+	})
+}
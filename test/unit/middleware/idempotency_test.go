@@ -0,0 +1,141 @@
+package middleware_test
+
+import (
+	"app/src/middleware"
+	"app/src/model"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryIdempotencyService keeps the responses of the keys in memory
+type memoryIdempotencyService struct {
+	responses map[string]*model.IdempotentResponse
+	released  []string
+}
+
+func newMemoryIdempotencyService() *memoryIdempotencyService {
+	return &memoryIdempotencyService{responses: make(map[string]*model.IdempotentResponse)}
+}
+
+func (s *memoryIdempotencyService) Begin(_ context.Context, scope, key, _ string) (*model.IdempotentResponse, error) {
+	return s.responses[scope+":"+key], nil
+}
+
+func (s *memoryIdempotencyService) Complete(_ context.Context, scope, key string, res *model.IdempotentResponse) error {
+	s.responses[scope+":"+key] = res
+	return nil
+}
+
+func (s *memoryIdempotencyService) Release(_ context.Context, scope, key string) error {
+	s.released = append(s.released, scope+":"+key)
+	return nil
+}
+
+func (s *memoryIdempotencyService) DeleteExpiredKeys(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func sendWithKey(t *testing.T, app *fiber.App, key string) (*http.Response, string) {
+	t.Helper()
+
+	request := httptest.NewRequest(fiber.MethodPost, "/", strings.NewReader(`{"name":"Test"}`))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(middleware.HeaderIdempotencyKey, key)
+
+	res, err := app.Test(request)
+	assert.NoError(t, err)
+
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+
+	return res, string(body)
+}
+
+func TestIdempotency(t *testing.T) {
+	t.Run("should replay the status, body and headers of the response", func(t *testing.T) {
+		idempotencyService := newMemoryIdempotencyService()
+		calls := 0
+
+		app := newApp()
+		app.Post("/", middleware.Idempotency(idempotencyService), func(c *fiber.Ctx) error {
+			calls++
+			c.Set(fiber.HeaderLocation, "/v1/users/1")
+			c.Set("RateLimit-Remaining", "4")
+			c.Set(fiber.HeaderXRequestID, "request-1")
+			return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": 1})
+		})
+
+		first, firstBody := sendWithKey(t, app, "key-1")
+		retry, retryBody := sendWithKey(t, app, "key-1")
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, fiber.StatusCreated, retry.StatusCode)
+		assert.Equal(t, firstBody, retryBody)
+		assert.Equal(t, "true", retry.Header.Get(middleware.HeaderIdempotencyReplayed))
+		assert.Equal(t, "/v1/users/1", retry.Header.Get(fiber.HeaderLocation))
+		assert.Equal(t, first.Header.Get(fiber.HeaderContentType), retry.Header.Get(fiber.HeaderContentType))
+		assert.Empty(t, retry.Header.Get("RateLimit-Remaining"))
+		assert.Empty(t, retry.Header.Get(fiber.HeaderXRequestID))
+	})
+
+	t.Run("should keep the error responses returned by the handler", func(t *testing.T) {
+		idempotencyService := newMemoryIdempotencyService()
+
+		app := newApp()
+		app.Post("/", middleware.Idempotency(idempotencyService), func(c *fiber.Ctx) error {
+			return fiber.NewError(fiber.StatusConflict, "Email is already in use")
+		})
+
+		_, firstBody := sendWithKey(t, app, "key-1")
+		retry, retryBody := sendWithKey(t, app, "key-1")
+
+		assert.Equal(t, fiber.StatusConflict, retry.StatusCode)
+		assert.Equal(t, firstBody, retryBody)
+		assert.Contains(t, retryBody, "Email is already in use")
+		assert.Empty(t, idempotencyService.released)
+	})
+
+	t.Run("should release the key of a response with a 5xx status", func(t *testing.T) {
+		idempotencyService := newMemoryIdempotencyService()
+		calls := 0
+
+		app := newApp()
+		app.Post("/", middleware.Idempotency(idempotencyService), func(c *fiber.Ctx) error {
+			calls++
+			return fiber.ErrServiceUnavailable
+		})
+
+		res, _ := sendWithKey(t, app, "key-1")
+		assert.Equal(t, fiber.StatusServiceUnavailable, res.StatusCode)
+		res, _ = sendWithKey(t, app, "key-1")
+		assert.Empty(t, res.Header.Get(middleware.HeaderIdempotencyReplayed))
+
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, []string{"anonymous:key-1", "anonymous:key-1"}, idempotencyService.released)
+	})
+
+	t.Run("should handle the requests without a key as usual", func(t *testing.T) {
+		idempotencyService := newMemoryIdempotencyService()
+		calls := 0
+
+		app := newApp()
+		app.Post("/", middleware.Idempotency(idempotencyService), func(c *fiber.Ctx) error {
+			calls++
+			return c.SendStatus(fiber.StatusCreated)
+		})
+
+		sendWithKey(t, app, "")
+		sendWithKey(t, app, "")
+
+		assert.Equal(t, 2, calls)
+		assert.Empty(t, idempotencyService.responses)
+	})
+}
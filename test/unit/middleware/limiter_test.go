@@ -0,0 +1,183 @@
+package middleware_test
+
+import (
+	"app/src/config"
+	"app/src/middleware"
+	"app/src/model"
+	"app/src/ratelimit"
+	"app/src/utils"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingStore struct{}
+
+func (failingStore) Hit(context.Context, string, time.Duration) (ratelimit.Window, error) {
+	return ratelimit.Window{}, errors.New("connection refused")
+}
+
+func (failingStore) Undo(context.Context, string) error {
+	return errors.New("connection refused")
+}
+
+func newLive(policies map[string]config.RateLimitPolicy) *config.Live {
+	cfg := &config.Config{RateLimit: config.RateLimit{Policies: policies}}
+	return config.NewLive(cfg, func() (*config.Config, error) { return cfg, nil })
+}
+
+func newApp() *fiber.App {
+	return fiber.New(fiber.Config{ErrorHandler: utils.ErrorHandler})
+}
+
+func send(t *testing.T, app *fiber.App, body string) *http.Response {
+	t.Helper()
+
+	request := httptest.NewRequest(fiber.MethodPost, "/", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+
+	res, err := app.Test(request)
+	assert.NoError(t, err)
+
+	return res
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("should only count the failed requests of a failed only policy", func(t *testing.T) {
+		live := newLive(map[string]config.RateLimitPolicy{
+			"login": {Key: config.RateLimitByIP, Max: 2, Window: time.Minute, FailedOnly: true},
+		})
+		limiter := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), live)
+
+		app := newApp()
+		app.Post("/", limiter.Limit("login"), func(c *fiber.Ctx) error {
+			if strings.Contains(string(c.Body()), "wrong") {
+				return fiber.NewError(fiber.StatusUnauthorized, "Invalid email or password")
+			}
+			return c.SendStatus(fiber.StatusOK)
+		})
+
+		res := send(t, app, `{"password":"right"}`)
+		assert.Equal(t, fiber.StatusOK, res.StatusCode)
+		assert.Equal(t, "2", res.Header.Get("RateLimit-Remaining"))
+
+		assert.Equal(t, fiber.StatusUnauthorized, send(t, app, `{"password":"wrong"}`).StatusCode)
+		res = send(t, app, `{"password":"wrong"}`)
+		assert.Equal(t, fiber.StatusUnauthorized, res.StatusCode)
+		assert.Equal(t, "2", res.Header.Get("RateLimit-Limit"))
+		assert.Equal(t, "0", res.Header.Get("RateLimit-Remaining"))
+		assert.Equal(t, "60", res.Header.Get("RateLimit-Reset"))
+		assert.Equal(t, "2;w=60", res.Header.Get("RateLimit-Policy"))
+
+		res = send(t, app, `{"password":"right"}`)
+		assert.Equal(t, fiber.StatusTooManyRequests, res.StatusCode)
+		assert.Equal(t, "60", res.Header.Get(fiber.HeaderRetryAfter))
+	})
+
+	t.Run("should count the requests per email of the body", func(t *testing.T) {
+		live := newLive(map[string]config.RateLimitPolicy{
+			"forgot-password": {Key: config.RateLimitByEmail, Max: 1, Window: time.Hour},
+		})
+		limiter := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), live)
+
+		app := newApp()
+		app.Post("/", limiter.Limit("forgot-password"), func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusNoContent)
+		})
+
+		assert.Equal(t, fiber.StatusNoContent, send(t, app, `{"email":"test1@gmail.com"}`).StatusCode)
+		assert.Equal(t, fiber.StatusTooManyRequests, send(t, app, `{"email":" TEST1@gmail.com"}`).StatusCode)
+		assert.Equal(t, fiber.StatusNoContent, send(t, app, `{"email":"test2@gmail.com"}`).StatusCode)
+	})
+
+	t.Run("should count the requests per user", func(t *testing.T) {
+		live := newLive(map[string]config.RateLimitPolicy{
+			"verification-email": {Key: config.RateLimitByUser, Max: 1, Window: time.Hour},
+		})
+		limiter := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), live)
+
+		app := newApp()
+		app.Post("/", func(c *fiber.Ctx) error {
+			c.Locals("user", &model.User{ID: uuid.MustParse(string(c.Body()))})
+			return c.Next()
+		}, limiter.Limit("verification-email"), func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusNoContent)
+		})
+
+		user1, user2 := uuid.NewString(), uuid.NewString()
+		assert.Equal(t, fiber.StatusNoContent, send(t, app, user1).StatusCode)
+		assert.Equal(t, fiber.StatusTooManyRequests, send(t, app, user1).StatusCode)
+		assert.Equal(t, fiber.StatusNoContent, send(t, app, user2).StatusCode)
+	})
+
+	t.Run("should describe the policy closest to its limit", func(t *testing.T) {
+		live := newLive(map[string]config.RateLimitPolicy{
+			"auth":  {Key: config.RateLimitByIP, Max: 20, Window: 15 * time.Minute},
+			"login": {Key: config.RateLimitByEmail, Max: 5, Window: 15 * time.Minute},
+		})
+		limiter := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), live)
+
+		app := newApp()
+		app.Post("/", limiter.Limit("auth"), limiter.Limit("login"), func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusOK)
+		})
+
+		res := send(t, app, `{"email":"test1@gmail.com"}`)
+		assert.Equal(t, "5", res.Header.Get("RateLimit-Limit"))
+		assert.Equal(t, "4", res.Header.Get("RateLimit-Remaining"))
+	})
+
+	t.Run("should enforce the reloaded limit", func(t *testing.T) {
+		policies := map[string]config.RateLimitPolicy{
+			"auth": {Key: config.RateLimitByIP, Max: 1, Window: time.Minute},
+		}
+		live := newLive(policies)
+		limiter := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), live)
+
+		app := newApp()
+		app.Post("/", limiter.Limit("auth"), func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusOK)
+		})
+
+		assert.Equal(t, fiber.StatusOK, send(t, app, "").StatusCode)
+		assert.Equal(t, fiber.StatusTooManyRequests, send(t, app, "").StatusCode)
+
+		policies["auth"] = config.RateLimitPolicy{Key: config.RateLimitByIP, Max: 5, Window: time.Minute}
+		assert.NoError(t, live.Reload())
+
+		res := send(t, app, "")
+		assert.Equal(t, fiber.StatusOK, res.StatusCode)
+		assert.Equal(t, "2", res.Header.Get("RateLimit-Remaining"))
+	})
+
+	t.Run("should let the requests through when the store fails", func(t *testing.T) {
+		live := newLive(map[string]config.RateLimitPolicy{
+			"auth": {Key: config.RateLimitByIP, Max: 1, Window: time.Minute},
+		})
+		limiter := middleware.NewRateLimiter(failingStore{}, live)
+
+		app := newApp()
+		app.Post("/", limiter.Limit("auth"), func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusOK)
+		})
+
+		assert.Equal(t, fiber.StatusOK, send(t, app, "").StatusCode)
+		res := send(t, app, "")
+		assert.Equal(t, fiber.StatusOK, res.StatusCode)
+		assert.Empty(t, res.Header.Get("RateLimit-Limit"))
+	})
+
+	t.Run("should panic on an unknown policy", func(t *testing.T) {
+		limiter := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), newLive(nil))
+
+		assert.PanicsWithValue(t, `unknown rate limit policy "unknown"`, func() { limiter.Limit("unknown") })
+	})
+}
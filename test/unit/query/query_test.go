@@ -0,0 +1,102 @@
+package query_test
+
+import (
+	"app/src/query"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type item struct {
+	ID   string `gorm:"primaryKey"`
+	Name string
+}
+
+var options = query.Options{
+	Sortable:     map[string]string{"name": "name"},
+	DefaultSort:  "name",
+	DefaultOrder: query.OrderAsc,
+}
+
+// itemsDB returns a database holding five items, the longer the name the later
+func itemsDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&item{}))
+	for i, name := range []string{"a", "bb", "ccc", "dddd", "eeeee"} {
+		require.NoError(t, db.Create(&item{ID: fmt.Sprint(i), Name: name}).Error)
+	}
+
+	return db
+}
+
+func TestPaginate(t *testing.T) {
+	t.Run("should reject a limit which isn't positive", func(t *testing.T) {
+		db := itemsDB(t)
+
+		for _, limit := range []int{0, -1} {
+			_, err := query.Paginate[item](context.Background(), db, query.Params{Limit: limit}, options)
+			assert.ErrorIs(t, err, query.ErrInvalidLimit)
+		}
+	})
+}
+
+func TestPaginateByRank(t *testing.T) {
+	// The longest names rank first
+	rank := &clause.Expr{SQL: "LENGTH(name) * ?", Vars: []interface{}{1}}
+
+	names := func(items []item) []string {
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+
+	t.Run("should follow the pages by relevance", func(t *testing.T) {
+		db := itemsDB(t)
+		params := query.Params{Limit: 2, Rank: rank}
+
+		page, err := query.Paginate[item](context.Background(), db, params, options)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"eeeee", "dddd"}, names(page.Items))
+		require.NotEmpty(t, page.NextCursor)
+
+		params.Cursor = page.NextCursor
+		page, err = query.Paginate[item](context.Background(), db, params, options)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"ccc", "bb"}, names(page.Items))
+
+		params.Cursor = page.NextCursor
+		page, err = query.Paginate[item](context.Background(), db, params, options)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a"}, names(page.Items))
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("should reject a cursor whose offset isn't a count", func(t *testing.T) {
+		db := itemsDB(t)
+
+		cursors := []string{
+			`{"s":"relevance","o":"desc","k":"string","v":"2"}`,
+			`{"s":"relevance","o":"desc","k":"bool","v":true}`,
+			`{"s":"relevance","o":"desc","k":"number","v":-2}`,
+			`{"s":"relevance","o":"desc","k":"number","v":1.5}`,
+			`{"s":"name","o":"asc","k":"number","v":2}`,
+		}
+		for _, cursor := range cursors {
+			params := query.Params{Limit: 2, Rank: rank, Cursor: base64.RawURLEncoding.EncodeToString([]byte(cursor))}
+			_, err := query.Paginate[item](context.Background(), db, params, options)
+			assert.ErrorIs(t, err, query.ErrInvalidCursor, cursor)
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package ratelimit_test
+
+import (
+	"app/src/ratelimit"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should count the requests of a key until its window is over", func(t *testing.T) {
+		store := ratelimit.NewMemoryStore()
+
+		first, err := store.Hit(ctx, "login:a", 50*time.Millisecond)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, first.Hits)
+		assert.WithinDuration(t, time.Now().Add(50*time.Millisecond), first.ResetAt, 10*time.Millisecond)
+
+		second, _ := store.Hit(ctx, "login:a", 50*time.Millisecond)
+		assert.Equal(t, 2, second.Hits)
+		assert.Equal(t, first.ResetAt, second.ResetAt)
+
+		other, _ := store.Hit(ctx, "login:b", 50*time.Millisecond)
+		assert.Equal(t, 1, other.Hits)
+
+		time.Sleep(60 * time.Millisecond)
+		next, _ := store.Hit(ctx, "login:a", 50*time.Millisecond)
+		assert.Equal(t, 1, next.Hits)
+	})
+
+	t.Run("should take back a request", func(t *testing.T) {
+		store := ratelimit.NewMemoryStore()
+
+		_, _ = store.Hit(ctx, "login:a", time.Minute)
+		assert.NoError(t, store.Undo(ctx, "login:a"))
+		assert.NoError(t, store.Undo(ctx, "login:unknown"))
+
+		window, _ := store.Hit(ctx, "login:a", time.Minute)
+		assert.Equal(t, 1, window.Hits)
+	})
+}
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should count the requests of a key until its window is over", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		store, err := ratelimit.NewRedisStore("redis://" + server.Addr())
+		assert.NoError(t, err)
+		defer store.Close()
+
+		first, err := store.Hit(ctx, "login:a", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, first.Hits)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), first.ResetAt, time.Second)
+		assert.Equal(t, time.Minute, server.TTL("ratelimit:login:a"))
+
+		second, err := store.Hit(ctx, "login:a", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, second.Hits)
+
+		server.FastForward(time.Minute)
+		next, err := store.Hit(ctx, "login:a", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, next.Hits)
+	})
+
+	t.Run("should take back a request without leaving a key that never expires", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		store, err := ratelimit.NewRedisStore("redis://" + server.Addr())
+		assert.NoError(t, err)
+		defer store.Close()
+
+		_, _ = store.Hit(ctx, "login:a", time.Minute)
+		_, _ = store.Hit(ctx, "login:a", time.Minute)
+		assert.NoError(t, store.Undo(ctx, "login:a"))
+
+		window, _ := store.Hit(ctx, "login:a", time.Minute)
+		assert.Equal(t, 2, window.Hits)
+
+		server.FastForward(time.Minute)
+		assert.NoError(t, store.Undo(ctx, "login:a"))
+		assert.False(t, server.Exists("ratelimit:login:a"))
+	})
+
+	t.Run("should authenticate and select the database of the URL", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		server.RequireAuth("secret")
+		store, err := ratelimit.NewRedisStore("redis://:secret@" + server.Addr() + "/2")
+		assert.NoError(t, err)
+		defer store.Close()
+
+		_, err = store.Hit(ctx, "login:a", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, server.DB(2).Exists("ratelimit:login:a"))
+		assert.False(t, server.Exists("ratelimit:login:a"))
+	})
+
+	t.Run("should return the error replies", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		server.RequireAuth("secret")
+		store, err := ratelimit.NewRedisStore("redis://:wrong@" + server.Addr())
+		assert.NoError(t, err)
+		defer store.Close()
+
+		_, err = store.Hit(ctx, "login:a", time.Minute)
+		assert.ErrorContains(t, err, "WRONGPASS")
+	})
+
+	t.Run("should fail when the server is down", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		addr := listener.Addr().String()
+		listener.Close()
+
+		store, err := ratelimit.NewRedisStore("redis://" + addr)
+		assert.NoError(t, err)
+		defer store.Close()
+
+		assert.ErrorContains(t, store.Ping(ctx), "connection refused")
+	})
+
+	t.Run("should reject invalid URLs", func(t *testing.T) {
+		_, err := ratelimit.NewRedisStore("http://localhost:6379")
+		assert.ErrorContains(t, err, "invalid redis URL")
+
+		_, err = ratelimit.NewRedisStore("redis://localhost:6379/cache")
+		assert.ErrorContains(t, err, "invalid redis URL")
+	})
+}
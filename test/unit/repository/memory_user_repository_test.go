@@ -0,0 +1,43 @@
+package repository_test
+
+import (
+	"app/src/model"
+	"app/src/repository"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryUserRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Restore", func(t *testing.T) {
+		t.Run("should bump the version like the database", func(t *testing.T) {
+			users := repository.NewMemoryUserRepository()
+			user := &model.User{Name: "Test", Email: "test@gmail.com"}
+			assert.NoError(t, users.Create(ctx, user))
+			assert.NoError(t, users.Delete(ctx, user.ID.String(), user.Version))
+
+			restored, err := users.Restore(ctx, user.ID.String())
+			assert.NoError(t, err)
+			assert.Equal(t, user.Version+1, restored.Version)
+
+			stored, err := users.FindByID(ctx, user.ID.String())
+			assert.NoError(t, err)
+			assert.Equal(t, restored.Version, stored.Version)
+		})
+
+		t.Run("should reject the version seen before the restore", func(t *testing.T) {
+			users := repository.NewMemoryUserRepository()
+			user := &model.User{Name: "Test", Email: "test@gmail.com"}
+			assert.NoError(t, users.Create(ctx, user))
+			assert.NoError(t, users.Delete(ctx, user.ID.String(), user.Version))
+			_, err := users.Restore(ctx, user.ID.String())
+			assert.NoError(t, err)
+
+			err = users.Delete(ctx, user.ID.String(), user.Version)
+			assert.ErrorIs(t, err, repository.ErrVersionConflict)
+		})
+	})
+}
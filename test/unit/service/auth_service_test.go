@@ -0,0 +1,351 @@
+package service_test
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/repository"
+	"app/src/service"
+	"app/src/utils"
+	"app/src/validation"
+	"app/test/helper"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type authDeps struct {
+	users        *helper.FailingUserRepository
+	tokens       *helper.FailingTokenRepository
+	tokenService service.TokenService
+	authService  service.AuthService
+}
+
+func newAuthDeps() *authDeps {
+	users := &helper.FailingUserRepository{
+		UserRepository: repository.NewMemoryUserRepository(),
+		Fail:           make(map[string]bool),
+	}
+	tokens := &helper.FailingTokenRepository{
+		TokenRepository: repository.NewMemoryTokenRepository(),
+		Fail:            make(map[string]bool),
+	}
+
+	return newAuthDepsOver(users, tokens, jwtConfig("thisisasamplesecret"))
+}
+
+// newAuthDepsOver builds the services over existing repositories
+func newAuthDepsOver(
+	users *helper.FailingUserRepository, tokens *helper.FailingTokenRepository, jwt config.JWT,
+) *authDeps {
+	validate := validation.Validator()
+	unitOfWork := repository.NewMemoryUnitOfWork(users.UserRepository, tokens.TokenRepository)
+
+	userService := service.NewUserService(users, validate)
+	live := config.NewLive(&config.Config{JWT: jwt}, nil)
+	tokenService := service.NewTokenService(tokens, unitOfWork, validate, userService, live)
+
+	return &authDeps{
+		users:        users,
+		tokens:       tokens,
+		tokenService: tokenService,
+		authService:  service.NewAuthService(users, unitOfWork, validate, userService, tokenService),
+	}
+}
+
+func jwtConfig(secret string) config.JWT {
+	return config.JWT{
+		Secret:           secret,
+		AccessExp:        30 * time.Minute,
+		RefreshExp:       30 * 24 * time.Hour,
+		ResetPasswordExp: 10 * time.Minute,
+		VerifyEmailExp:   10 * time.Minute,
+	}
+}
+
+func (d *authDeps) register(t *testing.T) *model.User {
+	user, err := d.authService.Register(context.Background(), &validation.Register{
+		Name:     "Test User",
+		Email:    "test@gmail.com",
+		Password: "password1",
+	})
+	assert.NoError(t, err)
+
+	return user
+}
+
+// storedToken reports whether token is stored for its user and type
+func (d *authDeps) storedToken(t *testing.T, tokenType, token, userID string) bool {
+	tokenDoc, err := d.tokens.FindByHash(context.Background(), tokenType, userID, utils.HashToken(token))
+	if err != nil {
+		return false
+	}
+
+	assert.NotEqual(t, token, tokenDoc.Token)
+
+	return true
+}
+
+func assertStatus(t *testing.T, err error, status int) {
+	var fiberErr *fiber.Error
+	if assert.True(t, errors.As(err, &fiberErr)) {
+		assert.Equal(t, status, fiberErr.Code)
+	}
+}
+
+func TestAuthService(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Register", func(t *testing.T) {
+		t.Run("should create a user with a hashed password", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+
+			stored, err := d.users.FindByID(ctx, user.ID.String())
+			assert.NoError(t, err)
+			assert.Equal(t, "test@gmail.com", stored.Email)
+			assert.Equal(t, "user", stored.Role)
+			assert.NotEqual(t, "password1", stored.Password)
+		})
+
+		t.Run("should return 409 if email is already taken", func(t *testing.T) {
+			d := newAuthDeps()
+			d.register(t)
+
+			_, err := d.authService.Register(ctx, &validation.Register{
+				Name:     "Other User",
+				Email:    "test@gmail.com",
+				Password: "password1",
+			})
+			assertStatus(t, err, fiber.StatusConflict)
+		})
+
+		t.Run("should return a validation error if password is invalid", func(t *testing.T) {
+			d := newAuthDeps()
+
+			_, err := d.authService.Register(ctx, &validation.Register{
+				Name:     "Test User",
+				Email:    "test@gmail.com",
+				Password: "password",
+			})
+			assert.Error(t, err)
+		})
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		t.Run("should return the user if email and password match", func(t *testing.T) {
+			d := newAuthDeps()
+			registered := d.register(t)
+
+			user, err := d.authService.Login(ctx, &validation.Login{Email: "test@gmail.com", Password: "password1"})
+			assert.NoError(t, err)
+			assert.Equal(t, registered.ID, user.ID)
+		})
+
+		t.Run("should return 401 if password is wrong", func(t *testing.T) {
+			d := newAuthDeps()
+			d.register(t)
+
+			_, err := d.authService.Login(ctx, &validation.Login{Email: "test@gmail.com", Password: "wrongPassword1"})
+			assertStatus(t, err, fiber.StatusUnauthorized)
+		})
+
+		t.Run("should return 401 if the user is deleted", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			assert.NoError(t, d.users.Delete(ctx, user.ID.String(), 0))
+
+			_, err := d.authService.Login(ctx, &validation.Login{Email: "test@gmail.com", Password: "password1"})
+			assertStatus(t, err, fiber.StatusUnauthorized)
+		})
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		t.Run("should delete the refresh token", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			tokens, err := d.tokenService.GenerateAuthTokens(ctx, user)
+			assert.NoError(t, err)
+
+			err = d.authService.Logout(ctx, &validation.Logout{RefreshToken: tokens.Refresh.Token})
+			assert.NoError(t, err)
+
+			assert.False(t, d.storedToken(t, config.TokenTypeRefresh, tokens.Refresh.Token, user.ID.String()))
+		})
+
+		t.Run("should keep the sessions of the other devices", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			first, err := d.tokenService.GenerateAuthTokens(ctx, user)
+			assert.NoError(t, err)
+			second, err := d.tokenService.GenerateAuthTokens(ctx, user)
+			assert.NoError(t, err)
+
+			err = d.authService.Logout(ctx, &validation.Logout{RefreshToken: first.Refresh.Token})
+			assert.NoError(t, err)
+
+			assert.False(t, d.storedToken(t, config.TokenTypeRefresh, first.Refresh.Token, user.ID.String()))
+			assert.True(t, d.storedToken(t, config.TokenTypeRefresh, second.Refresh.Token, user.ID.String()))
+		})
+
+		t.Run("should return 404 if refresh token is not stored", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			tokens, err := d.tokenService.GenerateAuthTokens(ctx, user)
+			assert.NoError(t, err)
+			assert.NoError(t, d.tokens.DeleteByUser(ctx, user.ID.String()))
+
+			err = d.authService.Logout(ctx, &validation.Logout{RefreshToken: tokens.Refresh.Token})
+			assertStatus(t, err, fiber.StatusNotFound)
+		})
+	})
+
+	t.Run("RefreshAuth", func(t *testing.T) {
+		t.Run("should return new tokens if refresh token is valid", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			tokens, err := d.tokenService.GenerateAuthTokens(ctx, user)
+			assert.NoError(t, err)
+
+			newTokens, err := d.authService.RefreshAuth(ctx, &validation.RefreshToken{RefreshToken: tokens.Refresh.Token})
+			assert.NoError(t, err)
+			assert.NotEmpty(t, newTokens.Access.Token)
+
+			assert.True(t, d.storedToken(t, config.TokenTypeRefresh, newTokens.Refresh.Token, user.ID.String()))
+			assert.False(t, d.storedToken(t, config.TokenTypeRefresh, tokens.Refresh.Token, user.ID.String()))
+		})
+
+		t.Run("should return 401 if the refresh token was already used", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			tokens, err := d.tokenService.GenerateAuthTokens(ctx, user)
+			assert.NoError(t, err)
+			other, err := d.tokenService.GenerateAuthTokens(ctx, user)
+			assert.NoError(t, err)
+
+			_, err = d.authService.RefreshAuth(ctx, &validation.RefreshToken{RefreshToken: tokens.Refresh.Token})
+			assert.NoError(t, err)
+
+			_, err = d.authService.RefreshAuth(ctx, &validation.RefreshToken{RefreshToken: tokens.Refresh.Token})
+			assertStatus(t, err, fiber.StatusUnauthorized)
+
+			assert.True(t, d.storedToken(t, config.TokenTypeRefresh, other.Refresh.Token, user.ID.String()))
+		})
+
+		t.Run("should return 401 if refresh token is signed with an invalid secret", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			tokens, err := d.tokenService.GenerateAuthTokens(ctx, user)
+			assert.NoError(t, err)
+
+			other := newAuthDepsOver(d.users, d.tokens, jwtConfig("anothersecret"))
+			_, err = other.authService.RefreshAuth(ctx, &validation.RefreshToken{RefreshToken: tokens.Refresh.Token})
+			assertStatus(t, err, fiber.StatusUnauthorized)
+		})
+
+		t.Run("should return 401 if the user is deleted", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			tokens, err := d.tokenService.GenerateAuthTokens(ctx, user)
+			assert.NoError(t, err)
+			assert.NoError(t, d.users.Delete(ctx, user.ID.String(), 0))
+
+			_, err = d.authService.RefreshAuth(ctx, &validation.RefreshToken{RefreshToken: tokens.Refresh.Token})
+			assertStatus(t, err, fiber.StatusUnauthorized)
+		})
+	})
+
+	t.Run("ResetPassword", func(t *testing.T) {
+		t.Run("should update the password and delete reset tokens", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			token, err := d.tokenService.GenerateResetPasswordToken(ctx, &validation.ForgotPassword{Email: user.Email})
+			assert.NoError(t, err)
+
+			err = d.authService.ResetPassword(ctx, &validation.Token{Token: token},
+				&validation.UpdatePassOrVerify{Password: "newPassword1"})
+			assert.NoError(t, err)
+
+			_, err = d.authService.Login(ctx, &validation.Login{Email: user.Email, Password: "newPassword1"})
+			assert.NoError(t, err)
+
+			assert.False(t, d.storedToken(t, config.TokenTypeResetPassword, token, user.ID.String()))
+		})
+
+		t.Run("should keep the old password if deleting the tokens fails", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			token, err := d.tokenService.GenerateResetPasswordToken(ctx, &validation.ForgotPassword{Email: user.Email})
+			assert.NoError(t, err)
+
+			d.tokens.Fail["DeleteByType"] = true
+			err = d.authService.ResetPassword(ctx, &validation.Token{Token: token},
+				&validation.UpdatePassOrVerify{Password: "newPassword1"})
+			assert.ErrorIs(t, err, helper.ErrInjected)
+
+			_, err = d.authService.Login(ctx, &validation.Login{Email: user.Email, Password: "password1"})
+			assert.NoError(t, err)
+
+			assert.True(t, d.storedToken(t, config.TokenTypeResetPassword, token, user.ID.String()))
+		})
+
+		t.Run("should return 401 if token is not a reset password token", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			tokens, err := d.tokenService.GenerateAuthTokens(ctx, user)
+			assert.NoError(t, err)
+
+			err = d.authService.ResetPassword(ctx, &validation.Token{Token: tokens.Access.Token},
+				&validation.UpdatePassOrVerify{Password: "newPassword1"})
+			assertStatus(t, err, fiber.StatusUnauthorized)
+		})
+	})
+
+	t.Run("VerifyEmail", func(t *testing.T) {
+		t.Run("should mark the email as verified and delete verify tokens", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			token, err := d.tokenService.GenerateVerifyEmailToken(ctx, user)
+			assert.NoError(t, err)
+
+			assert.NoError(t, d.authService.VerifyEmail(ctx, &validation.Token{Token: *token}))
+
+			stored, err := d.users.FindByID(ctx, user.ID.String())
+			assert.NoError(t, err)
+			assert.True(t, stored.VerifiedEmail)
+
+			assert.False(t, d.storedToken(t, config.TokenTypeVerifyEmail, *token, user.ID.String()))
+		})
+
+		t.Run("should keep the verify token if updating the user fails", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			token, err := d.tokenService.GenerateVerifyEmailToken(ctx, user)
+			assert.NoError(t, err)
+
+			d.users.Fail["Update"] = true
+			err = d.authService.VerifyEmail(ctx, &validation.Token{Token: *token})
+			assert.ErrorIs(t, err, helper.ErrInjected)
+
+			stored, err := d.users.FindByID(ctx, user.ID.String())
+			assert.NoError(t, err)
+			assert.False(t, stored.VerifiedEmail)
+
+			assert.True(t, d.storedToken(t, config.TokenTypeVerifyEmail, *token, user.ID.String()))
+		})
+
+		t.Run("should return 401 if the user no longer exists", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			token, err := d.tokenService.GenerateVerifyEmailToken(ctx, user)
+			assert.NoError(t, err)
+			assert.NoError(t, d.users.Delete(ctx, user.ID.String(), 0))
+
+			err = d.authService.VerifyEmail(ctx, &validation.Token{Token: *token})
+			assertStatus(t, err, fiber.StatusUnauthorized)
+		})
+	})
+}
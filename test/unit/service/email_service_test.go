@@ -0,0 +1,143 @@
+package service_test
+
+import (
+	"app/src/config"
+	"app/src/i18n"
+	"app/src/service"
+	"app/test/helper"
+	"context"
+	"io"
+	"mime/quotedprintable"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailService(t *testing.T) {
+	ctx := context.Background()
+
+	newEmailService := func(t *testing.T, templatesDir string) (service.EmailService, *helper.SMTPServer, *config.Live) {
+		server := helper.StartSMTPServer(t)
+		cfg := &config.Config{SMTP: config.SMTP{
+			Host:         server.Addr.IP.String(),
+			Port:         server.Addr.Port,
+			From:         "support@yourapp.com",
+			TemplatesDir: templatesDir,
+		}}
+		live := config.NewLive(cfg, func() (*config.Config, error) { return cfg, nil })
+
+		return service.NewEmailService(live), server, live
+	}
+
+	t.Run("should send the built-in templates", func(t *testing.T) {
+		emailService, server, _ := newEmailService(t, "")
+
+		assert.NoError(t, emailService.SendResetPasswordEmail(ctx, "user@gmail.com", "abc"))
+
+		messages := server.Messages()
+		assert.Len(t, messages, 1)
+		assert.Contains(t, messages[0], "Subject: Reset password")
+		assert.Contains(t, messages[0], "To reset your password, click on this link")
+	})
+
+	t.Run("should send the data export and confirm erasure emails", func(t *testing.T) {
+		emailService, server, _ := newEmailService(t, "")
+
+		expires := time.Date(2024, time.November, 5, 10, 0, 0, 0, time.UTC)
+		assert.NoError(t, emailService.SendDataExportEmail(ctx, "user@gmail.com", "abc", expires))
+		assert.NoError(t, emailService.SendConfirmErasureEmail(ctx, "user@gmail.com", "def", 14*24*time.Hour))
+
+		// The bodies are quoted-printable, which wraps the links
+		messages := server.Messages()
+		assert.Len(t, messages, 2)
+		for i, message := range messages {
+			decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(message)))
+			assert.NoError(t, err)
+			messages[i] = string(decoded)
+		}
+		assert.Contains(t, messages[0], "Subject: Your data export")
+		assert.Contains(t, messages[0], "/account/exports/abc")
+		assert.Contains(t, messages[0], "deleted on 2024-11-05")
+		assert.Contains(t, messages[1], "Subject: Confirm the erasure of your account")
+		assert.Contains(t, messages[1], "/confirm-erasure?token=def")
+		assert.Contains(t, messages[1], "erased 14 days after you confirm")
+	})
+
+	t.Run("should send the invitation with the days it lasts", func(t *testing.T) {
+		emailService, server, _ := newEmailService(t, "")
+
+		assert.NoError(t, emailService.SendInvitationEmail(ctx, "user@gmail.com", "abc", 7*24*time.Hour))
+
+		messages := server.Messages()
+		assert.Len(t, messages, 1)
+		decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(messages[0])))
+		assert.NoError(t, err)
+		assert.Contains(t, string(decoded), "Subject: You are invited")
+		assert.Contains(t, string(decoded), "/reset-password?token=abc")
+		assert.Contains(t, string(decoded), "valid for 7 days")
+	})
+
+	t.Run("should send the reauth link", func(t *testing.T) {
+		emailService, server, _ := newEmailService(t, "")
+
+		assert.NoError(t, emailService.SendReauthEmail(ctx, "user@gmail.com", "abc"))
+
+		messages := server.Messages()
+		assert.Len(t, messages, 1)
+		decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(messages[0])))
+		assert.NoError(t, err)
+		assert.Contains(t, string(decoded), "Subject: Confirm it is you")
+		assert.Contains(t, string(decoded), "/reauth?token=abc")
+	})
+
+	t.Run("should send the emails in the language of the context", func(t *testing.T) {
+		emailService, server, _ := newEmailService(t, "")
+
+		assert.NoError(t, emailService.SendVerificationEmail(i18n.WithLanguage(ctx, "id"), "user@gmail.com", "abc"))
+
+		messages := server.Messages()
+		assert.Len(t, messages, 1)
+		assert.Contains(t, messages[0], "Subject: Verifikasi Email")
+		assert.Contains(t, messages[0], "Untuk memverifikasi email Anda, klik tautan ini")
+	})
+
+	t.Run("should use the templates of the templates dir and parse them again on reload", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "verify-email.tmpl")
+		template := `{{define "verify-email-subject"}}Confirm your email{{end}}Hello, open {{.URL}}`
+		assert.NoError(t, os.WriteFile(path, []byte(template), 0o600))
+
+		emailService, server, live := newEmailService(t, dir)
+		assert.NoError(t, emailService.SendVerificationEmail(ctx, "user@gmail.com", "abc"))
+
+		template = `{{define "verify-email-subject"}}Welcome{{end}}Hi again`
+		assert.NoError(t, os.WriteFile(path, []byte(template), 0o600))
+		assert.NoError(t, live.Reload())
+		assert.NoError(t, emailService.SendVerificationEmail(ctx, "user@gmail.com", "abc"))
+
+		messages := server.Messages()
+		assert.Len(t, messages, 2)
+		assert.Contains(t, messages[0], "Subject: Confirm your email")
+		assert.Contains(t, messages[0], "Hello, open")
+		assert.Contains(t, messages[1], "Subject: Welcome")
+		assert.Contains(t, messages[1], "Hi again")
+	})
+
+	t.Run("should keep the current templates if the new ones don't parse", func(t *testing.T) {
+		dir := t.TempDir()
+		emailService, server, live := newEmailService(t, dir)
+
+		broken := `{{define "reset-password-subject"}}Reset{{end}}{{.URL`
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "reset-password.tmpl"), []byte(broken), 0o600))
+		assert.NoError(t, live.Reload())
+		assert.NoError(t, emailService.SendResetPasswordEmail(ctx, "user@gmail.com", "abc"))
+
+		messages := server.Messages()
+		assert.Len(t, messages, 1)
+		assert.Contains(t, messages[0], "Subject: Reset password")
+	})
+}
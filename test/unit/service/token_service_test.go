@@ -0,0 +1,95 @@
+package service_test
+
+import (
+	"app/src/config"
+	"app/src/repository"
+	"app/src/utils"
+	"app/src/validation"
+	"app/test/helper"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenService(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("SaveToken", func(t *testing.T) {
+		t.Run("should replace the previous token of the same type", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			userID := user.ID.String()
+			expires := time.Now().Add(time.Hour)
+
+			assert.NoError(t, d.tokenService.SaveToken(ctx, "first", userID, config.TokenTypeVerifyEmail, expires))
+			assert.NoError(t, d.tokenService.SaveToken(ctx, "second", userID, config.TokenTypeVerifyEmail, expires))
+
+			assert.False(t, d.storedToken(t, config.TokenTypeVerifyEmail, "first", userID))
+			assert.True(t, d.storedToken(t, config.TokenTypeVerifyEmail, "second", userID))
+		})
+
+		t.Run("should keep one refresh token per session", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			userID := user.ID.String()
+			expires := time.Now().Add(time.Hour)
+
+			assert.NoError(t, d.tokenService.SaveToken(ctx, "first", userID, config.TokenTypeRefresh, expires))
+			assert.NoError(t, d.tokenService.SaveToken(ctx, "second", userID, config.TokenTypeRefresh, expires))
+
+			assert.True(t, d.storedToken(t, config.TokenTypeRefresh, "first", userID))
+			assert.True(t, d.storedToken(t, config.TokenTypeRefresh, "second", userID))
+		})
+
+		t.Run("should store only the hash of the token", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			userID := user.ID.String()
+
+			assert.NoError(t, d.tokenService.SaveToken(ctx, "raw", userID, config.TokenTypeRefresh, time.Now().Add(time.Hour)))
+
+			tokenDoc, err := d.tokens.FindByType(ctx, config.TokenTypeRefresh, userID)
+			assert.NoError(t, err)
+			assert.Equal(t, utils.HashToken("raw"), tokenDoc.Token)
+		})
+
+		t.Run("should keep the previous token if saving the new one fails", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+			userID := user.ID.String()
+			expires := time.Now().Add(time.Hour)
+
+			assert.NoError(t, d.tokenService.SaveToken(ctx, "first", userID, config.TokenTypeVerifyEmail, expires))
+
+			d.tokens.Fail["Create"] = true
+			err := d.tokenService.SaveToken(ctx, "second", userID, config.TokenTypeVerifyEmail, expires)
+			assert.ErrorIs(t, err, helper.ErrInjected)
+
+			assert.True(t, d.storedToken(t, config.TokenTypeVerifyEmail, "first", userID))
+		})
+	})
+	t.Run("VerifyToken", func(t *testing.T) {
+		t.Run("should reject a valid token that was replaced by a newer one", func(t *testing.T) {
+			d := newAuthDeps()
+			user := d.register(t)
+
+			first, err := d.tokenService.GenerateResetPasswordToken(ctx, &validation.ForgotPassword{Email: user.Email})
+			assert.NoError(t, err)
+
+			// A later token of the same type replaces the first one
+			second, err := d.tokenService.GenerateToken(user.ID.String(), time.Now().Add(time.Hour), config.TokenTypeResetPassword)
+			assert.NoError(t, err)
+			assert.NoError(t, d.tokenService.SaveToken(ctx, second, user.ID.String(),
+				config.TokenTypeResetPassword, time.Now().Add(time.Hour)))
+
+			_, err = d.tokenService.VerifyToken(ctx, first, config.TokenTypeResetPassword)
+			assert.ErrorIs(t, err, repository.ErrNotFound)
+
+			tokenDoc, err := d.tokenService.VerifyToken(ctx, second, config.TokenTypeResetPassword)
+			assert.NoError(t, err)
+			assert.Equal(t, user.ID, tokenDoc.UserID)
+		})
+	})
+}
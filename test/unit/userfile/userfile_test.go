@@ -0,0 +1,139 @@
+package userfile_test
+
+import (
+	"app/src/model"
+	"app/src/userfile"
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRead(t *testing.T) {
+	t.Run("should read the known CSV columns whatever their order and case", func(t *testing.T) {
+		data := []byte("\xef\xbb\xbfTeam, EMAIL ,name,Password\n" +
+			"sales, jane@gmail.com ,Jane, pass word1\n" +
+			"\"ops\nteam\",john@gmail.com,John\n")
+
+		rows, err := userfile.Read(data, userfile.FormatCSV)
+		assert.NoError(t, err)
+		assert.Len(t, rows, 2)
+
+		assert.Equal(t, 2, rows[0].Line)
+		assert.Equal(t, "jane@gmail.com", rows[0].User.Email)
+		assert.Equal(t, "Jane", rows[0].User.Name)
+		assert.Equal(t, " pass word1", rows[0].User.Password)
+
+		assert.Equal(t, 3, rows[1].Line)
+		assert.Equal(t, "john@gmail.com", rows[1].User.Email)
+		assert.Empty(t, rows[1].User.Password)
+	})
+
+	t.Run("should report the CSV rows which can't be parsed and go on", func(t *testing.T) {
+		data := []byte("name,email\nJa\"ne,jane@gmail.com\nJohn,john@gmail.com\n")
+
+		rows, err := userfile.Read(data, userfile.FormatCSV)
+		assert.NoError(t, err)
+		assert.Len(t, rows, 2)
+		assert.Equal(t, 2, rows[0].Line)
+		assert.ErrorIs(t, rows[0].Err, userfile.ErrInvalidRow)
+		assert.Equal(t, 3, rows[1].Line)
+		assert.NoError(t, rows[1].Err)
+	})
+
+	t.Run("should reject a CSV file without an email column", func(t *testing.T) {
+		_, err := userfile.Read([]byte("name,role\nJane,user\n"), userfile.FormatCSV)
+		assert.ErrorIs(t, err, userfile.ErrInvalidFile)
+
+		_, err = userfile.Read(nil, userfile.FormatCSV)
+		assert.ErrorIs(t, err, userfile.ErrInvalidFile)
+	})
+
+	t.Run("should reject a CSV file with a header only", func(t *testing.T) {
+		_, err := userfile.Read([]byte("name,email\n"), userfile.FormatCSV)
+		assert.ErrorIs(t, err, userfile.ErrInvalidFile)
+
+		_, err = userfile.Read([]byte("\xef\xbb\xbfname,email"), userfile.FormatCSV)
+		assert.ErrorIs(t, err, userfile.ErrInvalidFile)
+	})
+
+	t.Run("should read a NDJSON object per line and skip the blank lines", func(t *testing.T) {
+		data := []byte(`{"name":"Jane","email":"jane@gmail.com"}` + "\n\n" + `{"email":` + "\r\n")
+
+		rows, err := userfile.Read(data, userfile.FormatNDJSON)
+		assert.NoError(t, err)
+		assert.Len(t, rows, 2)
+		assert.Equal(t, 1, rows[0].Line)
+		assert.Equal(t, "jane@gmail.com", rows[0].User.Email)
+		assert.Equal(t, 3, rows[1].Line)
+		assert.ErrorIs(t, rows[1].Err, userfile.ErrInvalidRow)
+
+		_, err = userfile.Read([]byte("\n\n"), userfile.FormatNDJSON)
+		assert.ErrorIs(t, err, userfile.ErrInvalidFile)
+	})
+
+	t.Run("should tell the format from the extension", func(t *testing.T) {
+		assert.Equal(t, userfile.FormatCSV, userfile.FormatOf("users.CSV"))
+		assert.Equal(t, userfile.FormatNDJSON, userfile.FormatOf("users.jsonl"))
+		assert.Empty(t, userfile.FormatOf("users.txt"))
+
+		_, err := userfile.Read(nil, "xml")
+		assert.ErrorIs(t, err, userfile.ErrUnknownFormat)
+	})
+}
+
+func TestWriter(t *testing.T) {
+	user := &model.User{ID: uuid.MustParse("e088d183-9eea-4a11-8d5d-74d7ec91bdf5"), Name: "Jane",
+		Email: "jane@gmail.com", Password: "secret", Role: "user", VerifiedEmail: true}
+
+	t.Run("should write a CSV header and a record per user", func(t *testing.T) {
+		var out bytes.Buffer
+		writer, err := userfile.NewWriter(&out, userfile.FormatCSV)
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Write(user))
+		assert.NoError(t, writer.Flush())
+
+		assert.Equal(t, "id,name,email,role,verified_email,language,avatar_url\n"+
+			"e088d183-9eea-4a11-8d5d-74d7ec91bdf5,Jane,jane@gmail.com,user,true,,\n", out.String())
+	})
+
+	t.Run("should quote the CSV values a spreadsheet would run as formulas", func(t *testing.T) {
+		values := []string{"=HYPERLINK(\"http://evil\")", "+1", "-1", "@SUM(A1)", "\tJane", "\rJane"}
+		for _, value := range values {
+			var out bytes.Buffer
+			writer, err := userfile.NewWriter(&out, userfile.FormatCSV)
+			assert.NoError(t, err)
+			assert.NoError(t, writer.Write(&model.User{ID: user.ID, Name: value, Email: value, Role: "user"}))
+			assert.NoError(t, writer.Flush())
+
+			records, err := csv.NewReader(&out).ReadAll()
+			assert.NoError(t, err)
+			assert.Len(t, records, 2)
+			assert.Equal(t, "'"+value, records[1][1])
+			assert.Equal(t, "'"+value, records[1][2])
+		}
+
+		var out bytes.Buffer
+		writer, err := userfile.NewWriter(&out, userfile.FormatCSV)
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Write(&model.User{ID: user.ID, Name: "Jane = Doe", Email: "jane@gmail.com"}))
+		assert.NoError(t, writer.Flush())
+		assert.Contains(t, out.String(), ",Jane = Doe,jane@gmail.com,")
+	})
+
+	t.Run("should write the users as the API returns them without their password", func(t *testing.T) {
+		var out bytes.Buffer
+		writer, err := userfile.NewWriter(&out, userfile.FormatNDJSON)
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Write(user))
+		assert.NoError(t, writer.Write(user))
+		assert.NoError(t, writer.Flush())
+
+		lines := bytes.Split(bytes.TrimSuffix(out.Bytes(), []byte("\n")), []byte("\n"))
+		assert.Len(t, lines, 2)
+		assert.Contains(t, string(lines[0]), `"email":"jane@gmail.com"`)
+		assert.NotContains(t, out.String(), "secret")
+	})
+}
@@ -0,0 +1,154 @@
+package utils_test
+
+import (
+	"app/src/i18n"
+	"app/src/problem"
+	"app/src/utils"
+	"app/src/validation"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type register struct {
+	Name    string `json:"name" validate:"required"`
+	Email   string `json:"email" validate:"required,email"`
+	Address struct {
+		City string `json:"city" validate:"required"`
+	} `json:"address"`
+}
+
+func sendError(t *testing.T, handler fiber.ErrorHandler, err error) (int, string, map[string]interface{}) {
+	t.Helper()
+	return sendErrorIn(t, handler, err, i18n.Default)
+}
+
+func sendErrorIn(t *testing.T, handler fiber.ErrorHandler, err error, lang string) (int, string, map[string]interface{}) {
+	t.Helper()
+
+	app := fiber.New(fiber.Config{ErrorHandler: handler})
+	app.Get("/v1/users/:userId", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderXRequestID, "request-1")
+		c.SetUserContext(i18n.WithLanguage(c.UserContext(), lang))
+		return err
+	})
+
+	res, testErr := app.Test(httptest.NewRequest(fiber.MethodGet, "/v1/users/1?token=secret", nil))
+	assert.NoError(t, testErr)
+
+	bytes, readErr := io.ReadAll(res.Body)
+	assert.NoError(t, readErr)
+
+	body := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal(bytes, &body))
+
+	return res.StatusCode, res.Header.Get(fiber.HeaderContentType), body
+}
+
+func TestErrorHandler(t *testing.T) {
+	t.Run("should render the errors of the catalogue as problems", func(t *testing.T) {
+		status, contentType, body := sendError(t, utils.ErrorHandler, problem.ErrUserNotFound)
+
+		assert.Equal(t, fiber.StatusNotFound, status)
+		assert.Equal(t, "application/problem+json", contentType)
+		assert.Equal(t, map[string]interface{}{
+			"type":       "/v1/problems/user-not-found",
+			"title":      "User not found",
+			"status":     float64(fiber.StatusNotFound),
+			"instance":   "/v1/users/1",
+			"code":       "user-not-found",
+			"request_id": "request-1",
+		}, body)
+	})
+
+	t.Run("should add the detail of the occurrence", func(t *testing.T) {
+		_, _, body := sendError(t, utils.ErrorHandler, problem.ErrEmailTaken.WithDetail("Email already taken"))
+
+		assert.Equal(t, "Email is already in use", body["title"])
+		assert.Equal(t, "Email already taken", body["detail"])
+		assert.Equal(t, "email-taken", body["code"])
+	})
+
+	t.Run("should key the field errors by their json path", func(t *testing.T) {
+		err := validation.Validator().Struct(&register{Email: "invalid"})
+		status, _, body := sendError(t, utils.ErrorHandler, err)
+
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Equal(t, "validation-failed", body["code"])
+		assert.Equal(t, map[string]interface{}{
+			"name":         map[string]interface{}{"code": "required", "message": "Field name must be filled"},
+			"email":        map[string]interface{}{"code": "email", "message": "Invalid email address for field email"},
+			"address.city": map[string]interface{}{"code": "required", "message": "Field city must be filled"},
+		}, body["errors"])
+	})
+
+	t.Run("should translate the title and the field errors", func(t *testing.T) {
+		_, _, body := sendErrorIn(t, utils.ErrorHandler, problem.ErrUserNotFound, "id")
+		assert.Equal(t, "Pengguna tidak ditemukan", body["title"])
+		assert.Equal(t, "user-not-found", body["code"])
+
+		err := validation.Validator().Struct(&register{Name: "Test", Email: "test@gmail.com"})
+		_, _, body = sendErrorIn(t, utils.ErrorHandler, err, "id")
+		assert.Equal(t, "Validasi gagal", body["title"])
+		assert.Equal(t, map[string]interface{}{
+			"address.city": map[string]interface{}{"code": "required", "message": "Kolom city wajib diisi"},
+		}, body["errors"])
+	})
+
+	t.Run("should derive the code of the errors of the framework from their status", func(t *testing.T) {
+		status, _, body := sendError(t, utils.ErrorHandler, fiber.ErrMethodNotAllowed)
+
+		assert.Equal(t, fiber.StatusMethodNotAllowed, status)
+		assert.Equal(t, "method-not-allowed", body["code"])
+		assert.Equal(t, "Method Not Allowed", body["title"])
+	})
+
+	t.Run("should hide the unhandled errors", func(t *testing.T) {
+		status, _, body := sendError(t, utils.ErrorHandler, errors.New("connection refused"))
+
+		assert.Equal(t, fiber.StatusInternalServerError, status)
+		assert.Equal(t, "internal-error", body["code"])
+		assert.NotContains(t, body, "detail")
+	})
+}
+
+func TestLegacyErrorHandler(t *testing.T) {
+	t.Run("should render the errors of the catalogue with their message", func(t *testing.T) {
+		status, contentType, body := sendError(t, utils.LegacyErrorHandler,
+			problem.ErrEmailTaken.WithDetail("Email already taken"))
+
+		assert.Equal(t, fiber.StatusConflict, status)
+		assert.True(t, strings.HasPrefix(contentType, fiber.MIMEApplicationJSON))
+		assert.Equal(t, map[string]interface{}{
+			"code":    float64(fiber.StatusConflict),
+			"status":  "error",
+			"message": "Email already taken",
+		}, body)
+	})
+
+	t.Run("should translate the messages of the catalogue without detail", func(t *testing.T) {
+		_, _, body := sendErrorIn(t, utils.LegacyErrorHandler, problem.ErrUserNotFound, "id")
+		assert.Equal(t, "Pengguna tidak ditemukan", body["message"])
+
+		_, _, body = sendErrorIn(t, utils.LegacyErrorHandler, problem.ErrEmailTaken.WithDetail("Email already taken"), "id")
+		assert.Equal(t, "Email already taken", body["message"])
+	})
+
+	t.Run("should key the field errors by their Go namespace", func(t *testing.T) {
+		err := validation.Validator().Struct(&register{Name: "Test", Email: "invalid"})
+		status, _, body := sendError(t, utils.LegacyErrorHandler, err)
+
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Equal(t, "Bad Request", body["message"])
+		assert.Equal(t, map[string]interface{}{
+			"register.Email":        "Invalid email address for field Email",
+			"register.Address.City": "Field City must be filled",
+		}, body["errors"])
+	})
+}
@@ -0,0 +1,27 @@
+package utils_test
+
+import (
+	"app/src/utils"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	t.Run("should return the global logger if the context has none", func(t *testing.T) {
+		entry := utils.Logger(context.Background())
+
+		assert.Same(t, utils.Log, entry.Logger)
+		assert.Empty(t, entry.Data)
+	})
+
+	t.Run("should return the logger carried by the context", func(t *testing.T) {
+		ctx := utils.WithLogger(context.Background(), utils.Log.WithField("request_id", "abc"))
+		ctx = utils.WithLogger(ctx, utils.Logger(ctx).WithField("user_id", "42"))
+
+		entry := utils.Logger(ctx)
+		assert.Equal(t, "abc", entry.Data["request_id"])
+		assert.Equal(t, "42", entry.Data["user_id"])
+	})
+}